@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	cmdnode "github.com/celestiaorg/celestia-node/cmd"
+)
+
+func init() {
+	headerCmd.AddCommand(
+		cmdnode.Dump(),
+	)
+}
+
+var headerCmd = &cobra.Command{
+	Use:   "header [subcommand]",
+	Args:  cobra.NoArgs,
+	Short: "Inspect the headers stored by your node",
+}
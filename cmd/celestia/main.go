@@ -12,7 +12,9 @@ import (
 func init() {
 	rootCmd.AddCommand(
 		bridgeCmd,
+		headerCmd,
 		lightCmd,
+		storeCmd,
 		versionCmd,
 	)
 	rootCmd.SetHelpCommand(&cobra.Command{})
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	cmdnode "github.com/celestiaorg/celestia-node/cmd"
+)
+
+func init() {
+	storeCmd.AddCommand(
+		cmdnode.Migrate(),
+		cmdnode.Verify(),
+		cmdnode.Compact(),
+	)
+}
+
+var storeCmd = &cobra.Command{
+	Use:   "store [subcommand]",
+	Args:  cobra.NoArgs,
+	Short: "Manage the Store of your node",
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/celestiaorg/celestia-node/node"
+)
+
+// Compact constructs a CLI command to compact the Store of Celestia Node of any type.
+func Compact() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "compact",
+		Short:        "Reclaims the disk space taken by deleted data, e.g. after pruning, from the Store of a stopped Node.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := cmd.Flag(nodeStoreFlag).Value.String()
+			if path == "" {
+				return fmt.Errorf("cmd: '%s' flag is required", nodeStoreFlag)
+			}
+
+			return node.CompactStore(cmd.Context(), path)
+		},
+	}
+
+	cmd.Flags().String(nodeStoreFlag, "", "The path to root/home directory of your Celestia Node Store")
+	return cmd
+}
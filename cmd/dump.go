@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/celestiaorg/celestia-node/node"
+)
+
+var (
+	dumpFromFlag   = "from"
+	dumpToFlag     = "to"
+	dumpFormatFlag = "format"
+)
+
+// Dump constructs a CLI command to write the headers stored by Celestia Node of any type to stdout.
+func Dump() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "dump",
+		Short:        "Writes the headers stored by a stopped Node to stdout for debugging.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := cmd.Flag(nodeStoreFlag).Value.String()
+			if path == "" {
+				return fmt.Errorf("cmd: '%s' flag is required", nodeStoreFlag)
+			}
+
+			from, err := cmd.Flags().GetUint64(dumpFromFlag)
+			if err != nil {
+				return err
+			}
+			to, err := cmd.Flags().GetUint64(dumpToFlag)
+			if err != nil {
+				return err
+			}
+			format := cmd.Flag(dumpFormatFlag).Value.String()
+
+			return node.DumpHeaders(cmd.Context(), path, from, to, node.DumpFormat(format), cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().String(nodeStoreFlag, "", "The path to root/home directory of your Celestia Node Store")
+	cmd.Flags().Uint64(dumpFromFlag, 0, "The height to dump headers from. Defaults to the tail of the store")
+	cmd.Flags().Uint64(dumpToFlag, 0, "The height to dump headers up to, exclusive. Defaults to after the head")
+	cmd.Flags().String(dumpFormatFlag, string(node.DumpJSON), "The format to dump headers in: json or proto")
+	return cmd
+}
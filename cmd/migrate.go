@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/celestiaorg/celestia-node/node"
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+var (
+	migrateFromFlag   = "from"
+	migrateToFlag     = "to"
+	migrateDryRunFlag = "dry-run"
+)
+
+// Migrate constructs a CLI command to migrate the header store of Celestia Node of any type
+// between schema versions.
+func Migrate() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "migrate",
+		Short:        "Migrates the header store of a stopped Node between schema versions.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := cmd.Flag(nodeStoreFlag).Value.String()
+			if path == "" {
+				return fmt.Errorf("cmd: '%s' flag is required", nodeStoreFlag)
+			}
+
+			from, err := cmd.Flags().GetUint64(migrateFromFlag)
+			if err != nil {
+				return err
+			}
+			to, err := cmd.Flags().GetUint64(migrateToFlag)
+			if err != nil {
+				return err
+			}
+
+			var opts []node.MigrateOption
+			dryRun, err := cmd.Flags().GetBool(migrateDryRunFlag)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				opts = append(opts, node.WithDryRun())
+			}
+
+			return node.MigrateStore(cmd.Context(), path, header.SchemaVersion(from), header.SchemaVersion(to), opts...)
+		},
+	}
+
+	cmd.Flags().String(nodeStoreFlag, "", "The path to root/home directory of your Celestia Node Store")
+	cmd.Flags().Uint64(migrateFromFlag, uint64(header.SchemaV1), "The schema version the store is kept with")
+	cmd.Flags().Uint64(migrateToFlag, uint64(header.CurrentSchema), "The schema version to migrate the store to")
+	cmd.Flags().Bool(migrateDryRunFlag, false, "Only log what would change, without writing anything")
+	return cmd
+}
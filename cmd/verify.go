@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/celestiaorg/celestia-node/node"
+)
+
+// Verify constructs a CLI command to check the consistency of the header store of Celestia Node of any type.
+// The command fails if any header is missing or does not link to the previous one.
+func Verify() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "verify",
+		Short:        "Verifies that the header store of a stopped Node is consistent from its tail to its head.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := cmd.Flag(nodeStoreFlag).Value.String()
+			if path == "" {
+				return fmt.Errorf("cmd: '%s' flag is required", nodeStoreFlag)
+			}
+
+			return node.VerifyStore(cmd.Context(), path)
+		},
+	}
+
+	cmd.Flags().String(nodeStoreFlag, "", "The path to root/home directory of your Celestia Node Store")
+	return cmd
+}
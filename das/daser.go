@@ -2,10 +2,15 @@ package das
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
+	"github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log/v2"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
 
 	"github.com/celestiaorg/celestia-node/service/header"
 	"github.com/celestiaorg/celestia-node/service/share"
@@ -13,87 +18,367 @@ import (
 
 var log = logging.Logger("das")
 
+var (
+	meter = metric.Must(global.Meter("das"))
+	// headersSampled counts headers whose data availability was sampled.
+	headersSampled = meter.NewInt64Counter("das_sampled_total",
+		metric.WithDescription("Amount of headers sampled for data availability"))
+	// samplingFailures counts failed sampling attempts.
+	samplingFailures = meter.NewInt64Counter("das_sampling_failed_total",
+		metric.WithDescription("Amount of failed data availability sampling attempts"))
+)
+
+// DefaultFraudThreshold is the default amount of sampling attempts of a header finding its data unavailable,
+// after which the header is suspected to be malformed and a fraud proof is required.
+var DefaultFraudThreshold = 3
+
+// DefaultRetryBackoff is the default delay before sampling a header again after a failed attempt.
+// It doubles with every following attempt, up to maxRetryBackoff.
+var DefaultRetryBackoff = time.Second
+
+// DefaultMaxAttempts is the default amount of sampling attempts of a header, after which DASer gives up on it
+// and moves on to the following headers, leaving it not sampled.
+var DefaultMaxAttempts = 10
+
+// maxRetryBackoff caps the delay between sampling attempts of a header.
+var maxRetryBackoff = time.Minute
+
+// FraudTrigger is notified about the headers suspected to be malformed, e.g. fraud.Service.
+type FraudTrigger interface {
+	TriggerFraudProof(context.Context, *header.ExtendedHeader) error
+}
+
+// NetworkHead provides the heads of the network for the DASer to sample, e.g. header.NetworkHeadTracker.
+type NetworkHead interface {
+	WatchNetworkHead(context.Context) (<-chan *header.ExtendedHeader, error)
+}
+
+// Option is a functional option that configures DASer.
+type Option func(*DASer)
+
+// WithNetworkHead makes DASer sample the heads of the network provided by the given NetworkHead,
+// instead of the heads appended to the header.Store, so it follows the network even if the
+// header.Store is behind.
+func WithNetworkHead(head NetworkHead) Option {
+	return func(d *DASer) {
+		d.network = head
+	}
+}
+
+// WithFraudTrigger makes DASer notify the given FraudTrigger about every header whose data
+// failed sampling the threshold amount of times.
+func WithFraudTrigger(trigger FraudTrigger) Option {
+	return func(d *DASer) {
+		d.trigger = trigger
+	}
+}
+
+// WithFraudThreshold sets the amount of sampling attempts of a header finding its data unavailable,
+// after which a fraud proof is required. Failed headers are sampled again until then.
+// Defaults to DefaultFraudThreshold.
+func WithFraudThreshold(threshold int) Option {
+	return func(d *DASer) {
+		d.threshold = threshold
+	}
+}
+
+// WithMaxAttempts sets the amount of sampling attempts of a header, after which DASer gives up on it,
+// so failures other than unavailability do not stall sampling of the following headers.
+// Defaults to DefaultMaxAttempts.
+func WithMaxAttempts(attempts int) Option {
+	return func(d *DASer) {
+		d.maxAttempts = attempts
+	}
+}
+
+// WithRetryBackoff sets the delay before sampling a header again after a failed attempt.
+// Defaults to DefaultRetryBackoff.
+func WithRetryBackoff(backoff time.Duration) Option {
+	return func(d *DASer) {
+		d.backoff = backoff
+	}
+}
+
 // DASer continuously validates availability of data committed to headers.
 // TODO(@Wondertan): Start and Stop is better be thread-safe.
 type DASer struct {
-	da   share.Availability
-	hsub header.Subscriber
+	da      share.Availability
+	store   header.Store
+	sampled *sampledStore
+	// network provides the heads to sample instead of the store, if set
+	network NetworkHead
+
+	// trigger is notified about suspected headers, if set
+	trigger     FraudTrigger
+	threshold   int
+	maxAttempts int
+	backoff     time.Duration
+	// fraudRequired is set once any header failed sampling the threshold amount of times; accessed atomically
+	fraudRequired int32
+	// last is the height of the last head received from the store, kept across restarts,
+	// so the heads skipped in between are sampled as well; accessed atomically
+	last uint64
+	// attempts and active count the sampling attempts made and the ones in progress; accessed atomically
+	attempts uint64
+	active   int32
 
 	cancel context.CancelFunc
 	done   chan struct{}
 }
 
-// NewDASer creates a new DASer.
-func NewDASer(da share.Availability, hsub header.Subscriber) *DASer {
-	return &DASer{
-		da:   da,
-		hsub: hsub,
-		done: make(chan struct{}),
+// NewDASer creates a new DASer sampling every new head appended to the given header.Store.
+// Heights of sampled headers are persisted in the given datastore.
+func NewDASer(da share.Availability, store header.Store, ds datastore.Datastore, opts ...Option) (*DASer, error) {
+	sampled, err := newSampledStore(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DASer{
+		da:          da,
+		store:       store,
+		sampled:     sampled,
+		threshold:   DefaultFraudThreshold,
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultRetryBackoff,
 	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d, nil
 }
 
-// Start initiates subscription for new ExtendedHeaders and spawns a sampling routine.
+// Start starts watching for new heads of the header.Store, or the network if NetworkHead is given,
+// and spawns a sampling routine.
 func (d *DASer) Start(context.Context) error {
 	if d.cancel != nil {
 		return fmt.Errorf("da: DASer already started")
 	}
 
-	sub, err := d.hsub.Subscribe()
-	if err != nil {
-		return err
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		heads <-chan *header.ExtendedHeader
+		err   error
+	)
+	if d.network != nil {
+		// the subscription ends along with sampling, so restarts do not pile them up
+		heads, err = d.network.WatchNetworkHead(ctx)
+		if err != nil {
+			cancel()
+			return err
+		}
+	} else {
+		heads, err = d.store.WatchHead(ctx)
+		if err != nil {
+			cancel()
+			return err
+		}
+		// the headers stored before the first start are not sampled
+		if atomic.LoadUint64(&d.last) == 0 {
+			head, err := d.store.Head(ctx)
+			switch {
+			case err == nil:
+				d.advanceLast(uint64(head.Height))
+			case !errors.Is(err, header.ErrNoHead):
+				cancel()
+				return err
+			}
+		}
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	go d.sampling(ctx, sub)
+	// the DASer can be started again once stopped, e.g. by a Watchdog
+	d.done = make(chan struct{})
+	go d.sampling(ctx, heads, d.done)
 	d.cancel = cancel
 	return nil
 }
 
-// Stop stops sampling.
+// Stop stops sampling. If the context is done before the sampling routine returns,
+// the DASer is still considered stopped and can be started again, e.g. once stuck.
+// The routine left behind is canceled and returns on its own.
 func (d *DASer) Stop(ctx context.Context) error {
 	if d.cancel == nil {
 		return fmt.Errorf("da: DASer already stopped")
 	}
 
 	d.cancel()
+	d.cancel = nil
 	select {
 	case <-d.done:
-		d.cancel = nil
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
 	}
 }
 
-// sampling validates availability for each Header received from header subscription.
-func (d *DASer) sampling(ctx context.Context, sub header.Subscription) {
-	defer sub.Cancel()
-	defer close(d.done)
+// Attempts returns the amount of sampling attempts finished so far, whether successful or not.
+func (d *DASer) Attempts() uint64 {
+	return atomic.LoadUint64(&d.attempts)
+}
+
+// IsSampling reports whether the data of any header is being sampled at the moment.
+func (d *DASer) IsSampling() bool {
+	return atomic.LoadInt32(&d.active) > 0
+}
+
+// SampledHeight returns the highest height of a successfully sampled header.
+func (d *DASer) SampledHeight() uint64 {
+	return d.sampled.SampledHeight()
+}
+
+// FraudProofRequired reports whether the data of any sampled header failed sampling the threshold
+// amount of times, so the header may be malformed and a fraud proof is required.
+func (d *DASer) FraudProofRequired() bool {
+	return atomic.LoadInt32(&d.fraudRequired) == 1
+}
+
+// IsSampled reports whether the header at the given height was successfully sampled.
+func (d *DASer) IsSampled(height uint64) bool {
+	ok, err := d.sampled.IsSampled(height)
+	if err != nil {
+		log.Errorw("checking if height is sampled", "height", height, "err", err)
+		return false
+	}
+	return ok
+}
+
+// sampling validates availability for each new head until the channel is closed or the context is canceled.
+// The given 'done' channel is closed once sampling is over.
+func (d *DASer) sampling(ctx context.Context, heads <-chan *header.ExtendedHeader, done chan struct{}) {
+	defer close(done)
 	for {
-		h, err := sub.NextHeader(ctx)
-		if err != nil {
-			if err == context.Canceled {
+		select {
+		case h, ok := <-heads:
+			if !ok {
 				return
 			}
+			if d.network == nil {
+				d.sampleSkipped(ctx, uint64(h.Height))
+				d.advanceLast(uint64(h.Height))
+			}
+			d.sample(ctx, h)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
 
-			log.Errorw("failed to get next header", "err", err)
-			continue
+// sampleSkipped samples the stored headers between the last received head and the given height,
+// as only the latest head is kept for slow watchers of the store.
+func (d *DASer) sampleSkipped(ctx context.Context, to uint64) {
+	tail, err := d.store.Tail(ctx)
+	if err != nil {
+		// nothing is stored to catch up with
+		return
+	}
+	from := atomic.LoadUint64(&d.last) + 1
+	if uint64(tail.Height) > from {
+		from = uint64(tail.Height)
+	}
+	if from >= to {
+		return
+	}
+
+	headers, err := d.store.GetRangeByHeight(ctx, from, to)
+	if err != nil {
+		log.Errorw("getting skipped headers", "from", from, "to", to, "err", err)
+	}
+	for _, h := range headers {
+		d.sample(ctx, h)
+	}
+}
+
+// advanceLast sets the height of the last head received from the store, unless it is higher already.
+// The routine left behind by the timed out Stop may still advance it.
+func (d *DASer) advanceLast(height uint64) {
+	for {
+		last := atomic.LoadUint64(&d.last)
+		if height <= last || atomic.CompareAndSwapUint64(&d.last, last, height) {
+			return
 		}
+	}
+}
 
+// sample validates availability of the given header, sampling it again after a backoff on failures.
+// Only the attempts finding the data unavailable count towards the threshold, after which
+// a fraud proof is required, while other failures, e.g. of the network, are retried up to the max attempts,
+// after which the header is left not sampled.
+func (d *DASer) sample(ctx context.Context, h *header.ExtendedHeader) {
+	var unavailable int
+	for attempt := 1; ; attempt++ {
 		startTime := time.Now()
 
-		err = d.da.SharesAvailable(ctx, h.DAH)
+		atomic.AddInt32(&d.active, 1)
+		err := d.da.SharesAvailable(ctx, h.DAH)
+		atomic.AddInt32(&d.active, -1)
+		atomic.AddUint64(&d.attempts, 1)
 		if err != nil {
-			if err == context.Canceled {
+			if errors.Is(err, context.Canceled) {
 				return
 			}
-			log.Errorw("sampling failed", "height", h.Height, "hash", h.Hash(),
+			log.Errorw("sampling failed", "height", h.Height, "hash", h.Hash(), "attempt", attempt,
 				"square width", len(h.DAH.RowsRoots), "data root", h.DAH.Hash(), "err", err)
-			// continue sampling
+			samplingFailures.Add(ctx, 1)
+			if errors.Is(err, share.ErrNotAvailable) {
+				unavailable++
+				if unavailable >= d.threshold {
+					headersSampled.Add(ctx, 1)
+					d.requireFraudProof(ctx, h)
+					return
+				}
+			}
+			if attempt >= d.maxAttempts {
+				log.Errorw("giving up sampling", "height", h.Height, "hash", h.Hash(), "attempts", attempt)
+				return
+			}
+			if !d.waitRetry(ctx, attempt) {
+				return
+			}
+			continue
+		}
+		headersSampled.Add(ctx, 1)
+
+		err = d.sampled.MarkSampled(uint64(h.Height))
+		if err != nil {
+			log.Errorw("marking header as sampled", "height", h.Height, "err", err)
 		}
 
 		sampleTime := time.Since(startTime)
 		log.Infow("sampling successful", "height", h.Height, "hash", h.Hash(),
 			"square width", len(h.DAH.RowsRoots), "finished (s)", sampleTime.Seconds())
+		return
+	}
+}
+
+// waitRetry waits for the backoff before the next sampling attempt following the given failed one.
+// It reports false if the context is done in the meantime.
+func (d *DASer) waitRetry(ctx context.Context, attempt int) bool {
+	delay := d.backoff << (attempt - 1)
+	// the shift overflows on long retries
+	if delay > maxRetryBackoff || delay < d.backoff {
+		delay = maxRetryBackoff
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// requireFraudProof flags the given header as suspected to be malformed and notifies the FraudTrigger, if any.
+func (d *DASer) requireFraudProof(ctx context.Context, h *header.ExtendedHeader) {
+	atomic.StoreInt32(&d.fraudRequired, 1)
+	log.Warnw("fraud proof required", "height", h.Height, "hash", h.Hash(), "failed attempts", d.threshold)
+	if d.trigger == nil {
+		return
+	}
+
+	err := d.trigger.TriggerFraudProof(ctx, h)
+	if err != nil {
+		log.Errorw("triggering fraud proof", "height", h.Height, "err", err)
 	}
 }
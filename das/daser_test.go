@@ -2,10 +2,16 @@ package das
 
 import (
 	"context"
+	"errors"
+	"math"
 	"sync"
 	"testing"
+	"time"
 
-	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/ipfs/go-datastore"
+	ds_sync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/celestiaorg/celestia-node/service/header"
 	"github.com/celestiaorg/celestia-node/service/share"
@@ -18,39 +24,322 @@ func TestDASer(t *testing.T) {
 	randHeader.DataHash = dah.Hash()
 	randHeader.DAH = dah
 
-	sub := &mockHeaderSub{
-		headers: []*header.ExtendedHeader{randHeader},
-	}
-
-	daser := NewDASer(shareServ, sub)
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	daser, err := NewDASer(shareServ, header.NewMemStore(), ds)
+	require.NoError(t, err)
 
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 	go func(wg *sync.WaitGroup) {
-		daser.sampling(context.Background(), sub)
+		daser.sampling(context.Background(), headsOf(randHeader), make(chan struct{}))
 		wg.Done()
 	}(wg)
 	wg.Wait()
+
+	assert.EqualValues(t, randHeader.Height, daser.SampledHeight())
+	assert.True(t, daser.IsSampled(uint64(randHeader.Height)))
+	assert.False(t, daser.IsSampled(uint64(randHeader.Height+1)))
+
+	// sampled heights must survive restarts
+	daser, err = NewDASer(shareServ, header.NewMemStore(), ds)
+	require.NoError(t, err)
+	assert.EqualValues(t, randHeader.Height, daser.SampledHeight())
+	assert.True(t, daser.IsSampled(uint64(randHeader.Height)))
+}
+
+// TestDASer_SamplingFailed tests that headers are not marked as sampled if their data is not available.
+func TestDASer_SamplingFailed(t *testing.T) {
+	randHeader := header.RandExtendedHeader(t)
+	da := &mockAvailability{err: share.ErrNotAvailable}
+	daser, err := NewDASer(da, header.NewMemStore(), datastore.NewMapDatastore(), WithRetryBackoff(0))
+	require.NoError(t, err)
+
+	daser.sampling(context.Background(), headsOf(randHeader), make(chan struct{}))
+	assert.Zero(t, daser.SampledHeight())
+	assert.False(t, daser.IsSampled(uint64(randHeader.Height)))
+	assert.True(t, daser.FraudProofRequired())
+}
+
+// TestDASer_WatchHead tests that DASer samples the heads appended to the store.
+func TestDASer_WatchHead(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	store := header.NewMemStore()
+	daser, err := NewDASer(&mockAvailability{}, store, datastore.NewMapDatastore())
+	require.NoError(t, err)
+	err = daser.Start(ctx)
+	require.NoError(t, err)
+
+	suite := header.NewTestSuite(t, 3)
+	err = store.Append(ctx, suite.GenExtendedHeaders(5)...)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return daser.SampledHeight() == 5
+	}, time.Second, time.Millisecond*10)
+	for height := uint64(1); height <= 5; height++ {
+		assert.True(t, daser.IsSampled(height), height)
+	}
+
+	err = daser.Stop(ctx)
+	require.NoError(t, err)
+
+	// the restarted DASer keeps sampling new heads
+	err = daser.Start(ctx)
+	require.NoError(t, err)
+	err = store.Append(ctx, suite.GenExtendedHeaders(2)...)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return daser.SampledHeight() == 7
+	}, time.Second, time.Millisecond*10)
+
+	err = daser.Stop(ctx)
+	require.NoError(t, err)
+}
+
+// TestDASer_RestartStuck tests that DASer stuck sampling can be started again once its Stop times out.
+func TestDASer_RestartStuck(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	store := header.NewMemStore()
+	avail := &stuckAvailability{release: make(chan struct{})}
+	daser, err := NewDASer(avail, store, datastore.NewMapDatastore())
+	require.NoError(t, err)
+	err = daser.Start(ctx)
+	require.NoError(t, err)
+
+	suite := header.NewTestSuite(t, 3)
+	err = store.Append(ctx, suite.GenExtendedHeaders(1)...)
+	require.NoError(t, err)
+	require.Eventually(t, daser.IsSampling, time.Second, time.Millisecond*10)
+
+	stopCtx, stopCancel := context.WithTimeout(ctx, time.Millisecond*50)
+	defer stopCancel()
+	err = daser.Stop(stopCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, daser.Attempts())
+
+	err = daser.Start(ctx)
+	require.NoError(t, err)
+	close(avail.release)
+	err = store.Append(ctx, suite.GenExtendedHeaders(1)...)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return daser.SampledHeight() == 2
+	}, time.Second, time.Millisecond*10)
+
+	err = daser.Stop(ctx)
+	require.NoError(t, err)
+}
+
+// TestDASer_NetworkHead tests that DASer samples the heads of the network instead of the store, if given.
+func TestDASer_NetworkHead(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	network := &mockNetworkHead{heads: make(chan *header.ExtendedHeader, 1)}
+	daser, err := NewDASer(&mockAvailability{}, header.NewMemStore(), datastore.NewMapDatastore(),
+		WithNetworkHead(network))
+	require.NoError(t, err)
+	err = daser.Start(ctx)
+	require.NoError(t, err)
+
+	head := header.NewTestSuite(t, 3).GenExtendedHeaders(3)[2]
+	network.heads <- head
+	require.Eventually(t, func() bool {
+		return daser.IsSampled(uint64(head.Height))
+	}, time.Second, time.Millisecond*10)
+	assert.False(t, daser.IsSampled(uint64(head.Height-1)))
+
+	// the DASer stops while the network heads are still watched
+	err = daser.Stop(ctx)
+	require.NoError(t, err)
+}
+
+// TestDASer_FraudProofRequired tests that a fraud proof is required once a header systematically fails sampling.
+func TestDASer_FraudProofRequired(t *testing.T) {
+	ctx := context.Background()
+	heads := header.NewTestSuite(t, 3).GenExtendedHeaders(2)
+
+	// the header fails less times than the threshold
+	avail := &flakyAvailability{fails: 2}
+	trigger := &mockFraudTrigger{}
+	daser, err := NewDASer(avail, header.NewMemStore(), datastore.NewMapDatastore(),
+		WithFraudTrigger(trigger), WithFraudThreshold(3), WithRetryBackoff(0))
+	require.NoError(t, err)
+
+	daser.sampling(ctx, headsOf(heads[0]), make(chan struct{}))
+	assert.Equal(t, 3, avail.attempts)
+	assert.True(t, daser.IsSampled(uint64(heads[0].Height)))
+	assert.False(t, daser.FraudProofRequired())
+	assert.Empty(t, trigger.suspected)
+
+	// the header fails systematically
+	avail.attempts, avail.fails = 0, math.MaxInt32
+	daser.sampling(ctx, headsOf(heads[1]), make(chan struct{}))
+	assert.Equal(t, 3, avail.attempts)
+	assert.False(t, daser.IsSampled(uint64(heads[1].Height)))
+	assert.True(t, daser.FraudProofRequired())
+	require.Len(t, trigger.suspected, 1)
+	assert.Equal(t, heads[1].Hash(), trigger.suspected[0].Hash())
+}
+
+// TestDASer_RetryBackoff tests that DASer backs off before sampling a header again and only the data found
+// unavailable counts towards the fraud threshold.
+func TestDASer_RetryBackoff(t *testing.T) {
+	ctx := context.Background()
+	head := header.NewTestSuite(t, 3).GenExtendedHeaders(1)[0]
+
+	// failures of the network are no evidence of fraud
+	avail := &flakyAvailability{fails: 4, err: errors.New("network failure")}
+	trigger := &mockFraudTrigger{}
+	backoff := time.Millisecond * 10
+	daser, err := NewDASer(avail, header.NewMemStore(), datastore.NewMapDatastore(),
+		WithFraudTrigger(trigger), WithFraudThreshold(3), WithRetryBackoff(backoff))
+	require.NoError(t, err)
+
+	start := time.Now()
+	daser.sampling(ctx, headsOf(head), make(chan struct{}))
+	// the backoff doubles with every failure
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(backoff*(1+2+4+8)))
+	assert.Equal(t, 5, avail.attempts)
+	assert.EqualValues(t, 5, daser.Attempts())
+	assert.True(t, daser.IsSampled(uint64(head.Height)))
+	assert.False(t, daser.FraudProofRequired())
+	assert.Empty(t, trigger.suspected)
+
+	// the retries are abandoned once sampling is stopped
+	avail.attempts, avail.fails = 0, math.MaxInt32
+	ctx, cancel := context.WithTimeout(ctx, backoff*5)
+	defer cancel()
+	daser.sampling(ctx, headsOf(head), make(chan struct{}))
+	assert.Less(t, avail.attempts, 5)
+	assert.False(t, daser.FraudProofRequired())
+}
+
+func TestDASer_MaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	heads := header.NewTestSuite(t, 3).GenExtendedHeaders(2)
+
+	// the first head always fails, but does not stall sampling of the next one
+	avail := &brokenAvailability{broken: heads[0].DAH, err: errors.New("network failure")}
+	trigger := &mockFraudTrigger{}
+	daser, err := NewDASer(avail, header.NewMemStore(), datastore.NewMapDatastore(),
+		WithFraudTrigger(trigger), WithMaxAttempts(3), WithRetryBackoff(time.Millisecond))
+	require.NoError(t, err)
+
+	daser.sampling(ctx, headsOf(heads...), make(chan struct{}))
+	assert.Equal(t, 3, avail.attempts)
+	assert.EqualValues(t, 4, daser.Attempts())
+	assert.False(t, daser.IsSampled(uint64(heads[0].Height)))
+	assert.True(t, daser.IsSampled(uint64(heads[1].Height)))
+	assert.False(t, daser.FraudProofRequired())
+	assert.Empty(t, trigger.suspected)
+}
+
+func TestSampledStore(t *testing.T) {
+	store, err := newSampledStore(datastore.NewMapDatastore())
+	require.NoError(t, err)
+
+	heights := []uint64{1, 7, 8, chunkSize - 1, chunkSize, chunkSize*3 + 5}
+	for _, h := range heights {
+		err = store.MarkSampled(h)
+		require.NoError(t, err)
+	}
+	// marking a lower height does not decrease the sampled height
+	err = store.MarkSampled(2)
+	require.NoError(t, err)
+	assert.EqualValues(t, chunkSize*3+5, store.SampledHeight())
+
+	for _, h := range append(heights, 2) {
+		ok, err := store.IsSampled(h)
+		require.NoError(t, err)
+		assert.True(t, ok, h)
+	}
+	for _, h := range []uint64{0, 3, 9, chunkSize + 1, chunkSize * 2} {
+		ok, err := store.IsSampled(h)
+		require.NoError(t, err)
+		assert.False(t, ok, h)
+	}
+}
+
+type mockAvailability struct {
+	err error
 }
 
-type mockHeaderSub struct {
-	headers []*header.ExtendedHeader
+func (ma *mockAvailability) SharesAvailable(context.Context, *share.Root) error {
+	return ma.err
 }
 
-func (mhs *mockHeaderSub) Subscribe() (header.Subscription, error) {
-	return mhs, nil
+// flakyAvailability fails the given amount of times before data becomes available.
+// It fails with share.ErrNotAvailable, unless another error is given.
+type flakyAvailability struct {
+	fails    int
+	attempts int
+	err      error
 }
 
-func (mhs *mockHeaderSub) NextHeader(ctx context.Context) (*header.ExtendedHeader, error) {
-	defer func() {
-		mhs.headers = make([]*header.ExtendedHeader, 0)
-	}()
-	if len(mhs.headers) == 0 {
-		return nil, context.Canceled
+func (fa *flakyAvailability) SharesAvailable(context.Context, *share.Root) error {
+	fa.attempts++
+	if fa.attempts <= fa.fails {
+		if fa.err != nil {
+			return fa.err
+		}
+		return share.ErrNotAvailable
 	}
-	return mhs.headers[0], nil
+	return nil
 }
 
-func (mhs *mockHeaderSub) Cancel() {}
+// brokenAvailability always fails sampling of the broken root.
+type brokenAvailability struct {
+	broken   *share.Root
+	attempts int
+	err      error
+}
 
-func (mhs *mockHeaderSub) Topic() *pubsub.Topic { return nil }
+func (ba *brokenAvailability) SharesAvailable(_ context.Context, root *share.Root) error {
+	if root != ba.broken {
+		return nil
+	}
+	ba.attempts++
+	return ba.err
+}
+
+// stuckAvailability ignores the context and blocks until released.
+type stuckAvailability struct {
+	release chan struct{}
+}
+
+func (sa *stuckAvailability) SharesAvailable(context.Context, *share.Root) error {
+	<-sa.release
+	return nil
+}
+
+type mockFraudTrigger struct {
+	suspected []*header.ExtendedHeader
+}
+
+func (mt *mockFraudTrigger) TriggerFraudProof(_ context.Context, h *header.ExtendedHeader) error {
+	mt.suspected = append(mt.suspected, h)
+	return nil
+}
+
+type mockNetworkHead struct {
+	heads chan *header.ExtendedHeader
+}
+
+func (mn *mockNetworkHead) WatchNetworkHead(context.Context) (<-chan *header.ExtendedHeader, error) {
+	return mn.heads, nil
+}
+
+// headsOf returns a closed channel delivering the given headers.
+func headsOf(headers ...*header.ExtendedHeader) <-chan *header.ExtendedHeader {
+	heads := make(chan *header.ExtendedHeader, len(headers))
+	for _, h := range headers {
+		heads <- h
+	}
+	close(heads)
+	return heads
+}
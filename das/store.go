@@ -0,0 +1,115 @@
+package das
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+)
+
+// chunkSize defines the amount of heights tracked by a single bitset chunk saved on disk.
+const chunkSize = 8 * 1024
+
+var (
+	storePrefix   = datastore.NewKey("das")
+	sampledKey    = datastore.NewKey("sampled")
+	sampledHeight = datastore.NewKey("head")
+)
+
+// sampledStore persistently tracks heights of headers which were successfully sampled.
+// Heights are kept in a bitset split into chunks, so marking a height rewrites a single chunk only.
+type sampledStore struct {
+	ds datastore.Datastore
+
+	lk   sync.RWMutex
+	head uint64
+}
+
+// newSampledStore creates a new sampledStore over the given datastore.
+func newSampledStore(ds datastore.Datastore) (*sampledStore, error) {
+	s := &sampledStore{ds: namespace.Wrap(ds, storePrefix)}
+
+	b, err := s.ds.Get(sampledHeight)
+	switch err {
+	case nil:
+		s.head, err = strconv.ParseUint(string(b), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	case datastore.ErrNotFound:
+	default:
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SampledHeight returns the highest sampled height.
+func (s *sampledStore) SampledHeight() uint64 {
+	s.lk.RLock()
+	defer s.lk.RUnlock()
+	return s.head
+}
+
+// IsSampled reports whether the header at the given height was sampled.
+func (s *sampledStore) IsSampled(height uint64) (bool, error) {
+	s.lk.RLock()
+	defer s.lk.RUnlock()
+
+	chunk, err := s.chunk(height / chunkSize)
+	if err != nil {
+		return false, err
+	}
+
+	idx := height % chunkSize
+	return chunk[idx/8]&(1<<(idx%8)) != 0, nil
+}
+
+// MarkSampled marks the header at the given height as sampled.
+func (s *sampledStore) MarkSampled(height uint64) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	key := chunkKey(height / chunkSize)
+	chunk, err := s.chunk(height / chunkSize)
+	if err != nil {
+		return err
+	}
+
+	idx := height % chunkSize
+	chunk[idx/8] |= 1 << (idx % 8)
+	err = s.ds.Put(key, chunk)
+	if err != nil {
+		return err
+	}
+
+	if height <= s.head {
+		return nil
+	}
+
+	err = s.ds.Put(sampledHeight, []byte(strconv.FormatUint(height, 10)))
+	if err != nil {
+		return err
+	}
+
+	s.head = height
+	return nil
+}
+
+// chunk loads the bitset chunk with the given index or creates an empty one.
+func (s *sampledStore) chunk(idx uint64) ([]byte, error) {
+	chunk, err := s.ds.Get(chunkKey(idx))
+	switch err {
+	case nil:
+		return chunk, nil
+	case datastore.ErrNotFound:
+		return make([]byte, chunkSize/8), nil
+	default:
+		return nil, err
+	}
+}
+
+func chunkKey(idx uint64) datastore.Key {
+	return sampledKey.ChildString(strconv.FormatUint(idx, 10))
+}
@@ -250,6 +250,37 @@ func GetLeaf(ctx context.Context, dag ipld.NodeGetter, root cid.Cid, leaf, total
 	return GetLeaf(ctx, dag, root, leaf, total)
 }
 
+// GetProof fetches and returns the raw leaf along with the nodes proving its inclusion into the root.
+// Like GetLeaf, it walks down the IPLD NMT tree collecting siblings of the visited nodes on the way.
+// The proof nodes are ordered from left to right, as expected by nmt.Proof.
+func GetProof(ctx context.Context, dag ipld.NodeGetter, root cid.Cid, leaf, total int) (ipld.Node, [][]byte, error) {
+	var left, right [][]byte
+	for {
+		nd, err := dag.Get(ctx, root)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		lnks := nd.Links()
+		if len(lnks) == 1 {
+			// reached the leaf, right siblings were collected bottom up, so reverse them
+			for i := len(right) - 1; i >= 0; i-- {
+				left = append(left, right[i])
+			}
+			return nd, left, nil
+		}
+
+		total /= 2
+		if leaf < total {
+			root = lnks[0].Cid
+			right = append(right, plugin.NamespacedSha256FromCID(lnks[1].Cid))
+		} else {
+			root, leaf = lnks[1].Cid, leaf-total
+			left = append(left, plugin.NamespacedSha256FromCID(lnks[0].Cid))
+		}
+	}
+}
+
 // GetLeavesByNamespace returns all the shares from the given DataAvailabilityHeader root
 // with the given namespace.ID.
 func GetLeavesByNamespace(
@@ -287,3 +318,68 @@ func GetLeavesByNamespace(
 	}
 	return out, err
 }
+
+// GetLeavesByNamespaceWithProof returns all the leaves of the given namespace.ID committed to the root of a tree
+// with 'total' leaves, along with the nmt.Proof of their inclusion and completeness.
+// If the tree has no leaves of the namespace, no leaves and an empty proof are returned.
+func GetLeavesByNamespaceWithProof(
+	ctx context.Context,
+	dag ipld.NodeGetter,
+	root cid.Cid,
+	nID namespace.ID,
+	total int,
+) ([]ipld.Node, nmt.Proof, error) {
+	w := &namespaceWalker{dag: dag, nID: nID}
+	err := w.walk(ctx, root, 0, total)
+	if err != nil {
+		return nil, nmt.Proof{}, err
+	}
+	if len(w.leaves) == 0 {
+		return nil, nmt.NewEmptyRangeProof(true), nil
+	}
+
+	return w.leaves, nmt.NewInclusionProof(w.start, w.start+len(w.leaves), w.nodes, true), nil
+}
+
+// namespaceWalker walks down the IPLD NMT tree collecting leaves of the namespace.ID
+// and roots of the subtrees not containing it, which form the proof.
+type namespaceWalker struct {
+	dag ipld.NodeGetter
+	nID namespace.ID
+
+	leaves []ipld.Node
+	start  int
+	nodes  [][]byte
+}
+
+// walk visits the subtree with the given root, which covers 'size' leaves starting from 'pos'.
+func (w *namespaceWalker) walk(ctx context.Context, root cid.Cid, pos, size int) error {
+	rootH := plugin.NamespacedSha256FromCID(root)
+	if w.nID.Less(nmt.MinNamespace(rootH, w.nID.Size())) || !w.nID.LessOrEqual(nmt.MaxNamespace(rootH, w.nID.Size())) {
+		w.nodes = append(w.nodes, rootH)
+		return nil
+	}
+
+	nd, err := w.dag.Get(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	lnks := nd.Links()
+	if len(lnks) == 1 {
+		if len(w.leaves) == 0 {
+			w.start = pos
+		}
+		w.leaves = append(w.leaves, nd)
+		return nil
+	}
+
+	size /= 2
+	for i, lnk := range lnks {
+		err = w.walk(ctx, lnk.Cid, pos+i*size, size)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -45,6 +45,29 @@ func TestGetLeafData(t *testing.T) {
 	}
 }
 
+func TestGetProof(t *testing.T) {
+	const leaves = 16
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	dag := mdutils.Mock()
+
+	shares := RandNamespacedShares(t, leaves)
+	root, err := getNmtRoot(ctx, dag, shares.Raw())
+	require.NoError(t, err)
+
+	for i, leaf := range shares {
+		nd, nodes, err := GetProof(ctx, dag, root, i, len(shares))
+		require.NoError(t, err)
+		assert.Equal(t, leaf.Share, Share(nd.RawData()[1:]))
+
+		proof := nmt.NewInclusionProof(i, i+1, nodes, true)
+		rootHash := plugin.NamespacedSha256FromCID(root)
+		valid := proof.VerifyInclusion(sha256.New(), leaf.ID, leaf.Share[NamespaceSize:], rootHash)
+		assert.True(t, valid)
+	}
+}
+
 func TestBlockRecovery(t *testing.T) {
 	originalSquareWidth := 8
 	shareCount := originalSquareWidth * originalSquareWidth
@@ -280,6 +303,47 @@ func TestGetLeavesByNamespace(t *testing.T) {
 	}
 }
 
+func TestGetLeavesByNamespaceWithProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	rawData := generateRandNamespacedRawData(16, NamespaceSize, plugin.ShareSize)
+	// make the namespace span several shares
+	expected := rawData[len(rawData)/2]
+	nID := expected[:NamespaceSize]
+	rawData[len(rawData)/2+1] = expected
+
+	dag := mdutils.Mock()
+	eds, err := PutData(ctx, rawData, dag)
+	require.NoError(t, err)
+	dah := da.NewDataAvailabilityHeader(eds)
+
+	rowRootCIDs, err := rowRootsByNamespaceID(nID, &dah)
+	require.NoError(t, err)
+
+	var found int
+	for _, rowCID := range rowRootCIDs {
+		nodes, proof, err := GetLeavesByNamespaceWithProof(ctx, dag, rowCID, nID, len(dah.RowsRoots))
+		require.NoError(t, err)
+
+		leaves := make([][]byte, len(nodes))
+		for i, node := range nodes {
+			leaves[i] = node.RawData()[1:]
+			assert.Equal(t, expected, leaves[i][NamespaceSize:])
+		}
+		found += len(nodes)
+		if len(nodes) == 0 {
+			continue
+		}
+
+		root := plugin.NamespacedSha256FromCID(rowCID)
+		assert.True(t, proof.VerifyNamespace(sha256.New(), nID, leaves, root))
+		// incomplete namespace data must not verify
+		assert.False(t, proof.VerifyNamespace(sha256.New(), nID, leaves[1:], root))
+	}
+	assert.Equal(t, 2, found)
+}
+
 // rowRootsByNamespaceID is a convenience method that finds the row root(s)
 // that contain the given namespace ID.
 func rowRootsByNamespaceID(nID namespace.ID, dah *da.DataAvailabilityHeader) ([]cid.Cid, error) {
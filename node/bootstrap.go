@@ -0,0 +1,60 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/celestiaorg/celestia-node/node/fxutil"
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+// WithBootstrapHeaders pre-populates the header store of the Node with the given headers during New,
+// so the Node can start without any network access, e.g. in air-gapped deployments.
+// The headers must form a chain in ascending order of heights, which is verified before they are stored.
+// Headers already stored are not appended again.
+func WithBootstrapHeaders(headers []*header.ExtendedHeader) Option {
+	return func(cfg *Config, sets *settings) error {
+		for _, h := range headers {
+			if h == nil {
+				return errors.New("node: nil bootstrap header")
+			}
+		}
+		err := header.VerifyChain(headers)
+		if err != nil {
+			return fmt.Errorf("node: invalid bootstrap headers: %w", err)
+		}
+
+		sets.BootstrapHeaders = headers
+		return nil
+	}
+}
+
+// bootstrap collects the components required to pre-populate the header store, if enabled.
+func (sets *settings) bootstrap() fxutil.Option {
+	if len(sets.BootstrapHeaders) == 0 {
+		return fxutil.Options()
+	}
+	return fxutil.Invoke(bootstrapHeaders(sets.BootstrapHeaders))
+}
+
+// bootstrapHeaders appends the given headers to the store, unless they are stored already.
+func bootstrapHeaders(headers []*header.ExtendedHeader) func(ctx context.Context, store header.Store) error {
+	return func(ctx context.Context, store header.Store) error {
+		last := headers[len(headers)-1]
+		has, err := store.Has(ctx, last.Hash())
+		if err != nil {
+			return err
+		}
+		if has {
+			return nil
+		}
+
+		err = store.Append(ctx, headers...)
+		if err != nil {
+			return fmt.Errorf("node: storing bootstrap headers: %w", err)
+		}
+		log.Infow("bootstrapped header store", "from", headers[0].Height, "to", last.Height)
+		return nil
+	}
+}
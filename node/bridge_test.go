@@ -22,6 +22,7 @@ func TestNewBridge(t *testing.T) {
 	require.NotNil(t, node.Config)
 	require.NotNil(t, node.Host)
 	require.NotNil(t, node.HeaderServ)
+	require.NotNil(t, node.CoreListener)
 	require.Nil(t, node.BlockServ)
 	assert.NotZero(t, node.Type)
 }
@@ -38,6 +39,7 @@ func TestBridgeLifecycle(t *testing.T) {
 	require.NotNil(t, node.Host)
 	require.NotNil(t, node.CoreClient)
 	require.NotNil(t, node.HeaderServ)
+	require.NotNil(t, node.CoreListener)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	t.Cleanup(cancel)
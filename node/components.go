@@ -15,11 +15,13 @@ func lightComponents(cfg *Config, store Store) fxutil.Option {
 		fxutil.Supply(Light),
 		baseComponents(cfg, store),
 		fxutil.Provide(services.DASer),
+		fxutil.Provide(services.NetworkHeadTracker),
+		fxutil.InvokeIf(cfg.Services.WatchdogInterval > 0, watchDASer(cfg.Services.WatchdogInterval)),
 		fxutil.Provide(services.HeaderExchangeP2P(cfg.Services)),
 	)
 }
 
-// fullComponents keeps all the components as DI options required to build a Full Node.
+// bridgeComponents keeps all the components as DI options required to build a Bridge Node.
 func bridgeComponents(cfg *Config, store Store) fxutil.Option {
 	return fxutil.Options(
 		fxutil.Supply(Bridge),
@@ -42,7 +44,10 @@ func baseComponents(cfg *Config, store Store) fxutil.Option {
 		fxutil.Provide(services.HeaderSyncer(cfg.Services)),
 		fxutil.Provide(services.P2PSubscriber),
 		fxutil.Provide(services.HeaderP2PExchangeServer),
+		fxutil.Provide(services.FraudService),
 		fxutil.Provide(services.LightAvailability), // TODO(@Wondertan): Move to light once FullAvailability is implemented
+		fxutil.InvokeIf(cfg.Services.PruningInterval > 0, services.HeaderPruner(cfg.Services)),
+		fxutil.InvokeIf(cfg.Services.WatchdogInterval > 0, watchSyncer(cfg.Services.WatchdogInterval)),
 		p2p.Components(cfg.P2P),
 	)
 }
@@ -1,5 +1,12 @@
 package node
 
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
 // WithRemoteCore configures Node to start with remote Core.
 func WithRemoteCore(protocol string, address string) Option {
 	return func(cfg *Config, _ *settings) (_ error) {
@@ -26,6 +33,46 @@ func WithTrustedPeer(addr string) Option {
 	}
 }
 
+// WithTrustedPeers sets TrustedPeers to the Config.
+// Those peers are used as the initial peer set for header exchange.
+func WithTrustedPeers(peers []peer.AddrInfo) Option {
+	return func(cfg *Config, _ *settings) error {
+		for _, p := range peers {
+			p := p
+			addrs, err := peer.AddrInfoToP2pAddrs(&p)
+			if err != nil {
+				return err
+			}
+
+			for _, addr := range addrs {
+				cfg.Services.TrustedPeers = append(cfg.Services.TrustedPeers, addr.String())
+			}
+		}
+		return nil
+	}
+}
+
+// WithHostAddresses sets the addresses the libp2p Host listens on, replacing the configured ones.
+func WithHostAddresses(addrs []ma.Multiaddr) Option {
+	return func(cfg *Config, _ *settings) (_ error) {
+		cfg.P2P.ListenAddresses = make([]string, len(addrs))
+		for i, addr := range addrs {
+			cfg.P2P.ListenAddresses[i] = addr.String()
+		}
+		return
+	}
+}
+
+// WithPruningInterval enables periodic pruning of the header store with the given interval,
+// keeping only 'keepLast' latest headers.
+func WithPruningInterval(interval time.Duration, keepLast uint64) Option {
+	return func(cfg *Config, _ *settings) (_ error) {
+		cfg.Services.PruningInterval = interval
+		cfg.Services.PruningKeepLast = keepLast
+		return
+	}
+}
+
 // WithConfig sets the entire custom config.
 func WithConfig(custom *Config) Option {
 	return func(cfg *Config, _ *settings) (_ error) {
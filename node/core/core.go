@@ -60,10 +60,10 @@ func HeaderCoreListener(
 	dag format.DAGService,
 ) *header.CoreListener {
 	cl := header.NewCoreListener(p2pSub, ex, dag)
-	lc.Append(fx.Hook{
+	lc.Append(fxutil.Hook("core listener", fx.Hook{
 		OnStart: cl.Start,
 		OnStop:  cl.Stop,
-	})
+	}))
 	return cl
 }
 
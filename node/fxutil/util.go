@@ -2,10 +2,45 @@ package fxutil
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"go.uber.org/fx"
 )
 
+// StopTimeout is the maximum amount of time a single component is given to stop.
+var StopTimeout = time.Second * 5
+
+// Hook wraps the OnStop of the given hook for the named component, so that it is aborted if it does not complete
+// within StopTimeout. This way a component ignoring its context cannot block the shutdown of the rest.
+func Hook(name string, hook fx.Hook) fx.Hook {
+	if hook.OnStop == nil {
+		return hook
+	}
+
+	stop := hook.OnStop
+	hook.OnStop = func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, StopTimeout)
+		defer cancel()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- stop(ctx)
+		}()
+
+		select {
+		case err := <-errCh:
+			if err != nil {
+				return fmt.Errorf("stopping %s: %w", name, err)
+			}
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("stopping %s: timed out: %w", name, ctx.Err())
+		}
+	}
+	return hook
+}
+
 // WithLifecycle wraps a context to be canceled when the lifecycle stops.
 func WithLifecycle(ctx context.Context, lc fx.Lifecycle) context.Context {
 	ctx, cancel := context.WithCancel(ctx)
@@ -3,12 +3,34 @@ package node
 import (
 	"context"
 	"crypto/rand"
+	"fmt"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
+	logging "github.com/ipfs/go-log/v2"
+	mdutils "github.com/ipfs/go-merkledag/test"
 	"github.com/libp2p/go-libp2p-core/crypto"
+	crypto_pb "github.com/libp2p/go-libp2p-core/crypto/pb"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/celestiaorg/celestia-node/node/fxutil"
+	"github.com/celestiaorg/celestia-node/service/fraud"
+	"github.com/celestiaorg/celestia-node/service/header"
 )
 
 func TestNewLight(t *testing.T) {
@@ -18,6 +40,8 @@ func TestNewLight(t *testing.T) {
 	require.NotNil(t, nd)
 	require.NotNil(t, nd.Config)
 	require.NotNil(t, nd.HeaderServ)
+	require.Nil(t, nd.CoreListener)
+	require.NotNil(t, nd.DASer)
 	assert.NotZero(t, nd.Type)
 }
 
@@ -52,6 +76,29 @@ func TestNewLightWithP2PKey(t *testing.T) {
 	assert.True(t, node.Host.ID().MatchesPrivateKey(key))
 }
 
+func TestNewLightWithP2PPrivKeyBytes(t *testing.T) {
+	for _, typ := range []int{crypto.Ed25519, crypto.Secp256k1} {
+		key, _, err := crypto.GenerateKeyPairWithReader(typ, 256, rand.Reader)
+		require.NoError(t, err)
+		raw, err := key.Raw()
+		require.NoError(t, err)
+
+		name := strings.ToLower(crypto_pb.KeyType_name[int32(typ)])
+		opt, err := WithP2PPrivKeyBytes(name, raw)
+		require.NoError(t, err)
+
+		repo := MockStore(t, DefaultConfig(Light))
+		node, err := New(Light, repo, opt)
+		require.NoError(t, err)
+		assert.True(t, node.Host.ID().MatchesPrivateKey(key), name)
+	}
+
+	_, err := WithP2PPrivKeyBytes("rsa", []byte{1})
+	assert.Error(t, err)
+	_, err = WithP2PPrivKeyBytes("ed25519", []byte{1})
+	assert.Error(t, err)
+}
+
 func TestNewLightWithHost(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	t.Cleanup(cancel)
@@ -62,3 +109,458 @@ func TestNewLightWithHost(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, node.Host.ID(), nw.Peers()[0])
 }
+
+func TestNewLightWithPruningInterval(t *testing.T) {
+	repo := MockStore(t, DefaultConfig(Light))
+	node, err := New(Light, repo, WithPruningInterval(time.Minute, 10))
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, node.Config.Services.PruningInterval)
+	assert.EqualValues(t, 10, node.Config.Services.PruningKeepLast)
+}
+
+func TestNewLightWithHostAddresses(t *testing.T) {
+	// find a free port to listen on
+	lst, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := lst.Addr().(*net.TCPAddr).Port
+	require.NoError(t, lst.Close())
+
+	addr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", port))
+	require.NoError(t, err)
+
+	repo := MockStore(t, DefaultConfig(Light))
+	nd, err := New(Light, repo, WithHostAddresses([]ma.Multiaddr{addr}))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		nd.Host.Close() //nolint:errcheck
+	})
+
+	assert.Equal(t, []string{addr.String()}, nd.Config.P2P.ListenAddresses)
+	listening := nd.Host.Network().ListenAddresses()
+	require.Len(t, listening, 1)
+	assert.True(t, listening[0].Equal(addr), listening[0])
+	assert.Contains(t, nd.Host.Addrs(), addr)
+}
+
+func TestLightWithTrustedPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	nw, err := mocknet.WithNPeers(ctx, 3)
+	require.NoError(t, err)
+	err = nw.LinkAll()
+	require.NoError(t, err)
+	// the last peer is not linked, so it is unavailable
+	err = nw.UnlinkPeers(nw.Peers()[0], nw.Peers()[2])
+	require.NoError(t, err)
+
+	trusted := host.InfoFromHost(nw.Hosts()[1])
+	unavailable := host.InfoFromHost(nw.Hosts()[2])
+	repo := MockStore(t, DefaultConfig(Light))
+	nd, err := New(Light, repo,
+		WithHost(nw.Hosts()[0]),
+		WithTrustedPeers([]peer.AddrInfo{*unavailable, *trusted}),
+	)
+	require.NoError(t, err)
+	assert.Len(t, nd.Config.Services.TrustedPeers, 2)
+
+	// an unavailable trusted peer must not abort the start
+	err = nd.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		nd.Stop(context.Background()) //nolint:errcheck
+	})
+
+	assert.Equal(t, network.Connected, nd.Host.Network().Connectedness(trusted.ID))
+	assert.NotEqual(t, network.Connected, nd.Host.Network().Connectedness(unavailable.ID))
+}
+
+func TestLightStopTimeout(t *testing.T) {
+	stopTimeout := fxutil.StopTimeout
+	fxutil.StopTimeout = time.Millisecond * 100
+	t.Cleanup(func() {
+		fxutil.StopTimeout = stopTimeout
+	})
+
+	cfg := DefaultConfig(Light)
+	store := MockStore(t, cfg)
+	// the host is isolated from the real network, so only the broken service is slow to stop
+	nw, err := mocknet.WithNPeers(context.Background(), 1)
+	require.NoError(t, err)
+	sets := &settings{Host: nw.Hosts()[0]}
+	// inject a broken service ignoring the context on stop
+	block := make(chan struct{})
+	t.Cleanup(func() {
+		close(block)
+	})
+	nd, err := newNode(lightComponents(cfg, store), sets.overrides(), fxutil.Invoke(func(lc fx.Lifecycle) {
+		lc.Append(fxutil.Hook("broken", fx.Hook{
+			OnStop: func(context.Context) error {
+				<-block
+				return nil
+			},
+		}))
+	}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	err = nd.Start(ctx)
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = nd.Stop(ctx)
+	assert.Less(t, time.Since(start), Timeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "stopping broken: timed out")
+	// the rest of the services must be stopped regardless
+	assert.Len(t, multierr.Errors(err), 1)
+}
+
+func TestLightWithLogger(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	t.Cleanup(func() {
+		logging.SetAllLoggers(logging.LevelError)
+	})
+
+	nw, err := mocknet.WithNPeers(ctx, 2)
+	require.NoError(t, err)
+	// the trusted peer is not linked, so connecting to it fails
+	unavailable := host.InfoFromHost(nw.Hosts()[1])
+
+	repo := MockStore(t, DefaultConfig(Light))
+	nd, err := New(Light, repo,
+		WithHost(nw.Hosts()[0]),
+		WithTrustedPeers([]peer.AddrInfo{*unavailable}),
+		WithLogger(zap.New(core)),
+		WithLogLevel(zapcore.InfoLevel),
+	)
+	require.NoError(t, err)
+
+	err = nd.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		nd.Stop(context.Background()) //nolint:errcheck
+	})
+
+	// logs of services are named after them
+	started := logs.FilterMessage("p2p: starting p2p exchange").Filter(func(e observer.LoggedEntry) bool {
+		return e.LoggerName == "header-service"
+	})
+	assert.Equal(t, 1, started.Len())
+	// debug logs are filtered out by the level
+	assert.Zero(t, logs.FilterLevelExact(zapcore.DebugLevel).Len())
+
+	failed := logs.FilterMessage("p2p: connecting to peer").FilterField(zap.String("peer", unavailable.ID.ShortString()))
+	require.Equal(t, 1, failed.Len())
+	assert.Equal(t, zapcore.ErrorLevel, failed.All()[0].Level)
+	assert.Contains(t, failed.All()[0].ContextMap(), "err")
+}
+
+func TestLightStopOnFraud(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	t.Cleanup(cancel)
+
+	// messages are signed, so the peers need real keys
+	nw := mocknet.New(ctx)
+	for i := 0; i < 2; i++ {
+		key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+		require.NoError(t, err)
+		_, err = nw.AddPeer(key, ma.StringCast("/ip4/127.0.0.1/tcp/0"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, nw.LinkAll())
+	require.NoError(t, nw.ConnectAllButSelf())
+
+	// the Node knows the header the proof is made for
+	h, proof := fraud.ByzantineProof(ctx, t, mdutils.Mock())
+	cfg := DefaultConfig(Light)
+	repo := MockStore(t, cfg)
+	ds, err := repo.Datastore()
+	require.NoError(t, err)
+	_, err = header.NewStoreWithHead(ds, h)
+	require.NoError(t, err)
+
+	stopped := make(chan struct{})
+	sets := &settings{Host: nw.Hosts()[0]}
+	nd, err := newNode(lightComponents(cfg, repo), sets.overrides(), fxutil.Invoke(func(lc fx.Lifecycle) {
+		lc.Append(fx.Hook{
+			OnStop: func(context.Context) error {
+				close(stopped)
+				return nil
+			},
+		})
+	}))
+	require.NoError(t, err)
+	err = nd.Start(ctx)
+	require.NoError(t, err)
+
+	ps, err := pubsub.NewGossipSub(ctx, nw.Hosts()[1])
+	require.NoError(t, err)
+	topic, err := ps.Join(fraud.PubSubTopic)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(topic.ListPeers()) > 0
+	}, time.Second*5, time.Millisecond*10)
+
+	// the status is read while the Node stops itself
+	statusDone := make(chan struct{})
+	go func() {
+		defer close(statusDone)
+		for {
+			select {
+			case <-stopped:
+				return
+			default:
+				nd.Status(ctx) //nolint:errcheck
+			}
+		}
+	}()
+
+	bin, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	err = topic.Publish(ctx, bin)
+	require.NoError(t, err)
+
+	select {
+	case <-nd.FraudServ.Detected():
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+	// the Node stops itself
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+	<-statusDone
+	assert.Eventually(t, func() bool {
+		status, err := nd.Status(ctx)
+		return err == nil && status.UptimeSeconds == 0
+	}, time.Second*5, time.Millisecond*10)
+}
+
+// TestLightNodeToLightNodeSync tests that a Light Node serves headers of its store
+// to another Light Node trusting it.
+func TestLightNodeToLightNodeSync(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	t.Cleanup(cancel)
+
+	nw, err := mocknet.WithNPeers(ctx, 2)
+	require.NoError(t, err)
+	require.NoError(t, nw.LinkAll())
+
+	// seed the headers into the store of node A
+	in := header.NewTestSuite(t, 3).GenExtendedHeaders(10)
+	repoA := MockStore(t, DefaultConfig(Light))
+	ds, err := repoA.Datastore()
+	require.NoError(t, err)
+	storeA, err := header.NewStoreWithHead(ds, in[0])
+	require.NoError(t, err)
+	err = storeA.Append(ctx, in[1:]...)
+	require.NoError(t, err)
+
+	nodeA, err := New(Light, repoA, WithHost(nw.Hosts()[0]))
+	require.NoError(t, err)
+	err = nodeA.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		nodeA.Stop(context.Background()) //nolint:errcheck
+	})
+
+	// node B trusts node A and exposes its exchange for the test
+	cfgB := DefaultConfig(Light)
+	setsB := &settings{Host: nw.Hosts()[1]}
+	err = WithTrustedPeers([]peer.AddrInfo{*host.InfoFromHost(nodeA.Host)})(cfgB, setsB)
+	require.NoError(t, err)
+
+	var exB header.Exchange
+	nodeB, err := newNode(lightComponents(cfgB, MockStore(t, cfgB)), setsB.overrides(),
+		fxutil.Invoke(func(ex header.Exchange) {
+			exB = ex
+		}),
+	)
+	require.NoError(t, err)
+	err = nodeB.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		nodeB.Stop(context.Background()) //nolint:errcheck
+	})
+
+	head, err := exB.RequestHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[len(in)-1].Hash(), head.Hash())
+
+	out, err := exB.RequestHeaders(ctx, 1, uint64(len(in)))
+	require.NoError(t, err)
+	require.Len(t, out, len(in))
+	for i, h := range out {
+		assert.Equal(t, in[i].Hash(), h.Hash())
+	}
+
+	h, err := exB.RequestByHash(ctx, in[4].Hash())
+	require.NoError(t, err)
+	assert.EqualValues(t, in[4].Height, h.Height)
+}
+
+// TestLightStatus tests that a Light Node reports itself synced once it catches up with its trusted peer.
+func TestLightStatus(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	t.Cleanup(cancel)
+
+	nw, err := mocknet.WithNPeers(ctx, 2)
+	require.NoError(t, err)
+	require.NoError(t, nw.LinkAll())
+
+	in := header.NewTestSuite(t, 3).GenExtendedHeaders(10)
+	repoA := MockStore(t, DefaultConfig(Light))
+	ds, err := repoA.Datastore()
+	require.NoError(t, err)
+	storeA, err := header.NewStoreWithHead(ds, in[0])
+	require.NoError(t, err)
+	err = storeA.Append(ctx, in[1:]...)
+	require.NoError(t, err)
+
+	nodeA, err := New(Light, repoA, WithHost(nw.Hosts()[0]))
+	require.NoError(t, err)
+	err = nodeA.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		nodeA.Stop(context.Background()) //nolint:errcheck
+	})
+
+	nodeB, err := New(Light, MockStore(t, DefaultConfig(Light)),
+		WithHost(nw.Hosts()[1]),
+		WithTrustedPeers([]peer.AddrInfo{*host.InfoFromHost(nodeA.Host)}),
+		WithTrustedHash(in[0].Hash().String()),
+	)
+	require.NoError(t, err)
+
+	status, err := nodeB.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.IsSynced)
+	assert.Zero(t, status.UptimeSeconds)
+
+	err = nodeB.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		nodeB.Stop(context.Background()) //nolint:errcheck
+	})
+
+	require.Eventually(t, func() bool {
+		status, err = nodeB.Status(ctx)
+		require.NoError(t, err)
+		return status.IsSynced
+	}, time.Second*5, time.Millisecond*50)
+	assert.EqualValues(t, in[len(in)-1].Height, status.SyncHeight)
+	assert.EqualValues(t, in[len(in)-1].Height, status.NetworkHeight)
+	assert.Equal(t, 1, status.PeerCount)
+	require.Len(t, status.ConnectedPeers, 1)
+	assert.Equal(t, nodeA.Host.ID(), status.ConnectedPeers[0].ID)
+	assert.Positive(t, status.UptimeSeconds)
+}
+
+// TestLightConnectedPeers tests that a Light Node reports the peers it is connected to.
+func TestLightConnectedPeers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	t.Cleanup(cancel)
+
+	nw, err := mocknet.WithNPeers(ctx, 4)
+	require.NoError(t, err)
+	require.NoError(t, nw.LinkAll())
+
+	node, err := New(Light, MockStore(t, DefaultConfig(Light)), WithHost(nw.Hosts()[0]))
+	require.NoError(t, err)
+	assert.Zero(t, node.PeerCount())
+	peers, err := node.ConnectedPeers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, peers)
+
+	expected := make(map[peer.ID][]ma.Multiaddr)
+	for _, h := range nw.Hosts()[1:] {
+		node.Host.Peerstore().AddAddrs(h.ID(), h.Addrs(), peerstore.PermanentAddrTTL)
+		_, err = nw.ConnectPeers(node.Host.ID(), h.ID())
+		require.NoError(t, err)
+		expected[h.ID()] = h.Addrs()
+	}
+
+	assert.Equal(t, len(expected), node.PeerCount())
+	peers, err = node.ConnectedPeers(ctx)
+	require.NoError(t, err)
+	require.Len(t, peers, len(expected))
+	for _, p := range peers {
+		require.Contains(t, expected, p.ID)
+		assert.ElementsMatch(t, expected[p.ID], p.Addrs)
+	}
+}
+
+// TestLightBootstrapHeaders tests that a Light Node serves the bootstrap headers without the network.
+func TestLightBootstrapHeaders(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	t.Cleanup(cancel)
+
+	nw, err := mocknet.WithNPeers(ctx, 1)
+	require.NoError(t, err)
+
+	headers := header.NewTestSuite(t, 3).GenExtendedHeaders(10)
+	nd, err := New(Light, MockStore(t, DefaultConfig(Light)),
+		WithHost(nw.Hosts()[0]),
+		WithBootstrapHeaders(headers),
+	)
+	require.NoError(t, err)
+
+	err = nd.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		nd.Stop(context.Background()) //nolint:errcheck
+	})
+
+	for _, h := range headers {
+		got, err := nd.HeaderServ.GetByHeight(ctx, uint64(h.Height))
+		require.NoError(t, err)
+		assert.Equal(t, h.Hash(), got.Hash())
+	}
+	assert.Zero(t, nd.PeerCount())
+}
+
+func TestWithBootstrapHeaders_Invalid(t *testing.T) {
+	headers := header.NewTestSuite(t, 3).GenExtendedHeaders(10)
+	gap := append(headers[:4:4], headers[5:]...)
+	_, err := New(Light, MockStore(t, DefaultConfig(Light)), WithBootstrapHeaders(gap))
+	assert.Error(t, err)
+}
+
+func TestLightWithCustomStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	t.Cleanup(cancel)
+
+	nw, err := mocknet.WithNPeers(ctx, 1)
+	require.NoError(t, err)
+
+	headers := header.NewTestSuite(t, 3).GenExtendedHeaders(10)
+	store := header.NewMemStore()
+	err = store.Append(ctx, headers...)
+	require.NoError(t, err)
+
+	nd, err := New(Light, MockStore(t, DefaultConfig(Light)),
+		WithHost(nw.Hosts()[0]),
+		WithCustomStore(store),
+	)
+	require.NoError(t, err)
+
+	err = nd.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		nd.Stop(context.Background()) //nolint:errcheck
+	})
+
+	for _, h := range headers {
+		got, err := nd.HeaderServ.GetByHeight(ctx, uint64(h.Height))
+		require.NoError(t, err)
+		assert.Equal(t, h.Hash(), got.Hash())
+	}
+}
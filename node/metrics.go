@@ -0,0 +1,136 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"go.opentelemetry.io/otel/exporters/metric/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	"go.uber.org/fx"
+
+	"github.com/celestiaorg/celestia-node/node/fxutil"
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+// WithMetrics enables metrics of the Node and its services, periodically pushing them to the given exporter.
+// NOTE: Metrics are recorded to the global meter, so only one Node per process can have them enabled.
+func WithMetrics(exporter export.Exporter) Option {
+	return func(cfg *Config, sets *settings) (_ error) {
+		sets.MetricsExporter = exporter
+		return
+	}
+}
+
+// MetricsServer enables metrics of the Node and its services, serving them for Prometheus over HTTP
+// at the given address. The health of the Node is served at the "/-/health" path of the address as well.
+// NOTE: Metrics are recorded to the global meter, so only one Node per process can have them enabled.
+func MetricsServer(addr string) Option {
+	return func(cfg *Config, sets *settings) (_ error) {
+		sets.MetricsAddr = addr
+		return
+	}
+}
+
+// metrics collects all the components required to record and export metrics, if enabled.
+func (sets *settings) metrics() fxutil.Option {
+	switch {
+	case sets.MetricsExporter != nil:
+		return fxutil.Invoke(pushMetrics(sets.MetricsExporter), observeMetrics)
+	case sets.MetricsAddr != "":
+		return fxutil.Invoke(serveMetrics(sets.MetricsAddr), observeMetrics)
+	default:
+		return fxutil.Options()
+	}
+}
+
+// pushMetrics installs a global meter provider periodically pushing metrics to the given exporter.
+func pushMetrics(exporter export.Exporter) func(lc fx.Lifecycle) {
+	return func(lc fx.Lifecycle) {
+		ctrl := controller.New(
+			processor.New(simple.NewWithInexpensiveDistribution(), exporter),
+			controller.WithExporter(exporter),
+		)
+		global.SetMeterProvider(ctrl.MeterProvider())
+		lc.Append(fxutil.Hook("metrics", fx.Hook{
+			OnStart: ctrl.Start,
+			OnStop:  ctrl.Stop,
+		}))
+	}
+}
+
+// serveMetrics installs a global meter provider backed by Prometheus and serves metrics over HTTP
+// together with the health check.
+func serveMetrics(addr string) func(lc fx.Lifecycle, ex header.Exchange) error {
+	return func(lc fx.Lifecycle, ex header.Exchange) error {
+		exp, err := prometheus.InstallNewPipeline(prometheus.Config{})
+		if err != nil {
+			return err
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/-/health", healthHandler(ex))
+		mux.Handle("/", exp)
+		srv := &http.Server{Addr: addr, Handler: mux}
+		lc.Append(fxutil.Hook("metrics server", fx.Hook{
+			OnStart: func(context.Context) error {
+				lst, err := net.Listen("tcp", addr)
+				if err != nil {
+					return err
+				}
+
+				go func() {
+					err := srv.Serve(lst)
+					if err != nil && !errors.Is(err, http.ErrServerClosed) {
+						log.Errorw("serving metrics", "addr", addr, "err", err)
+					}
+				}()
+				return nil
+			},
+			OnStop: srv.Shutdown,
+		}))
+		return nil
+	}
+}
+
+// healthProbeTimeout limits the time the health check waits for the Exchange to respond.
+var healthProbeTimeout = time.Second * 5
+
+// healthHandler responds with 200 if the Exchange responds to the Probe, and with 503 otherwise.
+func healthHandler(ex header.Exchange) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthProbeTimeout)
+		defer cancel()
+
+		err := ex.Probe(ctx)
+		if err != nil {
+			log.Warnw("health check failed", "err", err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok")) //nolint:errcheck
+	})
+}
+
+// observeMetrics registers observers for metrics reported by the Node itself.
+func observeMetrics(host host.Host, store header.Store) error {
+	_, err := global.Meter("p2p").NewInt64ValueObserver("p2p_peers",
+		func(_ context.Context, res metric.Int64ObserverResult) {
+			res.Observe(int64(len(host.Network().Peers())))
+		},
+		metric.WithDescription("Amount of connected peers"),
+	)
+	if err != nil {
+		return err
+	}
+
+	return header.ObserveStore(store)
+}
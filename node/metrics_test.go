@@ -0,0 +1,129 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
+
+	headertest "github.com/celestiaorg/celestia-node/service/header/testing"
+)
+
+func TestLightWithMetrics(t *testing.T) {
+	exp := &memExporter{ExportKindSelector: export.CumulativeExportKindSelector(), values: make(map[string]int64)}
+	store := MockStore(t, DefaultConfig(Light))
+	nd, err := New(Light, store, WithMetrics(exp))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	err = nd.Start(ctx)
+	require.NoError(t, err)
+	// metrics are exported one last time on stop
+	err = nd.Stop(ctx)
+	require.NoError(t, err)
+
+	exp.lk.Lock()
+	defer exp.lk.Unlock()
+	assert.Contains(t, exp.values, "p2p_peers")
+}
+
+func TestLightWithMetricsServer(t *testing.T) {
+	lst, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lst.Addr().String()
+	require.NoError(t, lst.Close())
+
+	store := MockStore(t, DefaultConfig(Light))
+	nd, err := New(Light, store, MetricsServer(addr))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	err = nd.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		nd.Stop(ctx) //nolint:errcheck
+	})
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/metrics", addr))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "p2p_peers")
+
+	// the Node has no peers to probe
+	timeout := healthProbeTimeout
+	healthProbeTimeout = time.Millisecond * 100
+	t.Cleanup(func() {
+		healthProbeTimeout = timeout
+	})
+	health, err := http.Get(fmt.Sprintf("http://%s/-/health", addr))
+	require.NoError(t, err)
+	defer health.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, health.StatusCode)
+}
+
+func TestHealthHandler(t *testing.T) {
+	ex := headertest.NewMockExchange()
+	ex.ExpectProbe().Times(1)
+	ex.ExpectProbe().ReturnError(errors.New("no peers"))
+	handler := healthHandler(ex)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/-/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/-/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "no peers")
+	ex.AssertExpectations(t)
+}
+
+// memExporter keeps the latest exported values of metrics in memory.
+type memExporter struct {
+	export.ExportKindSelector
+
+	lk     sync.Mutex
+	values map[string]int64
+}
+
+func (e *memExporter) Export(_ context.Context, set export.CheckpointSet) error {
+	e.lk.Lock()
+	defer e.lk.Unlock()
+	return set.ForEach(e, func(r export.Record) error {
+		switch agg := r.Aggregation().(type) {
+		case aggregation.Sum:
+			sum, err := agg.Sum()
+			if err != nil {
+				return err
+			}
+			e.values[r.Descriptor().Name()] = sum.AsInt64()
+		case aggregation.LastValue:
+			last, _, err := agg.LastValue()
+			if err != nil {
+				return err
+			}
+			e.values[r.Descriptor().Name()] = last.AsInt64()
+		}
+		return nil
+	})
+}
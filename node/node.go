@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	exchange "github.com/ipfs/go-ipfs-exchange-interface"
@@ -21,6 +22,7 @@ import (
 	"github.com/celestiaorg/celestia-node/node/fxutil"
 	"github.com/celestiaorg/celestia-node/node/rpc"
 	"github.com/celestiaorg/celestia-node/service/block"
+	"github.com/celestiaorg/celestia-node/service/fraud"
 	"github.com/celestiaorg/celestia-node/service/header"
 	"github.com/celestiaorg/celestia-node/service/share"
 )
@@ -56,11 +58,20 @@ type Node struct {
 	BlockServ  *block.Service  `optional:"true"`
 	ShareServ  share.Service   // not optional
 	HeaderServ *header.Service // not optional
+	FraudServ  *fraud.Service  // not optional
 
 	DASer *das.DASer `optional:"true"`
+	// CoreListener broadcasts headers produced by the Core node to the network. Bridge only.
+	CoreListener *header.CoreListener `optional:"true"`
 
 	// start and stop control ref internal fx.App lifecycle funcs to be called from Start and Stop
 	start, stop lifecycleFunc
+	// cancelFraudWatch stops watching for fraud proofs once the Node is stopped
+	cancelFraudWatch context.CancelFunc
+	// startedAt is the time the Node was last started at, zero if it is not started.
+	// Guarded by startedLk, as the Node may be stopped on fraud concurrently with Status.
+	startedLk sync.Mutex
+	startedAt time.Time
 }
 
 // New assembles a new Node with the given type 'tp' over Store 'store'.
@@ -82,9 +93,9 @@ func New(tp Type, store Store, options ...Option) (*Node, error) {
 
 	switch tp {
 	case Bridge:
-		return newNode(bridgeComponents(cfg, store), s.overrides())
+		return newNode(bridgeComponents(cfg, store), s.overrides(), s.metrics(), s.bootstrap())
 	case Light:
-		return newNode(lightComponents(cfg, store), s.overrides())
+		return newNode(lightComponents(cfg, store), s.overrides(), s.metrics(), s.bootstrap())
 	default:
 		panic("node: unknown Node Type")
 	}
@@ -97,13 +108,18 @@ func (n *Node) Start(ctx context.Context) error {
 
 	err := n.start(ctx)
 	if err != nil {
-		log.Errorf("starting %s Node: %s", n.Type, err)
+		log.Errorw("starting Node", "type", n.Type, "err", err)
 		return fmt.Errorf("node: failed to start: %w", err)
 	}
 
 	// TODO(@Wondertan): Print useful information about the node:
 	//  * API/RPC address
 	log.Infof("started %s Node", n.Type)
+	n.setStartedAt(time.Now())
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	n.cancelFraudWatch = cancel
+	go n.stopOnFraud(watchCtx)
 
 	addrs, err := peer.AddrInfoToP2pAddrs(host.InfoFromHost(n.Host))
 	if err != nil {
@@ -140,20 +156,56 @@ func (n *Node) RegisterAPI(endpoint string, api http.Handler) error {
 // Stop shuts down the Node, all its running Components/Services and returns.
 // Canceling the given context earlier 'ctx' unblocks the Stop and aborts graceful shutdown forcing remaining
 // Components/Services to close immediately.
+// Every Component/Service is given at most fxutil.StopTimeout to stop, so a single hanging one does not block
+// the others. Errors of all Components/Services are combined, including the ones which timed out,
+// letting the caller decide whether to force exit.
 func (n *Node) Stop(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, Timeout)
 	defer cancel()
 
+	if n.cancelFraudWatch != nil {
+		n.cancelFraudWatch()
+	}
+
 	err := n.stop(ctx)
 	if err != nil {
-		log.Errorf("Stopping %s Node: %s", n.Type, err)
+		log.Errorw("stopping Node", "type", n.Type, "err", err)
 		return err
 	}
 
 	log.Infof("stopped %s Node", n.Type)
+	n.setStartedAt(time.Time{})
 	return nil
 }
 
+// setStartedAt sets the time the Node was started at, or resets it with the zero time.
+func (n *Node) setStartedAt(t time.Time) {
+	n.startedLk.Lock()
+	defer n.startedLk.Unlock()
+	n.startedAt = t
+}
+
+// uptime returns the time passed since the Node was started, zero if it is not started.
+func (n *Node) uptime() time.Duration {
+	n.startedLk.Lock()
+	defer n.startedLk.Unlock()
+	if n.startedAt.IsZero() {
+		return 0
+	}
+	return time.Since(n.startedAt)
+}
+
+// stopOnFraud stops the Node once a valid fraud proof for its chain is received,
+// as none of the following headers can be trusted anymore.
+func (n *Node) stopOnFraud(ctx context.Context) {
+	select {
+	case <-n.FraudServ.Detected():
+		log.Errorw("fraud proof received, stopping Node", "type", n.Type)
+		n.Stop(context.Background()) //nolint:errcheck // Stop logs errors itself
+	case <-ctx.Done():
+	}
+}
+
 // newNode creates a new Node from given DI options.
 // DI options allow initializing the Node with a customized set of components and services.
 // NOTE: newNode is currently meant to be used privately to create various custom Node types e.g. Light, unless we
@@ -53,9 +53,9 @@ func Host(cfg Config) func(hostParams) (HostBase, error) {
 			return nil, err
 		}
 
-		params.Lc.Append(fx.Hook{OnStop: func(context.Context) error {
+		params.Lc.Append(fxutil.Hook("p2p host", fx.Hook{OnStop: func(context.Context) error {
 			return h.Close()
-		}})
+		}}))
 
 		return h, nil
 	}
@@ -76,6 +76,7 @@ func Components(cfg Config) fxutil.Option {
 		fxutil.Provide(ContentRouting),
 		fxutil.Provide(AddrsFactory(cfg.AnnounceAddresses, cfg.NoAnnounceAddresses)),
 		fxutil.Invoke(Listen(cfg.ListenAddresses)),
+		fxutil.Invoke(PeerExchange(cfg)),
 	)
 }
 
@@ -0,0 +1,214 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	ma "github.com/multiformats/go-multiaddr"
+	"go.uber.org/fx"
+
+	"github.com/celestiaorg/go-libp2p-messenger/serde"
+
+	"github.com/celestiaorg/celestia-node/node/fxutil"
+	pb "github.com/celestiaorg/celestia-node/node/p2p/pb"
+)
+
+var log = logging.Logger("p2p")
+
+var pxProtocolID = protocol.ID("/celestia/px/v0.0.1")
+
+const (
+	// maxExchangedPeers limits the amount of peers shared within a single response.
+	maxExchangedPeers = 64
+	// maxPeersResponseSize limits the size of a response read by RequestPeers.
+	maxPeersResponseSize = 1 << 20
+	// pxTimeout limits the time a single exchange of peers may take.
+	pxTimeout = time.Second * 30
+)
+
+// PeerExchangeProtocol lets nodes request the peers known to each other,
+// so nodes joining the network quickly populate their peerstores.
+type PeerExchangeProtocol struct {
+	host host.Host
+}
+
+// NewPeerExchangeProtocol creates a new PeerExchangeProtocol serving peers known to the given host.
+func NewPeerExchangeProtocol(host host.Host) *PeerExchangeProtocol {
+	return &PeerExchangeProtocol{host: host}
+}
+
+// Start starts serving peer requests.
+func (px *PeerExchangeProtocol) Start(context.Context) error {
+	px.host.SetStreamHandler(pxProtocolID, px.handleRequest)
+	return nil
+}
+
+// Stop stops serving peer requests.
+func (px *PeerExchangeProtocol) Stop(context.Context) error {
+	px.host.RemoveStreamHandler(pxProtocolID)
+	return nil
+}
+
+// handleRequest responds with the peers known to the host, besides itself and the requester.
+func (px *PeerExchangeProtocol) handleRequest(stream network.Stream) {
+	defer stream.Close()
+	stream.SetWriteDeadline(time.Now().Add(pxTimeout)) //nolint:errcheck
+
+	from := stream.Conn().RemotePeer()
+	peers := make([]peer.AddrInfo, 0, maxExchangedPeers)
+	for _, id := range px.host.Peerstore().PeersWithAddrs() {
+		if len(peers) == maxExchangedPeers {
+			break
+		}
+		if id == px.host.ID() || id == from {
+			continue
+		}
+		peers = append(peers, px.host.Peerstore().PeerInfo(id))
+	}
+
+	_, err := serde.Write(stream, peersToProto(peers))
+	if err != nil {
+		stream.Reset() //nolint:errcheck
+		log.Errorw("px: writing peers", "peer", from.ShortString(), "err", err)
+		return
+	}
+	log.Debugw("px: served peers", "peer", from.ShortString(), "amount", len(peers))
+}
+
+// RequestPeers connects to the target peer and requests the peers it knows.
+func RequestPeers(ctx context.Context, host host.Host, target peer.AddrInfo) ([]peer.AddrInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, pxTimeout)
+	defer cancel()
+
+	err := host.Connect(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := host.NewStream(ctx, target.ID, pxProtocolID)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetReadDeadline(deadline) //nolint:errcheck
+	}
+	err = stream.CloseWrite()
+	if err != nil {
+		stream.Reset() //nolint:errcheck
+		return nil, err
+	}
+
+	resp := new(pb.PeerExchangeResponse)
+	_, err = serde.Read(io.LimitReader(stream, maxPeersResponseSize), resp)
+	if err != nil {
+		stream.Reset() //nolint:errcheck
+		return nil, fmt.Errorf("p2p: reading peers from %s: %w", target.ID.ShortString(), err)
+	}
+	if len(resp.Peers) > maxExchangedPeers {
+		resp.Peers = resp.Peers[:maxExchangedPeers]
+	}
+	peers, err := peersFromProto(resp)
+	if err != nil {
+		return nil, fmt.Errorf("p2p: reading peers from %s: %w", target.ID.ShortString(), err)
+	}
+	return peers, nil
+}
+
+// peersToProto converts the given peers to the wire format.
+func peersToProto(peers []peer.AddrInfo) *pb.PeerExchangeResponse {
+	resp := &pb.PeerExchangeResponse{Peers: make([]*pb.AddrInfo, len(peers))}
+	for i, p := range peers {
+		addrs := make([][]byte, len(p.Addrs))
+		for j, addr := range p.Addrs {
+			addrs[j] = addr.Bytes()
+		}
+		resp.Peers[i] = &pb.AddrInfo{Id: []byte(p.ID), Addrs: addrs}
+	}
+	return resp
+}
+
+// peersFromProto converts the peers received in the wire format.
+func peersFromProto(resp *pb.PeerExchangeResponse) ([]peer.AddrInfo, error) {
+	peers := make([]peer.AddrInfo, len(resp.Peers))
+	for i, p := range resp.Peers {
+		id, err := peer.IDFromBytes(p.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs := make([]ma.Multiaddr, len(p.Addrs))
+		for j, addr := range p.Addrs {
+			addrs[j], err = ma.NewMultiaddrBytes(addr)
+			if err != nil {
+				return nil, err
+			}
+		}
+		peers[i] = peer.AddrInfo{ID: id, Addrs: addrs}
+	}
+	return peers, nil
+}
+
+// PeerExchange serves peer requests and, unless the node is a bootstrapper,
+// requests the peers known to bootstrap peers once the node starts.
+func PeerExchange(cfg Config) func(pxParams) error {
+	return func(params pxParams) error {
+		bpeers, err := cfg.bootstrapPeers()
+		if err != nil {
+			return err
+		}
+
+		px := NewPeerExchangeProtocol(params.Host)
+		ctx := fxutil.WithLifecycle(params.Ctx, params.Lc)
+		params.Lc.Append(fxutil.Hook("peer exchange", fx.Hook{
+			OnStart: func(startCtx context.Context) error {
+				err := px.Start(startCtx)
+				if err != nil || cfg.Bootstrapper {
+					return err
+				}
+				// do not block the start on slow or unavailable bootstrappers
+				go requestBootstrapPeers(ctx, params.Host, bpeers)
+				return nil
+			},
+			OnStop: px.Stop,
+		}))
+		return nil
+	}
+}
+
+// requestBootstrapPeers requests the peers known to each bootstrap peer and adds them to the peerstore.
+func requestBootstrapPeers(ctx context.Context, host host.Host, bpeers []peer.AddrInfo) {
+	for _, bpeer := range bpeers {
+		peers, err := RequestPeers(ctx, host, bpeer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warnw("px: requesting peers from bootstrapper", "peer", bpeer.ID.ShortString(), "err", err)
+			continue
+		}
+
+		for _, p := range peers {
+			if p.ID == host.ID() {
+				continue
+			}
+			host.Peerstore().AddAddrs(p.ID, p.Addrs, peerstore.AddressTTL)
+		}
+		log.Infow("px: received peers from bootstrapper", "peer", bpeer.ID.ShortString(), "amount", len(peers))
+	}
+}
+
+type pxParams struct {
+	fx.In
+
+	Ctx  context.Context
+	Lc   fx.Lifecycle
+	Host host.Host
+}
@@ -0,0 +1,54 @@
+package p2p
+
+import (
+	"context"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerExchange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	net, err := mocknet.WithNPeers(ctx, 3)
+	require.NoError(t, err)
+	require.NoError(t, net.LinkAll())
+	joining, bootstrapper, other := net.Hosts()[0], net.Hosts()[1], net.Hosts()[2]
+	// only the bootstrapper knows the other peer
+	_, err = net.ConnectPeers(bootstrapper.ID(), other.ID())
+	require.NoError(t, err)
+
+	px := NewPeerExchangeProtocol(bootstrapper)
+	require.NoError(t, px.Start(ctx))
+	t.Cleanup(func() {
+		px.Stop(ctx) //nolint:errcheck
+	})
+
+	peers, err := RequestPeers(ctx, joining, *host.InfoFromHost(bootstrapper))
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	assert.Equal(t, other.ID(), peers[0].ID)
+	assert.ElementsMatch(t, other.Addrs(), peers[0].Addrs)
+
+	// the received peers populate the peerstore
+	joining.Peerstore().ClearAddrs(other.ID())
+	requestBootstrapPeers(ctx, joining, []peer.AddrInfo{*host.InfoFromHost(bootstrapper)})
+	assert.ElementsMatch(t, other.Addrs(), joining.Peerstore().Addrs(other.ID()))
+	require.NoError(t, joining.Connect(ctx, peer.AddrInfo{ID: other.ID()}))
+}
+
+func TestPeerExchange_NotSupported(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	net, err := mocknet.FullMeshLinked(ctx, 2)
+	require.NoError(t, err)
+
+	_, err = RequestPeers(ctx, net.Hosts()[0], *host.InfoFromHost(net.Hosts()[1]))
+	assert.Error(t, err)
+}
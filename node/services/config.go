@@ -2,6 +2,8 @@ package services
 
 import (
 	"encoding/hex"
+	"fmt"
+	"time"
 
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/multiformats/go-multiaddr"
@@ -16,13 +18,28 @@ type Config struct {
 	// Note: The trusted does *not* imply Headers are not verified, but trusted as reliable to fetch headers
 	// at any moment.
 	TrustedPeer string
+	// TrustedPeers are the peers preferred for fetching headers, in addition to TrustedPeer.
+	// Headers are requested from them before any discovered peers.
+	TrustedPeers []string
+	// PruningInterval is the interval at which old headers are pruned from the header store.
+	// Zero disables pruning.
+	PruningInterval time.Duration
+	// PruningKeepLast is the amount of the latest headers kept in the header store by pruning.
+	PruningKeepLast uint64
+	// WatchdogInterval is the interval at which the header Syncer and the DASer are checked for progress,
+	// so they are restarted once stuck. Zero disables watching.
+	WatchdogInterval time.Duration
 }
 
 // TODO(@Wondertan): We need to hardcode trustedHash hash and one bootstrap peer as trusted.
 func DefaultConfig() Config {
 	return Config{
-		TrustedHash: "",
-		TrustedPeer: "",
+		TrustedHash:      "",
+		TrustedPeer:      "",
+		TrustedPeers:     []string{},
+		PruningInterval:  0,
+		PruningKeepLast:  100000,
+		WatchdogInterval: time.Minute,
 	}
 }
 
@@ -39,6 +56,18 @@ func (cfg *Config) trustedPeer() (*peer.AddrInfo, error) {
 	return peer.AddrInfoFromP2pAddr(ma)
 }
 
+func (cfg *Config) trustedPeers() (_ []peer.AddrInfo, err error) {
+	maddrs := make([]multiaddr.Multiaddr, len(cfg.TrustedPeers))
+	for i, addr := range cfg.TrustedPeers {
+		maddrs[i], err = multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("failure to parse config.Services.TrustedPeers: %s", err)
+		}
+	}
+
+	return peer.AddrInfosFromP2pAddrs(maddrs...)
+}
+
 func (cfg *Config) trustedHash() (tmbytes.HexBytes, error) {
 	return hex.DecodeString(cfg.TrustedHash)
 }
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/ipfs/go-datastore"
 	ipld "github.com/ipfs/go-ipld-format"
@@ -13,22 +14,32 @@ import (
 	"github.com/celestiaorg/celestia-node/das"
 	"github.com/celestiaorg/celestia-node/node/fxutil"
 	"github.com/celestiaorg/celestia-node/service/block"
+	"github.com/celestiaorg/celestia-node/service/fraud"
 	"github.com/celestiaorg/celestia-node/service/header"
 	"github.com/celestiaorg/celestia-node/service/share"
 )
 
 // HeaderSyncer creates a new header.Syncer.
-func HeaderSyncer(cfg Config) func(lc fx.Lifecycle, ex header.Exchange, store header.Store) (*header.Syncer, error) {
-	return func(lc fx.Lifecycle, ex header.Exchange, store header.Store) (*header.Syncer, error) {
+func HeaderSyncer(cfg Config) func(
+	lc fx.Lifecycle,
+	ex header.Exchange,
+	store header.Store,
+	ds datastore.Batching,
+) (*header.Syncer, error) {
+	return func(lc fx.Lifecycle, ex header.Exchange, store header.Store, ds datastore.Batching) (*header.Syncer, error) {
 		trustedHash, err := cfg.trustedHash()
 		if err != nil {
 			return nil, err
 		}
 
-		syncer := header.NewSyncer(ex, store, trustedHash)
-		lc.Append(fx.Hook{
+		syncer := header.NewSyncer(ex, store, trustedHash,
+			header.WithCheckpoints(header.NewCheckpointStore(ds)),
+			header.WithSyncStateStore(header.NewSyncStateStore(ds)),
+		)
+		lc.Append(fxutil.Hook("header syncer", fx.Hook{
 			OnStart: syncer.Start,
-		})
+			OnStop:  syncer.Stop,
+		}))
 
 		return syncer, nil
 	}
@@ -37,10 +48,10 @@ func HeaderSyncer(cfg Config) func(lc fx.Lifecycle, ex header.Exchange, store he
 // P2PSubscriber creates a new header.P2PSubscriber.
 func P2PSubscriber(lc fx.Lifecycle, sub *pubsub.PubSub, syncer *header.Syncer) *header.P2PSubscriber {
 	p2pSub := header.NewP2PSubscriber(sub, syncer.Validate)
-	lc.Append(fx.Hook{
+	lc.Append(fxutil.Hook("header p2p subscriber", fx.Hook{
 		OnStart: p2pSub.Start,
 		OnStop:  p2pSub.Stop,
-	})
+	}))
 	return p2pSub
 }
 
@@ -50,8 +61,9 @@ func HeaderService(
 	p2pSub *header.P2PSubscriber,
 	p2pServer *header.P2PExchangeServer,
 	ex header.Exchange,
+	store header.Store,
 ) *header.Service {
-	return header.NewHeaderService(syncer, p2pSub, p2pServer, ex)
+	return header.NewHeaderService(syncer, p2pSub, p2pServer, ex, store)
 }
 
 // HeaderExchangeP2P constructs new P2PExchange for headers.
@@ -66,22 +78,43 @@ func HeaderExchangeP2P(cfg Config) func(
 			return nil, err
 		}
 
-		ex := header.NewP2PExchange(host, peer, store)
-		lc.Append(fx.Hook{
+		peers, err := cfg.trustedPeers()
+		if err != nil {
+			return nil, err
+		}
+
+		ex := header.NewP2PExchange(host, peer, store, header.WithPeers(peers))
+		lc.Append(fxutil.Hook("header p2p exchange", fx.Hook{
 			OnStart: ex.Start,
 			OnStop:  ex.Stop,
-		})
+		}))
 		return ex, nil
 	}
 }
 
+// NetworkHeadTracker constructs a new header.NetworkHeadTracker following the head of the network
+// through the P2PExchange peers.
+func NetworkHeadTracker(lc fx.Lifecycle, ex header.Exchange, store header.Store) (das.NetworkHead, error) {
+	p2pEx, ok := ex.(*header.P2PExchange)
+	if !ok {
+		return nil, fmt.Errorf("network head tracker: unsupported exchange %T", ex)
+	}
+
+	tracker := header.NewNetworkHeadTracker(p2pEx, store)
+	lc.Append(fxutil.Hook("network head tracker", fx.Hook{
+		OnStart: tracker.Start,
+		OnStop:  tracker.Stop,
+	}))
+	return tracker, nil
+}
+
 // HeaderP2PExchangeServer creates a new header.P2PExchangeServer.
 func HeaderP2PExchangeServer(lc fx.Lifecycle, host host.Host, store header.Store) *header.P2PExchangeServer {
 	p2pServ := header.NewP2PExchangeServer(host, store)
-	lc.Append(fx.Hook{
+	lc.Append(fxutil.Hook("header p2p exchange server", fx.Hook{
 		OnStart: p2pServ.Start,
 		OnStop:  p2pServ.Stop,
-	})
+	}))
 
 	return p2pServ
 }
@@ -91,37 +124,76 @@ func HeaderStore(ds datastore.Batching) (header.Store, error) {
 	return header.NewStore(ds)
 }
 
+// HeaderPruner constructs a new header.Pruner and registers it in the lifecycle.
+func HeaderPruner(cfg Config) func(lc fx.Lifecycle, store header.Store) {
+	return func(lc fx.Lifecycle, store header.Store) {
+		pruner := header.NewPruner(store, cfg.PruningInterval, cfg.PruningKeepLast)
+		lc.Append(fxutil.Hook("header pruner", fx.Hook{
+			OnStart: pruner.Start,
+			OnStop:  pruner.Stop,
+		}))
+	}
+}
+
 // BlockService constructs new block.Service.
 func BlockService(
 	lc fx.Lifecycle,
 	store ipld.DAGService,
 ) *block.Service {
 	service := block.NewBlockService(store)
-	lc.Append(fx.Hook{
+	lc.Append(fxutil.Hook("block service", fx.Hook{
 		OnStart: service.Start,
 		OnStop:  service.Stop,
-	})
+	}))
 	return service
 }
 
 // ShareService constructs new share.Service.
 func ShareService(lc fx.Lifecycle, dag ipld.DAGService, avail share.Availability) share.Service {
 	service := share.NewService(dag, avail)
-	lc.Append(fx.Hook{
+	lc.Append(fxutil.Hook("share service", fx.Hook{
 		OnStart: service.Start,
 		OnStop:  service.Stop,
-	})
+	}))
 	return service
 }
 
-// DASer constructs a new Data Availability Sampler.
-func DASer(lc fx.Lifecycle, avail share.Availability, sub header.Subscriber) *das.DASer {
-	das := das.NewDASer(avail, sub)
-	lc.Append(fx.Hook{
+// DASer constructs a new Data Availability Sampler, which samples the heads of the network
+// and triggers the fraud.Service on headers systematically failing sampling.
+func DASer(
+	lc fx.Lifecycle,
+	avail share.Availability,
+	store header.Store,
+	ds datastore.Batching,
+	fraudServ *fraud.Service,
+	head das.NetworkHead,
+) (*das.DASer, error) {
+	das, err := das.NewDASer(avail, store, ds, das.WithFraudTrigger(fraudServ), das.WithNetworkHead(head))
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(fxutil.Hook("DASer", fx.Hook{
 		OnStart: das.Start,
 		OnStop:  das.Stop,
-	})
-	return das
+	}))
+	return das, nil
+}
+
+// FraudService constructs a new fraud.Service validating fraud proofs against the local header.Store
+// and proving bad encodings of the data retrieved from the DAG.
+func FraudService(
+	lc fx.Lifecycle,
+	sub *pubsub.PubSub,
+	store header.Store,
+	ds datastore.Batching,
+	dag ipld.DAGService,
+) *fraud.Service {
+	service := fraud.NewService(sub, store, fraud.NewStore(ds), fraud.WithDAG(dag))
+	lc.Append(fxutil.Hook("fraud service", fx.Hook{
+		OnStart: service.Start,
+		OnStop:  service.Stop,
+	}))
+	return service
 }
 
 // LightAvailability constructs light share availability.
@@ -2,13 +2,22 @@ package node
 
 import (
 	"encoding/hex"
+	"fmt"
+	"strings"
 
+	logging "github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p-core/crypto"
+	crypto_pb "github.com/libp2p/go-libp2p-core/crypto/pb"
 	"github.com/libp2p/go-libp2p-core/host"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/celestiaorg/celestia-node/core"
+	"github.com/celestiaorg/celestia-node/logs"
 	"github.com/celestiaorg/celestia-node/node/fxutil"
 	"github.com/celestiaorg/celestia-node/node/p2p"
+	"github.com/celestiaorg/celestia-node/service/header"
 )
 
 // Option for Node's Config.
@@ -41,6 +50,27 @@ func WithP2PKeyStr(key string) Option {
 
 }
 
+// p2pKeyTypes are the supported types of p2p private keys by name.
+var p2pKeyTypes = map[string]crypto_pb.KeyType{
+	"ed25519":   crypto_pb.KeyType_Ed25519,
+	"secp256k1": crypto_pb.KeyType_Secp256k1,
+}
+
+// WithP2PPrivKeyBytes parses the raw private key of the given type for p2p networking,
+// either "ed25519" or "secp256k1", and returns an Option setting it.
+func WithP2PPrivKeyBytes(keyType string, raw []byte) (Option, error) {
+	typ, ok := p2pKeyTypes[strings.ToLower(keyType)]
+	if !ok {
+		return nil, fmt.Errorf("node: unsupported p2p key type %q", keyType)
+	}
+
+	key, err := crypto.PrivKeyUnmarshallers[typ](raw)
+	if err != nil {
+		return nil, fmt.Errorf("node: parsing %s p2p key: %w", keyType, err)
+	}
+	return WithP2PKey(key), nil
+}
+
 // WithHost sets custom Host's data for p2p networking.
 func WithHost(host host.Host) Option {
 	return func(cfg *Config, sets *settings) (_ error) {
@@ -57,11 +87,46 @@ func WithCoreClient(client core.Client) Option {
 	}
 }
 
+// WithLogger routes logs of the Node and all its components through the given logger.
+// Every component logs with a child logger named after it, e.g. "header-service" or "das".
+// NOTE: Logging is global, so the logger is used by all the Nodes within the process.
+func WithLogger(logger *zap.Logger) Option {
+	return func(cfg *Config, sets *settings) (_ error) {
+		logging.SetPrimaryCore(logger.Core())
+		return
+	}
+}
+
+// WithLogLevel sets the level of logs for all the components.
+// NOTE: Logging is global, so the level is applied to all the Nodes within the process.
+func WithLogLevel(level zapcore.Level) Option {
+	return func(cfg *Config, sets *settings) (_ error) {
+		logs.SetAllLoggers(logging.LogLevel(level))
+		return
+	}
+}
+
+// WithCustomStore sets a pre-built header Store for the Node instead of the one built over the Datastore of
+// the repository. The store may already contain headers, e.g. ones shared with other Node instances.
+func WithCustomStore(store header.Store) Option {
+	return func(cfg *Config, sets *settings) (_ error) {
+		sets.HeaderStore = store
+		return
+	}
+}
+
 // settings store all the non Config values that can be altered for Node with Options.
 type settings struct {
 	P2PKey     crypto.PrivKey
 	Host       p2p.HostBase
 	CoreClient core.Client
+
+	HeaderStore header.Store
+
+	MetricsExporter export.Exporter
+	MetricsAddr     string
+
+	BootstrapHeaders []*header.ExtendedHeader
 }
 
 // overrides collects all the custom Modules and Components set to be overridden for the Node.
@@ -71,5 +136,6 @@ func (sets *settings) overrides() fxutil.Option {
 		&sets.P2PKey,
 		&sets.Host,
 		&sets.CoreClient,
+		&sets.HeaderStore,
 	)
 }
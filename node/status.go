@@ -0,0 +1,67 @@
+package node
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// NodeStatus is a snapshot of the Node's health.
+type NodeStatus struct {
+	// SyncHeight is the height of the latest header stored by the Node.
+	SyncHeight uint64
+	// NetworkHeight is the height of the latest header known from the network.
+	NetworkHeight uint64
+	// PeerCount is the number of peers the Node is connected to.
+	PeerCount int
+	// ConnectedPeers are the peers the Node is connected to, with their known addresses.
+	ConnectedPeers []peer.AddrInfo
+	// IsSynced is set once the Node caught up with the network.
+	IsSynced bool
+	// UptimeSeconds is the time passed since the Node was started, zero if it is not started.
+	UptimeSeconds float64
+}
+
+// Status reports the current NodeStatus of the Node.
+func (n *Node) Status(ctx context.Context) (NodeStatus, error) {
+	syncStatus, err := n.HeaderServ.SyncStatus(ctx)
+	if err != nil {
+		return NodeStatus{}, fmt.Errorf("node: getting sync status: %w", err)
+	}
+
+	peers, err := n.ConnectedPeers(ctx)
+	if err != nil {
+		return NodeStatus{}, fmt.Errorf("node: getting connected peers: %w", err)
+	}
+
+	status := NodeStatus{
+		SyncHeight:     syncStatus.Height,
+		NetworkHeight:  syncStatus.NetworkHeight,
+		PeerCount:      len(peers),
+		ConnectedPeers: peers,
+		IsSynced:       syncStatus.Synced(),
+		UptimeSeconds:  n.uptime().Seconds(),
+	}
+	return status, nil
+}
+
+// ConnectedPeers returns the peers the Node is currently connected to,
+// together with their addresses known to the peerstore.
+func (n *Node) ConnectedPeers(ctx context.Context) ([]peer.AddrInfo, error) {
+	peers := n.Host.Network().Peers()
+	infos := make([]peer.AddrInfo, 0, len(peers))
+	for _, p := range peers {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		infos = append(infos, n.Host.Peerstore().PeerInfo(p))
+	}
+	return infos, nil
+}
+
+// PeerCount returns the number of peers the Node is currently connected to.
+// Unlike ConnectedPeers, it does not look up their addresses.
+func (n *Node) PeerCount() int {
+	return len(n.Host.Network().Peers())
+}
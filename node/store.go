@@ -1,11 +1,13 @@
 package node
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"sync"
 
+	"github.com/dgraph-io/badger/v2/options"
 	"github.com/ipfs/go-datastore"
 	dsbadger "github.com/ipfs/go-ds-badger2"
 	"github.com/mitchellh/go-homedir"
@@ -43,16 +45,63 @@ type Store interface {
 	// PutConfig alters the stored Node config.
 	PutConfig(*Config) error
 
+	// Compact reclaims the disk space taken by deleted data of the Datastore, e.g. after pruning.
+	// It is safe to call concurrently with reads and writes, and repeatedly.
+	Compact(context.Context) error
+
 	// Close closes the Store freeing up acquired resources and locks.
 	Close() error
 }
 
+// StoreOption configures OpenStore.
+type StoreOption func(*storeOptions)
+
+type storeOptions struct {
+	compression string
+}
+
+// WithStoreCompression sets the algorithm compressing the Datastore on disk: "snappy", "zstd" or "none",
+// which is the default.
+// Compression trades CPU time on every read and write for disk space. Snappy is cheap, but saves less,
+// while zstd saves more, but costs more CPU and requires the binary to be built with cgo.
+// NOTE: Only the blocks of the LSM tree are compressed, which keep keys and values below 1KiB,
+// while larger values, e.g. most headers, are kept in the value log uncompressed.
+// The algorithm can be changed between openings, as already written data keeps its own.
+func WithStoreCompression(algo string) StoreOption {
+	return func(opts *storeOptions) {
+		opts.compression = algo
+	}
+}
+
+// compressionType returns the Badger compression type for the given algorithm.
+func compressionType(algo string) (options.CompressionType, error) {
+	switch algo {
+	case "", "none":
+		return options.None, nil
+	case "snappy":
+		return options.Snappy, nil
+	case "zstd":
+		return options.ZSTD, nil
+	default:
+		return 0, fmt.Errorf("node: unknown store compression %q", algo)
+	}
+}
+
 // OpenStore creates new FS Store under the given 'path'.
 // To be opened the Store must be initialized first, otherwise ErrNotInited is thrown.
 // OpenStore takes a file Lock on directory, hence only one Store can be opened at a time under the given 'path',
 // otherwise ErrOpened is thrown.
-func OpenStore(path string, tp Type) (Store, error) {
-	path, err := storePath(path)
+func OpenStore(path string, tp Type, opts ...StoreOption) (Store, error) {
+	var sopts storeOptions
+	for _, opt := range opts {
+		opt(&sopts)
+	}
+	compression, err := compressionType(sopts.compression)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = storePath(path)
 	if err != nil {
 		return nil, err
 	}
@@ -72,8 +121,9 @@ func OpenStore(path string, tp Type) (Store, error) {
 	}
 
 	return &fsStore{
-		path:    path,
-		dirLock: flock,
+		path:        path,
+		compression: compression,
+		dirLock:     flock,
 	}, nil
 }
 
@@ -131,10 +181,12 @@ func (f *fsStore) Datastore() (_ datastore.Batching, err error) {
 
 	// TODO(@Wondertan): Study badger code and review available options to fine tune it for our use-cases.
 	opts := dsbadger.DefaultOptions // this should be copied
-	f.data, err = dsbadger.NewDatastore(dataPath(f.path), &opts)
+	opts.Compression = f.compression
+	data, err := dsbadger.NewDatastore(dataPath(f.path), &opts)
 	if err != nil {
 		return nil, fmt.Errorf("node: can't open Badger Datastore: %w", err)
 	}
+	f.data = data
 
 	return f.data, nil
 }
@@ -160,11 +212,15 @@ func (f *fsStore) Core() (_ core.Store, err error) {
 
 func (f *fsStore) Close() error {
 	defer f.dirLock.Unlock() // nolint: errcheck
+	if f.data == nil {
+		return nil
+	}
 	return f.data.Close()
 }
 
 type fsStore struct {
-	path string
+	path        string
+	compression options.CompressionType
 
 	data datastore.Batching
 	keys keystore.Keystore
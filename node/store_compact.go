@@ -0,0 +1,60 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/ipfs/go-datastore"
+)
+
+// compactLk serializes compactions, as Badger rejects garbage collection running concurrently.
+var compactLk sync.Mutex
+
+// CompactStore compacts the Datastore of the stopped Node Store under the given 'path'.
+func CompactStore(ctx context.Context, path string) error {
+	return withStoreData(path, func(ds datastore.Batching) error {
+		return compact(ctx, ds)
+	})
+}
+
+func (f *fsStore) Compact(ctx context.Context) error {
+	ds, err := f.Datastore()
+	if err != nil {
+		return err
+	}
+	return compact(ctx, ds)
+}
+
+func (m *memStore) Compact(context.Context) error {
+	return nil
+}
+
+// compact reclaims the space taken by deleted data of the given Datastore, if it supports it.
+// For Badger, the value log is garbage collected until no more files can be rewritten.
+func compact(ctx context.Context, ds datastore.Batching) error {
+	gcds, ok := ds.(datastore.GCDatastore)
+	if !ok {
+		return nil
+	}
+
+	compactLk.Lock()
+	defer compactLk.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := gcds.CollectGarbage()
+	// the periodic garbage collection of the Datastore itself is running, which does the same
+	if errors.Is(err, badger.ErrRejected) {
+		log.Debugw("compacting store: garbage collection is in progress")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info("compacted store")
+	return nil
+}
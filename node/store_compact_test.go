@@ -0,0 +1,48 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreCompact(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := NewMemStore().Compact(ctx)
+	assert.NoError(t, err)
+
+	path := t.TempDir()
+	err = CompactStore(ctx, path)
+	assert.ErrorIs(t, err, ErrNotInited)
+
+	err = Init(path, Light)
+	require.NoError(t, err)
+	store, err := OpenStore(path, Light)
+	require.NoError(t, err)
+
+	// compacting is idempotent and does not interfere with reads
+	ds, err := store.Datastore()
+	require.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_, err := ds.Has(datastore.NewKey("key"))
+			assert.NoError(t, err)
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		err = store.Compact(ctx)
+		require.NoError(t, err)
+	}
+	<-done
+	require.NoError(t, store.Close())
+
+	err = CompactStore(ctx, path)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,110 @@
+package node
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+
+	"github.com/ipfs/go-datastore"
+
+	"github.com/celestiaorg/celestia-node/service/header"
+	"github.com/celestiaorg/go-libp2p-messenger/serde"
+)
+
+// DumpFormat is the encoding of headers written by DumpHeaders.
+type DumpFormat string
+
+const (
+	// DumpJSON writes every header as JSON on its own line.
+	DumpJSON DumpFormat = "json"
+	// DumpProto writes every header as a length-delimited Protobuf message.
+	DumpProto DumpFormat = "proto"
+)
+
+// DumpHeaders writes the range [from:to) of headers kept within the Node Store under the given 'path' to 'w'
+// in the given format. Zero 'from' starts at the tail of the store and zero 'to' ends with its head.
+// Headers are read one by one, so the range may be of any size. The Node must not be running.
+// Dumping stops without an error once the reading side of 'w' is closed, e.g. when piped to 'head'.
+func DumpHeaders(ctx context.Context, path string, from, to uint64, format DumpFormat, w io.Writer) error {
+	var write func(io.Writer, *header.ExtendedHeader) error
+	switch format {
+	case DumpJSON:
+		write = writeHeaderJSON
+	case DumpProto:
+		write = writeHeaderProto
+	default:
+		return fmt.Errorf("node: unknown dump format %q", format)
+	}
+
+	return withStoreData(path, func(ds datastore.Batching) error {
+		store, err := header.NewStore(ds)
+		if err != nil {
+			return err
+		}
+
+		if from == 0 {
+			tail, err := store.Tail(ctx)
+			if err != nil {
+				return err
+			}
+			from = uint64(tail.Height)
+		}
+		if to == 0 {
+			head, err := store.Head(ctx)
+			if err != nil {
+				return err
+			}
+			to = uint64(head.Height) + 1
+		}
+		if from >= to {
+			return fmt.Errorf("node: empty dump range [%d:%d)", from, to)
+		}
+
+		it, err := store.IterateByHeight(ctx, from, to)
+		if err != nil {
+			return err
+		}
+		defer it.Close() //nolint:errcheck
+
+		bw := bufio.NewWriter(w)
+		for it.Next() {
+			err = write(bw, it.Value())
+			if err != nil {
+				return dumpErr(err)
+			}
+		}
+		if err = it.Close(); err != nil {
+			return err
+		}
+		return dumpErr(bw.Flush())
+	})
+}
+
+// dumpErr hides the error of writing to the closed pipe, as the reader does not want more headers.
+func dumpErr(err error) error {
+	if errors.Is(err, syscall.EPIPE) {
+		return nil
+	}
+	return err
+}
+
+func writeHeaderJSON(w io.Writer, h *header.ExtendedHeader) error {
+	b, err := h.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func writeHeaderProto(w io.Writer, h *header.ExtendedHeader) error {
+	pbh, err := header.ExtendedHeaderToProto(h)
+	if err != nil {
+		return err
+	}
+	_, err = serde.Write(w, pbh)
+	return err
+}
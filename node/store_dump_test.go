@@ -0,0 +1,109 @@
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/celestia-node/service/header"
+	pb "github.com/celestiaorg/celestia-node/service/header/pb"
+	"github.com/celestiaorg/go-libp2p-messenger/serde"
+)
+
+func TestDumpHeaders(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := t.TempDir()
+	err := DumpHeaders(ctx, path, 0, 0, DumpJSON, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrNotInited)
+
+	in := header.NewTestSuite(t, 3).GenExtendedHeaders(20)
+	writeHeaders(t, path, in)
+
+	err = DumpHeaders(ctx, path, 0, 0, "yaml", &bytes.Buffer{})
+	assert.Error(t, err)
+
+	// the whole store is dumped by default
+	var out bytes.Buffer
+	err = DumpHeaders(ctx, path, 0, 0, DumpJSON, &out)
+	require.NoError(t, err)
+	scanner := bufio.NewScanner(&out)
+	scanner.Buffer(nil, 1<<20)
+	var heights []int64
+	for scanner.Scan() {
+		h := new(header.ExtendedHeader)
+		require.NoError(t, h.UnmarshalJSON(scanner.Bytes()))
+		heights = append(heights, h.Height)
+	}
+	require.Len(t, heights, len(in))
+	assert.Equal(t, in[0].Height, heights[0])
+	assert.Equal(t, in[len(in)-1].Height, heights[len(heights)-1])
+
+	out.Reset()
+	err = DumpHeaders(ctx, path, 5, 8, DumpProto, &out)
+	require.NoError(t, err)
+	for height := int64(5); height < 8; height++ {
+		pbh := new(pb.ExtendedHeader)
+		_, err = serde.Read(&out, pbh)
+		require.NoError(t, err)
+		h, err := header.ProtoToExtendedHeader(pbh)
+		require.NoError(t, err)
+		assert.Equal(t, height, h.Height)
+	}
+	assert.Zero(t, out.Len())
+}
+
+// TestDumpHeaders_Pipe tests that dumped headers can be piped to other commands.
+func TestDumpHeaders_Pipe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := t.TempDir()
+	writeHeaders(t, path, header.NewTestSuite(t, 3).GenExtendedHeaders(20))
+
+	wc := exec.Command("wc", "-l")
+	stdin, err := wc.StdinPipe()
+	require.NoError(t, err)
+	var out bytes.Buffer
+	wc.Stdout = &out
+	err = wc.Start()
+	if err != nil {
+		t.Skipf("wc is not available: %v", err)
+	}
+
+	err = DumpHeaders(ctx, path, 1, 11, DumpJSON, stdin)
+	require.NoError(t, err)
+	require.NoError(t, stdin.Close())
+	require.NoError(t, wc.Wait())
+	assert.Equal(t, "10", strings.TrimSpace(out.String()))
+
+	// the reader going away stops dumping without an error
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	err = DumpHeaders(ctx, path, 0, 0, DumpJSON, w)
+	assert.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+// writeHeaders initializes the Node Store at the given path with the given headers.
+func writeHeaders(t *testing.T, path string, headers []*header.ExtendedHeader) {
+	require.NoError(t, Init(path, Light))
+	store, err := OpenStore(path, Light)
+	require.NoError(t, err)
+	defer store.Close()
+
+	ds, err := store.Datastore()
+	require.NoError(t, err)
+	hstore, err := header.NewStoreWithHead(ds, headers[0])
+	require.NoError(t, err)
+	require.NoError(t, hstore.Append(context.Background(), headers[1:]...))
+}
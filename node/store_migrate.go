@@ -0,0 +1,89 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ipfs/go-datastore"
+	dsbadger "github.com/ipfs/go-ds-badger2"
+
+	"github.com/celestiaorg/celestia-node/libs/fslock"
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+// MigrateOption configures MigrateStore.
+type MigrateOption func(*migrateOptions)
+
+type migrateOptions struct {
+	dryRun bool
+}
+
+// WithDryRun makes MigrateStore only log the changes it would make, without writing them.
+func WithDryRun() MigrateOption {
+	return func(opts *migrateOptions) {
+		opts.dryRun = true
+	}
+}
+
+// MigrateStore migrates the header store kept within the Node Store under the given 'path'
+// from one schema version to another. The Node must not be running.
+// The Node migrates an outdated header store to the current schema itself once started, so MigrateStore
+// is mostly useful to inspect the changes with a dry run beforehand.
+func MigrateStore(ctx context.Context, path string, from, to header.SchemaVersion, opts ...MigrateOption) error {
+	var options migrateOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var changes []header.MigrationChange
+	err := withStoreData(path, func(ds datastore.Batching) (err error) {
+		changes, err = header.MigrateSchema(ctx, ds, from, to, options.dryRun)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if options.dryRun {
+		for _, ch := range changes {
+			log.Infow("dry run: record would be migrated", "from", ch.From, "to", ch.To)
+		}
+		log.Infow("dry run: store would be migrated", "from", from, "to", to, "records", len(changes))
+		return nil
+	}
+
+	log.Infow("migrated store", "from", from, "to", to, "records", len(changes))
+	return nil
+}
+
+// withStoreData opens the Badger datastore of the stopped Node Store under the given 'path'
+// for the duration of 'f'.
+func withStoreData(path string, f func(datastore.Batching) error) error {
+	path, err := storePath(path)
+	if err != nil {
+		return err
+	}
+
+	flock, err := fslock.Lock(lockPath(path))
+	if err != nil {
+		if err == fslock.ErrLocked {
+			return ErrOpened
+		}
+		return err
+	}
+	defer flock.Unlock() //nolint: errcheck
+
+	if _, err := os.Stat(dataPath(path)); err != nil {
+		return ErrNotInited
+	}
+
+	dsOpts := dsbadger.DefaultOptions // this should be copied
+	ds, err := dsbadger.NewDatastore(dataPath(path), &dsOpts)
+	if err != nil {
+		return fmt.Errorf("node: can't open Badger Datastore: %w", err)
+	}
+	defer ds.Close()
+
+	return f(ds)
+}
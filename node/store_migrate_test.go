@@ -0,0 +1,121 @@
+package node
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dsbadger "github.com/ipfs/go-ds-badger2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+func TestMigrateStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := t.TempDir()
+	suite := header.NewTestSuite(t, 3)
+	in := suite.GenExtendedHeaders(20)
+	writeV1Fixture(t, dataPath(path), in)
+
+	// dry run changes nothing, so the store can still be migrated from the outdated schema
+	err := MigrateStore(ctx, path, header.SchemaV1, header.SchemaV2, WithDryRun())
+	require.NoError(t, err)
+
+	err = MigrateStore(ctx, path, header.SchemaV1, header.SchemaV2)
+	require.NoError(t, err)
+	openHeaderStore(t, path, func(ds datastore.Batching) {
+		store, err := header.NewStore(ds)
+		require.NoError(t, err)
+
+		head, err := store.Head(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, in[len(in)-1].Hash(), head.Hash())
+
+		tail, err := store.Tail(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, in[0].Hash(), tail.Hash())
+
+		out, err := store.GetRangeByHeight(ctx, 1, uint64(len(in)+1))
+		require.NoError(t, err)
+		require.Len(t, out, len(in))
+		for i, h := range out {
+			assert.Equal(t, in[i].Hash(), h.Hash())
+		}
+
+		// the old index is gone
+		has, err := ds.Has(datastore.NewKey("headers/1"))
+		require.NoError(t, err)
+		assert.False(t, has)
+	})
+
+	// the store is already migrated
+	err = MigrateStore(ctx, path, header.SchemaV1, header.SchemaV2)
+	assert.Error(t, err)
+}
+
+// TestMigrateStore_OnOpen tests that the outdated header store is migrated once opened, without MigrateStore.
+func TestMigrateStore_OnOpen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := t.TempDir()
+	in := header.NewTestSuite(t, 3).GenExtendedHeaders(20)
+	writeV1Fixture(t, dataPath(path), in)
+
+	openHeaderStore(t, path, func(ds datastore.Batching) {
+		store, err := header.NewStore(ds)
+		require.NoError(t, err)
+
+		out, err := store.GetRangeByHeight(ctx, 1, uint64(len(in)+1))
+		require.NoError(t, err)
+		require.Len(t, out, len(in))
+		for i, h := range out {
+			assert.Equal(t, in[i].Hash(), h.Hash())
+		}
+	})
+
+	// the store is already migrated
+	err := MigrateStore(ctx, path, header.SchemaV1, header.SchemaV2)
+	assert.Error(t, err)
+}
+
+func TestMigrateStore_NotInited(t *testing.T) {
+	err := MigrateStore(context.Background(), t.TempDir(), header.SchemaV1, header.SchemaV2)
+	assert.ErrorIs(t, err, ErrNotInited)
+}
+
+// writeV1Fixture writes the given headers into a Badger datastore at the given path the way
+// the header store kept them with the first schema.
+func writeV1Fixture(t *testing.T, path string, headers []*header.ExtendedHeader) {
+	opts := dsbadger.DefaultOptions
+	ds, err := dsbadger.NewDatastore(path, &opts)
+	require.NoError(t, err)
+	defer ds.Close()
+
+	prefix := datastore.NewKey("headers")
+	for _, h := range headers {
+		b, err := h.MarshalBinary()
+		require.NoError(t, err)
+		require.NoError(t, ds.Put(prefix.ChildString(h.Hash().String()), b))
+		require.NoError(t, ds.Put(prefix.ChildString(strconv.Itoa(int(h.Height))), h.Hash()))
+	}
+
+	head, err := headers[len(headers)-1].Hash().MarshalJSON()
+	require.NoError(t, err)
+	require.NoError(t, ds.Put(prefix.ChildString("head"), head))
+	require.NoError(t, ds.Put(prefix.ChildString("tail"), []byte(strconv.Itoa(int(headers[0].Height)))))
+}
+
+// openHeaderStore opens the Badger datastore of the Node Store at the given path for the duration of 'f'.
+func openHeaderStore(t *testing.T, path string, f func(datastore.Batching)) {
+	opts := dsbadger.DefaultOptions
+	ds, err := dsbadger.NewDatastore(dataPath(path), &opts)
+	require.NoError(t, err)
+	defer ds.Close()
+	f(ds)
+}
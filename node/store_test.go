@@ -1,10 +1,15 @@
 package node
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/celestia-node/service/header"
 )
 
 func TestRepoBridge(t *testing.T) {
@@ -70,3 +75,88 @@ func TestRepoLight(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, cfg)
 }
+
+func TestRepoCompression(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	err := Init(dir, Light)
+	require.NoError(t, err)
+
+	_, err = OpenStore(dir, Light, WithStoreCompression("lz4"))
+	assert.Error(t, err)
+
+	in := header.NewTestSuite(t, 3).GenExtendedHeaders(10)
+	store, err := OpenStore(dir, Light, WithStoreCompression("snappy"))
+	require.NoError(t, err)
+	ds, err := store.Datastore()
+	require.NoError(t, err)
+	hstore, err := header.NewStoreWithHead(ds, in[0])
+	require.NoError(t, err)
+	err = hstore.Append(ctx, in[1:]...)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	// data compressed before stays readable regardless of the algorithm
+	store, err = OpenStore(dir, Light, WithStoreCompression("none"))
+	require.NoError(t, err)
+	defer store.Close()
+	ds, err = store.Datastore()
+	require.NoError(t, err)
+	hstore, err = header.NewStore(ds)
+	require.NoError(t, err)
+	for _, h := range in {
+		out, err := hstore.GetByHeight(ctx, uint64(h.Height))
+		require.NoError(t, err)
+		assert.Equal(t, h.Hash(), out.Hash())
+	}
+}
+
+// BenchmarkStoreCompression reports the on-disk size of the Datastore keeping 10000 headers
+// with each of the compression algorithms.
+func BenchmarkStoreCompression(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := header.NewTestSuite(b, 3).GenExtendedHeaders(10000)
+	for _, algo := range []string{"none", "snappy", "zstd"} {
+		b.Run(algo, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				dir := b.TempDir()
+				err := Init(dir, Light)
+				require.NoError(b, err)
+
+				store, err := OpenStore(dir, Light, WithStoreCompression(algo))
+				require.NoError(b, err)
+				ds, err := store.Datastore()
+				if err != nil {
+					// zstd is not available without cgo
+					store.Close() //nolint:errcheck
+					b.Skip(err)
+				}
+				hstore, err := header.NewStoreWithHead(ds, in[0])
+				require.NoError(b, err)
+				err = hstore.Append(ctx, in[1:]...)
+				require.NoError(b, err)
+				require.NoError(b, store.Close())
+
+				b.ReportMetric(float64(dirSize(b, dataPath(dir))), "disk-bytes")
+			}
+		})
+	}
+}
+
+func dirSize(tb testing.TB, dir string) (size int64) {
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	require.NoError(tb, err)
+	return size
+}
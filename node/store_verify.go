@@ -0,0 +1,21 @@
+package node
+
+import (
+	"context"
+
+	"github.com/ipfs/go-datastore"
+
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+// VerifyStore checks the consistency of the header store kept within the Node Store under the given 'path'
+// with header.VerifyStore. The Node must not be running.
+func VerifyStore(ctx context.Context, path string) error {
+	return withStoreData(path, func(ds datastore.Batching) error {
+		store, err := header.NewStore(ds)
+		if err != nil {
+			return err
+		}
+		return header.VerifyStore(ctx, store)
+	})
+}
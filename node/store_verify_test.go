@@ -0,0 +1,39 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+func TestVerifyStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := t.TempDir()
+	err := VerifyStore(ctx, path)
+	assert.ErrorIs(t, err, ErrNotInited)
+
+	suite := header.NewTestSuite(t, 3)
+	in := suite.GenExtendedHeaders(20)
+	writeV1Fixture(t, dataPath(path), in)
+	err = MigrateStore(ctx, path, header.SchemaV1, header.SchemaV2)
+	require.NoError(t, err)
+
+	err = VerifyStore(ctx, path)
+	require.NoError(t, err)
+
+	// drop a header in the middle
+	openHeaderStore(t, path, func(ds datastore.Batching) {
+		require.NoError(t, ds.Delete(datastore.NewKey("headers").ChildString(in[10].Hash().String())))
+	})
+	err = VerifyStore(ctx, path)
+	var cerr *header.ConsistencyError
+	require.ErrorAs(t, err, &cerr)
+	assert.EqualValues(t, in[10].Height, cerr.Height)
+}
@@ -1,5 +1,12 @@
 package node
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // Type defines the Node type (e.g. `light`, `bridge`) for identity purposes.
 // The zero value for Type is invalid.
 type Type uint8
@@ -13,6 +20,9 @@ const (
 	Light
 )
 
+// ErrInvalidType is returned for strings not naming any valid Type.
+var ErrInvalidType = errors.New("node: invalid type")
+
 // String converts Type to its string representation.
 func (t Type) String() string {
 	if !t.IsValid() {
@@ -27,24 +37,49 @@ func (t Type) IsValid() bool {
 	return ok
 }
 
-// ParseType converts string in a type if possible.
-func ParseType(str string) Type {
-	tp, ok := stringToType[str]
+// MarshalJSON encodes the Type by its name, so it is readable in serialized configs.
+func (t Type) MarshalJSON() ([]byte, error) {
+	if !t.IsValid() {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidType, t)
+	}
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON decodes the Type from its name.
+func (t *Type) UnmarshalJSON(b []byte) error {
+	var str string
+	err := json.Unmarshal(b, &str)
+	if err != nil {
+		return err
+	}
+
+	tp, err := ParseType(str)
+	if err != nil {
+		return err
+	}
+	*t = tp
+	return nil
+}
+
+// ParseType converts the name of a Type in any case into the Type.
+// It errors with ErrInvalidType if there is no such Type.
+func ParseType(str string) (Type, error) {
+	tp, ok := stringToType[strings.ToLower(str)]
 	if !ok {
-		return 0
+		return 0, fmt.Errorf("%w: %q", ErrInvalidType, str)
 	}
 
-	return tp
+	return tp, nil
 }
 
 // typeToString keeps string representations of all valid Types.
 var typeToString = map[Type]string{
-	Bridge: "Bridge",
-	Light:  "Light",
+	Bridge: "bridge",
+	Light:  "light",
 }
 
 // typeToString maps strings representations of all valid Types.
 var stringToType = map[string]Type{
-	"Bridge": Bridge,
-	"Light":  Light,
+	"bridge": Bridge,
+	"light":  Light,
 }
@@ -0,0 +1,55 @@
+package node
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestType_RoundTrip(t *testing.T) {
+	for _, tp := range []Type{Bridge, Light} {
+		parsed, err := ParseType(tp.String())
+		require.NoError(t, err)
+		assert.Equal(t, tp, parsed)
+
+		b, err := json.Marshal(tp)
+		require.NoError(t, err)
+		assert.Equal(t, `"`+tp.String()+`"`, string(b))
+
+		var unmarshaled Type
+		err = json.Unmarshal(b, &unmarshaled)
+		require.NoError(t, err)
+		assert.Equal(t, tp, unmarshaled)
+	}
+
+	// types are referenced by name within serialized configs
+	cfg := struct {
+		Type Type
+	}{Type: Light}
+	b, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Type": "light"}`, string(b))
+}
+
+func TestParseType(t *testing.T) {
+	tp, err := ParseType("Bridge")
+	require.NoError(t, err)
+	assert.Equal(t, Bridge, tp)
+
+	for _, str := range []string{"", "full", "lite", "1"} {
+		_, err = ParseType(str)
+		assert.ErrorIs(t, err, ErrInvalidType, str)
+	}
+
+	var unmarshaled Type
+	err = json.Unmarshal([]byte(`"full"`), &unmarshaled)
+	assert.ErrorIs(t, err, ErrInvalidType)
+	err = json.Unmarshal([]byte(`2`), &unmarshaled)
+	assert.Error(t, err)
+
+	_, err = json.Marshal(Type(0))
+	assert.ErrorIs(t, err, ErrInvalidType)
+	assert.Equal(t, "unknown", Type(0).String())
+}
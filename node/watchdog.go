@@ -0,0 +1,149 @@
+package node
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/celestiaorg/celestia-node/das"
+	"github.com/celestiaorg/celestia-node/node/fxutil"
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+// Service is a component with a lifecycle, which can be restarted by Watchdog.
+type Service interface {
+	Start(context.Context) error
+	Stop(context.Context) error
+}
+
+// HealthCheck reports whether the watched Service is healthy, e.g. it made progress since the last check.
+type HealthCheck func() bool
+
+// ProgressCheck returns a HealthCheck failing once the Service stayed busy since the previous check,
+// but its progress did not advance in the meantime. Idle services are always healthy.
+func ProgressCheck(progress func() uint64, busy func() bool) HealthCheck {
+	var (
+		last    uint64
+		wasBusy bool
+	)
+	return func() bool {
+		current, isBusy := progress(), busy()
+		healthy := !wasBusy || !isBusy || current > last
+		last, wasBusy = current, isBusy
+		return healthy
+	}
+}
+
+// Watchdog periodically checks the health of a Service and restarts the Service once the check fails,
+// e.g. when the Service stopped making progress.
+type Watchdog struct {
+	service  Service
+	check    HealthCheck
+	interval time.Duration
+	// restarts is accessed atomically
+	restarts uint64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatchdog creates a new Watchdog checking the given Service every 'interval'.
+func NewWatchdog(service Service, check HealthCheck, interval time.Duration) *Watchdog {
+	return &Watchdog{
+		service:  service,
+		check:    check,
+		interval: interval,
+	}
+}
+
+// Start starts the watching routine. The Service itself must be started separately.
+func (w *Watchdog) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel, w.done = cancel, make(chan struct{})
+	go w.watch(ctx)
+	return nil
+}
+
+// Stop stops the watching routine, waiting for the restart in progress, if any.
+func (w *Watchdog) Stop(ctx context.Context) error {
+	w.cancel()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Restarts returns the amount of times the Service was restarted.
+func (w *Watchdog) Restarts() uint64 {
+	return atomic.LoadUint64(&w.restarts)
+}
+
+func (w *Watchdog) watch(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if w.check() {
+				continue
+			}
+			log.Warn("watchdog: service is unhealthy, restarting")
+			w.restart(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// restart stops and starts the Service again. The Service is started even if it fails to stop,
+// as it may be stuck.
+func (w *Watchdog) restart(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	err := w.service.Stop(ctx)
+	if err != nil {
+		log.Errorw("watchdog: stopping service", "err", err)
+	}
+	err = w.service.Start(ctx)
+	if err != nil {
+		log.Errorw("watchdog: starting service", "err", err)
+		return
+	}
+	atomic.AddUint64(&w.restarts, 1)
+}
+
+// watchSyncer restarts the header.Syncer once it is stuck syncing, i.e. the head of the header.Store
+// does not advance.
+func watchSyncer(interval time.Duration) func(fx.Lifecycle, *header.Syncer, header.Store) {
+	return func(lc fx.Lifecycle, syncer *header.Syncer, store header.Store) {
+		head := func() uint64 {
+			h, err := store.Head(context.Background())
+			if err != nil {
+				return 0
+			}
+			return uint64(h.Height)
+		}
+		wd := NewWatchdog(syncer, ProgressCheck(head, syncer.IsSyncing), interval)
+		lc.Append(fxutil.Hook("header syncer watchdog", fx.Hook{
+			OnStart: wd.Start,
+			OnStop:  wd.Stop,
+		}))
+	}
+}
+
+// watchDASer restarts the DASer once it is stuck sampling.
+func watchDASer(interval time.Duration) func(fx.Lifecycle, *das.DASer) {
+	return func(lc fx.Lifecycle, daser *das.DASer) {
+		wd := NewWatchdog(daser, ProgressCheck(daser.Attempts, daser.IsSampling), interval)
+		lc.Append(fxutil.Hook("DASer watchdog", fx.Hook{
+			OnStart: wd.Start,
+			OnStop:  wd.Stop,
+		}))
+	}
+}
@@ -0,0 +1,147 @@
+package node
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdog_RestartsStalledService(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	srv := &stallingService{}
+	err := srv.Start(ctx)
+	require.NoError(t, err)
+
+	var last uint64
+	wd := NewWatchdog(srv, func() bool {
+		progress := srv.Progress()
+		defer func() { last = progress }()
+		return progress > last
+	}, time.Millisecond*20)
+	err = wd.Start(ctx)
+	require.NoError(t, err)
+
+	// healthy service is left alone
+	srv.Advance()
+	time.Sleep(time.Millisecond * 30)
+	srv.Advance()
+	time.Sleep(time.Millisecond * 30)
+
+	// stalled service gets restarted
+	require.Eventually(t, func() bool {
+		return wd.Restarts() >= 1
+	}, time.Second, time.Millisecond*10)
+
+	err = wd.Stop(ctx)
+	require.NoError(t, err)
+
+	starts, stops := srv.Calls()
+	assert.Equal(t, wd.Restarts()+1, starts)
+	assert.Equal(t, wd.Restarts(), stops)
+}
+
+func TestWatchdog_LeavesHealthyService(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance bool
+		busy    bool
+	}{
+		{"busy", true, true},
+		{"idle", false, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			srv := &stallingService{}
+			err := srv.Start(ctx)
+			require.NoError(t, err)
+			progress := srv.Progress
+			if tt.advance {
+				// the service progresses between any two checks
+				progress = func() uint64 {
+					srv.Advance()
+					return srv.Progress()
+				}
+			}
+
+			wd := NewWatchdog(srv, ProgressCheck(progress, func() bool { return tt.busy }), time.Millisecond*20)
+			err = wd.Start(ctx)
+			require.NoError(t, err)
+			time.Sleep(time.Millisecond * 200)
+			err = wd.Stop(ctx)
+			require.NoError(t, err)
+
+			assert.Zero(t, wd.Restarts())
+			starts, stops := srv.Calls()
+			assert.EqualValues(t, 1, starts)
+			assert.Zero(t, stops)
+		})
+	}
+}
+
+func TestProgressCheck(t *testing.T) {
+	var (
+		progress uint64
+		busy     bool
+	)
+	check := ProgressCheck(func() uint64 { return progress }, func() bool { return busy })
+	// idle service is healthy without any progress
+	assert.True(t, check())
+	assert.True(t, check())
+	// service just became busy is given time to progress
+	busy = true
+	assert.True(t, check())
+	progress++
+	assert.True(t, check())
+	// service busy since the previous check without progress is stuck
+	assert.False(t, check())
+}
+
+// stallingService is a Service which only makes progress when told to.
+type stallingService struct {
+	lk       sync.Mutex
+	progress uint64
+	starts   uint64
+	stops    uint64
+}
+
+func (s *stallingService) Start(context.Context) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.starts++
+	return nil
+}
+
+func (s *stallingService) Stop(context.Context) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.stops++
+	return nil
+}
+
+func (s *stallingService) Advance() {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.progress++
+}
+
+func (s *stallingService) Progress() uint64 {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	return s.progress
+}
+
+func (s *stallingService) Calls() (starts, stops uint64) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	return s.starts, s.stops
+}
@@ -0,0 +1,218 @@
+package fraud
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	format "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/tendermint/tendermint/pkg/consts"
+	"github.com/tendermint/tendermint/pkg/da"
+	"github.com/tendermint/tendermint/pkg/wrapper"
+
+	"github.com/celestiaorg/celestia-node/ipld"
+	"github.com/celestiaorg/celestia-node/ipld/plugin"
+	fraud_pb "github.com/celestiaorg/celestia-node/service/fraud/pb"
+	"github.com/celestiaorg/celestia-node/service/header"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+var log = logging.Logger("fraud")
+
+// ErrInvalidProof is returned when a proof does not prove the fraud it claims.
+var ErrInvalidProof = errors.New("fraud: invalid proof")
+
+// Axis defines whether a proof is made for a row or a column of the extended data square.
+type Axis uint8
+
+const (
+	Row Axis = iota
+	Col
+)
+
+// ShareWithProof keeps a share along with the NMT nodes proving its inclusion into the axis root.
+type ShareWithProof struct {
+	Share []byte
+	Proof [][]byte
+}
+
+// BadEncodingProof proves that an axis of the extended data square committed to in the DataAvailabilityHeader
+// is not erasure coded correctly. Every given share is proven to be committed to the axis root, while decoding
+// the shares yields an axis with another root.
+type BadEncodingProof struct {
+	Height uint64
+	Index  uint32
+	Axis   Axis
+	// Shares of the axis. Missing shares are nil.
+	Shares []*ShareWithProof
+}
+
+// NewBadEncodingProof creates a BadEncodingProof for the header at the given height
+// out of the rsmt2d.ErrByzantineRow or rsmt2d.ErrByzantineCol returned by the repair of its data square.
+// Inclusion proofs of the shares are fetched from the given DAG.
+func NewBadEncodingProof(
+	ctx context.Context,
+	dag format.NodeGetter,
+	height uint64,
+	dah *da.DataAvailabilityHeader,
+	err error,
+) (*BadEncodingProof, error) {
+	var (
+		errRow *rsmt2d.ErrByzantineRow
+		errCol *rsmt2d.ErrByzantineCol
+		proof  = &BadEncodingProof{Height: height}
+		shares [][]byte
+		root   []byte
+	)
+	switch {
+	case errors.As(err, &errRow):
+		proof.Index, proof.Axis, shares = uint32(errRow.RowNumber), Row, errRow.Shares
+		root = dah.RowsRoots[errRow.RowNumber]
+	case errors.As(err, &errCol):
+		proof.Index, proof.Axis, shares = uint32(errCol.ColNumber), Col, errCol.Shares
+		root = dah.ColumnRoots[errCol.ColNumber]
+	default:
+		return nil, fmt.Errorf("fraud: not a bad encoding error: %w", err)
+	}
+
+	rootCid, err := plugin.CidFromNamespacedSha256(root)
+	if err != nil {
+		return nil, err
+	}
+
+	proof.Shares = make([]*ShareWithProof, len(shares))
+	for i, share := range shares {
+		if share == nil {
+			continue
+		}
+
+		leaf, nodes, err := ipld.GetProof(ctx, dag, rootCid, i, len(shares))
+		if err != nil {
+			return nil, err
+		}
+		// leaf data is prefixed with the namespace pushed to the tree
+		proof.Shares[i] = &ShareWithProof{Share: leaf.RawData()[1+ipld.NamespaceSize:], Proof: nodes}
+	}
+
+	return proof, nil
+}
+
+// Hash returns the hash identifying the proof.
+func (p *BadEncodingProof) Hash() ([]byte, error) {
+	bin, err := p.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(bin)
+	return hash[:], nil
+}
+
+// Validate checks the proof against the given ExtendedHeader, returning ErrInvalidProof
+// if the proof does not prove the header commits to a badly encoded data square.
+func (p *BadEncodingProof) Validate(h *header.ExtendedHeader) error {
+	if uint64(h.Height) != p.Height {
+		return fmt.Errorf("fraud: proof for height %d validated against header %d", p.Height, h.Height)
+	}
+
+	roots := h.DAH.RowsRoots
+	if p.Axis == Col {
+		roots = h.DAH.ColumnRoots
+	}
+	if int(p.Index) >= len(roots) || len(p.Shares) != len(roots) {
+		return fmt.Errorf("%w: axis %d out of square of width %d", ErrInvalidProof, p.Index, len(roots))
+	}
+	root, odsWidth := roots[p.Index], len(roots)/2
+
+	var (
+		shares = make([][]byte, len(p.Shares))
+		size   int
+		amount int
+	)
+	for i, share := range p.Shares {
+		if share == nil || len(share.Share) == 0 {
+			continue
+		}
+		if size == 0 {
+			size = len(share.Share)
+		}
+		if len(share.Share) != size || size < ipld.NamespaceSize {
+			return fmt.Errorf("%w: malformed share %d", ErrInvalidProof, i)
+		}
+
+		// cap the namespace, so the verification does not append to the share
+		nid := share.Share[:ipld.NamespaceSize:ipld.NamespaceSize]
+		if int(p.Index) >= odsWidth || i >= odsWidth {
+			nid = consts.ParitySharesNamespaceID
+		}
+		proof := nmt.NewInclusionProof(i, i+1, share.Proof, true)
+		if !proof.VerifyInclusion(consts.NewBaseHashFunc(), nid, share.Share, root) {
+			return fmt.Errorf("%w: share %d is not committed to the axis", ErrInvalidProof, i)
+		}
+
+		shares[i] = share.Share
+		amount++
+	}
+	if amount < odsWidth {
+		return fmt.Errorf("%w: %d shares are not enough to recover the axis", ErrInvalidProof, amount)
+	}
+
+	codec := rsmt2d.NewRSGF8Codec()
+	ods, err := codec.Decode(shares)
+	if err != nil {
+		return fmt.Errorf("%w: decoding shares: %s", ErrInvalidProof, err)
+	}
+	parity, err := codec.Encode(ods)
+	if err != nil {
+		return fmt.Errorf("%w: encoding shares: %s", ErrInvalidProof, err)
+	}
+
+	tree := wrapper.NewErasuredNamespacedMerkleTree(uint64(odsWidth))
+	for i, share := range append(ods, parity...) {
+		tree.Push(share, rsmt2d.SquareIndex{Axis: uint(p.Index), Cell: uint(i)})
+	}
+	if bytes.Equal(tree.Root(), root) {
+		return fmt.Errorf("%w: axis %d is encoded correctly", ErrInvalidProof, p.Index)
+	}
+
+	return nil
+}
+
+// MarshalBinary serializes the proof to bytes using protobuf.
+func (p *BadEncodingProof) MarshalBinary() ([]byte, error) {
+	out := &fraud_pb.BadEncodingProof{
+		Height: p.Height,
+		Index:  p.Index,
+		Axis:   fraud_pb.Axis(p.Axis),
+		Shares: make([]*fraud_pb.Share, len(p.Shares)),
+	}
+	for i, share := range p.Shares {
+		out.Shares[i] = &fraud_pb.Share{}
+		if share != nil {
+			out.Shares[i].Data, out.Shares[i].Proof = share.Share, share.Proof
+		}
+	}
+	return out.Marshal()
+}
+
+// UnmarshalBinary deserializes the proof from bytes using protobuf.
+func (p *BadEncodingProof) UnmarshalBinary(data []byte) error {
+	in := &fraud_pb.BadEncodingProof{}
+	err := in.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+
+	p.Height, p.Index, p.Axis = in.Height, in.Index, Axis(in.Axis)
+	p.Shares = make([]*ShareWithProof, len(in.Shares))
+	for i, share := range in.Shares {
+		if len(share.Data) != 0 {
+			p.Shares[i] = &ShareWithProof{Share: share.Data, Proof: share.Proof}
+		}
+	}
+	return nil
+}
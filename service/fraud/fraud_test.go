@@ -0,0 +1,71 @@
+package fraud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mdutils "github.com/ipfs/go-merkledag/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/pkg/da"
+
+	"github.com/celestiaorg/celestia-node/ipld"
+	"github.com/celestiaorg/celestia-node/service/header"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+func TestBadEncodingProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	dag := mdutils.Mock()
+	h, proof := ByzantineProof(ctx, t, dag)
+	assert.Equal(t, Row, proof.Axis)
+	assert.EqualValues(t, 0, proof.Index)
+
+	err := proof.Validate(h)
+	require.NoError(t, err)
+
+	bin, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	got := &BadEncodingProof{}
+	err = got.UnmarshalBinary(bin)
+	require.NoError(t, err)
+	assert.Equal(t, proof, got)
+	assert.NoError(t, got.Validate(h))
+}
+
+func TestBadEncodingProof_Invalid(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	dag := mdutils.Mock()
+	eds, err := ipld.PutData(ctx, ipld.RandNamespacedShares(t, 16).Raw(), dag)
+	require.NoError(t, err)
+	h := header.RandExtendedHeader(t)
+	dah := da.NewDataAvailabilityHeader(eds)
+	h.DAH = &dah
+
+	// the row is encoded correctly, so it cannot be proven otherwise
+	errRow := &rsmt2d.ErrByzantineRow{RowNumber: 1, Shares: eds.Row(1)}
+	proof, err := NewBadEncodingProof(ctx, dag, uint64(h.Height), h.DAH, errRow)
+	require.NoError(t, err)
+	assert.ErrorIs(t, proof.Validate(h), ErrInvalidProof)
+
+	h, proof = ByzantineProof(ctx, t, dag)
+	// the share is not committed to the row
+	proof.Shares[1].Share = proof.Shares[2].Share
+	assert.ErrorIs(t, proof.Validate(h), ErrInvalidProof)
+
+	h, proof = ByzantineProof(ctx, t, dag)
+	// not enough shares to decode the row
+	for i := range proof.Shares[1:] {
+		proof.Shares[i+1] = nil
+	}
+	assert.ErrorIs(t, proof.Validate(h), ErrInvalidProof)
+
+	h, proof = ByzantineProof(ctx, t, dag)
+	h.Height++
+	assert.Error(t, proof.Validate(h))
+}
@@ -0,0 +1,235 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+
+	"github.com/tendermint/tendermint/pkg/da"
+	"github.com/tendermint/tendermint/pkg/wrapper"
+
+	"github.com/celestiaorg/celestia-node/ipld"
+	"github.com/celestiaorg/celestia-node/ipld/plugin"
+	"github.com/celestiaorg/celestia-node/service/header"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// PubSubTopic hardcodes the name of the fraud proof gossipsub topic.
+const PubSubTopic = "fraud-sub"
+
+// HeaderGetter provides headers fraud proofs are validated against.
+type HeaderGetter interface {
+	GetByHeight(context.Context, uint64) (*header.ExtendedHeader, error)
+}
+
+// Option is a functional option that configures Service.
+type Option func(*Service)
+
+// WithDAG sets the DAG the data of suspected headers is retrieved from by TriggerFraudProof.
+func WithDAG(dag format.NodeGetter) Option {
+	return func(s *Service) {
+		s.dag = dag
+	}
+}
+
+// Service manages the relationship with the "fraud-sub" gossipsub topic.
+// It validates fraud proofs received from the network against the local chain of headers,
+// stores the valid ones and signals the chain is compromised via Detected.
+type Service struct {
+	pubsub  *pubsub.PubSub
+	topic   *pubsub.Topic
+	sub     *pubsub.Subscription
+	headers HeaderGetter
+	store   *Store
+	// dag is where the data of suspected headers is retrieved from, if set
+	dag format.NodeGetter
+
+	detectedOnce sync.Once
+	detected     chan struct{}
+}
+
+// NewService creates a new fraud proof Service.
+func NewService(ps *pubsub.PubSub, headers HeaderGetter, store *Store, opts ...Option) *Service {
+	s := &Service{
+		pubsub:   ps,
+		headers:  headers,
+		store:    store,
+		detected: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start registers the topic validator for the "fraud-sub" topic, joins and subscribes to it.
+func (s *Service) Start(context.Context) (err error) {
+	err = s.pubsub.RegisterTopicValidator(PubSubTopic, s.validate)
+	if err != nil {
+		return err
+	}
+
+	s.topic, err = s.pubsub.Join(PubSubTopic)
+	if err != nil {
+		return err
+	}
+
+	s.sub, err = s.topic.Subscribe()
+	if err != nil {
+		return err
+	}
+
+	go s.listen()
+	return nil
+}
+
+// Stop cancels the subscription, closes the topic and unregisters its validator.
+func (s *Service) Stop(context.Context) error {
+	s.sub.Cancel()
+	err := s.topic.Close()
+	if err != nil {
+		return err
+	}
+
+	return s.pubsub.UnregisterTopicValidator(PubSubTopic)
+}
+
+// Broadcast broadcasts the given proof to the topic.
+func (s *Service) Broadcast(ctx context.Context, proof *BadEncodingProof) error {
+	if s.topic == nil {
+		return fmt.Errorf("fraud: topic is not instantiated, service must be started before broadcasting")
+	}
+
+	bin, err := proof.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return s.topic.Publish(ctx, bin)
+}
+
+// TriggerFraudProof attempts to prove the data of the given header is not erasure coded correctly,
+// once the header is suspected to be malformed, e.g. its data repeatedly failed sampling.
+// The whole data square is retrieved from the DAG and repaired, and if the repair reveals a bad encoding,
+// a BadEncodingProof is broadcasted, which is then validated and signaled via Detected like any other proof.
+// It errors if the data cannot be retrieved, as that proves nothing.
+func (s *Service) TriggerFraudProof(ctx context.Context, h *header.ExtendedHeader) error {
+	if s.dag == nil {
+		return fmt.Errorf("fraud: no DAG to retrieve data of suspected header at %d", h.Height)
+	}
+
+	err := repairSquare(ctx, s.dag, h.DAH)
+	if err == nil {
+		log.Infow("suspected header is encoded correctly", "height", h.Height)
+		return nil
+	}
+	proof, proofErr := NewBadEncodingProof(ctx, s.dag, uint64(h.Height), h.DAH, err)
+	if proofErr != nil {
+		log.Errorw("repairing data of suspected header", "height", h.Height, "err", err)
+		return fmt.Errorf("fraud: retrieving data of suspected header at %d: %w", h.Height, proofErr)
+	}
+
+	log.Warnw("broadcasting bad encoding fraud proof", "height", proof.Height, "axis", proof.Axis, "index", proof.Index)
+	return s.Broadcast(ctx, proof)
+}
+
+// repairSquare retrieves all the shares of the data square committed to the given DataAvailabilityHeader
+// and repairs the square, which verifies the encoding of every axis against its root.
+// Unlike sampling, all the shares are retrieved, so the repair does not depend on which ones are available.
+func repairSquare(ctx context.Context, dag format.NodeGetter, dah *da.DataAvailabilityHeader) (err error) {
+	width := uint32(len(dah.RowsRoots))
+	shares := make([][]byte, 0, width*width)
+	for _, root := range dah.RowsRoots {
+		rootCid, err := plugin.CidFromNamespacedSha256(root)
+		if err != nil {
+			return err
+		}
+		for i := uint32(0); i < width; i++ {
+			data, err := ipld.GetLeafData(ctx, rootCid, i, width, dag)
+			if err != nil {
+				return err
+			}
+			// leaf data is prefixed with the namespace pushed to the tree
+			shares = append(shares, data[ipld.NamespaceSize:])
+		}
+	}
+
+	// maliciously encoded data may break the ordering of namespaces the trees rely on
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("fraud: repairing data square: %v", r)
+		}
+	}()
+	tree := wrapper.NewErasuredNamespacedMerkleTree(uint64(width) / 2)
+	_, err = rsmt2d.RepairExtendedDataSquare(dah.RowsRoots, dah.ColumnRoots, shares, rsmt2d.NewRSGF8Codec(),
+		tree.Constructor)
+	return err
+}
+
+// Detected returns a channel which is closed once a valid fraud proof for the local chain is received.
+func (s *Service) Detected() <-chan struct{} {
+	return s.detected
+}
+
+// listen reads messages from the subscription, so that the topic validator receives them.
+// All the processing is done by the validator.
+func (s *Service) listen() {
+	for {
+		_, err := s.sub.Next(context.Background())
+		if err != nil {
+			return
+		}
+	}
+}
+
+// validate validates fraud proofs received from the network and stores the valid ones.
+// Proofs for headers missing locally are ignored, as they cannot be verified.
+func (s *Service) validate(ctx context.Context, p peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	proof := &BadEncodingProof{}
+	err := proof.UnmarshalBinary(msg.Data)
+	if err != nil {
+		log.Errorw("unmarshalling fraud proof received from the PubSub", "err", err, "peer", p.ShortString())
+		return pubsub.ValidationReject
+	}
+
+	hash, err := proof.Hash()
+	if err != nil {
+		return pubsub.ValidationReject
+	}
+	has, err := s.store.Has(ctx, hash)
+	if err != nil {
+		log.Errorw("checking fraud proof", "height", proof.Height, "err", err)
+		return pubsub.ValidationIgnore
+	}
+	if has {
+		// we've already got and spread the proof
+		return pubsub.ValidationIgnore
+	}
+
+	h, err := s.headers.GetByHeight(ctx, proof.Height)
+	if err != nil {
+		log.Debugw("getting header for fraud proof", "height", proof.Height, "err", err)
+		return pubsub.ValidationIgnore
+	}
+
+	err = proof.Validate(h)
+	if err != nil {
+		log.Warnw("invalid fraud proof", "height", proof.Height, "peer", p.ShortString(), "err", err)
+		return pubsub.ValidationReject
+	}
+
+	err = s.store.Append(ctx, proof)
+	if err != nil {
+		log.Errorw("storing fraud proof", "height", proof.Height, "err", err)
+	}
+
+	log.Errorw("received valid bad encoding fraud proof", "height", proof.Height, "axis", proof.Axis,
+		"index", proof.Index, "peer", p.ShortString())
+	s.detectedOnce.Do(func() {
+		close(s.detected)
+	})
+	return pubsub.ValidationAccept
+}
@@ -0,0 +1,175 @@
+package fraud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	mdutils "github.com/ipfs/go-merkledag/test"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/pkg/da"
+
+	"github.com/celestiaorg/celestia-node/ipld"
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+func TestService(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	h, proof := ByzantineProof(ctx, t, mdutils.Mock())
+	hash, err := proof.Hash()
+	require.NoError(t, err)
+
+	serv, topic := newTestService(ctx, t, h)
+
+	// invalid proofs are neither stored nor signaled
+	invalid := *proof
+	invalid.Shares = proof.Shares[:len(proof.Shares)-1]
+	bin, err := invalid.MarshalBinary()
+	require.NoError(t, err)
+	err = topic.Publish(ctx, bin)
+	require.NoError(t, err)
+
+	select {
+	case <-serv.Detected():
+		t.Fatal("invalid proof detected")
+	case <-time.After(time.Millisecond * 100):
+	}
+	invalidHash, err := invalid.Hash()
+	require.NoError(t, err)
+	has, err := serv.store.Has(ctx, invalidHash)
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	// valid proofs are stored and signaled
+	bin, err = proof.MarshalBinary()
+	require.NoError(t, err)
+	err = topic.Publish(ctx, bin)
+	require.NoError(t, err)
+
+	select {
+	case <-serv.Detected():
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+	got, err := serv.store.Get(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, proof, got)
+}
+
+func TestService_Duplicate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	h, proof := ByzantineProof(ctx, t, mdutils.Mock())
+	serv, _ := newTestService(ctx, t, h)
+
+	bin, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	msg := &pubsub.Message{Message: &pubsub_pb.Message{Data: bin}}
+
+	res := serv.validate(ctx, "", msg)
+	assert.Equal(t, pubsub.ValidationAccept, res)
+	// the same proof is not spread twice
+	res = serv.validate(ctx, "", msg)
+	assert.Equal(t, pubsub.ValidationIgnore, res)
+}
+
+func TestService_UnknownHeader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	h, proof := ByzantineProof(ctx, t, mdutils.Mock())
+	serv, _ := newTestService(ctx, t, h)
+	proof.Height++
+
+	bin, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	res := serv.validate(ctx, "", &pubsub.Message{Message: &pubsub_pb.Message{Data: bin}})
+	assert.Equal(t, pubsub.ValidationIgnore, res)
+}
+
+// TestService_TriggerFraudProof tests that a bad encoding of the suspected header is proven and signaled.
+func TestService_TriggerFraudProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	dag := mdutils.Mock()
+	h, _ := ByzantineProof(ctx, t, dag)
+	serv, _ := newTestService(ctx, t, h)
+
+	// nothing can be proven without the data
+	err := serv.TriggerFraudProof(ctx, h)
+	assert.Error(t, err)
+
+	serv.dag = dag
+	// correctly encoded data is not proven anything about
+	eds, err := ipld.PutData(ctx, ipld.RandNamespacedShares(t, 16).Raw(), dag)
+	require.NoError(t, err)
+	dah := da.NewDataAvailabilityHeader(eds)
+	valid := header.RandExtendedHeader(t)
+	valid.DAH = &dah
+	err = serv.TriggerFraudProof(ctx, valid)
+	require.NoError(t, err)
+	select {
+	case <-serv.Detected():
+		t.Fatal("correctly encoded data detected")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	err = serv.TriggerFraudProof(ctx, h)
+	require.NoError(t, err)
+	select {
+	case <-serv.Detected():
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+}
+
+// newTestService starts a Service with the given headers, returning it along with the topic joined by another peer.
+func newTestService(ctx context.Context, t *testing.T, headers ...*header.ExtendedHeader) (*Service, *pubsub.Topic) {
+	net, err := mocknet.FullMeshConnected(ctx, 2)
+	require.NoError(t, err)
+
+	ps1, err := pubsub.NewGossipSub(ctx, net.Hosts()[0], pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign))
+	require.NoError(t, err)
+	serv := NewService(ps1, headerGetter(headers), NewStore(datastore.NewMapDatastore()))
+	err = serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	// the other peer does not validate proofs, so it can publish invalid ones
+	ps2, err := pubsub.NewGossipSub(ctx, net.Hosts()[1], pubsub.WithMessageSignaturePolicy(pubsub.StrictNoSign))
+	require.NoError(t, err)
+	topic, err := ps2.Join(PubSubTopic)
+	require.NoError(t, err)
+
+	// wait for the peers to find each other in the topic
+	for len(topic.ListPeers()) == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+	return serv, topic
+}
+
+type headerGetter []*header.ExtendedHeader
+
+func (hg headerGetter) GetByHeight(_ context.Context, height uint64) (*header.ExtendedHeader, error) {
+	for _, h := range hg {
+		if uint64(h.Height) == height {
+			return h, nil
+		}
+	}
+	return nil, header.ErrNotFound
+}
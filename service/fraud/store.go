@@ -0,0 +1,63 @@
+package fraud
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+)
+
+// ErrNotFound is returned when there is no requested proof.
+var ErrNotFound = errors.New("fraud: proof not found")
+
+var storePrefix = datastore.NewKey("fraud")
+
+// Store persistently keeps valid fraud proofs indexed by their hashes.
+type Store struct {
+	ds datastore.Datastore
+}
+
+// NewStore creates a new Store over the given datastore.
+func NewStore(ds datastore.Datastore) *Store {
+	return &Store{ds: namespace.Wrap(ds, storePrefix)}
+}
+
+// Append stores the given proof. Appending an already stored proof is a no-op.
+func (s *Store) Append(_ context.Context, proof *BadEncodingProof) error {
+	hash, err := proof.Hash()
+	if err != nil {
+		return err
+	}
+
+	bin, err := proof.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return s.ds.Put(proofKey(hash), bin)
+}
+
+// Get returns the proof with the given hash or ErrNotFound.
+func (s *Store) Get(_ context.Context, hash []byte) (*BadEncodingProof, error) {
+	bin, err := s.ds.Get(proofKey(hash))
+	if err != nil {
+		if err == datastore.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	proof := &BadEncodingProof{}
+	return proof, proof.UnmarshalBinary(bin)
+}
+
+// Has checks whether the proof with the given hash is stored.
+func (s *Store) Has(_ context.Context, hash []byte) (bool, error) {
+	return s.ds.Has(proofKey(hash))
+}
+
+func proofKey(hash []byte) datastore.Key {
+	return datastore.NewKey(hex.EncodeToString(hash))
+}
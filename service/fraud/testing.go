@@ -0,0 +1,53 @@
+package fraud
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/pkg/da"
+	"github.com/tendermint/tendermint/pkg/wrapper"
+
+	"github.com/celestiaorg/celestia-node/ipld"
+	"github.com/celestiaorg/celestia-node/service/header"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// ByzantineProof puts an extended data square with a corrupted parity share of the first row into the given DAG
+// and returns a header committing to it along with a BadEncodingProof for the row.
+func ByzantineProof(
+	ctx context.Context,
+	t *testing.T,
+	dag format.DAGService,
+) (*header.ExtendedHeader, *BadEncodingProof) {
+	eds := ipld.RandEDS(t, 4)
+	width := int(eds.Width())
+	shares := make([][]byte, 0, width*width)
+	for i := 0; i < width; i++ {
+		shares = append(shares, eds.Row(uint(i))...)
+	}
+	corrupted := make([]byte, len(shares[width/2]))
+	copy(corrupted, shares[width-1])
+	shares[width/2] = corrupted
+
+	adder := ipld.NewNmtNodeAdder(ctx, dag)
+	tree := wrapper.NewErasuredNamespacedMerkleTree(uint64(width/2), nmt.NodeVisitor(adder.Visit))
+	eds, err := rsmt2d.ImportExtendedDataSquare(shares, rsmt2d.NewRSGF8Codec(), tree.Constructor)
+	require.NoError(t, err)
+	dah := da.NewDataAvailabilityHeader(eds)
+	require.NoError(t, adder.Commit())
+
+	h := header.RandExtendedHeader(t)
+	h.DAH = &dah
+
+	_, err = rsmt2d.RepairExtendedDataSquare(dah.RowsRoots, dah.ColumnRoots, shares, rsmt2d.NewRSGF8Codec(),
+		wrapper.NewErasuredNamespacedMerkleTree(uint64(width/2)).Constructor)
+	require.True(t, errors.As(err, new(*rsmt2d.ErrByzantineRow)), err)
+
+	proof, err := NewBadEncodingProof(ctx, dag, uint64(h.Height), h.DAH, err)
+	require.NoError(t, err)
+	return h, proof
+}
@@ -70,11 +70,50 @@ func (ce *CoreExchange) RequestByHash(ctx context.Context, hash tmbytes.HexBytes
 	return eh, nil
 }
 
+func (ce *CoreExchange) RequestHeadersByHashes(
+	ctx context.Context,
+	hashes []tmbytes.HexBytes,
+) ([]*ExtendedHeader, error) {
+	log.Debugw("core: requesting headers by hashes", "amount", len(hashes))
+	headers := make([]*ExtendedHeader, len(hashes))
+	for i, hash := range hashes {
+		extHeader, err := ce.RequestByHash(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		headers[i] = extHeader
+	}
+
+	return headers, nil
+}
+
+func (ce *CoreExchange) RequestHeaderSince(
+	ctx context.Context,
+	hash tmbytes.HexBytes,
+	amount uint64,
+) ([]*ExtendedHeader, error) {
+	log.Debugw("core: requesting headers since hash", "hash", hash.String(), "amount", amount)
+	if amount == 0 {
+		return nil, nil
+	}
+	eh, err := ce.RequestByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return requestRest(ctx, ce, hash, amount, []*ExtendedHeader{eh})
+}
+
 func (ce *CoreExchange) RequestHead(ctx context.Context) (*ExtendedHeader, error) {
 	log.Debug("core: requesting head")
 	return ce.getExtendedHeaderByHeight(ctx, nil)
 }
 
+// Probe requests the latest block from the core node and discards it.
+func (ce *CoreExchange) Probe(ctx context.Context) error {
+	return probeHead(ctx, ce.RequestHead)
+}
+
 func (ce *CoreExchange) getExtendedHeaderByHeight(ctx context.Context, height *int64) (*ExtendedHeader, error) {
 	b, err := ce.fetcher.GetBlock(ctx, height)
 	if err != nil {
@@ -1,9 +1,41 @@
 package header
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/nmt/namespace"
 	"github.com/celestiaorg/rsmt2d"
+
+	"github.com/celestiaorg/celestia-node/ipld"
+)
+
+var (
+	// ErrNamespaceOutOfRange is returned when the namespace is outside the range of namespaces
+	// committed to by the row root, so the row has no shares of it.
+	ErrNamespaceOutOfRange = errors.New("header: namespace out of range of the row")
+	// ErrMalformedNamespaceProof is returned when the namespace proof cannot be a proof
+	// for the row it refers to, e.g. its range is outside the row.
+	ErrMalformedNamespaceProof = errors.New("header: malformed namespace proof")
+	// ErrNamespaceProofMismatch is returned when the namespace proof does not match the row root.
+	ErrNamespaceProofMismatch = errors.New("header: namespace proof does not match the row root")
 )
 
+// NamespaceProof proves all the shares of a namespace within a row of the extended data square,
+// or their absence, against the row root committed to by a DataAvailabilityHeader.
+type NamespaceProof struct {
+	// Row is the index of the row the proof is made for.
+	Row int
+	// Shares are the namespaced leaves of the row within the proof range, as pushed to its NMT.
+	// They are empty for the proof of absence.
+	Shares [][]byte
+	// Proof is the NMT proof of the range of the namespace in the row.
+	Proof nmt.Proof
+}
+
 // DataAvailabilityHeaderFromExtendedData generates a DataAvailabilityHeader from the given data square.
 // TODO @renaynay: use da.NewDataAvailabilityHeader
 func DataAvailabilityHeaderFromExtendedData(data *rsmt2d.ExtendedDataSquare) (DataAvailabilityHeader, error) {
@@ -17,3 +49,81 @@ func DataAvailabilityHeaderFromExtendedData(data *rsmt2d.ExtendedDataSquare) (Da
 
 	return dah, nil
 }
+
+// EqualDataAvailabilityHeaders compares all the row and column roots of the given DataAvailabilityHeaders byte by byte.
+// Unlike DataAvailabilityHeader.Equals, it does not rely on the hashes, which are cached once computed
+// and so may be stale.
+func EqualDataAvailabilityHeaders(a, b *DataAvailabilityHeader) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return equalRoots(a.RowsRoots, b.RowsRoots) && equalRoots(a.ColumnRoots, b.ColumnRoots)
+}
+
+func equalRoots(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyNamespaceProof verifies the given proof of the shares of the namespace, or their absence,
+// against the respective row root of the DataAvailabilityHeader.
+// It errors with ErrNamespaceOutOfRange if the row root alone proves there are no shares of the namespace.
+func VerifyNamespaceProof(dah *DataAvailabilityHeader, nID namespace.ID, proof *NamespaceProof) error {
+	if nID.Size() != ipld.NamespaceSize {
+		return fmt.Errorf("header: namespace ID of size %d, expected %d", nID.Size(), ipld.NamespaceSize)
+	}
+	width := len(dah.RowsRoots)
+	if proof.Row < 0 || proof.Row >= width {
+		return fmt.Errorf("%w: row %d out of %d", ErrMalformedNamespaceProof, proof.Row, width)
+	}
+	root := dah.RowsRoots[proof.Row]
+	if len(root) != 2*ipld.NamespaceSize+sha256.Size {
+		return fmt.Errorf("header: row root %d of size %d", proof.Row, len(root))
+	}
+	if nID.Less(nmt.MinNamespace(root, nID.Size())) || !nID.LessOrEqual(nmt.MaxNamespace(root, nID.Size())) {
+		return ErrNamespaceOutOfRange
+	}
+
+	err := validateNamespaceProof(proof, width)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrMalformedNamespaceProof, err)
+	}
+	if !proof.Proof.VerifyNamespace(sha256.New(), nID, proof.Shares, root) {
+		return ErrNamespaceProofMismatch
+	}
+	return nil
+}
+
+// validateNamespaceProof checks the proof is well-formed for the row of the given width,
+// as nmt does not guard against malformed proofs.
+func validateNamespaceProof(proof *NamespaceProof, width int) error {
+	start, end := proof.Proof.Start(), proof.Proof.End()
+	switch {
+	case start < 0 || end > width || start >= end:
+		// the namespace is within the row, so the range must not be empty
+		return fmt.Errorf("range [%d:%d) in row of %d shares", start, end, width)
+	case proof.Proof.IsOfAbsence() && end-start != 1:
+		return fmt.Errorf("range [%d:%d) of absence proof", start, end)
+	case proof.Proof.IsOfAbsence() && len(proof.Shares) != 0:
+		return fmt.Errorf("%d shares with absence proof", len(proof.Shares))
+	case !proof.Proof.IsOfAbsence() && len(proof.Shares) != end-start:
+		return fmt.Errorf("%d shares for range [%d:%d)", len(proof.Shares), start, end)
+	}
+
+	for _, node := range proof.Proof.Nodes() {
+		if len(node) != 2*ipld.NamespaceSize+sha256.Size {
+			return fmt.Errorf("node of size %d", len(node))
+		}
+	}
+	if proof.Proof.IsOfAbsence() && len(proof.Proof.LeafHash()) != 2*ipld.NamespaceSize+sha256.Size {
+		return fmt.Errorf("leaf hash of size %d", len(proof.Proof.LeafHash()))
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package header
+
+import (
+	"crypto/sha256"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/pkg/consts"
+	"github.com/tendermint/tendermint/pkg/wrapper"
+
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/nmt/namespace"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+func TestEqualDataAvailabilityHeaders(t *testing.T) {
+	dah := &DataAvailabilityHeader{
+		RowsRoots:   [][]byte{{1}, {2}},
+		ColumnRoots: [][]byte{{3}, {4}},
+	}
+	// the hash is cached, so it does not reflect the changes below
+	dah.Hash()
+
+	same := &DataAvailabilityHeader{
+		RowsRoots:   [][]byte{{1}, {2}},
+		ColumnRoots: [][]byte{{3}, {4}},
+	}
+	assert.True(t, EqualDataAvailabilityHeaders(dah, same))
+	assert.True(t, EqualDataAvailabilityHeaders(nil, nil))
+	assert.False(t, EqualDataAvailabilityHeaders(dah, nil))
+
+	changed := *dah
+	changed.ColumnRoots = [][]byte{{3}, {5}}
+	assert.True(t, dah.Equals(&changed))
+	assert.False(t, EqualDataAvailabilityHeaders(dah, &changed))
+
+	changed.ColumnRoots = [][]byte{{3}}
+	assert.False(t, EqualDataAvailabilityHeaders(dah, &changed))
+}
+
+// TestEqualDataAvailabilityHeaders_Random checks that the comparison of random DataAvailabilityHeaders
+// is reflexive, symmetric and transitive.
+func TestEqualDataAvailabilityHeaders_Random(t *testing.T) {
+	// roots are drawn from a small set, so equal headers are generated often enough
+	randDAH := func() *DataAvailabilityHeader {
+		width := 1 + rand.Intn(2) //nolint:gosec
+		dah := &DataAvailabilityHeader{}
+		for i := 0; i < width; i++ {
+			dah.RowsRoots = append(dah.RowsRoots, []byte{byte(rand.Intn(2))})     //nolint:gosec
+			dah.ColumnRoots = append(dah.ColumnRoots, []byte{byte(rand.Intn(2))}) //nolint:gosec
+		}
+		return dah
+	}
+
+	var equal int
+	for i := 0; i < 10000; i++ {
+		a, b, c := randDAH(), randDAH(), randDAH()
+		assert.True(t, EqualDataAvailabilityHeaders(a, a))
+
+		ab := EqualDataAvailabilityHeaders(a, b)
+		assert.Equal(t, ab, EqualDataAvailabilityHeaders(b, a))
+		if ab && EqualDataAvailabilityHeaders(b, c) {
+			assert.True(t, EqualDataAvailabilityHeaders(a, c))
+			equal++
+		}
+	}
+	assert.NotZero(t, equal)
+}
+
+func TestVerifyNamespaceProof(t *testing.T) {
+	// rows of the original square keep namespaces [1, 1] and [3, 5]
+	eds := namespacedEDS(t, 1, 1, 3, 5)
+	dah, err := DataAvailabilityHeaderFromExtendedData(eds)
+	require.NoError(t, err)
+
+	inclusion := namespaceProof(t, eds, 0, testNamespace(1))
+	require.Len(t, inclusion.Shares, 2)
+	err = VerifyNamespaceProof(&dah, testNamespace(1), inclusion)
+	assert.NoError(t, err)
+
+	absence := namespaceProof(t, eds, 1, testNamespace(4))
+	require.True(t, absence.Proof.IsOfAbsence())
+	err = VerifyNamespaceProof(&dah, testNamespace(4), absence)
+	assert.NoError(t, err)
+
+	// the proof of absence does not prove the absence of the present namespace and vice versa
+	err = VerifyNamespaceProof(&dah, testNamespace(3), absence)
+	assert.ErrorIs(t, err, ErrNamespaceProofMismatch)
+	err = VerifyNamespaceProof(&dah, testNamespace(4), namespaceProof(t, eds, 1, testNamespace(3)))
+	assert.ErrorIs(t, err, ErrNamespaceProofMismatch)
+
+	tampered := *inclusion
+	tampered.Shares = [][]byte{inclusion.Shares[0], append([]byte{}, inclusion.Shares[1]...)}
+	tampered.Shares[1][len(tampered.Shares[1])-1]++
+	err = VerifyNamespaceProof(&dah, testNamespace(1), &tampered)
+	assert.ErrorIs(t, err, ErrNamespaceProofMismatch)
+	// a share left out breaks the completeness of the namespace
+	incomplete := *inclusion
+	incomplete.Shares = inclusion.Shares[:1]
+	incomplete.Proof = nmt.NewInclusionProof(0, 1, inclusion.Proof.Nodes(), true)
+	err = VerifyNamespaceProof(&dah, testNamespace(1), &incomplete)
+	assert.ErrorIs(t, err, ErrNamespaceProofMismatch)
+
+	// namespaces outside the rows need no proof
+	err = VerifyNamespaceProof(&dah, testNamespace(2), &NamespaceProof{Row: 0})
+	assert.ErrorIs(t, err, ErrNamespaceOutOfRange)
+	err = VerifyNamespaceProof(&dah, testNamespace(9), &NamespaceProof{Row: 1})
+	assert.ErrorIs(t, err, ErrNamespaceOutOfRange)
+
+	malformed := []*NamespaceProof{
+		{Row: 4, Shares: inclusion.Shares, Proof: inclusion.Proof},
+		{Row: 0, Shares: inclusion.Shares[:1], Proof: inclusion.Proof},
+		{Row: 0, Proof: nmt.NewEmptyRangeProof(true)},
+		{Row: 0, Shares: inclusion.Shares, Proof: nmt.NewInclusionProof(3, 5, inclusion.Proof.Nodes(), true)},
+		{Row: 0, Shares: inclusion.Shares, Proof: nmt.NewInclusionProof(0, 2, [][]byte{{1}}, true)},
+		{Row: 1, Proof: nmt.NewAbsenceProof(0, 2, absence.Proof.Nodes(), absence.Proof.LeafHash(), true)},
+		{Row: 1, Shares: inclusion.Shares[:1], Proof: absence.Proof},
+	}
+	for i, proof := range malformed {
+		nID := testNamespace(1)
+		if proof.Row == 1 {
+			nID = testNamespace(4)
+		}
+		err = VerifyNamespaceProof(&dah, nID, proof)
+		assert.ErrorIs(t, err, ErrMalformedNamespaceProof, "proof %d", i)
+	}
+}
+
+// namespacedEDS extends the square of shares with the given namespaces in ascending order.
+func namespacedEDS(t *testing.T, namespaces ...byte) *rsmt2d.ExtendedDataSquare {
+	shares := make([][]byte, len(namespaces))
+	for i, ns := range namespaces {
+		shares[i] = make([]byte, consts.ShareSize)
+		copy(shares[i], testNamespace(ns))
+		_, err := rand.Read(shares[i][consts.NamespaceSize:]) //nolint:gosec
+		require.NoError(t, err)
+	}
+
+	width := 1
+	for width*width < len(shares) {
+		width++
+	}
+	tree := wrapper.NewErasuredNamespacedMerkleTree(uint64(width))
+	eds, err := rsmt2d.ComputeExtendedDataSquare(shares, rsmt2d.NewRSGF8Codec(), tree.Constructor)
+	require.NoError(t, err)
+	return eds
+}
+
+// namespaceProof proves the namespace within the given row of the square,
+// rebuilding the NMT of the row the same way the row root is computed.
+func namespaceProof(t *testing.T, eds *rsmt2d.ExtendedDataSquare, row int, nID namespace.ID) *NamespaceProof {
+	width := int(eds.Width()) / 2
+	tree := nmt.New(sha256.New())
+	var leaves [][]byte
+	for col, share := range eds.Row(uint(row)) {
+		leaf := make([]byte, 0, consts.NamespaceSize+len(share))
+		if row < width && col < width {
+			leaf = append(leaf, share[:consts.NamespaceSize]...)
+		} else {
+			leaf = append(leaf, consts.ParitySharesNamespaceID...)
+		}
+		leaf = append(leaf, share...)
+		require.NoError(t, tree.Push(leaf))
+		leaves = append(leaves, leaf)
+	}
+	require.Equal(t, eds.RowRoots()[row], tree.Root())
+
+	proof, err := tree.ProveNamespace(nID)
+	require.NoError(t, err)
+	out := &NamespaceProof{Row: row, Proof: proof}
+	if !proof.IsOfAbsence() {
+		out.Shares = leaves[proof.Start():proof.End()]
+	}
+	return out
+}
+
+func testNamespace(b byte) namespace.ID {
+	return namespace.ID{0, 0, 0, 0, 0, 0, 0, b}
+}
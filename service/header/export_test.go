@@ -0,0 +1,5 @@
+package header
+
+// TestStores exposes the Store implementations checked by the Store tests,
+// so the external tests can add the implementations living outside of the package.
+var TestStores = testStores
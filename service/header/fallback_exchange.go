@@ -0,0 +1,171 @@
+package header
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tendermint/tendermint/libs/bytes"
+)
+
+// FallbackExchange is an Exchange serving ExtendedHeaders from the local Store first
+// and requesting the remote Exchange only for the ones missing locally.
+// ExtendedHeaders received from the remote Exchange are written to the Store,
+// so they are served locally afterwards.
+// NOTE: Like any Store, an empty local Store trusts the first ExtendedHeaders written to it.
+type FallbackExchange struct {
+	local  Store
+	remote Exchange
+}
+
+// NewFallbackExchange creates new Exchange falling back to the remote Exchange on misses of the local Store.
+func NewFallbackExchange(local Store, remote Exchange) Exchange {
+	return &FallbackExchange{
+		local:  local,
+		remote: remote,
+	}
+}
+
+// RequestHead always requests the remote Exchange, as the local head may be behind the network.
+func (f *FallbackExchange) RequestHead(ctx context.Context) (*ExtendedHeader, error) {
+	return f.remote.RequestHead(ctx)
+}
+
+// Probe probes the remote Exchange, as the local Store is always there.
+func (f *FallbackExchange) Probe(ctx context.Context) error {
+	return f.remote.Probe(ctx)
+}
+
+func (f *FallbackExchange) RequestHeader(ctx context.Context, height uint64) (*ExtendedHeader, error) {
+	h, err := f.local.GetByHeight(ctx, height)
+	if !errors.Is(err, ErrNotFound) {
+		return h, err
+	}
+
+	h, err = f.remote.RequestHeader(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	f.store(ctx, h)
+	return h, nil
+}
+
+func (f *FallbackExchange) RequestHeaders(ctx context.Context, origin, amount uint64) ([]*ExtendedHeader, error) {
+	headers, err := f.local.GetRangeByHeight(ctx, origin, origin+amount)
+	if !errors.Is(err, ErrNotFound) {
+		return headers, err
+	}
+
+	headers, err = f.remote.RequestHeaders(ctx, origin, amount)
+	if err != nil {
+		return nil, err
+	}
+	f.store(ctx, headers...)
+	return headers, nil
+}
+
+func (f *FallbackExchange) RequestByHash(ctx context.Context, hash bytes.HexBytes) (*ExtendedHeader, error) {
+	h, err := f.local.Get(ctx, hash)
+	if !errors.Is(err, ErrNotFound) {
+		return h, err
+	}
+
+	h, err = f.remote.RequestByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	f.store(ctx, h)
+	return h, nil
+}
+
+// RequestHeaderSince serves the range by height once the height of the hash is known locally,
+// so only the headers missing locally are requested remotely.
+func (f *FallbackExchange) RequestHeaderSince(
+	ctx context.Context,
+	hash bytes.HexBytes,
+	amount uint64,
+) ([]*ExtendedHeader, error) {
+	h, err := f.local.Get(ctx, hash)
+	switch {
+	case err == nil:
+		return f.RequestHeaders(ctx, uint64(h.Height), amount)
+	case !errors.Is(err, ErrNotFound):
+		return nil, err
+	}
+
+	headers, err := f.remote.RequestHeaderSince(ctx, hash, amount)
+	if err != nil {
+		return nil, err
+	}
+	f.store(ctx, headers...)
+	return headers, nil
+}
+
+func (f *FallbackExchange) RequestHeadersByHashes(
+	ctx context.Context,
+	hashes []bytes.HexBytes,
+) ([]*ExtendedHeader, error) {
+	headers := make([]*ExtendedHeader, len(hashes))
+	var missing []bytes.HexBytes
+	for i, hash := range hashes {
+		h, err := f.local.Get(ctx, hash)
+		switch {
+		case err == nil:
+			headers[i] = h
+		case errors.Is(err, ErrNotFound):
+			missing = append(missing, hash)
+		default:
+			return nil, err
+		}
+	}
+	if len(missing) == 0 {
+		return headers, nil
+	}
+
+	requested, err := f.remote.RequestHeadersByHashes(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(requested) != len(missing) {
+		return nil, fmt.Errorf("header: requested %d headers, received %d", len(missing), len(requested))
+	}
+	f.store(ctx, requested...)
+
+	// fill the missing headers in the order of the given hashes
+	for i := range headers {
+		if headers[i] == nil {
+			headers[i], requested = requested[0], requested[1:]
+		}
+	}
+	return headers, nil
+}
+
+// store writes the remotely requested headers to the local Store, skipping the ones it already has.
+// Headers which do not link to the stored chain are rejected by the Store, in which case they are still
+// returned to the caller, but requested again next time.
+func (f *FallbackExchange) store(ctx context.Context, headers ...*ExtendedHeader) {
+	missing := make([]*ExtendedHeader, 0, len(headers))
+	for _, h := range headers {
+		has, err := f.local.Has(ctx, h.Hash())
+		if err != nil {
+			log.Warnw("fallback exchange: checking requested header", "height", h.Height, "err", err)
+			return
+		}
+		if !has {
+			missing = append(missing, h)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	// the Store expects headers in ascending order
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Height < missing[j].Height
+	})
+
+	err := f.local.Append(ctx, missing...)
+	if err != nil {
+		log.Warnw("fallback exchange: storing requested headers", "amount", len(missing), "err", err)
+	}
+}
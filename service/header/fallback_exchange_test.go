@@ -0,0 +1,102 @@
+package header
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+func TestFallbackExchange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	in := suite.GenExtendedHeaders(10)
+	remoteStore := NewMemStore()
+	err := remoteStore.Append(ctx, in...)
+	require.NoError(t, err)
+
+	local := NewMemStore()
+	err = local.Append(ctx, in[:3]...)
+	require.NoError(t, err)
+	remote := &countingExchange{Exchange: NewLocalExchange(remoteStore)}
+	ex := NewFallbackExchange(local, remote)
+
+	// stored headers are served locally
+	h, err := ex.RequestHeader(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, in[1].Hash(), h.Hash())
+	assert.EqualValues(t, 0, atomic.LoadInt32(&remote.requests))
+
+	// the miss is requested remotely and stored
+	h, err = ex.RequestHeader(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, in[3].Hash(), h.Hash())
+	assert.EqualValues(t, 1, atomic.LoadInt32(&remote.requests))
+	has, err := local.Has(ctx, in[3].Hash())
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	// the second request for the same height hits the local store only
+	h, err = ex.RequestHeader(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, in[3].Hash(), h.Hash())
+	assert.EqualValues(t, 1, atomic.LoadInt32(&remote.requests))
+
+	// the range partially missing locally is requested and stored at once
+	headers, err := ex.RequestHeaders(ctx, 3, 4)
+	require.NoError(t, err)
+	require.Len(t, headers, 4)
+	assert.Equal(t, in[5].Hash(), headers[3].Hash())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&remote.requests))
+	_, err = ex.RequestHeaders(ctx, 1, 6)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&remote.requests))
+
+	h, err = ex.RequestByHash(ctx, in[6].Hash())
+	require.NoError(t, err)
+	assert.EqualValues(t, in[6].Height, h.Height)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&remote.requests))
+	_, err = ex.RequestByHash(ctx, in[6].Hash())
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&remote.requests))
+
+	// only the missing hashes are requested and the order of the given ones is kept
+	hashes := []tmbytes.HexBytes{in[8].Hash(), in[0].Hash(), in[7].Hash()}
+	headers, err = ex.RequestHeadersByHashes(ctx, hashes)
+	require.NoError(t, err)
+	require.Len(t, headers, len(hashes))
+	for i, hash := range hashes {
+		assert.Equal(t, hash, headers[i].Hash())
+	}
+	assert.EqualValues(t, 4, atomic.LoadInt32(&remote.requests))
+
+	// the head is always requested remotely
+	head, err := ex.RequestHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[9].Hash(), head.Hash())
+	assert.EqualValues(t, 5, atomic.LoadInt32(&remote.requests))
+
+	// the range since a stored hash is served by height
+	headers, err = ex.RequestHeaderSince(ctx, in[5].Hash(), 3)
+	require.NoError(t, err)
+	require.Len(t, headers, 3)
+	assert.Equal(t, in[7].Hash(), headers[2].Hash())
+	assert.EqualValues(t, 5, atomic.LoadInt32(&remote.requests))
+	// while the unknown hash is requested remotely
+	headers, err = ex.RequestHeaderSince(ctx, in[9].Hash(), 1)
+	require.NoError(t, err)
+	require.Len(t, headers, 1)
+	assert.EqualValues(t, 6, atomic.LoadInt32(&remote.requests))
+	has, err = local.Has(ctx, in[9].Hash())
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	// headers missing in both are not found
+	_, err = ex.RequestHeader(ctx, 20)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,181 @@
+package header
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+
+	pb "github.com/celestiaorg/celestia-node/service/header/pb"
+)
+
+// Fetcher separates the transport of headers from their deserialization, so each of the two layers
+// can be tested and mocked on its own.
+type Fetcher interface {
+	// FetchRaw sends the request and returns the headers received in response as they are.
+	// The headers are neither decoded nor validated.
+	FetchRaw(ctx context.Context, req *pb.ExtendedHeaderRequest) ([]*pb.ExtendedHeader, error)
+}
+
+var _ Fetcher = (*P2PExchange)(nil)
+
+// DecodeAndValidate decodes the raw headers and validates each of them with the DefaultValidator.
+// A header adjacent to the one preceding it is validated against it, so a contiguous range of headers
+// must link together.
+// Any of the headers failing the checks fails the whole batch with ErrInvalidResponse.
+func DecodeAndValidate(ctx context.Context, raw []*pb.ExtendedHeader) ([]*ExtendedHeader, error) {
+	headers := make([]*ExtendedHeader, len(raw))
+	for i, r := range raw {
+		var trusted *ExtendedHeader
+		if i > 0 && r != nil && r.Header != nil && r.Header.Height == headers[i-1].Height+1 {
+			trusted = headers[i-1]
+		}
+
+		header, err := decodeAndValidate(ctx, DefaultValidator, r, trusted)
+		if err != nil {
+			return nil, err
+		}
+		headers[i] = header
+	}
+	return headers, nil
+}
+
+// decodeAndValidate decodes the raw header and validates it against the trusted one, if any.
+func decodeAndValidate(
+	ctx context.Context,
+	v Validator,
+	raw *pb.ExtendedHeader,
+	trusted *ExtendedHeader,
+) (*ExtendedHeader, error) {
+	header, err := ProtoToExtendedHeader(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+	}
+	// sanity check the header
+	err = header.ValidateBasic()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+	}
+	err = v.Validate(ctx, header, trusted)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+	}
+	return header, nil
+}
+
+// fetcherExchange implements Exchange on top of a Fetcher.
+type fetcherExchange struct {
+	fetcher Fetcher
+}
+
+// NewFetcherExchange creates an Exchange requesting headers through the given Fetcher
+// and decoding them with DecodeAndValidate.
+func NewFetcherExchange(f Fetcher) Exchange {
+	return &fetcherExchange{fetcher: f}
+}
+
+func (fe *fetcherExchange) RequestHead(ctx context.Context) (*ExtendedHeader, error) {
+	headers, err := fe.fetch(ctx, &pb.ExtendedHeaderRequest{Origin: 0, Amount: 1})
+	if err != nil {
+		return nil, err
+	}
+	return headers[0], nil
+}
+
+func (fe *fetcherExchange) Probe(ctx context.Context) error {
+	return probeHead(ctx, fe.RequestHead)
+}
+
+func (fe *fetcherExchange) RequestHeader(ctx context.Context, height uint64) (*ExtendedHeader, error) {
+	// sanity check height
+	if height == 0 {
+		return nil, fmt.Errorf("specified request height must be greater than 0")
+	}
+	headers, err := fe.fetch(ctx, &pb.ExtendedHeaderRequest{Origin: height, Amount: 1})
+	if err != nil {
+		return nil, err
+	}
+	return headers[0], nil
+}
+
+func (fe *fetcherExchange) RequestHeaders(ctx context.Context, from, amount uint64) ([]*ExtendedHeader, error) {
+	var headers []*ExtendedHeader
+	// the response may be truncated, so keep requesting until all the headers are received
+	for uint64(len(headers)) < amount {
+		page, err := fe.fetch(ctx, &pb.ExtendedHeaderRequest{
+			Origin: from + uint64(len(headers)),
+			Amount: amount - uint64(len(headers)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, page...)
+	}
+	return headers, nil
+}
+
+func (fe *fetcherExchange) RequestByHash(ctx context.Context, hash tmbytes.HexBytes) (*ExtendedHeader, error) {
+	headers, err := fe.RequestHeadersByHashes(ctx, []tmbytes.HexBytes{hash})
+	if err != nil {
+		return nil, err
+	}
+	return headers[0], nil
+}
+
+func (fe *fetcherExchange) RequestHeadersByHashes(
+	ctx context.Context,
+	hashes []tmbytes.HexBytes,
+) ([]*ExtendedHeader, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	req := &pb.ExtendedHeaderRequest{
+		Hashes: make([][]byte, len(hashes)),
+		Amount: uint64(len(hashes)),
+	}
+	for i, hash := range hashes {
+		req.Hashes[i] = hash.Bytes()
+	}
+	headers, err := fe.fetch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) != len(hashes) {
+		return nil, fmt.Errorf("%w: expected %d headers, got %d", ErrInvalidResponse, len(hashes), len(headers))
+	}
+
+	for i, hash := range hashes {
+		if !bytes.Equal(headers[i].Hash().Bytes(), hash) {
+			return nil, fmt.Errorf("incorrect hash in header: expected %x, got %x", hash, headers[i].Hash().Bytes())
+		}
+	}
+	return headers, nil
+}
+
+func (fe *fetcherExchange) RequestHeaderSince(
+	ctx context.Context,
+	hash tmbytes.HexBytes,
+	amount uint64,
+) ([]*ExtendedHeader, error) {
+	if amount == 0 {
+		return nil, nil
+	}
+	headers, err := fe.fetch(ctx, &pb.ExtendedHeaderRequest{Hash: hash.Bytes(), Amount: amount})
+	if err != nil {
+		return nil, err
+	}
+	return requestRest(ctx, fe, hash, amount, headers)
+}
+
+// fetch fetches the raw headers and decodes them, ensuring at least one header was received.
+func (fe *fetcherExchange) fetch(ctx context.Context, req *pb.ExtendedHeaderRequest) ([]*ExtendedHeader, error) {
+	raw, err := fe.fetcher.FetchRaw(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, ErrNotFound
+	}
+	return DecodeAndValidate(ctx, raw)
+}
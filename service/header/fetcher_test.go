@@ -0,0 +1,164 @@
+package header
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+
+	pb "github.com/celestiaorg/celestia-node/service/header/pb"
+)
+
+func TestDecodeAndValidate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := NewTestSuite(t, 3).GenExtendedHeaders(5)
+	raw := toProto(t, in...)
+
+	out, err := DecodeAndValidate(ctx, raw)
+	require.NoError(t, err)
+	require.Len(t, out, len(in))
+	for i := range in {
+		assert.Equal(t, in[i].Hash(), out[i].Hash())
+	}
+
+	// headers which are not adjacent are validated on their own
+	out, err = DecodeAndValidate(ctx, toProto(t, in[4], in[0], in[2]))
+	require.NoError(t, err)
+	assert.Equal(t, in[0].Hash(), out[1].Hash())
+
+	// a contiguous range must link together
+	other := NewTestSuite(t, 3).GenExtendedHeaders(2)
+	_, err = DecodeAndValidate(ctx, toProto(t, in[0], other[1]))
+	assert.ErrorIs(t, err, ErrInvalidResponse)
+
+	tampered := toProto(t, in[1])[0]
+	tampered.Header.DataHash = []byte{1, 2, 3}
+	_, err = DecodeAndValidate(ctx, []*pb.ExtendedHeader{raw[0], tampered})
+	assert.ErrorIs(t, err, ErrInvalidResponse)
+
+	_, err = DecodeAndValidate(ctx, []*pb.ExtendedHeader{{}})
+	assert.ErrorIs(t, err, ErrInvalidResponse)
+}
+
+func TestFetcherExchange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := createStore(t, 10)
+	exchg := NewFetcherExchange(&storeFetcher{t: t, store: store})
+
+	head, err := exchg.RequestHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, store.head.Hash(), head.Hash())
+
+	header, err := exchg.RequestHeader(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, store.byHeight[3].Hash(), header.Hash())
+
+	headers, err := exchg.RequestHeaders(ctx, 2, 6)
+	require.NoError(t, err)
+	require.Len(t, headers, 6)
+	for i, h := range headers {
+		assert.Equal(t, store.byHeight[uint64(2+i)].Hash(), h.Hash())
+	}
+
+	header, err = exchg.RequestByHash(ctx, store.byHeight[5].Hash())
+	require.NoError(t, err)
+	assert.Equal(t, store.byHeight[5].Hash(), header.Hash())
+
+	hashes := []tmbytes.HexBytes{store.byHeight[7].Hash(), store.byHeight[2].Hash()}
+	headers, err = exchg.RequestHeadersByHashes(ctx, hashes)
+	require.NoError(t, err)
+	require.Len(t, headers, 2)
+	assert.Equal(t, hashes[0], headers[0].Hash())
+	assert.Equal(t, hashes[1], headers[1].Hash())
+
+	_, err = exchg.RequestHeader(ctx, 100)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// the raw headers fetched are decoded and validated
+	exchg = NewFetcherExchange(&storeFetcher{t: t, store: store, tamper: true})
+	_, err = exchg.RequestHeader(ctx, 3)
+	assert.ErrorIs(t, err, ErrInvalidResponse)
+}
+
+func TestP2PExchange_FetchRaw(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	exchg, store := createP2PExAndServer(t, host, peer)
+
+	raw, err := exchg.(Fetcher).FetchRaw(ctx, &pb.ExtendedHeaderRequest{Origin: 1, Amount: 3})
+	require.NoError(t, err)
+	require.Len(t, raw, 3)
+	for i, r := range raw {
+		assert.Equal(t, store.byHeight[uint64(1+i)].Height, r.Header.Height)
+	}
+
+	out, err := DecodeAndValidate(ctx, raw)
+	require.NoError(t, err)
+	for i, h := range out {
+		assert.Equal(t, store.byHeight[uint64(1+i)].Hash(), h.Hash())
+	}
+
+	// the exchange on top of the P2PExchange behaves the same as the P2PExchange itself
+	headers, err := NewFetcherExchange(exchg.(Fetcher)).RequestHeaders(ctx, 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, out, headers)
+}
+
+// storeFetcher serves the raw headers from the Store, optionally tampering them.
+type storeFetcher struct {
+	t      *testing.T
+	store  Store
+	tamper bool
+}
+
+func (f *storeFetcher) FetchRaw(ctx context.Context, req *pb.ExtendedHeaderRequest) ([]*pb.ExtendedHeader, error) {
+	var (
+		headers []*ExtendedHeader
+		err     error
+	)
+	switch {
+	case len(req.Hashes) > 0:
+		for _, hash := range req.Hashes {
+			h, err := f.store.Get(ctx, hash)
+			if err != nil {
+				return nil, err
+			}
+			headers = append(headers, h)
+		}
+	case req.Origin == 0:
+		var h *ExtendedHeader
+		h, err = f.store.Head(ctx)
+		headers = []*ExtendedHeader{h}
+	default:
+		headers, err = f.store.GetRangeByHeight(ctx, req.Origin, req.Origin+req.Amount)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	raw := toProto(f.t, headers...)
+	if f.tamper {
+		for _, r := range raw {
+			r.Header.DataHash = []byte{1, 2, 3}
+		}
+	}
+	return raw, nil
+}
+
+func toProto(t *testing.T, headers ...*ExtendedHeader) []*pb.ExtendedHeader {
+	raw := make([]*pb.ExtendedHeader, len(headers))
+	for i, h := range headers {
+		var err error
+		raw[i], err = ExtendedHeaderToProto(h)
+		require.NoError(t, err)
+	}
+	return raw
+}
@@ -3,13 +3,19 @@ package header
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"time"
 
 	format "github.com/ipfs/go-ipld-format"
 	bts "github.com/tendermint/tendermint/libs/bytes"
 	"github.com/tendermint/tendermint/pkg/da"
+	"github.com/tendermint/tendermint/pkg/wrapper"
 	core "github.com/tendermint/tendermint/types"
 
+	"github.com/celestiaorg/rsmt2d"
+
 	"github.com/celestiaorg/celestia-node/ipld"
 
 	header_pb "github.com/celestiaorg/celestia-node/service/header/pb"
@@ -43,12 +49,59 @@ func MakeExtendedHeader(
 	vals *core.ValidatorSet,
 	dag format.NodeAdder,
 ) (*ExtendedHeader, error) {
+	if b == nil {
+		return nil, errNilBlock
+	}
 	namespacedShares, _ := b.Data.ComputeShares()
 	extended, err := ipld.PutData(ctx, namespacedShares.RawShares(), dag)
 	if err != nil {
 		return nil, err
 	}
 
+	return newExtendedHeader(b, comm, vals, extended)
+}
+
+// NewExtendedHeaderFromTendermint builds the ExtendedHeader of the given block, erasure coding
+// the block data in memory to compute its DataAvailabilityHeader.
+// Unlike MakeExtendedHeader, the block data is not stored anywhere.
+// As a Tendermint block does not carry its own commit and validator set, they are given separately
+// and it errors if either of them is missing.
+func NewExtendedHeaderFromTendermint(
+	b *core.Block,
+	comm *core.Commit,
+	vals *core.ValidatorSet,
+) (*ExtendedHeader, error) {
+	if b == nil {
+		return nil, errNilBlock
+	}
+	namespacedShares, _ := b.Data.ComputeShares()
+	shares := namespacedShares.RawShares()
+	tree := wrapper.NewErasuredNamespacedMerkleTree(uint64(math.Sqrt(float64(len(shares)))))
+	extended, err := rsmt2d.ComputeExtendedDataSquare(shares, rsmt2d.NewRSGF8Codec(), tree.Constructor)
+	if err != nil {
+		return nil, fmt.Errorf("header: extending block data: %w", err)
+	}
+
+	return newExtendedHeader(b, comm, vals, extended)
+}
+
+var errNilBlock = errors.New("header: nil block")
+
+// newExtendedHeader assembles the ExtendedHeader of the given block with its extended data
+// and ensures it is valid.
+func newExtendedHeader(
+	b *core.Block,
+	comm *core.Commit,
+	vals *core.ValidatorSet,
+	extended *rsmt2d.ExtendedDataSquare,
+) (*ExtendedHeader, error) {
+	if comm == nil {
+		return nil, errors.New("header: nil commit")
+	}
+	if vals.IsNilOrEmpty() {
+		return nil, errors.New("header: empty validator set")
+	}
+
 	dah := da.NewDataAvailabilityHeader(extended)
 	eh := &ExtendedHeader{
 		RawHeader:    b.Header,
@@ -101,6 +154,38 @@ func (eh *ExtendedHeader) ValidateBasic() error {
 	return eh.DAH.ValidateBasic()
 }
 
+// SquareDimension returns the amount of rows and columns of the original data square
+// committed to by the DataAvailabilityHeader, which is half of the extended one.
+func (eh *ExtendedHeader) SquareDimension() int {
+	if eh.DAH == nil {
+		return 0
+	}
+	return len(eh.DAH.RowsRoots) / 2
+}
+
+// DataSquareSize returns the amount of shares in the original data square committed to
+// by the DataAvailabilityHeader. It errors with ErrInvalidSquare for a malformed square.
+func (eh *ExtendedHeader) DataSquareSize() (int, error) {
+	err := validateSquare(eh)
+	if err != nil {
+		return 0, err
+	}
+	dim := eh.SquareDimension()
+	return dim * dim, nil
+}
+
+// Age returns the time passed by 'now' since the block of the wrapped RawHeader was produced.
+// It is negative for blocks timed after 'now'.
+func (eh *ExtendedHeader) Age(now time.Time) time.Duration {
+	return now.Sub(eh.Time)
+}
+
+// IsExpired reports whether the ExtendedHeader is older than the given weak subjectivity period,
+// so it cannot be trusted anymore.
+func (eh *ExtendedHeader) IsExpired(weakSubjectivityPeriod time.Duration) bool {
+	return eh.Age(time.Now()) > weakSubjectivityPeriod
+}
+
 // MarshalBinary marshals ExtendedHeader to binary.
 func (eh *ExtendedHeader) MarshalBinary() ([]byte, error) {
 	return MarshalExtendedHeader(eh)
@@ -121,6 +206,47 @@ func (eh *ExtendedHeader) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// MarshalJSON marshals ExtendedHeader to JSON.
+// The encoding is stable: keys are sorted and hashes are base64 encoded.
+func (eh *ExtendedHeader) MarshalJSON() ([]byte, error) {
+	return MarshalExtendedHeaderJSON(eh)
+}
+
+// UnmarshalJSON unmarshals ExtendedHeader from JSON.
+func (eh *ExtendedHeader) UnmarshalJSON(data []byte) error {
+	if eh == nil {
+		return fmt.Errorf("header: cannot UnmarshalJSON - nil ExtendedHeader")
+	}
+
+	out, err := UnmarshalExtendedHeaderJSON(data)
+	if err != nil {
+		return err
+	}
+
+	*eh = *out
+	return nil
+}
+
+// MarshalCBOR marshals ExtendedHeader to CBOR.
+func (eh *ExtendedHeader) MarshalCBOR() ([]byte, error) {
+	return MarshalExtendedHeaderCBOR(eh)
+}
+
+// UnmarshalCBOR unmarshals ExtendedHeader from CBOR.
+func (eh *ExtendedHeader) UnmarshalCBOR(data []byte) error {
+	if eh == nil {
+		return fmt.Errorf("header: cannot UnmarshalCBOR - nil ExtendedHeader")
+	}
+
+	out, err := UnmarshalExtendedHeaderCBOR(data)
+	if err != nil {
+		return err
+	}
+
+	*eh = *out
+	return nil
+}
+
 // ExtendedHeaderRequest is the packet format for nodes to request ExtendedHeaders
 // from the network.
 type ExtendedHeaderRequest struct {
@@ -0,0 +1,176 @@
+package header
+
+import (
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/tendermint/tendermint/proto/tendermint/crypto"
+	"github.com/tendermint/tendermint/proto/tendermint/da"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+
+	header_pb "github.com/celestiaorg/celestia-node/service/header/pb"
+)
+
+// cborEncMode encodes ExtendedHeaders deterministically, with times as RFC3339 strings,
+// so the encoding does not depend on CBOR extensions for times.
+var cborEncMode = func() cbor.EncMode {
+	opts := cbor.CoreDetEncOptions()
+	opts.Time = cbor.TimeRFC3339Nano
+	em, err := opts.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return em
+}()
+
+// cborExtendedHeader is the CBOR representation of ExtendedHeader.
+// It mirrors the protobuf one with the same field names, besides public keys of validators,
+// as CBOR has no notion of protobuf's oneof.
+type cborExtendedHeader struct {
+	Header       *tmproto.Header            `cbor:"header"`
+	Commit       *tmproto.Commit            `cbor:"commit"`
+	ValidatorSet *cborValidatorSet          `cbor:"validator_set"`
+	DAH          *da.DataAvailabilityHeader `cbor:"dah"`
+}
+
+type cborValidatorSet struct {
+	Validators       []*cborValidator `cbor:"validators,omitempty"`
+	Proposer         *cborValidator   `cbor:"proposer,omitempty"`
+	TotalVotingPower int64            `cbor:"total_voting_power,omitempty"`
+}
+
+type cborValidator struct {
+	Address          []byte        `cbor:"address,omitempty"`
+	PubKey           cborPublicKey `cbor:"pub_key"`
+	VotingPower      int64         `cbor:"voting_power,omitempty"`
+	ProposerPriority int64         `cbor:"proposer_priority,omitempty"`
+}
+
+// cborPublicKey keeps the key under the field of its type.
+type cborPublicKey struct {
+	Ed25519   []byte `cbor:"ed25519,omitempty"`
+	Secp256k1 []byte `cbor:"secp256k1,omitempty"`
+}
+
+// MarshalExtendedHeaderCBOR serializes given ExtendedHeader to CBOR, for consumers unable to use protobuf.
+// The encoding follows the protobuf one, so the ExtendedHeader decoded from it has the same protobuf encoding.
+// Paired with UnmarshalExtendedHeaderCBOR.
+func MarshalExtendedHeaderCBOR(in *ExtendedHeader) ([]byte, error) {
+	pb, err := ExtendedHeaderToProto(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &cborExtendedHeader{
+		Header: pb.Header,
+		Commit: pb.Commit,
+		DAH:    pb.Dah,
+	}
+	out.ValidatorSet, err = validatorSetToCBOR(pb.ValidatorSet)
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(out)
+}
+
+// UnmarshalExtendedHeaderCBOR deserializes given CBOR data into a new ExtendedHeader.
+// Paired with MarshalExtendedHeaderCBOR.
+func UnmarshalExtendedHeaderCBOR(data []byte) (*ExtendedHeader, error) {
+	in := &cborExtendedHeader{}
+	err := cbor.Unmarshal(data, in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Header == nil || in.Commit == nil || in.ValidatorSet == nil || in.DAH == nil {
+		return nil, fmt.Errorf("header: incomplete CBOR ExtendedHeader")
+	}
+
+	pb := &header_pb.ExtendedHeader{
+		Header: in.Header,
+		Commit: in.Commit,
+		Dah:    in.DAH,
+	}
+	pb.ValidatorSet, err = validatorSetFromCBOR(in.ValidatorSet)
+	if err != nil {
+		return nil, err
+	}
+	return ProtoToExtendedHeader(pb)
+}
+
+func validatorSetToCBOR(in *tmproto.ValidatorSet) (_ *cborValidatorSet, err error) {
+	out := &cborValidatorSet{
+		Validators:       make([]*cborValidator, len(in.Validators)),
+		TotalVotingPower: in.TotalVotingPower,
+	}
+	for i, val := range in.Validators {
+		out.Validators[i], err = validatorToCBOR(val)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if in.Proposer != nil {
+		out.Proposer, err = validatorToCBOR(in.Proposer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func validatorSetFromCBOR(in *cborValidatorSet) (_ *tmproto.ValidatorSet, err error) {
+	out := &tmproto.ValidatorSet{
+		Validators:       make([]*tmproto.Validator, len(in.Validators)),
+		TotalVotingPower: in.TotalVotingPower,
+	}
+	for i, val := range in.Validators {
+		out.Validators[i], err = validatorFromCBOR(val)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if in.Proposer != nil {
+		out.Proposer, err = validatorFromCBOR(in.Proposer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func validatorToCBOR(in *tmproto.Validator) (*cborValidator, error) {
+	out := &cborValidator{
+		Address:          in.Address,
+		VotingPower:      in.VotingPower,
+		ProposerPriority: in.ProposerPriority,
+	}
+	switch key := in.PubKey.Sum.(type) {
+	case *crypto.PublicKey_Ed25519:
+		out.PubKey.Ed25519 = key.Ed25519
+	case *crypto.PublicKey_Secp256K1:
+		out.PubKey.Secp256k1 = key.Secp256K1
+	default:
+		return nil, fmt.Errorf("header: unsupported public key type %T", key)
+	}
+	return out, nil
+}
+
+func validatorFromCBOR(in *cborValidator) (*tmproto.Validator, error) {
+	if in == nil {
+		return nil, fmt.Errorf("header: nil CBOR validator")
+	}
+
+	out := &tmproto.Validator{
+		Address:          in.Address,
+		VotingPower:      in.VotingPower,
+		ProposerPriority: in.ProposerPriority,
+	}
+	switch {
+	case in.PubKey.Ed25519 != nil && in.PubKey.Secp256k1 == nil:
+		out.PubKey.Sum = &crypto.PublicKey_Ed25519{Ed25519: in.PubKey.Ed25519}
+	case in.PubKey.Secp256k1 != nil && in.PubKey.Ed25519 == nil:
+		out.PubKey.Sum = &crypto.PublicKey_Secp256K1{Secp256K1: in.PubKey.Secp256k1}
+	default:
+		return nil, fmt.Errorf("header: CBOR validator must have exactly one public key")
+	}
+	return out, nil
+}
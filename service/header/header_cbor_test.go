@@ -0,0 +1,116 @@
+package header
+
+import (
+	mrand "math/rand"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalUnmarshalExtendedHeaderCBOR(t *testing.T) {
+	in := NewTestSuite(t, 3).GenExtendedHeaders(2)[1]
+	data, err := cbor.Marshal(in)
+	require.NoError(t, err)
+
+	out := &ExtendedHeader{}
+	err = cbor.Unmarshal(data, out)
+	require.NoError(t, err)
+	assert.Equal(t, in.Hash(), out.Hash())
+	assert.Equal(t, in.ValidatorSet.Hash(), out.ValidatorSet.Hash())
+	assert.True(t, EqualDataAvailabilityHeaders(in.DAH, out.DAH))
+	// decoding CBOR must produce the same protobuf encoding
+	inBin, err := in.MarshalBinary()
+	require.NoError(t, err)
+	outBin, err := out.MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, inBin, outBin)
+	// the decoded header is still valid
+	assert.NoError(t, out.ValidateBasic())
+
+	// keys follow the protobuf field names
+	var raw map[string]map[string]interface{}
+	err = cbor.Unmarshal(data, &raw)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(in.DataHash), raw["header"]["data_hash"])
+
+	_, err = UnmarshalExtendedHeaderCBOR([]byte{0xa0}) // empty map
+	assert.Error(t, err)
+}
+
+// TestExtendedHeaderCBOR_Fuzz checks CBOR round trips of randomly filled headers
+// match their protobuf encodings.
+func TestExtendedHeaderCBOR_Fuzz(t *testing.T) {
+	seed := time.Now().UnixNano()
+	rand := mrand.New(mrand.NewSource(seed)) //nolint:gosec
+	t.Logf("seed: %d", seed)
+
+	for i := 0; i < 100; i++ {
+		in := randFuzzedHeader(t, rand)
+		inBin, err := in.MarshalBinary()
+		require.NoError(t, err)
+
+		data, err := in.MarshalCBOR()
+		require.NoError(t, err)
+		out := &ExtendedHeader{}
+		err = out.UnmarshalCBOR(data)
+		require.NoError(t, err)
+
+		outBin, err := out.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, inBin, outBin)
+		again, err := out.MarshalCBOR()
+		require.NoError(t, err)
+		require.Equal(t, data, again)
+
+		// corrupted CBOR must be rejected gracefully
+		corrupted := append([]byte{}, data...)
+		corrupted[rand.Intn(len(corrupted))] = byte(rand.Intn(256))
+		assert.NotPanics(t, func() {
+			_ = (&ExtendedHeader{}).UnmarshalCBOR(corrupted)
+		})
+	}
+}
+
+func BenchmarkExtendedHeader_Encoding(b *testing.B) {
+	in := NewTestSuite(b, 3).GenExtendedHeaders(2)[1]
+	bin, err := in.MarshalBinary()
+	require.NoError(b, err)
+	cb, err := in.MarshalCBOR()
+	require.NoError(b, err)
+
+	b.Run("Protobuf/Encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := in.MarshalBinary(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Protobuf/Decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := UnmarshalExtendedHeader(bin); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("CBOR/Encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := in.MarshalCBOR(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("CBOR/Decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := UnmarshalExtendedHeaderCBOR(cb); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
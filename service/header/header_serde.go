@@ -1,6 +1,10 @@
 package header
 
 import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/gogo/protobuf/jsonpb"
 	"github.com/tendermint/tendermint/pkg/da"
 	core "github.com/tendermint/tendermint/types"
 
@@ -61,6 +65,50 @@ func UnmarshalExtendedHeader(data []byte) (*ExtendedHeader, error) {
 	return out, nil
 }
 
+// MarshalExtendedHeaderJSON serializes given ExtendedHeader to JSON using the canonical JSON mapping of its
+// protobuf representation, so bytes are base64 encoded. Keys are sorted to keep the encoding stable.
+// Paired with UnmarshalExtendedHeaderJSON.
+func MarshalExtendedHeaderJSON(in *ExtendedHeader) ([]byte, error) {
+	// go through the binary encoding, so that headers with equal protobuf encodings have equal JSON ones
+	bin, err := MarshalExtendedHeader(in)
+	if err != nil {
+		return nil, err
+	}
+	pb := &header_pb.ExtendedHeader{}
+	err = pb.Unmarshal(bin)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	err = (&jsonpb.Marshaler{OrigName: true}).Marshal(buf, pb)
+	if err != nil {
+		return nil, err
+	}
+
+	// jsonpb orders keys by field numbers, while decoding into a generic value and encoding back sorts them
+	var v interface{}
+	dec := json.NewDecoder(buf)
+	dec.UseNumber()
+	err = dec.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalExtendedHeaderJSON deserializes given JSON data into a new ExtendedHeader.
+// Paired with MarshalExtendedHeaderJSON.
+func UnmarshalExtendedHeaderJSON(data []byte) (*ExtendedHeader, error) {
+	in := &header_pb.ExtendedHeader{}
+	err := jsonpb.Unmarshal(bytes.NewReader(data), in)
+	if err != nil {
+		return nil, err
+	}
+
+	return ProtoToExtendedHeader(in)
+}
+
 func ExtendedHeaderToProto(eh *ExtendedHeader) (*header_pb.ExtendedHeader, error) {
 	pb := &header_pb.ExtendedHeader{
 		Header: eh.RawHeader.ToProto(),
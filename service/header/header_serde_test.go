@@ -1,10 +1,19 @@
 package header
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	mrand "math/rand"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/celestia-node/ipld"
 )
 
 func TestMarshalUnmarshalExtendedHeader(t *testing.T) {
@@ -16,8 +25,142 @@ func TestMarshalUnmarshalExtendedHeader(t *testing.T) {
 	err = out.UnmarshalBinary(data)
 	require.NoError(t, err)
 	assert.Equal(t, in.ValidatorSet, out.ValidatorSet)
-	assert.True(t, in.DAH.Equals(out.DAH))
+	assert.True(t, EqualDataAvailabilityHeaders(in.DAH, out.DAH))
 	// not the check for equality as time.Time is not serialized exactly 1:1
 	assert.NotZero(t, out.RawHeader)
 	assert.NotNil(t, out.Commit)
 }
+
+func TestMarshalUnmarshalExtendedHeaderJSON(t *testing.T) {
+	in := NewTestSuite(t, 3).GenExtendedHeaders(2)[1]
+	data, err := json.Marshal(in)
+	require.NoError(t, err)
+
+	out := &ExtendedHeader{}
+	err = json.Unmarshal(data, out)
+	require.NoError(t, err)
+	assert.Equal(t, in.Hash(), out.Hash())
+	assert.Equal(t, in.ValidatorSet.Hash(), out.ValidatorSet.Hash())
+	assert.True(t, EqualDataAvailabilityHeaders(in.DAH, out.DAH))
+	// decoding JSON must produce the same protobuf encoding
+	inBin, err := in.MarshalBinary()
+	require.NoError(t, err)
+	outBin, err := out.MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, inBin, outBin)
+	// the decoded header is still valid
+	assert.NoError(t, out.ValidateBasic())
+
+	// hashes are base64 encoded
+	var raw map[string]map[string]interface{}
+	err = json.Unmarshal(data, &raw)
+	require.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(in.DataHash), raw["header"]["data_hash"])
+}
+
+func TestMarshalExtendedHeaderJSON_Stable(t *testing.T) {
+	in := RandExtendedHeader(t)
+	data, err := in.MarshalJSON()
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		again, err := in.MarshalJSON()
+		require.NoError(t, err)
+		assert.Equal(t, data, again)
+	}
+
+	// keys are sorted on every level of nesting
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var keys [][]string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		switch tok {
+		case json.Delim('{'):
+			keys = append(keys, nil)
+		case json.Delim('}'):
+			level := keys[len(keys)-1]
+			assert.True(t, sort.StringsAreSorted(level), level)
+			keys = keys[:len(keys)-1]
+		default:
+			// keys are the strings directly inside an object expecting a key
+			if s, ok := tok.(string); ok && len(keys) > 0 && isKey(data, dec.InputOffset()) {
+				keys[len(keys)-1] = append(keys[len(keys)-1], s)
+			}
+		}
+	}
+}
+
+// isKey reports whether the token ending at the given offset is followed by a colon.
+func isKey(data []byte, offset int64) bool {
+	rest := bytes.TrimLeft(data[offset:], " \t\n\r")
+	return len(rest) > 0 && rest[0] == ':'
+}
+
+// TestExtendedHeaderJSON_Fuzz checks JSON round trips of randomly filled headers.
+func TestExtendedHeaderJSON_Fuzz(t *testing.T) {
+	seed := time.Now().UnixNano()
+	rand := mrand.New(mrand.NewSource(seed)) //nolint:gosec
+	t.Logf("seed: %d", seed)
+
+	for i := 0; i < 100; i++ {
+		in := randFuzzedHeader(t, rand)
+		inBin, err := in.MarshalBinary()
+		require.NoError(t, err)
+
+		data, err := in.MarshalJSON()
+		require.NoError(t, err)
+		out := &ExtendedHeader{}
+		err = out.UnmarshalJSON(data)
+		require.NoError(t, err, string(data))
+
+		outBin, err := out.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, inBin, outBin, string(data))
+		again, err := out.MarshalJSON()
+		require.NoError(t, err)
+		require.Equal(t, data, again)
+
+		// corrupted JSON must be rejected gracefully
+		corrupted := append([]byte{}, data...)
+		corrupted[rand.Intn(len(corrupted))] = byte(rand.Intn(256))
+		assert.NotPanics(t, func() {
+			_ = (&ExtendedHeader{}).UnmarshalJSON(corrupted)
+		})
+	}
+}
+
+// randFuzzedHeader provides an ExtendedHeader with randomly filled fields.
+func randFuzzedHeader(t *testing.T, rand *mrand.Rand) *ExtendedHeader {
+	randBytes := func(n int) []byte {
+		b := make([]byte, n)
+		rand.Read(b)
+		return b
+	}
+	randString := func() string {
+		r := make([]rune, rand.Intn(16))
+		for i := range r {
+			r[i] = rune(rand.Intn(0x10000))
+		}
+		return string(r)
+	}
+
+	eh := RandExtendedHeader(t)
+	eh.ChainID = randString()
+	eh.Height = rand.Int63()
+	eh.Time = time.Unix(rand.Int63n(1<<34), rand.Int63n(int64(time.Second)))
+	eh.LastBlockID.Hash = randBytes(32)
+	eh.DataHash = randBytes(32)
+	eh.AppHash = randBytes(rand.Intn(64))
+	eh.Version.App = rand.Uint64()
+
+	width := 2 << rand.Intn(5)
+	eh.DAH = &DataAvailabilityHeader{}
+	for i := 0; i < width; i++ {
+		eh.DAH.RowsRoots = append(eh.DAH.RowsRoots, randBytes(2*ipld.NamespaceSize+32))
+		eh.DAH.ColumnRoots = append(eh.DAH.ColumnRoots, randBytes(2*ipld.NamespaceSize+32))
+	}
+	return eh
+}
@@ -0,0 +1,122 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mdutils "github.com/ipfs/go-merkledag/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "github.com/tendermint/tendermint/types"
+)
+
+func TestExtendedHeader_DataSquareSize(t *testing.T) {
+	roots := func(n int) [][]byte {
+		return make([][]byte, n)
+	}
+
+	tests := []struct {
+		name string
+		dah  *DataAvailabilityHeader
+		size int
+		err  bool
+	}{
+		{"minimum", &DataAvailabilityHeader{RowsRoots: roots(2), ColumnRoots: roots(2)}, 1, false},
+		{"8x8", &DataAvailabilityHeader{RowsRoots: roots(16), ColumnRoots: roots(16)}, 64, false},
+		{"mismatched roots", &DataAvailabilityHeader{RowsRoots: roots(16), ColumnRoots: roots(8)}, 0, true},
+		{"odd roots", &DataAvailabilityHeader{RowsRoots: roots(3), ColumnRoots: roots(3)}, 0, true},
+		{"no roots", &DataAvailabilityHeader{}, 0, true},
+		{"no DAH", nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eh := &ExtendedHeader{DAH: tt.dah}
+			size, err := eh.DataSquareSize()
+			if tt.err {
+				assert.ErrorIs(t, err, ErrInvalidSquare)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.size, size)
+		})
+	}
+
+	// headers of the suite commit to the minimal square
+	size, err := NewTestSuite(t, 1).GenExtendedHeader().DataSquareSize()
+	require.NoError(t, err)
+	assert.Equal(t, 1, size)
+}
+
+func TestNewExtendedHeaderFromTendermint(t *testing.T) {
+	suite := NewTestSuite(t, 3)
+	dah := EmptyDAH()
+	rh := suite.GenRawHeader(1, suite.Head().Hash(), suite.Head().Commit.Hash(), dah.Hash())
+	block, comm := &core.Block{Header: *rh}, suite.Commit(rh)
+
+	eh, err := NewExtendedHeaderFromTendermint(block, comm, suite.valSet)
+	require.NoError(t, err)
+	assert.Equal(t, rh.Hash(), eh.Hash())
+	assert.Equal(t, dah.Hash(), eh.DAH.Hash())
+
+	// the DAH is the same as of the block data stored to the DAG
+	made, err := MakeExtendedHeader(context.Background(), block, comm, suite.valSet, mdutils.Mock())
+	require.NoError(t, err)
+	assert.Equal(t, made.DAH.Hash(), eh.DAH.Hash())
+
+	_, err = NewExtendedHeaderFromTendermint(nil, comm, suite.valSet)
+	assert.Error(t, err)
+	_, err = NewExtendedHeaderFromTendermint(block, nil, suite.valSet)
+	assert.Error(t, err)
+	_, err = NewExtendedHeaderFromTendermint(block, comm, nil)
+	assert.Error(t, err)
+	_, err = NewExtendedHeaderFromTendermint(block, comm, &core.ValidatorSet{})
+	assert.Error(t, err)
+	// the commit must be for the block
+	_, err = NewExtendedHeaderFromTendermint(block, suite.Head().Commit, suite.valSet)
+	assert.Error(t, err)
+}
+
+func TestExtendedHeader_Age(t *testing.T) {
+	blockTime := time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+	eh := &ExtendedHeader{RawHeader: RawHeader{Time: blockTime}}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		age  time.Duration
+	}{
+		{"at block time", blockTime, 0},
+		{"after block time", blockTime.Add(time.Hour), time.Hour},
+		{"weeks after block time", blockTime.AddDate(0, 0, 21), 21 * 24 * time.Hour},
+		{"before block time", blockTime.Add(-time.Minute), -time.Minute},
+		{"in other time zone", blockTime.In(time.FixedZone("UTC+3", 3*60*60)).Add(time.Second), time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.age, eh.Age(tt.now))
+		})
+	}
+}
+
+func TestExtendedHeader_IsExpired(t *testing.T) {
+	const period = 14 * 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		age     time.Duration
+		expired bool
+	}{
+		{"fresh", time.Minute, false},
+		{"from the future", -time.Hour, false},
+		{"within period", period - time.Hour, false},
+		{"beyond period", period + time.Hour, true},
+		{"long expired", 10 * period, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eh := &ExtendedHeader{RawHeader: RawHeader{Time: time.Now().Add(-tt.age)}}
+			assert.Equal(t, tt.expired, eh.IsExpired(period))
+		})
+	}
+}
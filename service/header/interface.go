@@ -46,6 +46,17 @@ type Exchange interface {
 	// RequestByHash performs a request for the ExtendedHeader by the given hash corresponding
 	// to the RawHeader. Note that the ExtendedHeader must be verified thereafter.
 	RequestByHash(ctx context.Context, hash tmbytes.HexBytes) (*ExtendedHeader, error)
+	// RequestHeadersByHashes performs a request for the ExtendedHeaders by the given hashes corresponding
+	// to the RawHeaders. ExtendedHeaders are returned in the order of given hashes and the request fails
+	// if any of them cannot be found. Note that the ExtendedHeaders must be verified thereafter.
+	RequestHeadersByHashes(ctx context.Context, hashes []tmbytes.HexBytes) ([]*ExtendedHeader, error)
+	// RequestHeaderSince performs a request for the range of 'amount' ExtendedHeaders starting at the one
+	// with the given hash, e.g. when its height is not known. Note that the ExtendedHeaders must be verified
+	// thereafter.
+	RequestHeaderSince(ctx context.Context, hash tmbytes.HexBytes, amount uint64) ([]*ExtendedHeader, error)
+	// Probe checks the source of ExtendedHeaders is alive with a lightweight request,
+	// e.g. for the head, which is not stored anywhere.
+	Probe(ctx context.Context) error
 }
 
 var (
@@ -54,27 +65,110 @@ var (
 
 	// ErrNoHead is returned when Store does not contain Head of the chain,
 	ErrNoHead = fmt.Errorf("header/store: no chain head")
+
+	// ErrCannotDeleteHead is returned when deleting the head of the chain is requested.
+	ErrCannotDeleteHead = errors.New("header/store: cannot delete head")
+
+	// ErrNotAboveHead is returned when a single appended header is not above the head of the chain.
+	ErrNotAboveHead = errors.New("header/store: header is not above the head")
+
+	// ErrHeightMismatch is returned when the head is replaced with a header at another height.
+	ErrHeightMismatch = errors.New("header/store: height mismatch")
+
+	// ErrInvalidRange is returned when a requested range of heights is empty or goes beyond the head.
+	ErrInvalidRange = errors.New("header/store: invalid range")
 )
 
+// HeaderIterator iterates over ExtendedHeaders in ascending order of heights.
+type HeaderIterator interface {
+	// Next advances the iterator to the next ExtendedHeader and reports whether there is one.
+	Next() bool
+	// Value returns the ExtendedHeader the iterator is at.
+	Value() *ExtendedHeader
+	// Close releases the iterator and returns the error which stopped the iteration, if any.
+	Close() error
+}
+
 // Store encompasses the behavior necessary to store and retrieve ExtendedHeaders
 // from a node's local storage.
 type Store interface {
 	// Head returns the ExtendedHeader of the chain head.
 	Head(context.Context) (*ExtendedHeader, error)
 
+	// Tail returns the ExtendedHeader of the lowest stored height, which is bumped by pruning.
+	Tail(context.Context) (*ExtendedHeader, error)
+
 	// Get returns the ExtendedHeader corresponding to the given hash.
 	Get(context.Context, tmbytes.HexBytes) (*ExtendedHeader, error)
 
 	// GetByHeight returns the ExtendedHeader corresponding to the given block height.
 	GetByHeight(context.Context, uint64) (*ExtendedHeader, error)
 
+	// GetByHashPrefix returns the ExtendedHeaders whose hashes start with the given prefix,
+	// in ascending order of heights. At most MaxHashPrefixMatches headers are returned.
+	// There are no matches for an empty prefix.
+	GetByHashPrefix(ctx context.Context, prefix []byte) ([]*ExtendedHeader, error)
+
 	// GetRangeByHeight returns the given range [from:to) of ExtendedHeaders.
+	// If the context is canceled in the middle, the headers fetched so far are returned
+	// together with the wrapped context error.
 	GetRangeByHeight(ctx context.Context, from, to uint64) ([]*ExtendedHeader, error)
 
+	// GetByHeightRange returns the ExtendedHeaders from the height 'from' inclusive to the height 'to'
+	// exclusive, i.e. [from:to), in ascending order of heights. Unlike GetRangeByHeight, the bounds
+	// are checked first: it errors with ErrInvalidRange if 'from' is not below 'to' or if 'to' is
+	// above the height following the head, and with ErrNoHead if there is no head yet.
+	// Heights within the bounds which are not stored, e.g. pruned ones, fail with ErrNotFound.
+	GetByHeightRange(ctx context.Context, from, to uint64) ([]*ExtendedHeader, error)
+
+	// IterateByHeight returns a HeaderIterator over the given range [from:to) of ExtendedHeaders,
+	// which are loaded one by one instead of all at once. It errors with ErrNotFound if the range
+	// is not fully stored.
+	IterateByHeight(ctx context.Context, from, to uint64) (HeaderIterator, error)
+
+	// ForEach calls 'fn' for every stored ExtendedHeader from the tail to the head, skipping gaps.
+	// Iteration stops on the first error returned by 'fn', which is returned.
+	ForEach(ctx context.Context, fn func(*ExtendedHeader) error) error
+
 	// Has checks whether ExtendedHeader is already stored.
 	Has(context.Context, tmbytes.HexBytes) (bool, error)
 
+	// HasAt checks whether ExtendedHeader at the given height is already stored, without loading it.
+	HasAt(context.Context, uint64) (bool, error)
+
+	// CountHeaders returns the amount of stored ExtendedHeaders without iterating over them.
+	CountHeaders(context.Context) (uint64, error)
+
 	// Append stores and verifies the given ExtendedHeader(s).
 	// It requires them to be adjacent and in ascending order.
+	// Headers below the head fill a gap in the stored chain and must link to the stored headers around it.
 	Append(context.Context, ...*ExtendedHeader) error
+
+	// AppendSingle stores and verifies the given ExtendedHeader on top of the head.
+	// Unlike Append, it errors on nil header and with ErrNotAboveHead on the one not above the head.
+	AppendSingle(context.Context, *ExtendedHeader) error
+
+	// ReplaceLast atomically replaces the head with the given ExtendedHeader at the same height,
+	// e.g. when a longer valid fork is chosen. It errors with ErrHeightMismatch if the heights differ,
+	// and the header must link to the stored one preceding the head, if any.
+	// Replacing the head with itself is a no-op.
+	ReplaceLast(context.Context, *ExtendedHeader) error
+
+	// Prune removes all the ExtendedHeaders except the 'keepLast' latest ones.
+	Prune(ctx context.Context, keepLast uint64) error
+
+	// DeleteByHeight removes the ExtendedHeader at the given height, e.g. when it is proven invalid.
+	// The head cannot be deleted. Deleting the tail makes the next stored header a new tail.
+	// The height is marked as deleted, so the Syncer does not refetch it as a gap.
+	DeleteByHeight(ctx context.Context, height uint64) error
+
+	// Deleted checks whether the ExtendedHeader at the given height was removed with DeleteByHeight
+	// and not stored again since.
+	Deleted(ctx context.Context, height uint64) (bool, error)
+
+	// WatchHead returns a channel delivering the ExtendedHeaders that become new heads, in order.
+	// Only the latest head is kept for slow readers, so the heads superseded before being read are skipped.
+	// Headers filling gaps below the head are not delivered.
+	// The channel is closed once the given context is canceled.
+	WatchHead(ctx context.Context) (<-chan *ExtendedHeader, error)
 }
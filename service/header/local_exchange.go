@@ -30,6 +30,10 @@ func (l *LocalExchange) RequestHead(ctx context.Context) (*ExtendedHeader, error
 	return l.store.Head(ctx)
 }
 
+func (l *LocalExchange) Probe(ctx context.Context) error {
+	return probeHead(ctx, l.store.Head)
+}
+
 func (l *LocalExchange) RequestHeader(ctx context.Context, height uint64) (*ExtendedHeader, error) {
 	return l.store.GetByHeight(ctx, height)
 }
@@ -41,3 +45,35 @@ func (l *LocalExchange) RequestHeaders(ctx context.Context, origin, amount uint6
 func (l *LocalExchange) RequestByHash(ctx context.Context, hash bytes.HexBytes) (*ExtendedHeader, error) {
 	return l.store.Get(ctx, hash)
 }
+
+func (l *LocalExchange) RequestHeaderSince(
+	ctx context.Context,
+	hash bytes.HexBytes,
+	amount uint64,
+) ([]*ExtendedHeader, error) {
+	if amount == 0 {
+		return nil, nil
+	}
+	h, err := l.store.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return l.store.GetRangeByHeight(ctx, uint64(h.Height), uint64(h.Height)+amount)
+}
+
+func (l *LocalExchange) RequestHeadersByHashes(
+	ctx context.Context,
+	hashes []bytes.HexBytes,
+) ([]*ExtendedHeader, error) {
+	headers := make([]*ExtendedHeader, len(hashes))
+	for i, hash := range hashes {
+		h, err := l.store.Get(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		headers[i] = h
+	}
+
+	return headers, nil
+}
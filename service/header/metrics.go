@@ -0,0 +1,48 @@
+package header
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/unit"
+)
+
+var meter = global.Meter("header")
+
+var (
+	// headersReceived counts headers received from the header-sub gossipsub network.
+	headersReceived = metric.Must(meter).NewInt64Counter("header_received_total",
+		metric.WithDescription("Amount of headers received from the network via gossip"))
+	// headersRequested counts headers requested from peers via P2PExchange.
+	headersRequested = metric.Must(meter).NewInt64Counter("header_requested_total",
+		metric.WithDescription("Amount of headers requested from peers"))
+	// requestsTotal counts requests sent to peers via P2PExchange.
+	requestsTotal = metric.Must(meter).NewInt64Counter("header_p2p_requests_total",
+		metric.WithDescription("Amount of requests sent to peers"))
+	// requestBytesOut records the size of every request sent via P2PExchange.
+	requestBytesOut = metric.Must(meter).NewInt64ValueRecorder("header_p2p_request_bytes_out",
+		metric.WithDescription("Size of requests sent to peers before compression"),
+		metric.WithUnit(unit.Bytes))
+	// responseBytesIn records the size of every response received via P2PExchange.
+	responseBytesIn = metric.Must(meter).NewInt64ValueRecorder("header_p2p_response_bytes_in",
+		metric.WithDescription("Size of responses received from peers after decompression"),
+		metric.WithUnit(unit.Bytes))
+)
+
+// ObserveStore starts reporting the amount of headers kept by the given Store.
+// It must be called at most once per process.
+func ObserveStore(s Store) error {
+	_, err := meter.NewInt64ValueObserver("header_store_size",
+		func(ctx context.Context, res metric.Int64ObserverResult) {
+			size, err := s.CountHeaders(ctx)
+			if err != nil {
+				log.Debugw("header/store: observing size", "err", err)
+				return
+			}
+			res.Observe(int64(size))
+		},
+		metric.WithDescription("Amount of headers kept by the header store"),
+	)
+	return err
+}
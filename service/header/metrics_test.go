@@ -0,0 +1,105 @@
+package header
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/global"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+
+	pb "github.com/celestiaorg/celestia-node/service/header/pb"
+	"github.com/celestiaorg/go-libp2p-messenger/serde"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ctrl := controller.New(
+		processor.New(simple.NewWithInexpensiveDistribution(), export.CumulativeExportKindSelector()),
+		controller.WithCollectPeriod(0),
+	)
+	global.SetMeterProvider(ctrl.MeterProvider())
+
+	host, peer := createMocknet(ctx, t)
+	exchg, store := createP2PExAndServer(t, host, peer)
+	err := ObserveStore(store)
+	require.NoError(t, err)
+
+	_, err = exchg.RequestHeaders(ctx, 1, 3)
+	require.NoError(t, err)
+
+	// the sizes of the batch request and its response are recorded once
+	var reqSize, respSize bytes.Buffer
+	_, err = serde.Write(&reqSize, &pb.ExtendedHeaderRequest{Origin: 1, Amount: 3})
+	require.NoError(t, err)
+	for height := uint64(1); height <= 3; height++ {
+		pbh, err := ExtendedHeaderToProto(store.byHeight[height])
+		require.NoError(t, err)
+		_, err = serde.Write(&respSize, &pb.ExtendedHeaderResponse{Header: pbh, Code: pb.StatusCode_OK})
+		require.NoError(t, err)
+	}
+	values, counts := collectMetrics(ctx, t, ctrl)
+	assert.EqualValues(t, 1, values["header_p2p_requests_total"])
+	assert.EqualValues(t, 1, counts["header_p2p_request_bytes_out"])
+	assert.EqualValues(t, reqSize.Len(), values["header_p2p_request_bytes_out"])
+	assert.EqualValues(t, 1, counts["header_p2p_response_bytes_in"])
+	assert.EqualValues(t, respSize.Len(), values["header_p2p_response_bytes_in"])
+
+	_, err = exchg.RequestHead(ctx)
+	require.NoError(t, err)
+
+	values, _ = collectMetrics(ctx, t, ctrl)
+	assert.EqualValues(t, 4, values["header_requested_total"])
+	assert.EqualValues(t, 2, values["header_p2p_requests_total"])
+	assert.EqualValues(t, len(store.byHeight), values["header_store_size"])
+}
+
+// collectMetrics collects the current values of all the metrics by name, along with the counts of
+// observations of the recorded ones.
+func collectMetrics(
+	ctx context.Context,
+	t *testing.T,
+	ctrl *controller.Controller,
+) (map[string]int64, map[string]int64) {
+	err := ctrl.Collect(ctx)
+	require.NoError(t, err)
+
+	values, counts := make(map[string]int64), make(map[string]int64)
+	err = ctrl.ForEach(export.CumulativeExportKindSelector(), func(r export.Record) error {
+		switch agg := r.Aggregation().(type) {
+		case aggregation.MinMaxSumCount:
+			sum, err := agg.Sum()
+			if err != nil {
+				return err
+			}
+			count, err := agg.Count()
+			if err != nil {
+				return err
+			}
+			values[r.Descriptor().Name()], counts[r.Descriptor().Name()] = sum.AsInt64(), int64(count)
+		case aggregation.Sum:
+			sum, err := agg.Sum()
+			if err != nil {
+				return err
+			}
+			values[r.Descriptor().Name()] = sum.AsInt64()
+		case aggregation.LastValue:
+			last, _, err := agg.LastValue()
+			if err != nil {
+				return err
+			}
+			values[r.Descriptor().Name()] = last.AsInt64()
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return values, counts
+}
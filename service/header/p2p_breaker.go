@@ -0,0 +1,134 @@
+package header
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets all the requests through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all the requests until the recovery timeout passes.
+	BreakerOpen
+	// BreakerHalfOpen lets a single probing request through to check whether the peer recovered.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// CircuitBreaker stops requests to a peer failing consistently.
+// It opens after 'threshold' failures in a row and rejects requests for the 'recovery' timeout.
+// Then, it becomes half-open and lets a single request through: the breaker closes if it succeeds
+// and opens again otherwise.
+type CircuitBreaker struct {
+	threshold int
+	recovery  time.Duration
+	now       func() time.Time
+
+	lk       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a new closed CircuitBreaker.
+func NewCircuitBreaker(threshold int, recovery time.Duration) *CircuitBreaker {
+	return newCircuitBreaker(threshold, recovery, time.Now)
+}
+
+func newCircuitBreaker(threshold int, recovery time.Duration, now func() time.Time) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		recovery:  recovery,
+		now:       now,
+	}
+}
+
+// State returns the current state of the breaker.
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.lk.Lock()
+	defer cb.lk.Unlock()
+	cb.refresh()
+	return cb.state
+}
+
+// Ready reports whether the breaker would let a request through, without reserving it.
+func (cb *CircuitBreaker) Ready() bool {
+	cb.lk.Lock()
+	defer cb.lk.Unlock()
+	cb.refresh()
+	return cb.state == BreakerClosed || (cb.state == BreakerHalfOpen && !cb.probing)
+}
+
+// Allow reports whether a request may be sent. In the half-open state, only the first request
+// is allowed until its result is reported.
+func (cb *CircuitBreaker) Allow() bool {
+	cb.lk.Lock()
+	defer cb.lk.Unlock()
+	cb.refresh()
+
+	switch cb.state {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if cb.probing {
+			return false
+		}
+		cb.probing = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Success reports a successful request, closing the breaker.
+func (cb *CircuitBreaker) Success() {
+	cb.lk.Lock()
+	defer cb.lk.Unlock()
+	cb.state, cb.failures, cb.probing = BreakerClosed, 0, false
+}
+
+// Failure reports a failed request, opening the breaker once the threshold is reached
+// or if the probing request failed.
+func (cb *CircuitBreaker) Failure() {
+	cb.lk.Lock()
+	defer cb.lk.Unlock()
+	cb.refresh()
+
+	cb.failures++
+	if cb.state == BreakerHalfOpen || cb.failures >= cb.threshold {
+		cb.state, cb.openedAt, cb.probing = BreakerOpen, cb.now(), false
+	}
+}
+
+// Abort reports a request which completed with neither success nor failure, e.g. the one canceled
+// by the requester, so the half-open breaker lets another request through.
+func (cb *CircuitBreaker) Abort() {
+	cb.lk.Lock()
+	defer cb.lk.Unlock()
+	cb.probing = false
+}
+
+// refresh moves the open breaker to the half-open state once the recovery timeout passes.
+// The caller must hold the lock.
+func (cb *CircuitBreaker) refresh() {
+	if cb.state == BreakerOpen && cb.now().Sub(cb.openedAt) >= cb.recovery {
+		cb.state = BreakerHalfOpen
+	}
+}
@@ -0,0 +1,56 @@
+package header
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker(3, time.Second*30, func() time.Time { return now })
+	assert.Equal(t, BreakerClosed, cb.State())
+
+	// failures below the threshold keep the breaker closed
+	for i := 0; i < 2; i++ {
+		assert.True(t, cb.Allow())
+		cb.Failure()
+	}
+	assert.Equal(t, BreakerClosed, cb.State())
+
+	// a success resets the failures
+	cb.Success()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, BreakerClosed, cb.State())
+		assert.True(t, cb.Allow())
+		cb.Failure()
+	}
+	assert.Equal(t, BreakerOpen, cb.State())
+	assert.False(t, cb.Ready())
+	assert.False(t, cb.Allow())
+
+	// only a single probe is let through once recovered
+	now = now.Add(time.Second * 30)
+	assert.Equal(t, BreakerHalfOpen, cb.State())
+	assert.True(t, cb.Ready())
+	assert.True(t, cb.Allow())
+	assert.False(t, cb.Ready())
+	assert.False(t, cb.Allow())
+
+	// the failed probe opens the breaker again
+	cb.Failure()
+	assert.Equal(t, BreakerOpen, cb.State())
+	assert.False(t, cb.Allow())
+
+	// the aborted probe lets another one through
+	now = now.Add(time.Second * 30)
+	assert.True(t, cb.Allow())
+	cb.Abort()
+	assert.True(t, cb.Allow())
+
+	// the successful probe closes the breaker
+	cb.Success()
+	assert.Equal(t, BreakerClosed, cb.State())
+	assert.True(t, cb.Allow())
+}
@@ -0,0 +1,154 @@
+package header
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/libp2p/go-libp2p-core/network"
+)
+
+// CompressionAlgo identifies the algorithm the header exchange streams are compressed with.
+// The algorithm is chosen by the requesting side and announced to the server with a single
+// handshake byte at the start of every stream, so both sides always use the same one.
+type CompressionAlgo byte
+
+const (
+	// NoCompression leaves the streams uncompressed.
+	NoCompression CompressionAlgo = iota
+	// Snappy compresses the streams with the Snappy framing format.
+	Snappy
+	// Zstd compresses the streams with Zstandard.
+	Zstd
+)
+
+func (algo CompressionAlgo) String() string {
+	switch algo {
+	case NoCompression:
+		return "none"
+	case Snappy:
+		return "snappy"
+	case Zstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(algo))
+	}
+}
+
+// compressedStream is a network.Stream reading and writing through the compression algorithm
+// negotiated for it. Written data is buffered until CloseWrite or Close.
+type compressedStream struct {
+	network.Stream
+
+	r io.Reader
+	w io.WriteCloser
+	// release frees the resources held by the reader, if any.
+	release func()
+	// closedWrite is set once the compressed data is finalized
+	closedWrite bool
+}
+
+// openStream writes the handshake byte for the given algorithm to the outbound stream
+// and wraps the stream with it.
+func openStream(stream network.Stream, algo CompressionAlgo) (*compressedStream, error) {
+	_, err := stream.Write([]byte{byte(algo)})
+	if err != nil {
+		return nil, err
+	}
+	return newCompressedStream(stream, algo)
+}
+
+// acceptStream reads the handshake byte from the inbound stream and wraps the stream
+// with the announced algorithm.
+func acceptStream(stream network.Stream) (*compressedStream, error) {
+	var handshake [1]byte
+	_, err := io.ReadFull(stream, handshake[:])
+	if err != nil {
+		return nil, err
+	}
+	return newCompressedStream(stream, CompressionAlgo(handshake[0]))
+}
+
+func newCompressedStream(stream network.Stream, algo CompressionAlgo) (*compressedStream, error) {
+	cs := &compressedStream{Stream: stream, release: func() {}}
+	switch algo {
+	case NoCompression:
+		cs.r, cs.w = stream, nopCompressor{stream}
+	case Snappy:
+		cs.r, cs.w = snappy.NewReader(stream), snappy.NewBufferedWriter(stream)
+	case Zstd:
+		dec, err := zstd.NewReader(stream, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		enc, err := zstd.NewWriter(stream, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			dec.Close()
+			return nil, err
+		}
+		cs.r, cs.w, cs.release = dec, enc, dec.Close
+	default:
+		return nil, fmt.Errorf("header/p2p: unsupported compression %s", algo)
+	}
+	return cs, nil
+}
+
+func (cs *compressedStream) Read(p []byte) (int, error) {
+	return cs.r.Read(p)
+}
+
+func (cs *compressedStream) Write(p []byte) (int, error) {
+	return cs.w.Write(p)
+}
+
+// Flush writes out the buffered data, so the other side can read it without waiting for
+// the stream to be closed.
+func (cs *compressedStream) Flush() error {
+	if f, ok := cs.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// CloseWrite flushes the buffered data and closes the stream for writing.
+func (cs *compressedStream) CloseWrite() error {
+	err := cs.finish()
+	if err != nil {
+		return err
+	}
+	return cs.Stream.CloseWrite()
+}
+
+// Close flushes the buffered data, unless the stream is already closed for writing, and closes the stream.
+func (cs *compressedStream) Close() error {
+	defer cs.release()
+	err := cs.finish()
+	if err != nil {
+		cs.Stream.Reset() //nolint:errcheck
+		return err
+	}
+	return cs.Stream.Close()
+}
+
+// Reset drops the buffered data and resets the stream.
+func (cs *compressedStream) Reset() error {
+	defer cs.release()
+	return cs.Stream.Reset()
+}
+
+// finish writes out the rest of the compressed data.
+func (cs *compressedStream) finish() error {
+	if cs.closedWrite {
+		return nil
+	}
+	cs.closedWrite = true
+	return cs.w.Close()
+}
+
+// nopCompressor passes the data through as is.
+type nopCompressor struct {
+	io.Writer
+}
+
+func (nopCompressor) Close() error { return nil }
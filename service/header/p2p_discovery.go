@@ -0,0 +1,124 @@
+package header
+
+import (
+	"context"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/routing"
+	discovery "github.com/libp2p/go-libp2p-discovery"
+	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
+)
+
+// discoveryRetryInterval is the delay between failed attempts to advertise or discover peers.
+var discoveryRetryInterval = time.Minute
+
+// WithDHTDiscovery makes P2PExchange advertise itself in the given DHT under the exchange protocol
+// and discover other peers serving headers there, if none of the given peers is reachable.
+// Any content router works, but it is meant to be the node's *dht.IpfsDHT with providers enabled.
+func WithDHTDiscovery(r routing.ContentRouting) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.discovery = discovery.NewRoutingDiscovery(r)
+	}
+}
+
+// WithMDNSDiscovery makes P2PExchange announce itself over mDNS in the local network under the given
+// service name and add the peers announcing themselves under the same name to the pool.
+// It is meant for local deployments, like devnets, where nodes find each other without any bootstrap peers.
+func WithMDNSDiscovery(serviceName string) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.mdnsName = serviceName
+	}
+}
+
+// startMDNS starts announcing the exchange over mDNS and adding the peers found to the pool.
+func (ex *P2PExchange) startMDNS(ctx context.Context) {
+	ex.mdns = mdns.NewMdnsService(ex.host, ex.mdnsName)
+	ex.mdns.RegisterNotifee(&mdnsNotifee{ctx: ctx, ex: ex})
+}
+
+// mdnsNotifee adds the peers found over mDNS to the pool of the exchange.
+type mdnsNotifee struct {
+	ctx context.Context
+	ex  *P2PExchange
+}
+
+func (n *mdnsNotifee) HandlePeerFound(p peer.AddrInfo) {
+	// peers are announced periodically, including the exchange itself
+	if p.ID == n.ex.host.ID() || n.ctx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(n.ctx, n.ex.requestTimeout)
+	defer cancel()
+	err := n.ex.AddPeer(ctx, p)
+	if err != nil {
+		log.Debugw("p2p: connecting to peer found over mDNS", "peer", p.ID.ShortString(), "err", err)
+		return
+	}
+	log.Debugw("p2p: found peer over mDNS", "peer", p.ID.ShortString())
+}
+
+// advertise keeps the exchange advertised until it is stopped.
+func (ex *P2PExchange) advertise(ctx context.Context) {
+	ns := string(exchangeProtocolID)
+	for {
+		wait := discoveryRetryInterval
+		ttl, err := ex.discovery.Advertise(ctx, ns)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Debugw("p2p: advertising", "err", err)
+		} else {
+			wait = 7 * ttl / 8
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// discover looks for peers serving headers until it finds reachable ones and adds them to the pool.
+func (ex *P2PExchange) discover(ctx context.Context) {
+	for {
+		log.Info("p2p: discovering peers")
+		if ex.discoverOnce(ctx) > 0 {
+			return
+		}
+
+		select {
+		case <-time.After(discoveryRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// discoverOnce runs a single round of discovery and returns the amount of added peers.
+func (ex *P2PExchange) discoverOnce(ctx context.Context) int {
+	peers, err := ex.discovery.FindPeers(ctx, string(exchangeProtocolID))
+	if err != nil {
+		log.Errorw("p2p: discovering peers", "err", err)
+		return 0
+	}
+
+	var added int
+	for p := range peers {
+		if p.ID == ex.host.ID() {
+			continue
+		}
+
+		err = ex.AddPeer(ctx, p)
+		if err != nil {
+			log.Debugw("p2p: connecting to discovered peer", "peer", p.ID.ShortString(), "err", err)
+			continue
+		}
+		log.Infow("p2p: discovered peer", "peer", p.ID.ShortString())
+		added++
+	}
+	return added
+}
@@ -3,13 +3,19 @@ package header
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	mrand "math/rand"
 	"sync"
+	"time"
 
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/protocol"
+	discovery "github.com/libp2p/go-libp2p-discovery"
+	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
 	tmbytes "github.com/tendermint/tendermint/libs/bytes"
 
 	"github.com/celestiaorg/go-libp2p-messenger/serde"
@@ -17,7 +23,121 @@ import (
 	pb "github.com/celestiaorg/celestia-node/service/header/pb"
 )
 
-var exchangeProtocolID = protocol.ID("/header-ex/v0.0.1")
+// DefaultRequestTimeout is the default amount of time P2PExchange waits for a single request to complete.
+var DefaultRequestTimeout = time.Second * 10
+
+// maxBackoff caps the delay between retried requests, before jitter.
+var maxBackoff = time.Minute
+
+// DefaultChunkSize is the default maximum amount of headers RequestHeaders requests over a single stream.
+var DefaultChunkSize uint64 = 64
+
+var (
+	// ErrRequestTimeout is returned when a request to a peer does not complete within the configured timeout.
+	ErrRequestTimeout = errors.New("header/p2p: request timed out")
+	// ErrInvalidResponse is returned when a peer responds with malformed or invalid headers.
+	ErrInvalidResponse = errors.New("header/p2p: invalid response")
+	// ErrTooManyRequests is returned when a peer rejects a request due to the load caused by the requester.
+	ErrTooManyRequests = errors.New("header/p2p: too many requests")
+	// ErrNoPeers is returned when the pool of peers to request is empty.
+	ErrNoPeers = errors.New("header/p2p: no peers")
+	// ErrCircuitOpen is returned when requests to a peer are stopped by its CircuitBreaker.
+	ErrCircuitOpen = errors.New("header/p2p: circuit breaker is open")
+	// ErrPeersBehind is returned when no peer of the pool advertises the head at the minimum height.
+	ErrPeersBehind = errors.New("header/p2p: no peer at the minimum height")
+	// ErrUnexpectedPeer is returned when the remote peer of a stream is not the expected one.
+	ErrUnexpectedPeer = errors.New("header/p2p: unexpected peer")
+	// ErrUnsupportedRequest is returned when a peer speaking the baseline exchange protocol cannot serve the request,
+	// i.e. requests by multiple hashes or since a hash.
+	ErrUnsupportedRequest = errors.New("header/p2p: request unsupported by peer")
+)
+
+// P2PExchangeOption is a functional option that configures P2PExchange.
+type P2PExchangeOption func(*P2PExchange)
+
+// WithRequestTimeout sets the maximum amount of time a single request to a peer may take.
+func WithRequestTimeout(timeout time.Duration) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.requestTimeout = timeout
+	}
+}
+
+// WithRetry makes P2PExchange retry failed requests up to 'maxAttempts' times in total.
+// Attempts are separated by an exponentially growing delay, starting from 'baseDelay', plus jitter.
+func WithRetry(maxAttempts int, baseDelay time.Duration) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.maxAttempts = maxAttempts
+		ex.baseDelay = baseDelay
+	}
+}
+
+// WithPeers adds the given peers to the pool of peers P2PExchange requests headers from.
+func WithPeers(peers []peer.AddrInfo) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.peers = append(ex.peers, peers...)
+	}
+}
+
+// WithValidator sets the Validator every header received from the network is checked with.
+// Defaults to DefaultValidator.
+func WithValidator(v Validator) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.validator = v
+	}
+}
+
+// WithCompression sets the algorithm the streams of requests are compressed with.
+// Servers follow the algorithm chosen by the requesting side. Defaults to NoCompression.
+func WithCompression(algo CompressionAlgo) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.compression = algo
+	}
+}
+
+// WithChunkSize sets the maximum amount of headers RequestHeaders requests over a single stream.
+// Zero disables chunking. Defaults to DefaultChunkSize.
+func WithChunkSize(size uint64) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.chunkSize = size
+	}
+}
+
+// WithStreamReuse makes P2PExchange keep up to 'maxIdle' streams per peer open after requests
+// and send subsequent requests over them, saving on opening a new stream for every request.
+func WithStreamReuse(maxIdle int) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		if maxIdle > 0 {
+			ex.pool = newStreamPool(maxIdle)
+		}
+	}
+}
+
+// WithCircuitBreaker attaches a CircuitBreaker to every peer of the pool, so that peers
+// failing 'threshold' requests in a row are not requested for the 'recovery' timeout.
+func WithCircuitBreaker(threshold int, recovery time.Duration) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.breakerThreshold = threshold
+		ex.breakerRecovery = recovery
+	}
+}
+
+// WithMinPeerHeight makes P2PExchange request only the peers advertising the head at the given
+// height or above, so the node behind requests peers which have the headers it does not.
+// Head heights are requested once a peer is added and refreshed periodically.
+func WithMinPeerHeight(height uint64) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.minPeerHeight = height
+	}
+}
+
+// WithExpectedPeerID makes P2PExchange send requests only over streams authenticated as the peer
+// with the given ID, failing any other with ErrUnexpectedPeer. This guards against peers
+// configured by their address, as anyone listening there would be requested otherwise.
+func WithExpectedPeerID(pid peer.ID) P2PExchangeOption {
+	return func(ex *P2PExchange) {
+		ex.expectedPeer = pid
+	}
+}
 
 // P2PExchange enables sending outbound ExtendedHeaderRequests to the network as well as
 // handling inbound ExtendedHeaderRequests from the network.
@@ -25,22 +145,64 @@ type P2PExchange struct {
 	host  host.Host
 	store Store
 
-	// TODO @renaynay: post-Devnet, we need to remove reliance of Exchange on one bootstrap peer
-	// Ref https://github.com/celestiaorg/celestia-node/issues/172#issuecomment-964306823.
-	trustedPeer *peer.AddrInfo
-	lk          sync.Mutex
-	connected   chan struct{} // if connected is closed, exchange is connected to peer
+	// peers is the pool of peers the exchange requests headers from.
+	// The trusted peer, if given, always comes first.
+	peers     []peer.AddrInfo
+	peersLk   sync.RWMutex
+	lk        sync.Mutex
+	connected chan struct{} // if connected is closed, exchange is connected to at least one peer
+
+	// breakers keep a CircuitBreaker per peer, if enabled; guarded by peersLk
+	breakers         map[peer.ID]*CircuitBreaker
+	breakerThreshold int
+	breakerRecovery  time.Duration
+	now              func() time.Time
+
+	// heights keep the last advertised head height per peer, if the minimum is set; guarded by peersLk
+	heights       map[peer.ID]uint64
+	minPeerHeight uint64
+
+	validator   Validator
+	compression CompressionAlgo
+	discovery   *discovery.RoutingDiscovery
+	// mdnsName is the service name to announce and find peers under over mDNS, if set
+	mdnsName string
+	mdns     mdns.Service
+	// expectedPeer is the only peer requests are sent to, if set
+	expectedPeer peer.ID
+	// protocols are the versions of the exchange protocol to request with, in order of preference
+	protocols []protocol.ID
+
+	requestTimeout time.Duration
+	maxAttempts    int
+	baseDelay      time.Duration
+	chunkSize      uint64
+	// pool keeps idle streams for reuse, if enabled
+	pool *streamPool
+	// policy selects the peer of requests not fanned out, if set; guarded by peersLk
+	policy PeerSelectionPolicy
 
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
-func NewP2PExchange(host host.Host, peer *peer.AddrInfo, store Store) *P2PExchange {
+func NewP2PExchange(host host.Host, peer *peer.AddrInfo, store Store, opts ...P2PExchangeOption) *P2PExchange {
 	ex := &P2PExchange{
-		host:        host,
-		store:       store,
-		trustedPeer: peer,
-		connected:   make(chan struct{}),
+		host:           host,
+		store:          store,
+		connected:      make(chan struct{}),
+		validator:      DefaultValidator,
+		protocols:      exchangeProtocols,
+		requestTimeout: DefaultRequestTimeout,
+		maxAttempts:    1,
+		chunkSize:      DefaultChunkSize,
+		now:            time.Now,
+	}
+	if peer != nil && peer.ID != "" {
+		ex.peers = append(ex.peers, *peer)
+	}
+	for _, opt := range opts {
+		opt(ex)
 	}
 	ex.host.Network().Notify(&network.NotifyBundle{ConnectedF: ex.Connected})
 	return ex
@@ -50,17 +212,39 @@ func (ex *P2PExchange) Start(ctx context.Context) error {
 	log.Info("p2p: starting p2p exchange")
 	ex.ctx, ex.cancel = context.WithCancel(context.Background())
 
-	if ex.trustedPeer.ID != "" {
-		if ex.host.Network().Connectedness(ex.trustedPeer.ID) == network.Connected {
-			close(ex.connected)
-			return nil
+	var connected bool
+	peers := ex.allPeers()
+	for _, p := range peers {
+		if ex.host.Network().Connectedness(p.ID) == network.Connected {
+			ex.markConnected()
+			connected = true
+			continue
 		}
 
-		err := ex.host.Connect(ctx, *ex.trustedPeer)
+		err := ex.host.Connect(ctx, p)
 		if err != nil {
-			log.Errorw("p2p: connecting to trusted peer", "err", err)
-			log.Warn("p2p: HEADERS WONT BE SYNCHRONIZED - PLEASE RESTART WITH TRUSTED PEER BEING ONLINE")
+			log.Errorw("p2p: connecting to peer", "peer", p.ID.ShortString(), "err", err)
+			continue
+		}
+		connected = true
+	}
+	if ex.minPeerHeight > 0 {
+		ex.refreshHeights(ctx, peers)
+		go ex.trackHeights(ex.ctx)
+	}
+	if ex.mdnsName != "" {
+		ex.startMDNS(ex.ctx)
+	}
+	if ex.discovery != nil {
+		go ex.advertise(ex.ctx)
+		// fall back to discovery if there is no peer to request
+		if !connected {
+			go ex.discover(ex.ctx)
 		}
+		return nil
+	}
+	if len(peers) > 0 && !connected {
+		log.Warn("p2p: HEADERS WONT BE SYNCHRONIZED - PLEASE RESTART WITH TRUSTED PEER BEING ONLINE")
 	}
 
 	return nil
@@ -70,9 +254,105 @@ func (ex *P2PExchange) Stop(context.Context) error {
 	log.Info("p2p: stopping p2p exchange")
 	ex.cancel()
 	ex.ctx, ex.cancel = nil, nil
+	if ex.mdns != nil {
+		ex.mdns.Close() //nolint:errcheck
+		ex.mdns = nil
+	}
+	if ex.pool != nil {
+		ex.pool.close()
+	}
+	return nil
+}
+
+// Peers returns a snapshot of the peers from the pool the exchange is currently connected to.
+func (ex *P2PExchange) Peers() []peer.AddrInfo {
+	peers := ex.allPeers()
+	connected := make([]peer.AddrInfo, 0, len(peers))
+	for _, p := range peers {
+		if ex.host.Network().Connectedness(p.ID) == network.Connected {
+			connected = append(connected, p)
+		}
+	}
+	return connected
+}
+
+// AddPeer connects to the given peer and adds it to the pool of peers the exchange requests headers from.
+// The peer is not added if the connection fails.
+func (ex *P2PExchange) AddPeer(ctx context.Context, addr peer.AddrInfo) error {
+	err := ex.host.Connect(ctx, addr)
+	if err != nil {
+		return err
+	}
+
+	ex.peersLk.Lock()
+	for _, p := range ex.peers {
+		if p.ID == addr.ID {
+			ex.peersLk.Unlock()
+			return nil
+		}
+	}
+	ex.peers = append(ex.peers, addr)
+	ex.peersLk.Unlock()
+
+	if ex.minPeerHeight > 0 {
+		ex.refreshHeights(ctx, []peer.AddrInfo{addr})
+	}
+	ex.markConnected()
 	return nil
 }
 
+// RemovePeer removes the peer with the given ID from the pool of peers the exchange requests headers from.
+// The connection to the peer is kept, as it may be used by other protocols.
+func (ex *P2PExchange) RemovePeer(id peer.ID) {
+	ex.peersLk.Lock()
+	defer ex.peersLk.Unlock()
+
+	delete(ex.breakers, id)
+	delete(ex.heights, id)
+	if ex.pool != nil {
+		ex.pool.drop(id)
+	}
+	for i, p := range ex.peers {
+		if p.ID == id {
+			ex.peers = append(ex.peers[:i:i], ex.peers[i+1:]...)
+			return
+		}
+	}
+}
+
+// errNoPeers explains why no peer could be selected for a request.
+func (ex *P2PExchange) errNoPeers() error {
+	peers := ex.allPeers()
+	if len(peers) == 0 {
+		return ErrNoPeers
+	}
+	for _, p := range peers {
+		if !ex.belowMinHeight(p.ID) {
+			return ErrCircuitOpen
+		}
+	}
+	return ErrPeersBehind
+}
+
+// breaker returns the CircuitBreaker of the given peer, or nil if circuit breaking is disabled.
+func (ex *P2PExchange) breaker(id peer.ID) *CircuitBreaker {
+	if ex.breakerThreshold <= 0 {
+		return nil
+	}
+
+	ex.peersLk.Lock()
+	defer ex.peersLk.Unlock()
+	if ex.breakers == nil {
+		ex.breakers = make(map[peer.ID]*CircuitBreaker)
+	}
+	cb, ok := ex.breakers[id]
+	if !ok {
+		cb = newCircuitBreaker(ex.breakerThreshold, ex.breakerRecovery, ex.now)
+		ex.breakers[id] = cb
+	}
+	return cb
+}
+
 func (ex *P2PExchange) RequestHead(ctx context.Context) (*ExtendedHeader, error) {
 	log.Debug("p2p: requesting head")
 	// create request
@@ -80,13 +360,30 @@ func (ex *P2PExchange) RequestHead(ctx context.Context) (*ExtendedHeader, error)
 		Origin: uint64(0),
 		Amount: 1,
 	}
-	headers, err := ex.performRequest(ctx, req)
+	headers, err := ex.performRequest(ctx, req, false)
 	if err != nil {
 		return nil, err
 	}
 	return headers[0], nil
 }
 
+// Probe requests the head from the first available peer and discards it.
+func (ex *P2PExchange) Probe(ctx context.Context) error {
+	return probeHead(ctx, ex.RequestHead)
+}
+
+// probeHead requests the head with the given function and ensures there is one.
+func probeHead(ctx context.Context, requestHead func(context.Context) (*ExtendedHeader, error)) error {
+	head, err := requestHead(ctx)
+	if err != nil {
+		return err
+	}
+	if head == nil {
+		return ErrNoHead
+	}
+	return nil
+}
+
 func (ex *P2PExchange) RequestHeader(ctx context.Context, height uint64) (*ExtendedHeader, error) {
 	log.Debugw("p2p: requesting header", "height", height)
 	// sanity check height
@@ -98,21 +395,120 @@ func (ex *P2PExchange) RequestHeader(ctx context.Context, height uint64) (*Exten
 		Origin: height,
 		Amount: 1,
 	}
-	headers, err := ex.performRequest(ctx, req)
+	headers, err := ex.performRequest(ctx, req, true)
 	if err != nil {
 		return nil, err
 	}
 	return headers[0], nil
 }
 
+// RequestHeaders requests the range of headers in chunks of the configured size, each sent over
+// its own stream, so a large range does not have to be held in a single response.
+// Every chunk is validated against the last header of the previous one.
 func (ex *P2PExchange) RequestHeaders(ctx context.Context, from, amount uint64) ([]*ExtendedHeader, error) {
 	log.Debugw("p2p: requesting headers", "from", from, "to", from+amount)
-	// create request
-	req := &pb.ExtendedHeaderRequest{
-		Origin: from,
-		Amount: amount,
+	var (
+		headers []*ExtendedHeader
+		trusted *ExtendedHeader
+	)
+	// the peer may truncate the response, so keep requesting until all the headers are received
+	for uint64(len(headers)) < amount {
+		// create request
+		req := &pb.ExtendedHeaderRequest{
+			Origin: from + uint64(len(headers)),
+			Amount: amount - uint64(len(headers)),
+		}
+		if ex.chunkSize > 0 && req.Amount > ex.chunkSize {
+			req.Amount = ex.chunkSize
+		}
+		resp, err := ex.perform(ctx, req, trusted, false, false)
+		if err != nil {
+			return nil, err
+		}
+
+		headers = append(headers, resp.headers...)
+		trusted = headers[len(headers)-1]
+	}
+	return headers, nil
+}
+
+// StreamHeaders requests headers in range [from; to) and sends each of them to the returned
+// channel as soon as it arrives from the network, so callers can process headers without
+// waiting for the whole range.
+// The headers channel is closed once the range is delivered or streaming fails. In the latter case
+// the error is sent to the error channel, which is closed afterwards as well.
+// Unlike other requests, streaming is never retried, as some of the headers may already be delivered.
+// The configured request timeout applies to every page of headers the peer responds with.
+func (ex *P2PExchange) StreamHeaders(ctx context.Context, from, to uint64) (<-chan *ExtendedHeader, <-chan error) {
+	log.Debugw("p2p: streaming headers", "from", from, "to", to)
+	headers, errCh := make(chan *ExtendedHeader), make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		defer close(headers)
+
+		err := ex.streamHeaders(ctx, from, to, headers)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Errorw("p2p: streaming headers", "from", from, "to", to, "err", err)
+			}
+			errCh <- err
+		}
+	}()
+	return headers, errCh
+}
+
+// streamHeaders sends headers in range [from; to) to the given channel page by page,
+// as the peer may truncate responses.
+func (ex *P2PExchange) streamHeaders(ctx context.Context, from, to uint64, out chan<- *ExtendedHeader) error {
+	next := from
+	// every page is validated against the last header of the previous one
+	var trusted *ExtendedHeader
+	for next < to {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ex.connected:
+		}
+
+		peers := ex.selectPeers()
+		if len(peers) == 0 {
+			return ex.errNoPeers()
+		}
+
+		req := &pb.ExtendedHeaderRequest{
+			Origin: next,
+			Amount: to - next,
+		}
+		injectTraceContext(ctx, req)
+		headersRequested.Add(ctx, int64(req.Amount))
+		reqCtx, cancel := context.WithTimeout(ctx, ex.requestTimeout)
+		origin := next
+		handle := ex.decoding(reqCtx, req, trusted, func(_ *pb.ExtendedHeader, header *ExtendedHeader) error {
+			select {
+			case out <- header:
+				next, trusted = next+1, header
+				return nil
+			case <-reqCtx.Done():
+				return reqCtx.Err()
+			}
+		})
+		err := ex.streamRequest(reqCtx, peers[0], req, handle)
+		if err != nil && errors.Is(reqCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			err = ErrRequestTimeout
+		}
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		// ensure the peer made progress to avoid requesting the same page forever
+		if next == origin {
+			return ErrNotFound
+		}
 	}
-	return ex.performRequest(ctx, req)
+	return nil
 }
 
 func (ex *P2PExchange) RequestByHash(ctx context.Context, hash tmbytes.HexBytes) (*ExtendedHeader, error) {
@@ -122,7 +518,7 @@ func (ex *P2PExchange) RequestByHash(ctx context.Context, hash tmbytes.HexBytes)
 		Hash:   hash.Bytes(),
 		Amount: 1,
 	}
-	headers, err := ex.performRequest(ctx, req)
+	headers, err := ex.performRequest(ctx, req, false)
 	if err != nil {
 		return nil, err
 	}
@@ -133,66 +529,629 @@ func (ex *P2PExchange) RequestByHash(ctx context.Context, hash tmbytes.HexBytes)
 	return headers[0], nil
 }
 
-func (ex *P2PExchange) performRequest(ctx context.Context, req *pb.ExtendedHeaderRequest) ([]*ExtendedHeader, error) {
+func (ex *P2PExchange) RequestHeadersByHashes(
+	ctx context.Context,
+	hashes []tmbytes.HexBytes,
+) ([]*ExtendedHeader, error) {
+	log.Debugw("p2p: requesting headers by hashes", "amount", len(hashes))
+	headers := make([]*ExtendedHeader, 0, len(hashes))
+	// the peer may truncate the response, so keep requesting until all the headers are received
+	for len(headers) < len(hashes) {
+		rest := hashes[len(headers):]
+		// create request
+		req := &pb.ExtendedHeaderRequest{
+			Hashes: make([][]byte, len(rest)),
+			Amount: uint64(len(rest)),
+		}
+		for i, hash := range rest {
+			req.Hashes[i] = hash.Bytes()
+		}
+		page, err := ex.performRequest(ctx, req, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) > len(rest) {
+			page = page[:len(rest)]
+		}
+
+		for i, header := range page {
+			if !bytes.Equal(header.Hash().Bytes(), rest[i]) {
+				return nil, fmt.Errorf("incorrect hash in header: expected %x, got %x", rest[i], header.Hash().Bytes())
+			}
+		}
+		headers = append(headers, page...)
+	}
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	return headers, nil
+}
+
+// RequestHeaderSince requests the range of headers starting at the header with the given hash.
+// The peer resolves the height of the hash, so the range is requested in chunks by height
+// only once the first chunk is received.
+func (ex *P2PExchange) RequestHeaderSince(
+	ctx context.Context,
+	hash tmbytes.HexBytes,
+	amount uint64,
+) ([]*ExtendedHeader, error) {
+	log.Debugw("p2p: requesting headers since hash", "hash", hash.String(), "amount", amount)
+	if amount == 0 {
+		return nil, nil
+	}
+	// create request
+	req := &pb.ExtendedHeaderRequest{
+		Hash:   hash.Bytes(),
+		Amount: amount,
+	}
+	if ex.chunkSize > 0 && req.Amount > ex.chunkSize {
+		req.Amount = ex.chunkSize
+	}
+	headers, err := ex.performRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	return requestRest(ctx, ex, hash, amount, headers)
+}
+
+// requestRest ensures the given headers start at the given hash and requests the rest of the range
+// of 'amount' headers by height, if they do not cover the whole range, e.g. as the response was truncated.
+func requestRest(
+	ctx context.Context,
+	ex Exchange,
+	hash tmbytes.HexBytes,
+	amount uint64,
+	headers []*ExtendedHeader,
+) ([]*ExtendedHeader, error) {
+	if !bytes.Equal(headers[0].Hash().Bytes(), hash) {
+		return nil, fmt.Errorf("incorrect hash in header: expected %x, got %x", hash, headers[0].Hash().Bytes())
+	}
+	if uint64(len(headers)) >= amount {
+		return headers[:amount], nil
+	}
+
+	last := headers[len(headers)-1]
+	rest, err := ex.RequestHeaders(ctx, uint64(last.Height)+1, amount-uint64(len(headers)))
+	if err != nil {
+		return nil, err
+	}
+	return append(headers, rest...), nil
+}
+
+// FetchRaw sends the given request to the first available peer and returns the headers
+// it responded with as they are, leaving decoding and validation to the caller.
+// Failed attempts are retried the same way as any other request.
+func (ex *P2PExchange) FetchRaw(ctx context.Context, req *pb.ExtendedHeaderRequest) ([]*pb.ExtendedHeader, error) {
+	resp, err := ex.perform(ctx, req, nil, false, true)
+	if err != nil {
+		return nil, err
+	}
+	return resp.raw, nil
+}
+
+// performRequest sends the given request to the network and reads the response.
+// If 'fanOut' is set, the request is sent to all available peers at once and the first
+// successful response wins. Otherwise, only the first available peer is requested.
+func (ex *P2PExchange) performRequest(
+	ctx context.Context,
+	req *pb.ExtendedHeaderRequest,
+	fanOut bool,
+) ([]*ExtendedHeader, error) {
+	resp, err := ex.perform(ctx, req, nil, fanOut, false)
+	if err != nil {
+		return nil, err
+	}
+	return resp.headers, nil
+}
+
+// response keeps the headers a peer responded with, either raw or decoded.
+type response struct {
+	raw     []*pb.ExtendedHeader
+	headers []*ExtendedHeader
+}
+
+// perform implements performRequest and FetchRaw. Unless 'raw' is set, the received headers are decoded
+// and validated as they are read, so the peers responding with invalid headers are blamed.
+// The first header of a range is validated against the given trusted one, if any.
+// Failed attempts are retried with exponential back-off if the exchange is configured to do so.
+func (ex *P2PExchange) perform(
+	ctx context.Context,
+	req *pb.ExtendedHeaderRequest,
+	trusted *ExtendedHeader,
+	fanOut, raw bool,
+) (*response, error) {
+	headersRequested.Add(ctx, int64(req.Amount))
+	injectTraceContext(ctx, req)
+	for attempt := 1; ; attempt++ {
+		resp, err := ex.attemptRequest(ctx, req, trusted, fanOut, raw)
+		if err == nil || attempt >= ex.maxAttempts || !isRetryable(ctx, err) {
+			logRequestErr(ctx, req, attempt, err)
+			return resp, err
+		}
+
+		delay := backoff(ex.baseDelay, attempt)
+		log.Debugw("p2p: retrying request", "attempt", attempt, "delay", delay, "err", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attemptRequest performs a single request attempt.
+// The attempt is aborted with ErrRequestTimeout if it does not complete within the configured timeout.
+func (ex *P2PExchange) attemptRequest(
+	ctx context.Context,
+	req *pb.ExtendedHeaderRequest,
+	trusted *ExtendedHeader,
+	fanOut, raw bool,
+) (*response, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, ex.requestTimeout)
+	defer cancel()
+
+	var (
+		resp *response
+		err  error
+	)
 	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	case <-reqCtx.Done():
+		err = reqCtx.Err()
 	case <-ex.connected:
+		peers := ex.selectPeers()
+		switch {
+		case len(peers) == 0:
+			err = ex.errNoPeers()
+		case fanOut:
+			resp, err = ex.requestAny(reqCtx, peers, req, trusted, raw)
+		default:
+			var (
+				to      peer.ID
+				observe func(error)
+			)
+			to, observe, err = ex.selectPeer(reqCtx, peers)
+			if err != nil {
+				break
+			}
+			resp, err = ex.doRequest(reqCtx, to, req, trusted, raw)
+			observe(err)
+		}
+	}
+	if err != nil && errors.Is(reqCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return nil, ErrRequestTimeout
+	}
+	return resp, err
+}
+
+// selectPeers returns the peers from the pool which are currently connected.
+// If there are none, the whole pool is returned, so the host attempts to dial them.
+// Peers with open circuit breakers are skipped.
+func (ex *P2PExchange) selectPeers() []peer.ID {
+	peers := ex.allPeers()
+	all := make([]peer.ID, 0, len(peers))
+	connected := make([]peer.ID, 0, len(peers))
+	for _, p := range peers {
+		if cb := ex.breaker(p.ID); cb != nil && !cb.Ready() {
+			continue
+		}
+		if ex.belowMinHeight(p.ID) {
+			continue
+		}
+
+		all = append(all, p.ID)
+		if ex.host.Network().Connectedness(p.ID) == network.Connected {
+			connected = append(connected, p.ID)
+		}
 	}
+	if len(connected) == 0 {
+		return all
+	}
+	return connected
+}
+
+// requestAny sends the given request to all the given peers concurrently and returns
+// the first successful response, cancelling all the others.
+func (ex *P2PExchange) requestAny(
+	ctx context.Context,
+	peers []peer.ID,
+	req *pb.ExtendedHeaderRequest,
+	trusted *ExtendedHeader,
+	raw bool,
+) (*response, error) {
+	if len(peers) == 1 {
+		return ex.doRequest(ctx, peers[0], req, trusted, raw)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		resp *response
+		err  error
+	}
+	results := make(chan result, len(peers))
+	for _, p := range peers {
+		go func(p peer.ID) {
+			resp, err := ex.doRequest(ctx, p, req, trusted, raw)
+			if err != nil {
+				log.Debugw("p2p: requesting peer", "peer", p.ShortString(), "err", err)
+			}
+			results <- result{resp: resp, err: err}
+		}(p)
+	}
+
+	var err error
+	for range peers {
+		res := <-results
+		if res.err == nil {
+			return res.resp, nil
+		}
+		err = res.err
+	}
+	return nil, err
+}
 
-	stream, err := ex.host.NewStream(ctx, ex.trustedPeer.ID, exchangeProtocolID)
+// doRequest sends the given request to the given peer and reads the response.
+// Unless 'raw' is set, the headers are decoded and validated as they are read, the first one
+// against the given trusted header, if any.
+func (ex *P2PExchange) doRequest(
+	ctx context.Context,
+	to peer.ID,
+	req *pb.ExtendedHeaderRequest,
+	trusted *ExtendedHeader,
+	raw bool,
+) (*response, error) {
+	resp := &response{raw: make([]*pb.ExtendedHeader, 0, req.Amount)}
+	handle := func(h *pb.ExtendedHeader) error {
+		resp.raw = append(resp.raw, h)
+		return nil
+	}
+	if !raw {
+		resp.headers = make([]*ExtendedHeader, 0, req.Amount)
+		handle = ex.decoding(ctx, req, trusted, func(h *pb.ExtendedHeader, header *ExtendedHeader) error {
+			resp.raw, resp.headers = append(resp.raw, h), append(resp.headers, header)
+			return nil
+		})
+	}
+
+	err := ex.streamRequest(ctx, to, req, handle)
 	if err != nil {
 		return nil, err
 	}
-	// send request
-	_, err = serde.Write(stream, req)
+	// ensure at least one header was retrieved
+	if len(resp.raw) == 0 {
+		return nil, ErrNotFound
+	}
+	return resp, nil
+}
+
+// decoding returns the handler of raw headers, which decodes and validates every header before
+// passing it to 'handle' along with the raw one.
+// Headers of a range request are validated against the previous header of the response, while the first one
+// is validated against the given trusted header, if any. Ranges requested by height must start at the origin
+// and have no gaps.
+func (ex *P2PExchange) decoding(
+	ctx context.Context,
+	req *pb.ExtendedHeaderRequest,
+	trusted *ExtendedHeader,
+	handle func(*pb.ExtendedHeader, *ExtendedHeader) error,
+) func(*pb.ExtendedHeader) error {
+	byHeight := len(req.Hashes) == 0 && len(req.Hash) == 0 && req.Origin != 0
+	next := req.Origin
+	return func(raw *pb.ExtendedHeader) error {
+		header, err := decodeAndValidate(ctx, ex.validator, raw, trusted)
+		if err != nil {
+			return err
+		}
+		if byHeight {
+			if uint64(header.Height) != next {
+				return fmt.Errorf("%w: expected header at height %d, got %d", ErrInvalidResponse, next, header.Height)
+			}
+			next++
+		}
+		// headers requested by hashes are not a contiguous range, unless starting at the hash
+		if len(req.Hashes) == 0 && (len(req.Hash) == 0 || req.Amount > 1) {
+			trusted = header
+		}
+		return handle(raw, header)
+	}
+}
+
+// streamRequest sends the given request to the given peer and passes every received raw header
+// to 'handle' as soon as it is read from the stream.
+// Reading stops on the first error returned by 'handle'.
+// The outcome is reported to the peer's CircuitBreaker, if any.
+func (ex *P2PExchange) streamRequest(
+	ctx context.Context,
+	to peer.ID,
+	req *pb.ExtendedHeaderRequest,
+	handle func(*pb.ExtendedHeader) error,
+) error {
+	cb := ex.breaker(to)
+	if cb == nil {
+		return ex.sendRequest(ctx, to, req, handle)
+	}
+	if !cb.Allow() {
+		return ErrCircuitOpen
+	}
+
+	err := ex.sendRequest(ctx, to, req, handle)
+	switch {
+	case err == nil, errors.Is(err, ErrNotFound):
+		cb.Success()
+	case ctx.Err() != nil:
+		// the peer is not to blame for the request being canceled
+		cb.Abort()
+	default:
+		cb.Failure()
+		if cb.State() == BreakerOpen {
+			log.Warnw("p2p: circuit breaker opened", "peer", to.ShortString(), "err", err)
+		}
+	}
+	return err
+}
+
+// sendRequest performs the request of streamRequest.
+// With stream reuse enabled, the request is sent over an idle stream to the peer, if any.
+// The idle stream may be closed by the peer in the meantime, so the request is sent over
+// a new stream if the idle one fails before any response.
+func (ex *P2PExchange) sendRequest(
+	ctx context.Context,
+	to peer.ID,
+	req *pb.ExtendedHeaderRequest,
+	handle func(*pb.ExtendedHeader) error,
+) error {
+	if ex.pool != nil {
+		if stream := ex.pool.get(to); stream != nil {
+			responded, err := ex.exchange(ctx, to, stream, req, handle)
+			if err == nil || responded || ctx.Err() != nil {
+				return err
+			}
+			log.Debugw("p2p: idle stream failed, opening new one", "peer", to.ShortString(), "err", err)
+		}
+	}
+
+	stream, err := newExchangeStream(ctx, ex.host, to, ex.protocols, ex.compression)
+	if err != nil {
+		return err
+	}
+	_, err = ex.exchange(ctx, to, stream, req, handle)
+	return err
+}
+
+// exchange sends the request over the given stream and reads the response, reporting whether
+// the peer responded at all. Once done, the stream is either returned to the pool or closed.
+func (ex *P2PExchange) exchange(
+	ctx context.Context,
+	to peer.ID,
+	stream *compressedStream,
+	req *pb.ExtendedHeaderRequest,
+	handle func(*pb.ExtendedHeader) error,
+) (bool, error) {
+	// not every transport supports deadlines, so the stream is also reset once the context is done
+	if deadline, ok := ctx.Deadline(); ok {
+		err := stream.SetDeadline(deadline)
+		if err != nil {
+			log.Debugw("p2p: setting stream deadline", "err", err)
+		}
+	}
+	if ex.expectedPeer != "" && stream.Conn().RemotePeer() != ex.expectedPeer {
+		log.Warnw("p2p: rejecting unexpected peer", "peer", stream.Conn().RemotePeer().ShortString(),
+			"expected", ex.expectedPeer.ShortString())
+		stream.Reset() //nolint:errcheck
+		return false, ErrUnexpectedPeer
+	}
+	baseline := isBaseline(stream)
+	if baseline && (len(req.Hashes) > 0 || (req.Hash != nil && req.Amount > 1)) {
+		stream.Reset() //nolint:errcheck
+		return false, ErrUnsupportedRequest
+	}
+	stop := resetOnDone(ctx, stream)
+	// send request, keeping the stream open for the next one if it is to be reused
+	written, err := serde.Write(stream, req)
+	requestsTotal.Add(ctx, 1)
+	requestBytesOut.Record(ctx, int64(written))
+	if err == nil {
+		if ex.pool != nil && !baseline {
+			err = stream.Flush()
+		} else {
+			err = stream.CloseWrite()
+		}
+	}
 	if err != nil {
+		stop()
 		stream.Reset() //nolint:errcheck
-		return nil, err
+		return false, err
+	}
+
+	var (
+		responded bool
+		read      int
+	)
+	if baseline {
+		responded, read, err = readBaselineResponse(stream, req, handle)
+	} else {
+		responded, read, err = ex.readResponse(stream, req, handle)
+	}
+	if responded {
+		responseBytesIn.Record(ctx, int64(read))
 	}
-	// read responses
-	headers := make([]*ExtendedHeader, req.Amount)
+	if !stop() || err != nil {
+		stream.Reset() //nolint:errcheck
+		return responded, err
+	}
+	// the baseline protocol serves a single request per stream
+	if ex.pool != nil && !baseline {
+		err = stream.SetDeadline(time.Time{})
+		if err != nil {
+			log.Debugw("p2p: clearing stream deadline", "err", err)
+		}
+		ex.pool.put(to, stream)
+		return true, nil
+	}
+	return true, stream.Close()
+}
+
+// readResponse reads the response to the given request from the stream.
+// Along with whether the peer responded, it reports the size of the response read.
+func (ex *P2PExchange) readResponse(
+	stream *compressedStream,
+	req *pb.ExtendedHeaderRequest,
+	handle func(*pb.ExtendedHeader) error,
+) (bool, int, error) {
+	var read int
+	// read responses until the requested amount or the end of a truncated response
 	for i := 0; i < int(req.Amount); i++ {
-		resp := new(pb.ExtendedHeader)
-		_, err := serde.Read(stream, resp)
+		resp := new(pb.ExtendedHeaderResponse)
+		n, err := serde.Read(stream, resp)
+		read += n
 		if err != nil {
-			stream.Reset() //nolint:errcheck
-			return nil, err
+			return i > 0, read, err
+		}
+		if err = statusToErr(resp.Code); err != nil {
+			return true, read, err
+		}
+		if resp.Header == nil {
+			return true, read, fmt.Errorf("%w: no header", ErrInvalidResponse)
 		}
 
-		header, err := ProtoToExtendedHeader(resp)
+		err = handle(resp.Header)
 		if err != nil {
-			stream.Reset() //nolint:errcheck
-			return nil, err
+			return true, read, err
+		}
+		if resp.Continuation != 0 {
+			break
+		}
+	}
+	return true, read, nil
+}
+
+// readBaselineResponse reads the response to the given request from the stream speaking the baseline
+// protocol. Its responses are bare headers without status codes, and the peer closes the stream
+// once done, so the response may end before the requested amount.
+func readBaselineResponse(
+	stream *compressedStream,
+	req *pb.ExtendedHeaderRequest,
+	handle func(*pb.ExtendedHeader) error,
+) (bool, int, error) {
+	var read int
+	for i := 0; i < int(req.Amount); i++ {
+		resp := new(pb.ExtendedHeader)
+		n, err := serde.Read(stream, resp)
+		read += n
+		if err != nil {
+			if i > 0 && errors.Is(err, io.EOF) {
+				break
+			}
+			return i > 0, read, err
 		}
-		// sanity check the header
-		err = header.ValidateBasic()
+
+		err = handle(resp)
 		if err != nil {
-			stream.Reset() //nolint:errcheck
-			return nil, err
+			return true, read, err
 		}
+	}
+	return true, read, nil
+}
 
-		headers[i] = header
+// resetOnDone resets the stream once the context is done, until the returned function is called.
+// The function reports whether the stream is still usable.
+func resetOnDone(ctx context.Context, stream *compressedStream) func() bool {
+	done, usable := make(chan struct{}), make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// the raw stream is reset, as the compressed one must not be released while in use
+			stream.Stream.Reset() //nolint:errcheck
+			usable <- false
+		case <-done:
+			usable <- true
+		}
+	}()
+	return func() bool {
+		close(done)
+		return <-usable
 	}
-	// ensure at least one header was retrieved
-	if len(headers) == 0 {
-		return nil, ErrNotFound
+}
+
+// logRequestErr logs the error a request finally failed with at the level matching its severity.
+// Requests aborted by the caller are not logged.
+func logRequestErr(ctx context.Context, req *pb.ExtendedHeaderRequest, attempts int, err error) {
+	switch {
+	case err == nil, ctx.Err() != nil:
+	case errors.Is(err, ErrNotFound):
+		log.Debugw("p2p: requested headers not found", "origin", req.Origin, "amount", req.Amount)
+	default:
+		log.Errorw("p2p: request failed", "origin", req.Origin, "amount", req.Amount,
+			"attempts", attempts, "err", err)
 	}
-	return headers, stream.Close()
 }
 
-func (ex *P2PExchange) Connected(_ network.Network, conn network.Conn) {
-	select {
-	// don't connect if already connected
-	case <-ex.connected:
-		return
+// statusToErr converts the status code of a response into the corresponding error.
+func statusToErr(code pb.StatusCode) error {
+	switch code {
+	case pb.StatusCode_OK:
+		return nil
+	case pb.StatusCode_NOT_FOUND:
+		return ErrNotFound
+	case pb.StatusCode_TOO_MANY_REQUESTS:
+		return ErrTooManyRequests
 	default:
+		return fmt.Errorf("%w: unknown status code %d", ErrInvalidResponse, code)
 	}
+}
+
+// isRetryable reports whether the given request error is worth retrying.
+// Only network-level failures and rejections are retried, while invalid responses, missing headers
+// and caller's cancellation are not.
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return !errors.Is(err, ErrInvalidResponse) && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrUnexpectedPeer)
+}
+
+// backoff calculates the delay before the next attempt, doubling 'base' for every failed attempt
+// up to maxBackoff and adding up to 50% of random jitter.
+func backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	delay := base << (attempt - 1)
+	// the shift overflows on long retries
+	if delay > maxBackoff || delay < base {
+		delay = maxBackoff
+	}
+	return delay + time.Duration(mrand.Int63n(int64(delay)/2+1)) //nolint:gosec
+}
+
+func (ex *P2PExchange) Connected(_ network.Network, conn network.Conn) {
+	for _, p := range ex.allPeers() {
+		if conn.RemotePeer() == p.ID {
+			ex.markConnected()
+			return
+		}
+	}
+}
 
+// markConnected signals that the exchange is connected to at least one peer from the pool.
+func (ex *P2PExchange) markConnected() {
 	ex.lk.Lock()
 	defer ex.lk.Unlock()
 
-	if conn.RemotePeer() == ex.trustedPeer.ID {
+	select {
+	// don't close if already connected
+	case <-ex.connected:
+	default:
 		close(ex.connected)
 	}
 }
+
+// allPeers returns a copy of the whole pool of peers.
+func (ex *P2PExchange) allPeers() []peer.AddrInfo {
+	ex.peersLk.RLock()
+	defer ex.peersLk.RUnlock()
+	return append([]peer.AddrInfo(nil), ex.peers...)
+}
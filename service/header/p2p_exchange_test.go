@@ -3,14 +3,34 @@ package header
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	mockrouting "github.com/ipfs/go-ipfs-routing/mock"
+	"github.com/libp2p/go-libp2p"
 	libhost "github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/libp2p/go-libp2p-core/routing"
+	tnet "github.com/libp2p/go-libp2p-testing/net"
 	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 
 	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	tmrand "github.com/tendermint/tendermint/libs/rand"
 
 	header_pb "github.com/celestiaorg/celestia-node/service/header/pb"
 	"github.com/celestiaorg/go-libp2p-messenger/serde"
@@ -26,8 +46,33 @@ func TestP2PExchange_RequestHead(t *testing.T) {
 	header, err := exchg.RequestHead(context.Background())
 	require.NoError(t, err)
 
-	assert.Equal(t, store.headers[store.headHeight].Height, header.Height)
-	assert.Equal(t, store.headers[store.headHeight].Hash(), header.Hash())
+	assert.Equal(t, store.head.Height, header.Height)
+	assert.Equal(t, store.head.Hash(), header.Hash())
+}
+
+func TestP2PExchange_Probe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	host, peer := createMocknet(ctx, t)
+	exchg, _ := createP2PExAndServer(t, host, peer)
+	err := exchg.Probe(ctx)
+	assert.NoError(t, err)
+
+	// the peer without any headers is not healthy
+	host, peer = createMocknet(ctx, t)
+	serv := NewP2PExchangeServer(peer, NewMemStore())
+	require.NoError(t, serv.Start(ctx))
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+	unhealthy := NewP2PExchange(host, libhost.InfoFromHost(peer), nil)
+	require.NoError(t, unhealthy.Start(ctx))
+	t.Cleanup(func() {
+		unhealthy.Stop(context.Background()) //nolint:errcheck
+	})
+	err = unhealthy.Probe(ctx)
+	assert.Error(t, err)
 }
 
 func TestP2PExchange_RequestHeader(t *testing.T) {
@@ -39,8 +84,21 @@ func TestP2PExchange_RequestHeader(t *testing.T) {
 	// perform expected request
 	header, err := exchg.RequestHeader(context.Background(), 5)
 	require.NoError(t, err)
-	assert.Equal(t, store.headers[5].Height, header.Height)
-	assert.Equal(t, store.headers[5].Hash(), header.Hash())
+	assert.Equal(t, store.byHeight[5].Height, header.Height)
+	assert.Equal(t, store.byHeight[5].Hash(), header.Hash())
+}
+
+// TestP2PExchange_RequestHeader_NotFound tests that requesting a height the server does not have
+// fails with ErrNotFound instead of returning no header.
+func TestP2PExchange_RequestHeader_NotFound(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	exchg, _ := createP2PExAndServer(t, host, peer)
+	header, err := exchg.RequestHeader(ctx, 999)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, header)
 }
 
 func TestP2PExchange_RequestHeaders(t *testing.T) {
@@ -53,8 +111,8 @@ func TestP2PExchange_RequestHeaders(t *testing.T) {
 	gotHeaders, err := exchg.RequestHeaders(context.Background(), 1, 5)
 	require.NoError(t, err)
 	for _, got := range gotHeaders {
-		assert.Equal(t, store.headers[got.Height].Height, got.Height)
-		assert.Equal(t, store.headers[got.Height].Hash(), got.Hash())
+		assert.Equal(t, store.byHeight[uint64(got.Height)].Height, got.Height)
+		assert.Equal(t, store.byHeight[uint64(got.Height)].Hash(), got.Hash())
 	}
 }
 
@@ -80,117 +138,1831 @@ func TestP2PExchange_RequestByHash(t *testing.T) {
 	// start a new stream via Peer to see if Host can handle inbound requests
 	stream, err := peer.NewStream(context.Background(), libhost.InfoFromHost(host).ID, exchangeProtocolID)
 	require.NoError(t, err)
+	// announce the stream is not compressed
+	_, err = stream.Write([]byte{byte(NoCompression)})
+	require.NoError(t, err)
 	// create request for a header at a random height
-	reqHeight := store.headHeight - 2
+	reqHeight := uint64(store.head.Height) - 2
+	// the hash takes priority over the origin
 	req := &header_pb.ExtendedHeaderRequest{
-		Hash:   store.headers[reqHeight].Hash(),
+		Origin: 1,
+		Hash:   store.byHeight[reqHeight].Hash(),
 		Amount: 1,
 	}
 	// send request
 	_, err = serde.Write(stream, req)
 	require.NoError(t, err)
 	// read resp
-	resp := new(header_pb.ExtendedHeader)
+	resp := new(header_pb.ExtendedHeaderResponse)
 	_, err = serde.Read(stream, resp)
 	require.NoError(t, err)
+	require.Equal(t, header_pb.StatusCode_OK, resp.Code)
 	// compare
-	eh, err := ProtoToExtendedHeader(resp)
+	eh, err := ProtoToExtendedHeader(resp.Header)
+	require.NoError(t, err)
+
+	assert.Equal(t, store.byHeight[reqHeight].Height, eh.Height)
+	assert.Equal(t, store.byHeight[reqHeight].Hash(), eh.Hash())
+
+	// the range of headers starting at a middle hash is served over the same stream
+	req = &header_pb.ExtendedHeaderRequest{
+		Hash:   store.byHeight[2].Hash(),
+		Amount: 3,
+	}
+	_, err = serde.Write(stream, req)
 	require.NoError(t, err)
+	for height := uint64(2); height < 5; height++ {
+		resp := new(header_pb.ExtendedHeaderResponse)
+		_, err = serde.Read(stream, resp)
+		require.NoError(t, err)
+		require.Equal(t, header_pb.StatusCode_OK, resp.Code)
+		eh, err := ProtoToExtendedHeader(resp.Header)
+		require.NoError(t, err)
+		assert.Equal(t, store.byHeight[height].Hash(), eh.Hash())
+	}
 
-	assert.Equal(t, store.headers[reqHeight].Height, eh.Height)
-	assert.Equal(t, store.headers[reqHeight].Hash(), eh.Hash())
+	exchg := NewP2PExchange(peer, libhost.InfoFromHost(host), nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+	headers, err := exchg.RequestHeaderSince(ctx, store.byHeight[2].Hash(), 3)
+	require.NoError(t, err)
+	require.Len(t, headers, 3)
+	for i, h := range headers {
+		assert.Equal(t, store.byHeight[uint64(i+2)].Hash(), h.Hash())
+	}
 }
 
-func createMocknet(ctx context.Context, t *testing.T) (libhost.Host, libhost.Host) {
-	net, err := mocknet.FullMeshConnected(ctx, 2)
+// TestP2PExchange_RequestHeaderSince_Truncated tests that the range starting at a hash is completed
+// by height once the server truncates the response.
+func TestP2PExchange_RequestHeaderSince_Truncated(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 10)
+	serv := NewP2PExchangeServer(peer, store, WithMaxResponseSize(2))
+	err := serv.Start(ctx)
 	require.NoError(t, err)
-	// get host and peer
-	return net.Hosts()[0], net.Hosts()[1]
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	headers, err := exchg.RequestHeaderSince(ctx, store.byHeight[3].Hash(), 5)
+	require.NoError(t, err)
+	require.Len(t, headers, 5)
+	for i, h := range headers {
+		assert.Equal(t, store.byHeight[uint64(i+3)].Hash(), h.Hash())
+	}
+
+	_, err = exchg.RequestHeaderSince(ctx, RandExtendedHeader(t).Hash(), 5)
+	assert.ErrorIs(t, err, ErrNotFound)
 }
 
-// createP2PExAndServer creates a P2PExchange with 5 headers already in its store.
-func createP2PExAndServer(t *testing.T, host, peer libhost.Host) (Exchange, *mockStore) {
-	store := createStore(t, 5)
-	serverSideEx := NewP2PExchangeServer(peer, store)
-	err := serverSideEx.Start(context.Background())
+// TestP2PExchange_RequestHeadersByHashes tests that the P2PExchange returns headers
+// in the order of requested hashes.
+func TestP2PExchange_RequestHeadersByHashes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	exchg, store := createP2PExAndServer(t, host, peer)
+
+	heights := []int64{4, 1, 5, 2}
+	hashes := make([]tmbytes.HexBytes, len(heights))
+	for i, height := range heights {
+		hashes[i] = store.byHeight[uint64(height)].Hash()
+	}
+
+	headers, err := exchg.RequestHeadersByHashes(ctx, hashes)
 	require.NoError(t, err)
+	require.Len(t, headers, len(heights))
+	for i, height := range heights {
+		assert.Equal(t, height, headers[i].Height)
+		assert.Equal(t, hashes[i], headers[i].Hash())
+	}
+}
 
-	// create new exchange
-	clientSideEx := NewP2PExchange(host, libhost.InfoFromHost(peer), nil) // we don't need the store on the requesting side
-	err = clientSideEx.Start(context.Background())
+// TestP2PExchange_RequestHeadersByHashes_Truncated tests that the server caps the amount of headers
+// requested by hashes and the P2PExchange requests the remaining ones.
+func TestP2PExchange_RequestHeadersByHashes_Truncated(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 10)
+	serv := NewP2PExchangeServer(peer, store, WithMaxResponseSize(2))
+	err := serv.Start(ctx)
 	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	var requests int32
+	peer.SetStreamHandler(exchangeProtocolID, func(stream network.Stream) {
+		atomic.AddInt32(&requests, 1)
+		serv.requestHandler(stream)
+	})
 
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
 	t.Cleanup(func() {
-		serverSideEx.Stop(context.Background()) //nolint:errcheck
-		clientSideEx.Stop(context.Background()) //nolint:errcheck
+		exchg.Stop(context.Background()) //nolint:errcheck
 	})
 
-	return clientSideEx, store
+	heights := []uint64{7, 1, 5, 2, 9}
+	hashes := make([]tmbytes.HexBytes, len(heights))
+	for i, height := range heights {
+		hashes[i] = store.byHeight[height].Hash()
+	}
+
+	headers, err := exchg.RequestHeadersByHashes(ctx, hashes)
+	require.NoError(t, err)
+	require.Len(t, headers, len(heights))
+	for i, h := range headers {
+		assert.Equal(t, hashes[i], h.Hash())
+	}
+	assert.EqualValues(t, 3, atomic.LoadInt32(&requests))
+}
+
+// TestP2PExchange_RequestHeadersByHashes_PartialFailure tests that the whole request fails
+// if any of the requested hashes is unknown to the peer.
+func TestP2PExchange_RequestHeadersByHashes_PartialFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	exchg, store := createP2PExAndServer(t, host, peer)
+
+	hashes := []tmbytes.HexBytes{store.byHeight[1].Hash(), tmrand.Bytes(32), store.byHeight[2].Hash()}
+	headers, err := exchg.RequestHeadersByHashes(ctx, hashes)
+	assert.Error(t, err)
+	assert.Nil(t, headers)
+}
+
+// TestP2PExchange_RequestHeaders_InvalidHeader tests that the P2PExchange validates every received header.
+func TestP2PExchange_RequestHeaders_InvalidHeader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	exchg, store := createP2PExAndServer(t, host, peer)
+	// the header still passes the basic validation, but does not commit to its DAH
+	store.byHeight[3].DataHash = tmrand.Bytes(32)
+
+	headers, err := exchg.RequestHeaders(ctx, 1, 5)
+	assert.ErrorIs(t, err, ErrInvalidResponse)
+	assert.Nil(t, headers)
+
+	_, err = exchg.RequestHeader(ctx, 3)
+	assert.ErrorIs(t, err, ErrInvalidResponse)
 }
 
-type mockStore struct {
-	headers    map[int64]*ExtendedHeader
-	headHeight int64
+// TestP2PExchange_WithValidator tests that the P2PExchange applies the given Validator
+// to the headers of a response in order.
+func TestP2PExchange_WithValidator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(peer, store)
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+
+	var trusted []*ExtendedHeader
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithValidator(ChainValidators(
+		DefaultValidator,
+		ValidatorFunc(func(_ context.Context, _, t *ExtendedHeader) error {
+			trusted = append(trusted, t)
+			return nil
+		}),
+	)))
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background())  //nolint:errcheck
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	headers, err := exchg.RequestHeaders(ctx, 1, 3)
+	require.NoError(t, err)
+	require.Len(t, trusted, 3)
+	assert.Nil(t, trusted[0])
+	assert.Equal(t, headers[0].Hash(), trusted[1].Hash())
+	assert.Equal(t, headers[1].Hash(), trusted[2].Hash())
 }
 
-// createStore creates a mock store and adds several random
-// headers
-func createStore(t *testing.T, numHeaders int) *mockStore {
-	store := &mockStore{
-		headers:    make(map[int64]*ExtendedHeader),
-		headHeight: 0,
+// TestP2PExchangeServer_Scoring tests that the P2PExchangeServer rejects requests from a flooding peer,
+// while still serving the others.
+func TestP2PExchangeServer_Scoring(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	net, err := mocknet.FullMeshConnected(ctx, 3)
+	require.NoError(t, err)
+	server, flooder, honest := net.Hosts()[0], net.Hosts()[1], net.Hosts()[2]
+
+	const threshold = 5
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(server, store, WithScoreThreshold(threshold), WithScoreWindow(time.Minute))
+	err = serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	floodEx := NewP2PExchange(flooder, libhost.InfoFromHost(server), nil)
+	err = floodEx.Start(ctx)
+	require.NoError(t, err)
+	honestEx := NewP2PExchange(honest, libhost.InfoFromHost(server), nil)
+	err = honestEx.Start(ctx)
+	require.NoError(t, err)
+
+	for i := 0; i < threshold; i++ {
+		_, err = floodEx.RequestHead(ctx)
+		require.NoError(t, err)
 	}
+	_, err = floodEx.RequestHead(ctx)
+	assert.ErrorIs(t, err, ErrTooManyRequests)
+	assert.Greater(t, serv.Score(flooder.ID()), float64(threshold))
 
-	suite := NewTestSuite(t, numHeaders)
+	head, err := honestEx.RequestHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, store.head.Hash(), head.Hash())
+	assert.Less(t, serv.Score(honest.ID()), float64(threshold))
+}
 
-	for i := 0; i < numHeaders; i++ {
-		header := suite.GenExtendedHeader()
-		store.headers[header.Height] = header
+// TestP2PExchangeServer_RateLimiter tests that the P2PExchangeServer rejects requests from a peer
+// exceeding its quota until the quota is refilled, while still serving the others.
+func TestP2PExchangeServer_RateLimiter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	net, err := mocknet.FullMeshConnected(ctx, 3)
+	require.NoError(t, err)
+	server, flooder, honest := net.Hosts()[0], net.Hosts()[1], net.Hosts()[2]
+
+	const burst, refill = 5, time.Millisecond * 500
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(server, store, WithRateLimiter(NewRateLimiter(burst, refill)))
+	err = serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	floodEx := NewP2PExchange(flooder, libhost.InfoFromHost(server), nil)
+	err = floodEx.Start(ctx)
+	require.NoError(t, err)
+	honestEx := NewP2PExchange(honest, libhost.InfoFromHost(server), nil)
+	err = honestEx.Start(ctx)
+	require.NoError(t, err)
 
-		if header.Height > store.headHeight {
-			store.headHeight = header.Height
+	start := time.Now()
+	var served, rejected int
+	for i := 0; i < burst*3; i++ {
+		_, err = floodEx.RequestHead(ctx)
+		switch {
+		case err == nil:
+			served++
+		case errors.Is(err, ErrTooManyRequests):
+			rejected++
+		default:
+			t.Fatal(err)
 		}
 	}
-	return store
-}
+	// the loop must be faster than the refill for the assertion to be exact
+	if time.Since(start) < refill {
+		assert.Equal(t, burst, served)
+		assert.Equal(t, burst*2, rejected)
+	} else {
+		assert.Less(t, served, burst*3)
+	}
+
+	head, err := honestEx.RequestHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, store.head.Hash(), head.Hash())
 
-func (m *mockStore) Head(context.Context) (*ExtendedHeader, error) {
-	return m.headers[m.headHeight], nil
+	// the flooder is served again once the quota is refilled
+	time.Sleep(refill)
+	_, err = floodEx.RequestHead(ctx)
+	assert.NoError(t, err)
 }
 
-func (m *mockStore) Get(ctx context.Context, hash tmbytes.HexBytes) (*ExtendedHeader, error) {
-	for _, header := range m.headers {
-		if bytes.Equal(header.Hash(), hash) {
-			return header, nil
+// TestP2PExchangeServer_MaxConcurrentStreams tests that requests above the limit of concurrently
+// served ones are rejected, while the served ones succeed.
+func TestP2PExchangeServer_MaxConcurrentStreams(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	const limit, requests = 2, 5
+	store := &blockingStore{
+		Store:   createStore(t, 5),
+		release: make(chan struct{}),
+	}
+	serv := NewP2PExchangeServer(host, store, WithMaxConcurrentStreams(limit))
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	exchg := NewP2PExchange(peer, libhost.InfoFromHost(host), nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+
+	type result struct {
+		headers []*ExtendedHeader
+		err     error
+	}
+	results := make(chan result, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			headers, err := exchg.RequestHeaders(ctx, 1, 5)
+			results <- result{headers, err}
+		}()
+	}
+
+	// the requests above the limit are rejected while the served ones are blocked
+	for i := 0; i < requests-limit; i++ {
+		select {
+		case res := <-results:
+			assert.ErrorIs(t, res.err, ErrTooManyRequests)
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+	}
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&store.entered) == limit
+	}, time.Second, time.Millisecond*10)
+
+	close(store.release)
+	for i := 0; i < limit; i++ {
+		select {
+		case res := <-results:
+			require.NoError(t, res.err)
+			require.Len(t, res.headers, 5)
+			for j, h := range res.headers {
+				assert.Equal(t, store.Store.(*memStore).byHeight[uint64(j+1)].Hash(), h.Hash())
+			}
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
 		}
 	}
-	return nil, nil
+
+	// slots are freed once the requests are served
+	_, err = exchg.RequestHeaders(ctx, 1, 5)
+	assert.NoError(t, err)
 }
 
-func (m *mockStore) GetByHeight(ctx context.Context, height uint64) (*ExtendedHeader, error) {
-	return m.headers[int64(height)], nil
+func TestP2PExchangeServer_GracefulShutdown(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := &blockingStore{
+		Store:   createStore(t, 5),
+		release: make(chan struct{}),
+	}
+	serv := NewP2PExchangeServer(host, store)
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+
+	exchg := NewP2PExchange(peer, libhost.InfoFromHost(host), nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	type result struct {
+		headers []*ExtendedHeader
+		err     error
+	}
+	requested := make(chan result, 1)
+	go func() {
+		headers, err := exchg.RequestHeaders(ctx, 1, 5)
+		requested <- result{headers, err}
+	}()
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&store.entered) == 1
+	}, time.Second, time.Millisecond*10)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- serv.GracefulShutdown(ctx)
+	}()
+	// the shutdown waits for the request in-flight
+	select {
+	case err = <-shutdown:
+		t.Fatalf("shutdown before the request is served: %v", err)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	close(store.release)
+	select {
+	case res := <-requested:
+		require.NoError(t, res.err)
+		assert.Len(t, res.headers, 5)
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+	select {
+	case err = <-shutdown:
+		require.NoError(t, err)
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	// no more requests are accepted
+	_, err = exchg.RequestHeaders(ctx, 1, 5)
+	assert.Error(t, err)
 }
 
-func (m *mockStore) GetRangeByHeight(ctx context.Context, from, to uint64) ([]*ExtendedHeader, error) {
-	headers := make([]*ExtendedHeader, to-from)
-	for i := range headers {
-		headers[i] = m.headers[int64(from)]
-		from++
+func TestP2PExchangeServer_GracefulShutdown_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := &blockingStore{
+		Store:   createStore(t, 5),
+		release: make(chan struct{}),
+	}
+	serv := NewP2PExchangeServer(host, store)
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+
+	exchg := NewP2PExchange(peer, libhost.InfoFromHost(host), nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	requested := make(chan error, 1)
+	go func() {
+		_, err := exchg.RequestHeaders(ctx, 1, 5)
+		requested <- err
+	}()
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&store.entered) == 1
+	}, time.Second, time.Millisecond*10)
+
+	// the request never finishes, so it is aborted once the deadline is hit
+	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer shutdownCancel()
+	err = serv.GracefulShutdown(shutdownCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	select {
+	case err = <-requested:
+		assert.Error(t, err)
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
 	}
-	return headers, nil
 }
 
-func (m *mockStore) Has(context.Context, tmbytes.HexBytes) (bool, error) {
-	return false, nil
+func TestP2PExchangeServer_ActivePeers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	net, err := mocknet.FullMeshConnected(ctx, 3)
+	require.NoError(t, err)
+	host, clients := net.Hosts()[0], net.Hosts()[1:]
+
+	store := &blockingStore{
+		Store:   createStore(t, 5),
+		release: make(chan struct{}),
+	}
+	serv := NewP2PExchangeServer(host, store)
+	err = serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+	assert.Empty(t, serv.ActivePeers())
+
+	errs := make(chan error, len(clients))
+	for _, client := range clients {
+		exchg := NewP2PExchange(client, libhost.InfoFromHost(host), nil)
+		err = exchg.Start(ctx)
+		require.NoError(t, err)
+
+		go func() {
+			_, err := exchg.RequestHeaders(ctx, 1, 5)
+			errs <- err
+		}()
+	}
+
+	// both streams are open while the requests are blocked
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&store.entered) == int32(len(clients))
+	}, time.Second, time.Millisecond*10)
+	assert.ElementsMatch(t, []peer.ID{clients[0].ID(), clients[1].ID()}, serv.ActivePeers())
+	assert.Equal(t, len(clients), serv.Metrics().ActiveStreams)
+
+	close(store.release)
+	for range clients {
+		require.NoError(t, <-errs)
+	}
+	assert.Eventually(t, func() bool {
+		return len(serv.ActivePeers()) == 0 && serv.Metrics().ActiveStreams == 0
+	}, time.Second, time.Millisecond*10)
 }
 
-func (m *mockStore) Append(ctx context.Context, headers ...*ExtendedHeader) error {
-	for _, header := range headers {
-		m.headers[header.Height] = header
-		// set head
-		if header.Height > m.headHeight {
-			m.headHeight = header.Height
+// TestP2PExchangeServer_Metrics tests that the server accounts for every request it serves exactly.
+func TestP2PExchangeServer_Metrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(peer, store)
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background())  //nolint:errcheck
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+	assert.Equal(t, ExchangeServerMetrics{}, serv.Metrics())
+
+	// every response is accounted with its size before compression
+	var sent bytes.Buffer
+	writeResponse := func(h *ExtendedHeader) {
+		resp := &header_pb.ExtendedHeaderResponse{Code: header_pb.StatusCode_OK}
+		if h == nil {
+			resp.Code = header_pb.StatusCode_NOT_FOUND
+		} else {
+			resp.Header, err = ExtendedHeaderToProto(h)
+			require.NoError(t, err)
 		}
+		_, err = serde.Write(&sent, resp)
+		require.NoError(t, err)
+	}
+
+	for height := uint64(1); height <= 3; height++ {
+		_, err = exchg.RequestHeader(ctx, height)
+		require.NoError(t, err)
+		writeResponse(store.byHeight[height])
+	}
+	_, err = exchg.RequestHeaders(ctx, 1, 5)
+	require.NoError(t, err)
+	for height := uint64(1); height <= 5; height++ {
+		writeResponse(store.byHeight[height])
+	}
+	for i := 0; i < 2; i++ {
+		_, err = exchg.RequestHeader(ctx, 999)
+		require.ErrorIs(t, err, ErrNotFound)
+		writeResponse(nil)
+	}
+
+	// streams are released after the responses are read, so wait until they are closed
+	require.Eventually(t, func() bool {
+		return serv.Metrics().ActiveStreams == 0
+	}, time.Second, time.Millisecond*10)
+	metrics := serv.Metrics()
+	assert.EqualValues(t, 4, metrics.RequestsHandled)
+	assert.EqualValues(t, 2, metrics.RequestsFailed)
+	assert.EqualValues(t, sent.Len(), metrics.BytesSent)
+	assert.Positive(t, int64(metrics.AverageResponseLatency))
+}
+
+// TestP2PExchange_ExpectedPeerID tests that P2PExchange requests only the expected peer.
+func TestP2PExchange_ExpectedPeerID(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	net, err := mocknet.FullMeshConnected(ctx, 3)
+	require.NoError(t, err)
+	client, server, expected := net.Hosts()[0], net.Hosts()[1], net.Hosts()[2]
+
+	serv := NewP2PExchangeServer(server, createStore(t, 5))
+	err = serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	// the peer listening at the configured address is not the expected one
+	unexpected := NewP2PExchange(client, libhost.InfoFromHost(server), nil,
+		WithExpectedPeerID(expected.ID()), WithRetry(3, time.Millisecond))
+	err = unexpected.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		unexpected.Stop(context.Background()) //nolint:errcheck
+	})
+	_, err = unexpected.RequestHeader(ctx, 1)
+	assert.ErrorIs(t, err, ErrUnexpectedPeer)
+	// nothing is sent to the peer
+	assert.Zero(t, serv.Metrics().RequestsHandled+serv.Metrics().RequestsFailed)
+
+	exchg := NewP2PExchange(client, libhost.InfoFromHost(server), nil, WithExpectedPeerID(server.ID()))
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+	h, err := exchg.RequestHeader(ctx, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, h.Height)
+}
+
+// TestP2PExchange_ConcurrentRequests tests that the server correctly serves many streams
+// opened by the same peer at once. It is meant to be run with the race detector as well.
+func TestP2PExchange_ConcurrentRequests(t *testing.T) {
+	const streams = 100
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(host, store, WithScoreThreshold(math.MaxFloat64))
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	request := func(height uint64) error {
+		stream, err := peer.NewStream(ctx, host.ID(), exchangeProtocolID)
+		if err != nil {
+			return err
+		}
+		defer stream.Close()
+
+		cs, err := openStream(stream, NoCompression)
+		if err != nil {
+			return err
+		}
+		_, err = serde.Write(cs, &header_pb.ExtendedHeaderRequest{Origin: height, Amount: 1})
+		if err != nil {
+			return err
+		}
+		resp := new(header_pb.ExtendedHeaderResponse)
+		_, err = serde.Read(cs, resp)
+		if err != nil {
+			return err
+		}
+		if resp.Code != header_pb.StatusCode_OK {
+			return fmt.Errorf("unexpected status %s", resp.Code)
+		}
+		eh, err := ProtoToExtendedHeader(resp.Header)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(store.byHeight[height].Hash(), eh.Hash()) {
+			return fmt.Errorf("wrong header at height %d", height)
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, streams)
+	for i := 0; i < streams; i++ {
+		wg.Add(1)
+		go func(height uint64) {
+			defer wg.Done()
+			errs <- request(height)
+		}(uint64(i%5) + 1)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+// TestP2PExchangeServer_Tracing tests that served requests are traced as part of the requesting side's trace.
+func TestP2PExchangeServer_Tracing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(peer, store, WithTracerProvider(tp))
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	reqCtx, parent := tp.Tracer("test").Start(ctx, "request headers")
+	_, err = exchg.RequestHeaders(reqCtx, 1, 3)
+	require.NoError(t, err)
+	parent.End()
+	// requests without trace context start a new trace
+	_, err = exchg.RequestByHash(ctx, store.byHeight[4].Hash())
+	require.NoError(t, err)
+
+	var served []*sdktrace.SpanSnapshot
+	require.Eventually(t, func() bool {
+		served = served[:0]
+		for _, span := range exporter.GetSpans() {
+			if span.SpanKind == trace.SpanKindServer {
+				served = append(served, span)
+			}
+		}
+		return len(served) == 2
+	}, time.Second, time.Millisecond*10)
+
+	byRange, byHash := served[0], served[1]
+	if !byRange.Parent.IsValid() {
+		byRange, byHash = byHash, byRange
+	}
+	assert.Equal(t, parent.SpanContext().TraceID(), byRange.SpanContext.TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), byRange.Parent.SpanID())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("peer", host.ID().String()),
+		attribute.Int64("origin", 1),
+		attribute.Int64("amount", 3),
+		attribute.Int("responses", 3),
+	}, byRange.Attributes)
+
+	assert.False(t, byHash.Parent.IsValid())
+	assert.NotEqual(t, parent.SpanContext().TraceID(), byHash.SpanContext.TraceID())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("peer", host.ID().String()),
+		attribute.String("hash", store.byHeight[4].Hash().String()),
+		attribute.Int("responses", 1),
+	}, byHash.Attributes)
+}
+
+// TestP2PExchange_RequestHeadWithVerification tests that the head is returned only if enough peers agree on it.
+func TestP2PExchange_RequestHeadWithVerification(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	net, err := mocknet.FullMeshConnected(ctx, 5)
+	require.NoError(t, err)
+	client, servers := net.Hosts()[0], net.Hosts()[1:]
+
+	honest, liar := createStore(t, 5), createStore(t, 5)
+	infos := make([]*peer.AddrInfo, len(servers))
+	for i, server := range servers {
+		store := honest
+		if i == len(servers)-1 {
+			store = liar
+		}
+		serv := NewP2PExchangeServer(server, store)
+		err = serv.Start(ctx)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			serv.Stop(context.Background()) //nolint:errcheck
+		})
+		infos[i] = libhost.InfoFromHost(server)
+	}
+
+	newExchange := func(peers ...*peer.AddrInfo) *P2PExchange {
+		addrs := make([]peer.AddrInfo, len(peers))
+		for i, p := range peers {
+			addrs[i] = *p
+		}
+		ex := NewP2PExchange(client, nil, nil, WithPeers(addrs))
+		err := ex.Start(ctx)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			ex.Stop(context.Background()) //nolint:errcheck
+		})
+		return ex
+	}
+
+	// the majority wins over the liar
+	head, err := newExchange(infos...).RequestHeadWithVerification(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, honest.head.Hash(), head.Hash())
+
+	// the liar is as trusted as the honest peer
+	_, err = newExchange(infos[0], infos[3]).RequestHeadWithVerification(ctx, 1)
+	var mismatch *ErrHeadMismatch
+	require.ErrorAs(t, err, &mismatch)
+	require.Len(t, mismatch.Heads, 2)
+	heads := []string{mismatch.Heads[0].Header.Hash().String(), mismatch.Heads[1].Header.Hash().String()}
+	assert.ElementsMatch(t, []string{honest.head.Hash().String(), liar.head.Hash().String()}, heads)
+
+	// the honest peers agree, but there are not enough of them
+	_, err = newExchange(infos[:3]...).RequestHeadWithVerification(ctx, 4)
+	assert.ErrorIs(t, err, ErrNotEnoughConfirmations)
+}
+
+// TestP2PExchange_RequestHeaders_Paginated tests that the P2PExchange transparently requests
+// the remaining headers when the server truncates the response.
+func TestP2PExchange_RequestHeaders_Paginated(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 10)
+	serv := NewP2PExchangeServer(peer, store, WithMaxResponseSize(3))
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	headers, err := exchg.RequestHeaders(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, headers, 10)
+	for i, h := range headers {
+		assert.EqualValues(t, i+1, h.Height)
+		assert.Equal(t, store.byHeight[uint64(h.Height)].Hash(), h.Hash())
+	}
+}
+
+// TestP2PExchange_RequestHeaders_Chunked tests that the P2PExchange requests a range
+// over a stream per chunk.
+func TestP2PExchange_RequestHeaders_Chunked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 20)
+	serv := NewP2PExchangeServer(peer, store)
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	var streams int32
+	peer.SetStreamHandler(exchangeProtocolID, func(stream network.Stream) {
+		atomic.AddInt32(&streams, 1)
+		serv.requestHandler(stream)
+	})
+
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithChunkSize(8))
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	headers, err := exchg.RequestHeaders(ctx, 1, 20)
+	require.NoError(t, err)
+	require.Len(t, headers, 20)
+	for i, h := range headers {
+		assert.EqualValues(t, i+1, h.Height)
+		assert.Equal(t, store.byHeight[uint64(h.Height)].Hash(), h.Hash())
+	}
+	assert.EqualValues(t, 3, atomic.LoadInt32(&streams))
+}
+
+// TestP2PExchange_RequestHeaders_ChunkBoundary tests that every chunk of a range is validated against
+// the previous one and must start at the requested height, so a peer cannot splice in another chain.
+func TestP2PExchange_RequestHeaders_ChunkBoundary(t *testing.T) {
+	tests := map[string]func(store *memStore){
+		"spliced chain": func(store *memStore) {
+			other := NewTestSuite(t, 3).GenExtendedHeaders(8)
+			store.put(other[4:]...)
+			store.head = other[7]
+		},
+		"shifted chunk": func(store *memStore) {
+			// the chunk starting at height 5 is served from the height 6 instead
+			for height := uint64(5); height < 8; height++ {
+				store.byHeight[height] = store.byHeight[height+1]
+			}
+		},
+	}
+	for name, corrupt := range tests {
+		corrupt := corrupt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+
+			host, peer := createMocknet(ctx, t)
+			store := createStore(t, 8)
+			corrupt(store)
+			serv := NewP2PExchangeServer(peer, store)
+			err := serv.Start(ctx)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				serv.Stop(context.Background()) //nolint:errcheck
+			})
+
+			exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithChunkSize(4))
+			err = exchg.Start(ctx)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				exchg.Stop(context.Background()) //nolint:errcheck
+			})
+
+			// every chunk alone is valid
+			_, err = exchg.RequestHeaders(ctx, 1, 4)
+			require.NoError(t, err)
+
+			_, err = exchg.RequestHeaders(ctx, 1, 7)
+			assert.ErrorIs(t, err, ErrInvalidResponse)
+		})
+	}
+}
+
+func TestP2PExchange_StreamHeaders(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 10)
+	serv := NewP2PExchangeServer(peer, store, WithMaxResponseSize(3))
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	headers, errCh := exchg.StreamHeaders(ctx, 1, 11)
+	height := int64(1)
+	for h := range headers {
+		assert.Equal(t, height, h.Height)
+		assert.Equal(t, store.byHeight[uint64(h.Height)].Hash(), h.Hash())
+		height++
+	}
+	require.NoError(t, <-errCh)
+	assert.EqualValues(t, 11, height)
+}
+
+// TestP2PExchange_StreamHeaders_Cancel tests that cancelling the context in the middle of streaming
+// stops it and does not leak goroutines.
+func TestP2PExchange_StreamHeaders_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	exchg, _ := createP2PExAndServer(t, host, peer)
+
+	before := runtime.NumGoroutine()
+
+	streamCtx, streamCancel := context.WithCancel(ctx)
+	headers, errCh := exchg.(*P2PExchange).StreamHeaders(streamCtx, 1, 5)
+	select {
+	case _, ok := <-headers:
+		require.True(t, ok)
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+	// stop consuming headers in the middle of the stream
+	streamCancel()
+
+	require.ErrorIs(t, <-errCh, context.Canceled)
+	_, ok := <-headers
+	assert.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before
+	}, time.Second, time.Millisecond*10)
+}
+
+// TestP2PExchange_RequestTimeout tests that the P2PExchange returns ErrRequestTimeout
+// instead of blocking when the peer does not respond in time.
+func TestP2PExchange_RequestTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	// set a handler that reads the request but delays the response past the deadline
+	peer.SetStreamHandler(exchangeProtocolID, func(raw network.Stream) {
+		stream, err := acceptStream(raw)
+		require.NoError(t, err)
+		_, err = serde.Read(stream, new(header_pb.ExtendedHeaderRequest))
+		require.NoError(t, err)
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+		}
+		stream.Reset() //nolint:errcheck
+	})
+
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithRequestTimeout(time.Millisecond*100))
+	err := exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	start := time.Now()
+	_, err = exchg.RequestHeader(ctx, 5)
+	assert.ErrorIs(t, err, ErrRequestTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// TestP2PExchange_Retry tests that the P2PExchange retries failed requests
+// and succeeds once the peer starts responding.
+func TestP2PExchange_Retry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(peer, store)
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	// fail the first 'failures' attempts by resetting the stream
+	const failures = 2
+	var attempts int32
+	peer.SetStreamHandler(exchangeProtocolID, func(stream network.Stream) {
+		if atomic.AddInt32(&attempts, 1) <= failures {
+			stream.Reset() //nolint:errcheck
+			return
+		}
+		serv.requestHandler(stream)
+	})
+
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithRetry(failures+1, time.Millisecond))
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	header, err := exchg.RequestHeader(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, store.byHeight[5].Hash(), header.Hash())
+	assert.EqualValues(t, failures+1, atomic.LoadInt32(&attempts))
+}
+
+// TestP2PExchange_RequestHeaderFromPeers tests that the P2PExchange gets the header
+// even if half of its peer pool does not serve it.
+func TestP2PExchange_RequestHeaderFromPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	net, err := mocknet.FullMeshConnected(ctx, 5)
+	require.NoError(t, err)
+	host, peers := net.Hosts()[0], net.Hosts()[1:]
+
+	store := createStore(t, 5)
+	pool := make([]peer.AddrInfo, len(peers))
+	for i, p := range peers {
+		pool[i] = *libhost.InfoFromHost(p)
+		// only every second peer serves headers
+		if i%2 == 0 {
+			continue
+		}
+
+		serv := NewP2PExchangeServer(p, store)
+		err = serv.Start(ctx)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			serv.Stop(context.Background()) //nolint:errcheck
+		})
+	}
+
+	exchg := NewP2PExchange(host, &peer.AddrInfo{}, nil, WithPeers(pool))
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	header, err := exchg.RequestHeader(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, store.byHeight[5].Hash(), header.Hash())
+}
+
+// TestP2PExchange_AddRemovePeer tests that peers can be added to and removed from the pool at runtime.
+func TestP2PExchange_AddRemovePeer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	net, err := mocknet.FullMeshLinked(ctx, 2)
+	require.NoError(t, err)
+	host, server := net.Hosts()[0], net.Hosts()[1]
+
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(server, store)
+	err = serv.Start(ctx)
+	require.NoError(t, err)
+
+	exchg := NewP2PExchange(host, nil, nil)
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background())  //nolint:errcheck
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+	assert.Empty(t, exchg.Peers())
+
+	err = exchg.AddPeer(ctx, *libhost.InfoFromHost(server))
+	require.NoError(t, err)
+	peers := exchg.Peers()
+	require.Len(t, peers, 1)
+	assert.Equal(t, server.ID(), peers[0].ID)
+
+	header, err := exchg.RequestHeader(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, store.byHeight[5].Hash(), header.Hash())
+
+	exchg.RemovePeer(server.ID())
+	assert.Empty(t, exchg.Peers())
+
+	_, err = exchg.RequestHeader(ctx, 5)
+	assert.ErrorIs(t, err, ErrNoPeers)
+}
+
+// TestP2PExchange_AddPeerUnavailable tests that a peer the exchange cannot connect to is not added.
+func TestP2PExchange_AddPeerUnavailable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// peers are not linked, so they cannot connect
+	net, err := mocknet.WithNPeers(ctx, 2)
+	require.NoError(t, err)
+
+	exchg := NewP2PExchange(net.Hosts()[0], nil, nil)
+	err = exchg.AddPeer(ctx, *libhost.InfoFromHost(net.Hosts()[1]))
+	assert.Error(t, err)
+	assert.Empty(t, exchg.Peers())
+}
+
+// TestP2PExchange_MinPeerHeight tests that the P2PExchange skips peers advertising the head
+// below the minimum height.
+func TestP2PExchange_MinPeerHeight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	net, err := mocknet.FullMeshConnected(ctx, 3)
+	require.NoError(t, err)
+	host, behind, ahead := net.Hosts()[0], net.Hosts()[1], net.Hosts()[2]
+
+	servBehind := NewP2PExchangeServer(behind, createStore(t, 3))
+	err = servBehind.Start(ctx)
+	require.NoError(t, err)
+	store := createStore(t, 10)
+	servAhead := NewP2PExchangeServer(ahead, store)
+	err = servAhead.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		servBehind.Stop(context.Background()) //nolint:errcheck
+		servAhead.Stop(context.Background())  //nolint:errcheck
+	})
+
+	peers := []peer.AddrInfo{*libhost.InfoFromHost(behind), *libhost.InfoFromHost(ahead)}
+	exchg := NewP2PExchange(host, nil, nil, WithPeers(peers), WithMinPeerHeight(5))
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+	assert.Equal(t, []peer.ID{ahead.ID()}, exchg.selectPeers())
+
+	for i := 0; i < 5; i++ {
+		h, err := exchg.RequestHeader(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, store.byHeight[2].Hash(), h.Hash())
+	}
+	assert.Zero(t, servBehind.Score(host.ID()))
+	assert.NotZero(t, servAhead.Score(host.ID()))
+
+	// no peer is high enough
+	behindAll := NewP2PExchange(host, nil, nil, WithPeers(peers), WithMinPeerHeight(20))
+	err = behindAll.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		behindAll.Stop(context.Background()) //nolint:errcheck
+	})
+	_, err = behindAll.RequestHeader(ctx, 2)
+	assert.ErrorIs(t, err, ErrPeersBehind)
+}
+
+// TestP2PExchange_CircuitBreaker tests that the P2PExchange stops requesting a failing peer
+// and requests it again once it recovers.
+func TestP2PExchange_CircuitBreaker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(peer, store)
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	// the peer fails by resetting the streams until it recovers
+	var recovered, requests int32
+	peer.SetStreamHandler(exchangeProtocolID, func(stream network.Stream) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&recovered) == 0 {
+			stream.Reset() //nolint:errcheck
+			return
+		}
+		serv.requestHandler(stream)
+	})
+
+	const threshold = 3
+	exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithCircuitBreaker(threshold, time.Second*30))
+	var lk sync.Mutex
+	now := time.Now()
+	exchg.now = func() time.Time {
+		lk.Lock()
+		defer lk.Unlock()
+		return now
+	}
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	for i := 0; i < threshold; i++ {
+		_, err = exchg.RequestHeader(ctx, 5)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+	assert.Equal(t, BreakerOpen, exchg.breaker(peer.ID()).State())
+
+	// the open breaker fails requests without reaching the peer
+	_, err = exchg.RequestHeader(ctx, 5)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.EqualValues(t, threshold, atomic.LoadInt32(&requests))
+
+	// the probe reaches the recovered peer and closes the breaker
+	atomic.StoreInt32(&recovered, 1)
+	lk.Lock()
+	now = now.Add(time.Second * 30)
+	lk.Unlock()
+	assert.Equal(t, BreakerHalfOpen, exchg.breaker(peer.ID()).State())
+
+	header, err := exchg.RequestHeader(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, store.byHeight[5].Hash(), header.Hash())
+	assert.Equal(t, BreakerClosed, exchg.breaker(peer.ID()).State())
+}
+
+// TestP2PExchange_StreamReuse tests that the P2PExchange sends sequential requests
+// over at most 'maxIdle' streams.
+func TestP2PExchange_StreamReuse(t *testing.T) {
+	for _, algo := range []CompressionAlgo{NoCompression, Snappy, Zstd} {
+		algo := algo
+		t.Run(algo.String(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			host, peer := createMocknet(ctx, t)
+			store := createStore(t, 10)
+			serv := NewP2PExchangeServer(peer, store)
+			err := serv.Start(ctx)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				serv.Stop(context.Background()) //nolint:errcheck
+			})
+
+			var streams int32
+			peer.SetStreamHandler(exchangeProtocolID, func(stream network.Stream) {
+				atomic.AddInt32(&streams, 1)
+				serv.requestHandler(stream)
+			})
+
+			const maxIdle = 2
+			exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithStreamReuse(maxIdle), WithCompression(algo))
+			err = exchg.Start(ctx)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				exchg.Stop(context.Background()) //nolint:errcheck
+			})
+
+			for height := uint64(1); height <= 10; height++ {
+				header, err := exchg.RequestHeader(ctx, height)
+				require.NoError(t, err)
+				assert.Equal(t, store.byHeight[height].Hash(), header.Hash())
+
+				headers, err := exchg.RequestHeaders(ctx, 1, height)
+				require.NoError(t, err)
+				assert.Len(t, headers, int(height))
+			}
+			assert.LessOrEqual(t, atomic.LoadInt32(&streams), int32(maxIdle))
+			// reusing streams is not penalized
+			assert.LessOrEqual(t, serv.Score(host.ID()), 20*requestWeight)
+
+			// the idle stream closed by the server is replaced
+			serv.Stop(ctx) //nolint:errcheck
+			err = serv.Start(ctx)
+			require.NoError(t, err)
+			peer.SetStreamHandler(exchangeProtocolID, func(stream network.Stream) {
+				atomic.AddInt32(&streams, 1)
+				serv.requestHandler(stream)
+			})
+			_, err = exchg.RequestHeader(ctx, 5)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func createMocknet(ctx context.Context, t *testing.T) (libhost.Host, libhost.Host) {
+	net, err := mocknet.FullMeshConnected(ctx, 2)
+	require.NoError(t, err)
+	// get host and peer
+	return net.Hosts()[0], net.Hosts()[1]
+}
+
+// createP2PExAndServer creates a P2PExchange with 5 headers already in its store.
+func createP2PExAndServer(t *testing.T, host, peer libhost.Host) (Exchange, *memStore) {
+	store := createStore(t, 5)
+	serverSideEx := NewP2PExchangeServer(peer, store)
+	err := serverSideEx.Start(context.Background())
+	require.NoError(t, err)
+
+	// create new exchange
+	clientSideEx := NewP2PExchange(host, libhost.InfoFromHost(peer), nil) // we don't need the store on the requesting side
+	err = clientSideEx.Start(context.Background())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		serverSideEx.Stop(context.Background()) //nolint:errcheck
+		clientSideEx.Stop(context.Background()) //nolint:errcheck
+	})
+
+	return clientSideEx, store
+}
+
+// createStore creates an in-memory store and adds several random headers.
+func createStore(t *testing.T, numHeaders int) *memStore {
+	store := NewMemStore().(*memStore)
+
+	suite := NewTestSuite(t, numHeaders)
+	err := store.Append(context.Background(), suite.GenExtendedHeaders(numHeaders)...)
+	require.NoError(t, err)
+	return store
+}
+
+// TestP2PExchange_Compression tests that the P2PExchange exchanges headers over streams compressed
+// with any of the supported algorithms.
+func TestP2PExchange_Compression(t *testing.T) {
+	for _, algo := range []CompressionAlgo{NoCompression, Snappy, Zstd} {
+		algo := algo
+		t.Run(algo.String(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			host, peer := createMocknet(ctx, t)
+			store := createStore(t, 10)
+			serv := NewP2PExchangeServer(peer, store, WithMaxResponseSize(4))
+			err := serv.Start(ctx)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				serv.Stop(context.Background()) //nolint:errcheck
+			})
+
+			exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithCompression(algo))
+			err = exchg.Start(ctx)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				exchg.Stop(context.Background()) //nolint:errcheck
+			})
+
+			headers, err := exchg.RequestHeaders(ctx, 1, 10)
+			require.NoError(t, err)
+			require.Len(t, headers, 10)
+			for i, h := range headers {
+				assert.Equal(t, store.byHeight[uint64(i+1)].Hash(), h.Hash())
+			}
+
+			h, err := exchg.RequestByHash(ctx, store.byHeight[3].Hash())
+			require.NoError(t, err)
+			assert.EqualValues(t, 3, h.Height)
+
+			_, err = exchg.RequestHeader(ctx, 20)
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+// TestP2PExchange_ProtocolVersions tests that the P2PExchange requests with the newest version
+// of the exchange protocol supported by both sides, falling back to older ones.
+func TestBackoff(t *testing.T) {
+	base := time.Millisecond * 100
+	for attempt := 1; attempt <= 3; attempt++ {
+		delay := backoff(base, attempt)
+		assert.GreaterOrEqual(t, int64(delay), int64(base<<(attempt-1)))
+		assert.LessOrEqual(t, int64(delay), int64(base<<(attempt-1)*3/2))
+	}
+
+	// the delay is capped, even once doubling overflows
+	for _, attempt := range []int{20, 64, 100, math.MaxInt32} {
+		delay := backoff(base, attempt)
+		assert.GreaterOrEqual(t, int64(delay), int64(maxBackoff), attempt)
+		assert.LessOrEqual(t, int64(delay), int64(maxBackoff*3/2), attempt)
+	}
+
+	assert.Zero(t, backoff(0, 100))
+}
+
+func TestP2PExchange_ProtocolVersions(t *testing.T) {
+	tests := []struct {
+		name           string
+		client, server []protocol.ID
+		expected       protocol.ID
+	}{
+		{"both newest", exchangeProtocols, exchangeProtocols, exchangeProtocolID},
+		{"baseline client", []protocol.ID{baselineExchangeProtocolID}, exchangeProtocols, baselineExchangeProtocolID},
+		{"baseline server", exchangeProtocols, []protocol.ID{baselineExchangeProtocolID}, baselineExchangeProtocolID},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			host, peer := createMocknet(ctx, t)
+			store := createStore(t, 5)
+			serv := NewP2PExchangeServer(peer, store)
+			serv.protocols = tt.server
+			err := serv.Start(ctx)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				serv.Stop(context.Background()) //nolint:errcheck
+			})
+			// record the negotiated versions
+			used := make(chan protocol.ID, 2)
+			for _, pid := range tt.server {
+				peer.SetStreamHandler(pid, func(stream network.Stream) {
+					used <- stream.Protocol()
+					serv.requestHandler(stream)
+				})
+			}
+
+			// compression is ignored with the baseline version
+			exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithCompression(Zstd))
+			exchg.protocols = tt.client
+			err = exchg.Start(ctx)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				exchg.Stop(context.Background()) //nolint:errcheck
+			})
+
+			headers, err := exchg.RequestHeaders(ctx, 1, 5)
+			require.NoError(t, err)
+			for i, h := range headers {
+				assert.Equal(t, store.byHeight[uint64(i+1)].Hash(), h.Hash())
+			}
+			h, err := exchg.RequestByHash(ctx, store.byHeight[3].Hash())
+			require.NoError(t, err)
+			assert.EqualValues(t, 3, h.Height)
+
+			assert.Equal(t, tt.expected, <-used)
+			assert.Equal(t, tt.expected, <-used)
+
+			// the baseline version cannot serve requests by multiple hashes
+			_, err = exchg.RequestHeadersByHashes(ctx, []tmbytes.HexBytes{
+				store.byHeight[1].Hash(),
+				store.byHeight[2].Hash(),
+			})
+			if tt.expected == baselineExchangeProtocolID {
+				assert.ErrorIs(t, err, ErrUnsupportedRequest)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestP2PExchangeServer_BaselineClient tests that the P2PExchangeServer serves the clients
+// speaking the baseline exchange protocol, i.e. responds with bare headers and closes the stream.
+func TestP2PExchangeServer_BaselineClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(peer, store)
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+
+	// request the same way the baseline P2PExchange does
+	request := func(req *header_pb.ExtendedHeaderRequest) ([]*ExtendedHeader, error) {
+		stream, err := host.NewStream(ctx, peer.ID(), baselineExchangeProtocolID)
+		require.NoError(t, err)
+		defer stream.Close() //nolint:errcheck
+		_, err = serde.Write(stream, req)
+		require.NoError(t, err)
+
+		headers := make([]*ExtendedHeader, req.Amount)
+		for i := range headers {
+			resp := new(header_pb.ExtendedHeader)
+			_, err = serde.Read(stream, resp)
+			if err != nil {
+				return nil, err
+			}
+			headers[i], err = ProtoToExtendedHeader(resp)
+			require.NoError(t, err)
+		}
+		// the stream serves a single request
+		_, err = serde.Read(stream, new(header_pb.ExtendedHeader))
+		assert.ErrorIs(t, err, io.EOF)
+		return headers, nil
+	}
+
+	headers, err := request(&header_pb.ExtendedHeaderRequest{Origin: 1, Amount: 5})
+	require.NoError(t, err)
+	for i, h := range headers {
+		assert.Equal(t, store.byHeight[uint64(i+1)].Hash(), h.Hash())
+	}
+
+	headers, err = request(&header_pb.ExtendedHeaderRequest{Hash: store.byHeight[3].Hash(), Amount: 1})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, headers[0].Height)
+
+	headers, err = request(&header_pb.ExtendedHeaderRequest{Origin: 0, Amount: 1})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, headers[0].Height)
+
+	// failures reset the stream, as there are no status codes
+	_, err = request(&header_pb.ExtendedHeaderRequest{Origin: 20, Amount: 1})
+	assert.Error(t, err)
+}
+
+// TestP2PExchangeServer_UnknownCompression tests that the P2PExchangeServer resets streams
+// announcing an unsupported compression algorithm.
+func TestP2PExchangeServer_UnknownCompression(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host, peer := createMocknet(ctx, t)
+	exchg, _ := createP2PExAndServer(t, host, peer)
+	exchg.(*P2PExchange).compression = CompressionAlgo(42)
+
+	_, err := exchg.RequestHead(ctx)
+	assert.Error(t, err)
+}
+
+// countingStream counts the bytes written to the stream.
+type countingStream struct {
+	network.Stream
+	written *int64
+}
+
+func (cs countingStream) Write(p []byte) (int, error) {
+	n, err := cs.Stream.Write(p)
+	atomic.AddInt64(cs.written, int64(n))
+	return n, err
+}
+
+func BenchmarkP2PExchange_RequestHeaders(b *testing.B) {
+	const amount = 1000
+	suite := NewTestSuite(b, 3)
+	store := NewMemStore()
+	err := store.Append(context.Background(), suite.GenExtendedHeaders(amount)...)
+	require.NoError(b, err)
+
+	// size of the uncompressed headers
+	var size int64
+	for height := uint64(1); height <= amount; height++ {
+		h, err := store.GetByHeight(context.Background(), height)
+		require.NoError(b, err)
+		bin, err := h.MarshalBinary()
+		require.NoError(b, err)
+		size += int64(len(bin))
+	}
+
+	for _, algo := range []CompressionAlgo{NoCompression, Snappy, Zstd} {
+		algo := algo
+		b.Run(algo.String(), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			net, err := mocknet.FullMeshConnected(ctx, 2)
+			require.NoError(b, err)
+			host, peer := net.Hosts()[0], net.Hosts()[1]
+
+			serv := NewP2PExchangeServer(peer, store, WithMaxResponseSize(amount))
+			var written int64
+			peer.SetStreamHandler(exchangeProtocolID, func(stream network.Stream) {
+				serv.requestHandler(countingStream{Stream: stream, written: &written})
+			})
+			serv.ctx, serv.cancel = context.WithCancel(ctx)
+
+			exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithCompression(algo))
+			err = exchg.Start(ctx)
+			require.NoError(b, err)
+			defer exchg.Stop(ctx) //nolint:errcheck
+
+			b.SetBytes(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				headers, err := exchg.RequestHeaders(ctx, 1, amount)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(headers) != amount {
+					b.Fatalf("expected %d headers, got %d", amount, len(headers))
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(atomic.LoadInt64(&written))/float64(int64(b.N)*size), "wire/raw")
+		})
+	}
+}
+
+// BenchmarkP2PExchange_RequestHeaders_Chunked measures the memory used to sync a long range
+// of headers with and without chunking.
+func BenchmarkP2PExchange_RequestHeaders_Chunked(b *testing.B) {
+	const amount = 10000
+	suite := NewTestSuite(b, 3)
+	store := NewMemStore()
+	err := store.Append(context.Background(), suite.GenExtendedHeaders(amount)...)
+	require.NoError(b, err)
+
+	for _, chunk := range []uint64{0, DefaultChunkSize} {
+		chunk := chunk
+		b.Run(fmt.Sprintf("chunk=%d", chunk), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			net, err := mocknet.FullMeshConnected(ctx, 2)
+			require.NoError(b, err)
+			host, peer := net.Hosts()[0], net.Hosts()[1]
+
+			// every chunk is a request scored by the server
+			serv := NewP2PExchangeServer(peer, store, WithScoreThreshold(math.MaxFloat64))
+			err = serv.Start(ctx)
+			require.NoError(b, err)
+			defer serv.Stop(ctx) //nolint:errcheck
+
+			exchg := NewP2PExchange(host, libhost.InfoFromHost(peer), nil, WithChunkSize(chunk))
+			err = exchg.Start(ctx)
+			require.NoError(b, err)
+			defer exchg.Stop(ctx) //nolint:errcheck
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				headers, err := exchg.RequestHeaders(ctx, 1, amount)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(headers) != amount {
+					b.Fatalf("expected %d headers, got %d", amount, len(headers))
+				}
+			}
+		})
+	}
+}
+
+// TestP2PExchange_DHTDiscovery tests that the P2PExchange without configured peers discovers
+// the peer serving headers through the DHT.
+func TestP2PExchange_DHTDiscovery(t *testing.T) {
+	retryInterval := discoveryRetryInterval
+	discoveryRetryInterval = time.Millisecond * 50
+	t.Cleanup(func() {
+		discoveryRetryInterval = retryInterval
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	net, err := mocknet.FullMeshConnected(ctx, 2)
+	require.NoError(t, err)
+	server, client := net.Hosts()[0], net.Hosts()[1]
+	// the in-memory routing server behaves like a DHT shared by both hosts
+	router := mockrouting.NewServer()
+	newDHT := func(host libhost.Host) routing.ContentRouting {
+		id, ps := host.ID(), host.Peerstore()
+		return router.Client(tnet.NewIdentity(id, host.Addrs()[0], ps.PrivKey(id), ps.PubKey(id)))
+	}
+
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(server, store)
+	err = serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+	// the serving side advertises itself
+	servEx := NewP2PExchange(server, nil, store, WithDHTDiscovery(newDHT(server)))
+	err = servEx.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		servEx.Stop(context.Background()) //nolint:errcheck
+	})
+
+	exchg := NewP2PExchange(client, nil, nil, WithDHTDiscovery(newDHT(client)))
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	require.Eventually(t, func() bool {
+		return len(exchg.Peers()) > 0
+	}, time.Second*5, time.Millisecond*50)
+	assert.Equal(t, server.ID(), exchg.Peers()[0].ID)
+
+	head, err := exchg.RequestHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, store.head.Hash(), head.Hash())
+}
+
+// TestP2PExchange_MDNSDiscovery tests that exchanges of two hosts in the same local network find each other
+// over mDNS without any peers given.
+func TestP2PExchange_MDNSDiscovery(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	newHost := func() libhost.Host {
+		host, err := libp2p.New(ctx, libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			host.Close()
+		})
+		return host
+	}
+	server, client := newHost(), newHost()
+	// a unique service name keeps hosts of concurrent test runs apart
+	serviceName := fmt.Sprintf("_celestia-test-%s._udp", tmrand.Str(8))
+
+	store := createStore(t, 5)
+	serv := NewP2PExchangeServer(server, store)
+	err := serv.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		serv.Stop(context.Background()) //nolint:errcheck
+	})
+	// the serving side announces itself
+	servEx := NewP2PExchange(server, nil, store, WithMDNSDiscovery(serviceName))
+	err = servEx.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		servEx.Stop(context.Background()) //nolint:errcheck
+	})
+
+	exchg := NewP2PExchange(client, nil, nil, WithMDNSDiscovery(serviceName))
+	err = exchg.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exchg.Stop(context.Background()) //nolint:errcheck
+	})
+
+	require.Eventually(t, func() bool {
+		return len(exchg.Peers()) > 0
+	}, time.Second*5, time.Millisecond*50)
+	assert.Equal(t, server.ID(), exchg.Peers()[0].ID)
+
+	headers, err := exchg.RequestHeaders(ctx, 1, 5)
+	require.NoError(t, err)
+	require.Len(t, headers, 5)
+	assert.Equal(t, store.head.Hash(), headers[4].Hash())
+}
+
+// blockingStore blocks iterating over ranges of headers until released.
+type blockingStore struct {
+	Store
+
+	entered int32
+	release chan struct{}
+}
+
+func (bs *blockingStore) IterateByHeight(ctx context.Context, from, to uint64) (HeaderIterator, error) {
+	atomic.AddInt32(&bs.entered, 1)
+	select {
+	case <-bs.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	return nil
+	return bs.Store.IterateByHeight(ctx, from, to)
 }
@@ -0,0 +1,126 @@
+package header
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	pb "github.com/celestiaorg/celestia-node/service/header/pb"
+)
+
+// ErrNotEnoughConfirmations is returned when too few peers respond with the head to confirm it.
+var ErrNotEnoughConfirmations = errors.New("header/p2p: not enough confirmations")
+
+// ConfirmedHead is a head along with the peers which responded with it.
+type ConfirmedHead struct {
+	Header *ExtendedHeader
+	Peers  []peer.ID
+}
+
+// ErrHeadMismatch is returned when peers respond with different heads and none of them
+// is confirmed by enough peers.
+type ErrHeadMismatch struct {
+	// Heads are the conflicting heads ordered by the amount of confirmations, descending.
+	Heads []ConfirmedHead
+}
+
+func (e *ErrHeadMismatch) Error() string {
+	heads := make([]string, len(e.Heads))
+	for i, h := range e.Heads {
+		heads[i] = fmt.Sprintf("%s at %d by %d peer(s)", h.Header.Hash(), h.Header.Height, len(h.Peers))
+	}
+	return fmt.Sprintf("header/p2p: peers disagree on head: %s", strings.Join(heads, ", "))
+}
+
+// RequestHeadWithVerification requests the head from all the peers of the pool and returns it
+// only if at least 'minConfirmations' of them respond with the same one. Otherwise, it errors with
+// ErrHeadMismatch if the peers disagree, or ErrNotEnoughConfirmations if too few of them respond.
+// Unlike RequestHead, it is not fooled by a single malicious peer.
+func (ex *P2PExchange) RequestHeadWithVerification(ctx context.Context, minConfirmations int) (*ExtendedHeader, error) {
+	if minConfirmations < 1 {
+		return nil, fmt.Errorf("header/p2p: invalid amount of confirmations %d", minConfirmations)
+	}
+	log.Debugw("p2p: requesting verified head", "confirmations", minConfirmations)
+
+	reqCtx, cancel := context.WithTimeout(ctx, ex.requestTimeout)
+	defer cancel()
+
+	var peers []peer.ID
+	select {
+	case <-reqCtx.Done():
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, ErrRequestTimeout
+	case <-ex.connected:
+		peers = ex.selectPeers()
+	}
+	if len(peers) < minConfirmations {
+		return nil, fmt.Errorf("%w: %d peers for %d confirmations", ErrNotEnoughConfirmations, len(peers), minConfirmations)
+	}
+
+	heads := ex.requestHeads(reqCtx, peers)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	switch {
+	case len(heads) == 0:
+		return nil, fmt.Errorf("%w: no peer responded", ErrNotEnoughConfirmations)
+	case len(heads[0].Peers) >= minConfirmations && (len(heads) == 1 || len(heads[1].Peers) < len(heads[0].Peers)):
+		return heads[0].Header, nil
+	case len(heads) == 1:
+		return nil, fmt.Errorf("%w: %d of %d", ErrNotEnoughConfirmations, len(heads[0].Peers), minConfirmations)
+	default:
+		return nil, &ErrHeadMismatch{Heads: heads}
+	}
+}
+
+// requestHeads requests the head from all the given peers concurrently and groups the responses by hash.
+// Peers failing to respond are skipped.
+func (ex *P2PExchange) requestHeads(ctx context.Context, peers []peer.ID) []ConfirmedHead {
+	type result struct {
+		peer peer.ID
+		head *ExtendedHeader
+	}
+	results := make(chan result, len(peers))
+	req := &pb.ExtendedHeaderRequest{Origin: 0, Amount: 1}
+	injectTraceContext(ctx, req)
+	for _, p := range peers {
+		go func(p peer.ID) {
+			resp, err := ex.doRequest(ctx, p, req, nil, false)
+			if err != nil {
+				log.Debugw("p2p: requesting head", "peer", p.ShortString(), "err", err)
+				results <- result{peer: p}
+				return
+			}
+			results <- result{peer: p, head: resp.headers[0]}
+		}(p)
+	}
+
+	var heads []ConfirmedHead
+	byHash := make(map[string]int)
+	for range peers {
+		res := <-results
+		if res.head == nil {
+			continue
+		}
+
+		hash := res.head.Hash().String()
+		i, ok := byHash[hash]
+		if !ok {
+			i = len(heads)
+			byHash[hash] = i
+			heads = append(heads, ConfirmedHead{Header: res.head})
+		}
+		heads[i].Peers = append(heads[i].Peers, res.peer)
+	}
+
+	sort.SliceStable(heads, func(i, j int) bool {
+		return len(heads[i].Peers) > len(heads[j].Peers)
+	})
+	return heads
+}
@@ -0,0 +1,116 @@
+package header
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+)
+
+// headHeightProtocolID is the protocol peers advertise the height of their head with.
+var headHeightProtocolID = protocol.ID("/header-ex/head-height/v0.0.1")
+
+// heightRefreshInterval is the interval P2PExchange refreshes the head heights of its peers at.
+var heightRefreshInterval = time.Second * 30
+
+// heightHandler responds with the height of the head, or zero if there is no head yet.
+func (serv *P2PExchangeServer) heightHandler(stream network.Stream) {
+	defer stream.Close()
+
+	var height uint64
+	head, err := serv.getHead()
+	switch err {
+	case nil:
+		height = uint64(head.Height)
+	case ErrNoHead:
+	default:
+		log.Errorw("p2p-server: getting head height", "err", err)
+		stream.Reset() //nolint:errcheck
+		return
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	_, err = stream.Write(buf[:binary.PutUvarint(buf, height)])
+	if err != nil {
+		log.Debugw("p2p-server: writing head height", "peer", stream.Conn().RemotePeer().ShortString(), "err", err)
+		stream.Reset() //nolint:errcheck
+	}
+}
+
+// trackHeights keeps the head heights of the peers up to date, until the context is canceled.
+func (ex *P2PExchange) trackHeights(ctx context.Context) {
+	ticker := time.NewTicker(heightRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ex.refreshHeights(ctx, ex.allPeers())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refreshHeights requests the head heights of the given peers concurrently.
+// Peers failing to respond keep the last known height.
+func (ex *P2PExchange) refreshHeights(ctx context.Context, peers []peer.AddrInfo) {
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		wg.Add(1)
+		go func(id peer.ID) {
+			defer wg.Done()
+			height, err := ex.requestHeight(ctx, id)
+			if err != nil {
+				log.Debugw("p2p: requesting head height", "peer", id.ShortString(), "err", err)
+				return
+			}
+
+			ex.peersLk.Lock()
+			if ex.heights == nil {
+				ex.heights = make(map[peer.ID]uint64)
+			}
+			ex.heights[id] = height
+			ex.peersLk.Unlock()
+		}(p.ID)
+	}
+	wg.Wait()
+}
+
+// requestHeight requests the height of the head advertised by the given peer.
+func (ex *P2PExchange) requestHeight(ctx context.Context, id peer.ID) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, ex.requestTimeout)
+	defer cancel()
+
+	stream, err := ex.host.NewStream(ctx, id, headHeightProtocolID)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetReadDeadline(deadline) //nolint:errcheck
+	}
+
+	height, err := binary.ReadUvarint(bufio.NewReaderSize(stream, binary.MaxVarintLen64))
+	if err != nil {
+		stream.Reset() //nolint:errcheck
+		return 0, err
+	}
+	return height, nil
+}
+
+// belowMinHeight reports whether the last known head height of the given peer is below the minimum one.
+// Peers with unknown heights are considered below it.
+func (ex *P2PExchange) belowMinHeight(id peer.ID) bool {
+	if ex.minPeerHeight == 0 {
+		return false
+	}
+
+	ex.peersLk.RLock()
+	defer ex.peersLk.RUnlock()
+	return ex.heights[id] < ex.minPeerHeight
+}
@@ -0,0 +1,243 @@
+package header
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultNetworkHeadInterval is the default interval NetworkHeadTracker samples the heads of peers at.
+var DefaultNetworkHeadInterval = time.Second * 10
+
+// DefaultNetworkHeadSampleSize is the default amount of peers NetworkHeadTracker requests the head from at once.
+var DefaultNetworkHeadSampleSize = 5
+
+// DefaultNetworkHeadConfirmations is the default amount of sampled peers which must respond with the same head
+// for NetworkHeadTracker to accept it, so a single peer cannot pin the tracker to a head of its choice.
+var DefaultNetworkHeadConfirmations = 2
+
+// NetworkHeadTrackerOption is a functional option that configures NetworkHeadTracker.
+type NetworkHeadTrackerOption func(*NetworkHeadTracker)
+
+// WithNetworkHeadInterval sets the interval NetworkHeadTracker samples the heads of peers at.
+func WithNetworkHeadInterval(interval time.Duration) NetworkHeadTrackerOption {
+	return func(t *NetworkHeadTracker) {
+		t.interval = interval
+	}
+}
+
+// WithNetworkHeadSampleSize sets the amount of random peers of the pool NetworkHeadTracker
+// requests the head from at once.
+func WithNetworkHeadSampleSize(size int) NetworkHeadTrackerOption {
+	return func(t *NetworkHeadTracker) {
+		t.sampleSize = size
+	}
+}
+
+// WithNetworkHeadConfirmations sets the amount of sampled peers which must respond with the same head
+// for NetworkHeadTracker to accept it. Defaults to DefaultNetworkHeadConfirmations.
+func WithNetworkHeadConfirmations(confirmations int) NetworkHeadTrackerOption {
+	return func(t *NetworkHeadTracker) {
+		t.confirmations = confirmations
+	}
+}
+
+// NetworkHeadTracker follows the highest head known to the network by periodically requesting
+// the head from a random subset of the peers of the P2PExchange.
+// Only heads confirmed by enough of the sampled peers and verified against the Store are accepted,
+// and the tracked head never goes back.
+type NetworkHeadTracker struct {
+	ex    *P2PExchange
+	store Store
+
+	interval      time.Duration
+	sampleSize    int
+	confirmations int
+
+	lk   sync.RWMutex
+	head *ExtendedHeader
+	// subs are notified about every new network head; guarded by lk
+	subs map[chan *ExtendedHeader]struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewNetworkHeadTracker creates a new NetworkHeadTracker sampling peers of the given P2PExchange
+// and verifying their heads against the head of the given Store.
+func NewNetworkHeadTracker(ex *P2PExchange, store Store, opts ...NetworkHeadTrackerOption) *NetworkHeadTracker {
+	t := &NetworkHeadTracker{
+		ex:            ex,
+		store:         store,
+		interval:      DefaultNetworkHeadInterval,
+		sampleSize:    DefaultNetworkHeadSampleSize,
+		confirmations: DefaultNetworkHeadConfirmations,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Start starts sampling the heads of peers. The first sample is taken right away.
+func (t *NetworkHeadTracker) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel, t.done = cancel, make(chan struct{})
+	go t.track(ctx)
+	return nil
+}
+
+// Stop stops sampling and closes all the channels returned by WatchNetworkHead.
+func (t *NetworkHeadTracker) Stop(ctx context.Context) error {
+	t.cancel()
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	for sub := range t.subs {
+		close(sub)
+	}
+	t.subs = nil
+	return nil
+}
+
+// NetworkHead returns the highest confirmed head of the network, or nil if none is known yet.
+func (t *NetworkHeadTracker) NetworkHead() *ExtendedHeader {
+	t.lk.RLock()
+	defer t.lk.RUnlock()
+	return t.head
+}
+
+// WatchNetworkHead subscribes to new heads of the network until the given context is done.
+// Only the latest head is kept for slow readers, so some heads may be skipped.
+// The channel is closed once the context is done or the NetworkHeadTracker is stopped.
+func (t *NetworkHeadTracker) WatchNetworkHead(ctx context.Context) (<-chan *ExtendedHeader, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	sub := make(chan *ExtendedHeader, 1)
+	t.lk.Lock()
+	if t.subs == nil {
+		t.subs = make(map[chan *ExtendedHeader]struct{})
+	}
+	t.subs[sub] = struct{}{}
+	t.lk.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		t.unsubscribe(sub)
+	}()
+	return sub, nil
+}
+
+// unsubscribe removes and closes the given subscription, unless Stop closed it already.
+func (t *NetworkHeadTracker) unsubscribe(sub chan *ExtendedHeader) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	if _, ok := t.subs[sub]; ok {
+		delete(t.subs, sub)
+		close(sub)
+	}
+}
+
+// track samples the heads of peers every interval, until the context is canceled.
+func (t *NetworkHeadTracker) track(ctx context.Context) {
+	defer close(t.done)
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		t.sample(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sample requests the head from random peers and updates the network head with the highest confirmed one.
+func (t *NetworkHeadTracker) sample(ctx context.Context) {
+	peers := t.ex.selectPeers()
+	if len(peers) == 0 {
+		log.Debug("p2p: no peers to sample network head from")
+		return
+	}
+	mrand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if len(peers) > t.sampleSize {
+		peers = peers[:t.sampleSize]
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.ex.requestTimeout)
+	defer cancel()
+	var highest *ExtendedHeader
+	for _, head := range t.ex.requestHeads(ctx, peers) {
+		if len(head.Peers) < t.confirmations {
+			continue
+		}
+		if highest != nil && head.Header.Height <= highest.Height {
+			continue
+		}
+
+		err := t.verify(ctx, head.Header)
+		if err != nil {
+			log.Warnw("p2p: invalid network head", "height", head.Header.Height, "hash", head.Header.Hash(),
+				"peers", len(head.Peers), "err", err)
+			continue
+		}
+		highest = head.Header
+	}
+	if highest != nil {
+		t.update(highest)
+	}
+}
+
+// verify verifies the given head against the head of the Store. Heads not above it must be stored already.
+func (t *NetworkHeadTracker) verify(ctx context.Context, head *ExtendedHeader) error {
+	trusted, err := t.store.Head(ctx)
+	if err != nil {
+		return err
+	}
+	if head.Height > trusted.Height {
+		return head.Verify(trusted)
+	}
+
+	stored, err := t.store.GetByHeight(ctx, uint64(head.Height))
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return fmt.Errorf("%w: height %d is pruned", ErrUnverifiable, head.Height)
+	case err != nil:
+		return err
+	case !bytes.Equal(stored.Hash(), head.Hash()):
+		return fmt.Errorf("%w: stored %X, got %X", ErrForked, stored.Hash(), head.Hash())
+	}
+	return nil
+}
+
+// update sets the given head as the network head and notifies subscribers, if it is higher than the current one.
+func (t *NetworkHeadTracker) update(head *ExtendedHeader) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	if t.head != nil && head.Height <= t.head.Height {
+		return
+	}
+
+	t.head = head
+	log.Debugw("p2p: new network head", "height", head.Height, "hash", head.Hash())
+	for sub := range t.subs {
+		// drop the stale head the subscriber did not read yet
+		select {
+		case <-sub:
+		default:
+		}
+		sub <- head
+	}
+}
@@ -0,0 +1,141 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	libhost "github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNetworkHeadTracker tests that NetworkHeadTracker follows the highest head confirmed by enough peers.
+func TestNetworkHeadTracker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	net, err := mocknet.FullMeshConnected(ctx, 4)
+	require.NoError(t, err)
+	client, servers := net.Hosts()[0], net.Hosts()[1:]
+
+	// all the peers follow the same chain, but one of them is ahead
+	headers := NewTestSuite(t, 3).GenExtendedHeaders(10)
+	stores := make([]*memStore, len(servers))
+	addrs := make([]peer.AddrInfo, len(servers))
+	for i, server := range servers {
+		stores[i] = NewMemStore().(*memStore)
+		err = stores[i].Append(ctx, headers[:5]...)
+		require.NoError(t, err)
+
+		serv := NewP2PExchangeServer(server, stores[i])
+		err = serv.Start(ctx)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			serv.Stop(context.Background()) //nolint:errcheck
+		})
+		addrs[i] = *libhost.InfoFromHost(server)
+	}
+	err = stores[0].Append(ctx, headers[5:8]...)
+	require.NoError(t, err)
+
+	ex := NewP2PExchange(client, nil, nil, WithPeers(addrs))
+	err = ex.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		ex.Stop(context.Background()) //nolint:errcheck
+	})
+
+	// the client trusts only the first header
+	trusted := NewMemStore()
+	err = trusted.Append(ctx, headers[0])
+	require.NoError(t, err)
+
+	newTracker := func(confirmations int) *NetworkHeadTracker {
+		tracker := NewNetworkHeadTracker(ex, trusted,
+			WithNetworkHeadInterval(time.Millisecond*50),
+			WithNetworkHeadSampleSize(len(servers)),
+			WithNetworkHeadConfirmations(confirmations),
+		)
+		err := tracker.Start(ctx)
+		require.NoError(t, err)
+		return tracker
+	}
+	waitHead := func(heads <-chan *ExtendedHeader) *ExtendedHeader {
+		select {
+		case head := <-heads:
+			return head
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+			return nil
+		}
+	}
+
+	// a single peer is enough to follow the one ahead
+	tracker := newTracker(1)
+	heads, err := tracker.WatchNetworkHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, headers[7].Hash(), waitHead(heads).Hash())
+	assert.Equal(t, headers[7].Hash(), tracker.NetworkHead().Hash())
+	err = tracker.Stop(ctx)
+	require.NoError(t, err)
+	_, ok := <-heads
+	assert.False(t, ok)
+
+	// the peer ahead is not followed, until others confirm its head
+	tracker = newTracker(2)
+	heads, err = tracker.WatchNetworkHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, headers[4].Hash(), waitHead(heads).Hash())
+
+	err = stores[1].Append(ctx, headers[5:10]...)
+	require.NoError(t, err)
+	err = stores[2].Append(ctx, headers[5:10]...)
+	require.NoError(t, err)
+	assert.Equal(t, headers[9].Hash(), waitHead(heads).Hash())
+	assert.Equal(t, headers[9].Hash(), tracker.NetworkHead().Hash())
+
+	// the network head never goes back
+	tracker.update(headers[6])
+	assert.Equal(t, headers[9].Hash(), tracker.NetworkHead().Hash())
+
+	// the subscription ends with its context
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	_, err = tracker.WatchNetworkHead(watchCtx)
+	require.NoError(t, err)
+	watchCancel()
+	assert.Eventually(t, func() bool {
+		tracker.lk.RLock()
+		defer tracker.lk.RUnlock()
+		return len(tracker.subs) == 1
+	}, time.Second, time.Millisecond*10)
+	err = tracker.Stop(ctx)
+	require.NoError(t, err)
+}
+
+// TestNetworkHeadTracker_Verify tests that NetworkHeadTracker rejects heads which do not verify against the Store.
+func TestNetworkHeadTracker_Verify(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	headers := NewTestSuite(t, 3).GenExtendedHeaders(5)
+	forged := NewTestSuite(t, 3).GenExtendedHeaders(5)
+
+	store := NewMemStore()
+	err := store.Append(ctx, headers[:3]...)
+	require.NoError(t, err)
+	tracker := NewNetworkHeadTracker(nil, store)
+
+	err = tracker.verify(ctx, headers[1])
+	assert.NoError(t, err)
+	err = tracker.verify(ctx, headers[4])
+	assert.NoError(t, err)
+
+	// heads of another validator set or a fork of the stored chain are rejected
+	err = tracker.verify(ctx, forged[4])
+	assert.Error(t, err)
+	err = tracker.verify(ctx, forged[1])
+	assert.ErrorIs(t, err, ErrForked)
+}
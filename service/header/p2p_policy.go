@@ -0,0 +1,161 @@
+package header
+
+import (
+	"context"
+	mrand "math/rand"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// PeerSelectionPolicy selects the peer P2PExchange sends a request to out of the available ones.
+// Requests fanned out to all the peers at once, e.g. RequestHeader, are not subject to the policy.
+type PeerSelectionPolicy interface {
+	// SelectPeer returns one of the given peers, which are never empty.
+	SelectPeer(ctx context.Context, peers []peer.AddrInfo) (peer.AddrInfo, error)
+}
+
+// RequestObserver is implemented by the PeerSelectionPolicies which learn from the outcome of the requests
+// to the selected peers. P2PExchange reports every request to the peer the policy selected.
+type RequestObserver interface {
+	// ObserveRequest records the time the request to the given peer took and its error, if any.
+	ObserveRequest(id peer.ID, took time.Duration, err error)
+}
+
+// RoundRobinPolicy selects the available peers in turns.
+type RoundRobinPolicy struct {
+	lk   sync.Mutex
+	next uint64
+}
+
+// NewRoundRobinPolicy creates a new RoundRobinPolicy.
+func NewRoundRobinPolicy() *RoundRobinPolicy {
+	return &RoundRobinPolicy{}
+}
+
+func (rr *RoundRobinPolicy) SelectPeer(_ context.Context, peers []peer.AddrInfo) (peer.AddrInfo, error) {
+	if len(peers) == 0 {
+		return peer.AddrInfo{}, ErrNoPeers
+	}
+
+	rr.lk.Lock()
+	defer rr.lk.Unlock()
+	p := peers[rr.next%uint64(len(peers))]
+	rr.next++
+	return p, nil
+}
+
+// RandomPolicy selects a random one of the available peers.
+type RandomPolicy struct{}
+
+// NewRandomPolicy creates a new RandomPolicy.
+func NewRandomPolicy() *RandomPolicy {
+	return &RandomPolicy{}
+}
+
+func (*RandomPolicy) SelectPeer(_ context.Context, peers []peer.AddrInfo) (peer.AddrInfo, error) {
+	if len(peers) == 0 {
+		return peer.AddrInfo{}, ErrNoPeers
+	}
+	return peers[mrand.Intn(len(peers))], nil //nolint:gosec
+}
+
+// latencySmoothing is the weight of the latest response time in the average tracked by LowestLatencyPolicy.
+const latencySmoothing = 0.3
+
+// LowestLatencyPolicy selects the peer with the lowest average response time.
+// Peers without response times yet are selected first, so every peer gets measured.
+// A failed request doubles the average response time of the peer, so it is selected less.
+type LowestLatencyPolicy struct {
+	lk        sync.Mutex
+	latencies map[peer.ID]time.Duration
+}
+
+// NewLowestLatencyPolicy creates a new LowestLatencyPolicy.
+func NewLowestLatencyPolicy() *LowestLatencyPolicy {
+	return &LowestLatencyPolicy{latencies: make(map[peer.ID]time.Duration)}
+}
+
+func (ll *LowestLatencyPolicy) SelectPeer(_ context.Context, peers []peer.AddrInfo) (peer.AddrInfo, error) {
+	if len(peers) == 0 {
+		return peer.AddrInfo{}, ErrNoPeers
+	}
+
+	ll.lk.Lock()
+	defer ll.lk.Unlock()
+	best := -1
+	for i, p := range peers {
+		latency, ok := ll.latencies[p.ID]
+		if !ok {
+			return p, nil
+		}
+		if best == -1 || latency < ll.latencies[peers[best].ID] {
+			best = i
+		}
+	}
+	return peers[best], nil
+}
+
+func (ll *LowestLatencyPolicy) ObserveRequest(id peer.ID, took time.Duration, err error) {
+	ll.lk.Lock()
+	defer ll.lk.Unlock()
+
+	avg, ok := ll.latencies[id]
+	switch {
+	case err != nil && ok:
+		ll.latencies[id] = avg * 2
+	case err != nil:
+		ll.latencies[id] = took * 2
+	case ok:
+		ll.latencies[id] = time.Duration(latencySmoothing*float64(took) + (1-latencySmoothing)*float64(avg))
+	default:
+		ll.latencies[id] = took
+	}
+}
+
+// Latency returns the average response time of the given peer and whether it was measured.
+func (ll *LowestLatencyPolicy) Latency(id peer.ID) (time.Duration, bool) {
+	ll.lk.Lock()
+	defer ll.lk.Unlock()
+	latency, ok := ll.latencies[id]
+	return latency, ok
+}
+
+// SetPeerSelectionPolicy sets the policy the peer of every request not fanned out is selected with.
+// By default, the first available peer of the pool, i.e. the trusted one, is requested.
+// A nil policy restores the default.
+func (ex *P2PExchange) SetPeerSelectionPolicy(policy PeerSelectionPolicy) {
+	ex.peersLk.Lock()
+	defer ex.peersLk.Unlock()
+	ex.policy = policy
+}
+
+// selectPeer selects the peer to request out of the given available ones with the configured policy,
+// and returns the function to report the outcome of the request with.
+func (ex *P2PExchange) selectPeer(ctx context.Context, peers []peer.ID) (peer.ID, func(error), error) {
+	ex.peersLk.RLock()
+	policy := ex.policy
+	ex.peersLk.RUnlock()
+	if policy == nil {
+		return peers[0], func(error) {}, nil
+	}
+
+	infos := make([]peer.AddrInfo, len(peers))
+	for i, id := range peers {
+		infos[i] = ex.host.Peerstore().PeerInfo(id)
+	}
+	selected, err := policy.SelectPeer(ctx, infos)
+	if err != nil {
+		return "", nil, err
+	}
+
+	observer, ok := policy.(RequestObserver)
+	if !ok {
+		return selected.ID, func(error) {}, nil
+	}
+	start := time.Now()
+	return selected.ID, func(err error) {
+		observer.ObserveRequest(selected.ID, time.Since(start), err)
+	}, nil
+}
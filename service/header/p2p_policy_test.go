@@ -0,0 +1,126 @@
+package header
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	libhost "github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestP2PExchange_RoundRobinPolicy tests that requests are spread evenly over the pool with RoundRobinPolicy.
+func TestP2PExchange_RoundRobinPolicy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	net, err := mocknet.FullMeshConnected(ctx, 4)
+	require.NoError(t, err)
+	client, servers := net.Hosts()[0], net.Hosts()[1:]
+
+	headers := NewTestSuite(t, 3).GenExtendedHeaders(5)
+	stores := make([]*countingStore, len(servers))
+	addrs := make([]peer.AddrInfo, len(servers))
+	for i, server := range servers {
+		store := NewMemStore()
+		err = store.Append(ctx, headers...)
+		require.NoError(t, err)
+		stores[i] = &countingStore{Store: store}
+
+		serv := NewP2PExchangeServer(server, stores[i])
+		err = serv.Start(ctx)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			serv.Stop(context.Background()) //nolint:errcheck
+		})
+		addrs[i] = *libhost.InfoFromHost(server)
+	}
+
+	ex := NewP2PExchange(client, nil, nil, WithPeers(addrs))
+	ex.SetPeerSelectionPolicy(NewRoundRobinPolicy())
+	err = ex.Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		ex.Stop(context.Background()) //nolint:errcheck
+	})
+
+	const requests = 9
+	for i := 0; i < requests; i++ {
+		out, err := ex.RequestHeaders(ctx, 1, 2)
+		require.NoError(t, err)
+		require.Len(t, out, 2)
+	}
+	for i, store := range stores {
+		assert.EqualValues(t, requests/len(stores), atomic.LoadInt32(&store.ranges), i)
+	}
+}
+
+func TestRoundRobinPolicy(t *testing.T) {
+	peers := []peer.AddrInfo{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	policy := NewRoundRobinPolicy()
+	for i := 0; i < 6; i++ {
+		p, err := policy.SelectPeer(context.Background(), peers)
+		require.NoError(t, err)
+		assert.Equal(t, peers[i%3].ID, p.ID)
+	}
+
+	_, err := policy.SelectPeer(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrNoPeers)
+}
+
+func TestRandomPolicy(t *testing.T) {
+	peers := []peer.AddrInfo{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	policy := NewRandomPolicy()
+	selected := make(map[peer.ID]bool)
+	for i := 0; i < 100; i++ {
+		p, err := policy.SelectPeer(context.Background(), peers)
+		require.NoError(t, err)
+		selected[p.ID] = true
+	}
+	assert.Len(t, selected, 3)
+
+	_, err := policy.SelectPeer(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrNoPeers)
+}
+
+func TestLowestLatencyPolicy(t *testing.T) {
+	peers := []peer.AddrInfo{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	policy := NewLowestLatencyPolicy()
+	selectPeer := func() peer.ID {
+		p, err := policy.SelectPeer(context.Background(), peers)
+		require.NoError(t, err)
+		return p.ID
+	}
+
+	// unmeasured peers go first
+	policy.ObserveRequest("a", time.Millisecond*30, nil)
+	assert.Equal(t, peer.ID("b"), selectPeer())
+	policy.ObserveRequest("b", time.Millisecond*10, nil)
+	policy.ObserveRequest("c", time.Millisecond*20, nil)
+	assert.Equal(t, peer.ID("b"), selectPeer())
+
+	// failures make peers slower
+	policy.ObserveRequest("b", time.Millisecond, errors.New("failed"))
+	latency, ok := policy.Latency("b")
+	require.True(t, ok)
+	assert.Equal(t, time.Millisecond*20, latency)
+	policy.ObserveRequest("b", time.Millisecond*30, nil)
+	assert.Equal(t, peer.ID("c"), selectPeer())
+}
+
+// countingStore counts the ranges of headers iterated over.
+type countingStore struct {
+	Store
+
+	ranges int32
+}
+
+func (cs *countingStore) IterateByHeight(ctx context.Context, from, to uint64) (HeaderIterator, error) {
+	atomic.AddInt32(&cs.ranges, 1)
+	return cs.Store.IterateByHeight(ctx, from, to)
+}
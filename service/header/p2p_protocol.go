@@ -0,0 +1,65 @@
+package header
+
+import (
+	"context"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+)
+
+var (
+	// exchangeProtocolID is the newest version of the exchange protocol.
+	// Its streams start with the handshake byte announcing their CompressionAlgo.
+	exchangeProtocolID = protocol.ID("/header-ex/v0.0.3")
+	// baselineExchangeProtocolID is the first released version of the exchange protocol, still spoken
+	// by the deployed nodes. Its streams serve a single request, are never compressed, and the responses
+	// are bare ExtendedHeaders without status codes, so any failure resets the stream.
+	baselineExchangeProtocolID = protocol.ID("/header-ex/v0.0.1")
+)
+
+// exchangeProtocols are the versions of the exchange protocol supported by both
+// P2PExchange and P2PExchangeServer, newest first.
+var exchangeProtocols = []protocol.ID{exchangeProtocolID, baselineExchangeProtocolID}
+
+// isBaseline reports whether the stream speaks the baseline version of the exchange protocol.
+func isBaseline(stream network.Stream) bool {
+	return stream.Protocol() == baselineExchangeProtocolID
+}
+
+// newExchangeStream opens a stream to the given peer with the first of the given protocol versions
+// the peer supports, and wraps it with the given algorithm if the version supports compression.
+func newExchangeStream(
+	ctx context.Context,
+	h host.Host,
+	to peer.ID,
+	protocols []protocol.ID,
+	algo CompressionAlgo,
+) (*compressedStream, error) {
+	raw, err := h.NewStream(ctx, to, protocols...)
+	if err != nil {
+		return nil, err
+	}
+
+	var stream *compressedStream
+	if isBaseline(raw) {
+		log.Debugw("p2p: peer supports baseline exchange protocol only", "peer", to.ShortString())
+		stream, err = newCompressedStream(raw, NoCompression)
+	} else {
+		stream, err = openStream(raw, algo)
+	}
+	if err != nil {
+		raw.Reset() //nolint:errcheck
+		return nil, err
+	}
+	return stream, nil
+}
+
+// acceptExchangeStream wraps the inbound stream according to the negotiated protocol version.
+func acceptExchangeStream(raw network.Stream) (*compressedStream, error) {
+	if isBaseline(raw) {
+		return newCompressedStream(raw, NoCompression)
+	}
+	return acceptStream(raw)
+}
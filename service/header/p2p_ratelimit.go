@@ -0,0 +1,93 @@
+package header
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// RateLimiter limits the rate of requests per remote peer with a token bucket.
+// Every peer starts with a full bucket of 'burst' tokens, every request takes one of them,
+// and one token is given back every 'refill' period.
+type RateLimiter struct {
+	burst  float64
+	refill time.Duration
+
+	lk      sync.Mutex
+	buckets map[peer.ID]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter allowing bursts of up to 'burst' requests per peer,
+// with one more request allowed every 'refill' period.
+func NewRateLimiter(burst int, refill time.Duration) *RateLimiter {
+	return &RateLimiter{
+		burst:   float64(burst),
+		refill:  refill,
+		buckets: make(map[peer.ID]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of the given peer and reports whether there was one.
+func (rl *RateLimiter) Allow(id peer.ID) bool {
+	rl.lk.Lock()
+	defer rl.lk.Unlock()
+
+	now := time.Now()
+	b, ok := rl.buckets[id]
+	if !ok {
+		b = &bucket{tokens: rl.burst, updated: now}
+		rl.buckets[id] = b
+	}
+
+	b.tokens, b.updated = rl.fill(b, now), now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// fill returns the amount of tokens in the given bucket refilled up to 'now'.
+func (rl *RateLimiter) fill(b *bucket, now time.Time) float64 {
+	if rl.refill <= 0 {
+		return rl.burst
+	}
+
+	tokens := b.tokens + float64(now.Sub(b.updated))/float64(rl.refill)
+	if tokens > rl.burst {
+		return rl.burst
+	}
+	return tokens
+}
+
+// gc periodically removes buckets which are full again, as those are no different from new ones.
+func (rl *RateLimiter) gc(ctx context.Context) {
+	if rl.refill <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(rl.refill * time.Duration(rl.burst+1))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rl.lk.Lock()
+			now := time.Now()
+			for id, b := range rl.buckets {
+				if rl.fill(b, now) >= rl.burst {
+					delete(rl.buckets, id)
+				}
+			}
+			rl.lk.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package header
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+var (
+	// DefaultScoreWindow is the default time window over which P2PExchangeServer scores peer activity.
+	DefaultScoreWindow = time.Second * 10
+	// DefaultScoreThreshold is the default peer score above which P2PExchangeServer rejects requests.
+	DefaultScoreThreshold = 100.0
+)
+
+const (
+	// requestWeight is the score added for every request of a peer.
+	requestWeight = 1.0
+	// errorWeight is the score added for every failed request of a peer.
+	errorWeight = 10.0
+)
+
+// peerScores tracks the activity of remote peers, so that those flooding the server with requests
+// or causing errors can be rejected without affecting the others.
+// Scores decay exponentially over the window, so a peer gets served again once it calms down.
+type peerScores struct {
+	window    time.Duration
+	threshold float64
+
+	lk     sync.Mutex
+	scores map[peer.ID]*score
+}
+
+type score struct {
+	value   float64
+	updated time.Time
+}
+
+func newPeerScores(window time.Duration, threshold float64) *peerScores {
+	return &peerScores{
+		window:    window,
+		threshold: threshold,
+		scores:    make(map[peer.ID]*score),
+	}
+}
+
+// Score returns the current score of the given peer.
+func (ps *peerScores) Score(id peer.ID) float64 {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	s, ok := ps.scores[id]
+	if !ok {
+		return 0
+	}
+	return ps.decay(s, time.Now())
+}
+
+// allow records a new request from the given peer and reports whether it should be served.
+// Rejected requests are still scored, so a flooding peer stays rejected until it backs off.
+func (ps *peerScores) allow(id peer.ID) bool {
+	return ps.add(id, requestWeight) <= ps.threshold
+}
+
+// penalize records a failed request from the given peer.
+func (ps *peerScores) penalize(id peer.ID) {
+	ps.add(id, errorWeight)
+}
+
+func (ps *peerScores) add(id peer.ID, weight float64) float64 {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	now := time.Now()
+	s, ok := ps.scores[id]
+	if !ok {
+		s = &score{updated: now}
+		ps.scores[id] = s
+	}
+	s.value, s.updated = ps.decay(s, now)+weight, now
+	return s.value
+}
+
+// decay returns the value of the given score decayed up to 'now'.
+func (ps *peerScores) decay(s *score, now time.Time) float64 {
+	if ps.window <= 0 {
+		return s.value
+	}
+	return s.value * math.Exp(-float64(now.Sub(s.updated))/float64(ps.window))
+}
+
+// gc periodically removes scores which decayed enough to be forgotten.
+func (ps *peerScores) gc(ctx context.Context) {
+	if ps.window <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(ps.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ps.lk.Lock()
+			now := time.Now()
+			for id, s := range ps.scores {
+				if ps.decay(s, now) < requestWeight {
+					delete(ps.scores, id)
+				}
+			}
+			ps.lk.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -2,9 +2,21 @@ package header
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	tmbytes "github.com/tendermint/tendermint/libs/bytes"
 
@@ -12,31 +24,131 @@ import (
 	"github.com/celestiaorg/go-libp2p-messenger/serde"
 )
 
+// DefaultMaxResponseSize is the default maximum amount of headers P2PExchangeServer returns for a single request.
+var DefaultMaxResponseSize uint64 = 512
+
+// P2PExchangeServerOption is a functional option that configures P2PExchangeServer.
+type P2PExchangeServerOption func(*P2PExchangeServer)
+
+// WithScoreWindow sets the time window over which the activity of a peer is scored.
+func WithScoreWindow(window time.Duration) P2PExchangeServerOption {
+	return func(serv *P2PExchangeServer) {
+		serv.scores.window = window
+	}
+}
+
+// WithScoreThreshold sets the score above which requests from a peer are rejected.
+func WithScoreThreshold(threshold float64) P2PExchangeServerOption {
+	return func(serv *P2PExchangeServer) {
+		serv.scores.threshold = threshold
+	}
+}
+
+// WithMaxResponseSize caps the amount of headers the server returns for a single range or hashes request.
+// Larger requests are truncated and the client is pointed to the height to continue from, if any.
+func WithMaxResponseSize(n uint64) P2PExchangeServerOption {
+	return func(serv *P2PExchangeServer) {
+		serv.maxResponseSize = n
+	}
+}
+
+// WithRateLimiter limits the rate of requests served per peer with the given RateLimiter.
+// Requests exceeding it are rejected with the TOO_MANY_REQUESTS status.
+func WithRateLimiter(rl *RateLimiter) P2PExchangeServerOption {
+	return func(serv *P2PExchangeServer) {
+		serv.limiter = rl
+	}
+}
+
+// WithMaxConcurrentStreams limits the amount of inbound requests served simultaneously.
+// Requests above the limit are rejected with the TOO_MANY_REQUESTS status, so clients back off.
+func WithMaxConcurrentStreams(n int) P2PExchangeServerOption {
+	return func(serv *P2PExchangeServer) {
+		serv.streams = make(chan struct{}, n)
+	}
+}
+
+// WithTracerProvider sets the TracerProvider to trace served requests with.
+// By default, the global one is used.
+func WithTracerProvider(tp trace.TracerProvider) P2PExchangeServerOption {
+	return func(serv *P2PExchangeServer) {
+		serv.tracer = tp.Tracer(tracerName)
+	}
+}
+
 // P2PExchangeServer represents the server-side component for
 // responding to inbound header-related requests.
 type P2PExchangeServer struct {
 	host  host.Host
 	store Store
 
+	// protocols are the versions of the exchange protocol served
+	protocols []protocol.ID
+
+	tracer          trace.Tracer
+	scores          *peerScores
+	limiter         *RateLimiter
+	maxResponseSize uint64
+	// streams is a semaphore of requests being served, if limited
+	streams chan struct{}
+	// active maps the open inbound streams to their peers
+	active sync.Map
+	// counters are updated atomically for every served request
+	counters *exchangeServerCounters
+
+	// head is the latest head of the store known from watching it
+	headLk sync.RWMutex
+	head   *ExtendedHeader
+
+	// inflight tracks the requests being served, so GracefulShutdown can wait for them
+	inflight sync.WaitGroup
+	// draining is closed once the server stops accepting requests.
+	// drainLk guards it together with adding to inflight and the busy flags of the streams.
+	drainLk  sync.Mutex
+	draining chan struct{}
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
 // NewP2PExchangeServer returns a new P2P server that handles inbound
 // header-related requests.
-func NewP2PExchangeServer(host host.Host, store Store) *P2PExchangeServer {
-	return &P2PExchangeServer{
-		host:  host,
-		store: store,
+func NewP2PExchangeServer(host host.Host, store Store, opts ...P2PExchangeServerOption) *P2PExchangeServer {
+	serv := &P2PExchangeServer{
+		host:            host,
+		store:           store,
+		protocols:       exchangeProtocols,
+		tracer:          otel.GetTracerProvider().Tracer(tracerName),
+		scores:          newPeerScores(DefaultScoreWindow, DefaultScoreThreshold),
+		maxResponseSize: DefaultMaxResponseSize,
+		counters:        new(exchangeServerCounters),
+		draining:        make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(serv)
+	}
+	return serv
 }
 
 // Start sets the stream handler for inbound header-related requests.
 func (serv *P2PExchangeServer) Start(context.Context) error {
 	serv.ctx, serv.cancel = context.WithCancel(context.Background())
+	heads, err := serv.store.WatchHead(serv.ctx)
+	if err != nil {
+		serv.cancel()
+		return err
+	}
+	go serv.watchHead(heads)
 	log.Info("p2p-server: listening for inbound header requests")
 
-	serv.host.SetStreamHandler(exchangeProtocolID, serv.requestHandler)
+	for _, pid := range serv.protocols {
+		serv.host.SetStreamHandler(pid, serv.requestHandler)
+	}
+	serv.host.SetStreamHandler(headHeightProtocolID, serv.heightHandler)
+	go serv.scores.gc(serv.ctx)
+	if serv.limiter != nil {
+		go serv.limiter.gc(serv.ctx)
+	}
 
 	return nil
 }
@@ -45,98 +157,487 @@ func (serv *P2PExchangeServer) Start(context.Context) error {
 func (serv *P2PExchangeServer) Stop(context.Context) error {
 	log.Info("p2p-server: stopping server")
 	serv.cancel()
-	serv.host.RemoveStreamHandler(exchangeProtocolID)
+	for _, pid := range serv.protocols {
+		serv.host.RemoveStreamHandler(pid)
+	}
+	serv.host.RemoveStreamHandler(headHeightProtocolID)
 	return nil
 }
 
+// GracefulShutdown stops the server like Stop, but lets the requests being served finish first.
+// New streams are not accepted anymore and idle streams are closed right away.
+// If the context is done before the requests are finished, they are aborted
+// and the context error is returned.
+func (serv *P2PExchangeServer) GracefulShutdown(ctx context.Context) error {
+	log.Info("p2p-server: shutting down gracefully")
+	for _, pid := range serv.protocols {
+		serv.host.RemoveStreamHandler(pid)
+	}
+	serv.host.RemoveStreamHandler(headHeightProtocolID)
+
+	serv.drainLk.Lock()
+	if !serv.isDraining() {
+		close(serv.draining)
+	}
+	serv.drainLk.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		serv.inflight.Wait()
+		close(finished)
+	}()
+
+	var err error
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		err = fmt.Errorf("p2p-server: waiting for in-flight requests: %w", ctx.Err())
+		log.Warnw("p2p-server: aborting in-flight requests", "err", ctx.Err())
+	}
+	serv.cancel()
+	return err
+}
+
+// isDraining reports whether the server stopped accepting requests.
+func (serv *P2PExchangeServer) isDraining() bool {
+	select {
+	case <-serv.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// beginRequest registers the request read from a stream as in-flight and marks the stream busy.
+// It reports false if the server is draining, so the request must not be served.
+func (serv *P2PExchangeServer) beginRequest(busy *bool) bool {
+	serv.drainLk.Lock()
+	defer serv.drainLk.Unlock()
+	if serv.isDraining() {
+		return false
+	}
+	*busy = true
+	serv.inflight.Add(1)
+	return true
+}
+
+// endRequest marks the stream idle again once the in-flight request is served.
+func (serv *P2PExchangeServer) endRequest(busy *bool) {
+	serv.drainLk.Lock()
+	*busy = false
+	serv.drainLk.Unlock()
+	serv.inflight.Done()
+}
+
+// isBusy reports whether the stream with the given flag is serving a request.
+func (serv *P2PExchangeServer) isBusy(busy *bool) bool {
+	serv.drainLk.Lock()
+	defer serv.drainLk.Unlock()
+	return *busy
+}
+
+// Score returns the current score of the given peer.
+// The higher the score, the more the peer has been loading the server.
+func (serv *P2PExchangeServer) Score(id peer.ID) float64 {
+	return serv.scores.Score(id)
+}
+
+// ActivePeers returns the peers with streams open to the server at the moment.
+func (serv *P2PExchangeServer) ActivePeers() []peer.ID {
+	seen := make(map[peer.ID]struct{})
+	var peers []peer.ID
+	serv.active.Range(func(_, v interface{}) bool {
+		id := v.(peer.ID)
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			peers = append(peers, id)
+		}
+		return true
+	})
+	return peers
+}
+
+// ExchangeServerMetrics is a snapshot of the activity of P2PExchangeServer since it was created.
+type ExchangeServerMetrics struct {
+	// RequestsHandled is the amount of requests answered successfully.
+	RequestsHandled uint64
+	// RequestsFailed is the amount of requests rejected, malformed or failed to be answered.
+	RequestsFailed uint64
+	// BytesSent is the size of all the written responses before compression.
+	BytesSent uint64
+	// AverageResponseLatency is the average time spent answering the handled requests.
+	AverageResponseLatency time.Duration
+	// ActiveStreams is the amount of inbound streams open at the moment.
+	ActiveStreams int
+}
+
+// exchangeServerCounters accumulates ExchangeServerMetrics. All the fields are accessed atomically,
+// so it is allocated separately to keep them 64-bit aligned.
+type exchangeServerCounters struct {
+	handled, failed, bytesSent uint64
+	// latency is the total time spent answering the handled requests in nanoseconds
+	latency uint64
+	streams int64
+}
+
+// Metrics returns a snapshot of the ExchangeServerMetrics.
+func (serv *P2PExchangeServer) Metrics() ExchangeServerMetrics {
+	m := ExchangeServerMetrics{
+		RequestsHandled: atomic.LoadUint64(&serv.counters.handled),
+		RequestsFailed:  atomic.LoadUint64(&serv.counters.failed),
+		BytesSent:       atomic.LoadUint64(&serv.counters.bytesSent),
+		ActiveStreams:   int(atomic.LoadInt64(&serv.counters.streams)),
+	}
+	if m.RequestsHandled > 0 {
+		latency := atomic.LoadUint64(&serv.counters.latency)
+		m.AverageResponseLatency = time.Duration(latency / m.RequestsHandled)
+	}
+	return m
+}
+
+// watchHead keeps the latest head of the store to serve head requests with.
+func (serv *P2PExchangeServer) watchHead(heads <-chan *ExtendedHeader) {
+	for h := range heads {
+		serv.headLk.Lock()
+		serv.head = h
+		serv.headLk.Unlock()
+	}
+}
+
+// getHead returns the latest known head, falling back to the store until a new head is appended.
+func (serv *P2PExchangeServer) getHead() (*ExtendedHeader, error) {
+	serv.headLk.RLock()
+	head := serv.head
+	serv.headLk.RUnlock()
+	if head != nil {
+		return head, nil
+	}
+	return serv.store.Head(serv.ctx)
+}
+
 // requestHandler handles inbound ExtendedHeaderRequests.
-func (serv *P2PExchangeServer) requestHandler(stream network.Stream) {
-	// unmarshal request
-	pbreq := new(pb.ExtendedHeaderRequest)
-	_, err := serde.Read(stream, pbreq)
+// Requests are served one after another until the requesting side closes the stream,
+// so it can reuse the stream for subsequent requests.
+func (serv *P2PExchangeServer) requestHandler(raw network.Stream) {
+	from := raw.Conn().RemotePeer()
+	serv.active.Store(raw, from)
+	defer serv.active.Delete(raw)
+	atomic.AddInt64(&serv.counters.streams, 1)
+	defer atomic.AddInt64(&serv.counters.streams, -1)
+
+	stream, err := acceptExchangeStream(raw)
 	if err != nil {
-		log.Errorw("p2p-server: reading header request from stream", "err", err)
-		stream.Reset() //nolint:errcheck
+		log.Errorw("p2p-server: accepting stream", "peer", from.ShortString(), "err", err)
+		serv.scores.penalize(from)
+		raw.Reset() //nolint:errcheck
 		return
 	}
-	// retrieve and write ExtendedHeaders
-	if pbreq.Hash != nil {
-		serv.handleRequestByHash(pbreq.Hash, stream)
-	} else {
-		serv.handleRequest(pbreq.Origin, pbreq.Origin+pbreq.Amount, stream)
+	// idle streams must not outlive the server, while the busy ones are let to finish when draining
+	ctx, done := serv.ctx, make(chan struct{})
+	defer close(done)
+	var busy bool
+	go func() {
+		draining := serv.draining
+		for {
+			select {
+			case <-draining:
+				draining = nil
+				if !serv.isBusy(&busy) {
+					raw.Reset() //nolint:errcheck
+					return
+				}
+			case <-ctx.Done():
+				raw.Reset() //nolint:errcheck
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for served := 0; ; served++ {
+		// unmarshal request
+		pbreq := new(pb.ExtendedHeaderRequest)
+		_, err = serde.Read(stream, pbreq)
+		if err != nil {
+			if served > 0 && errors.Is(err, io.EOF) {
+				break
+			}
+			if ctx.Err() == nil && !serv.isDraining() {
+				log.Errorw("p2p-server: reading header request from stream", "err", err)
+				serv.scores.penalize(from)
+				atomic.AddUint64(&serv.counters.failed, 1)
+			}
+			stream.Reset() //nolint:errcheck
+			return
+		}
+
+		if !serv.beginRequest(&busy) {
+			stream.Reset() //nolint:errcheck
+			return
+		}
+		start := time.Now()
+		if !serv.serveRequest(from, stream, pbreq) {
+			serv.endRequest(&busy)
+			atomic.AddUint64(&serv.counters.failed, 1)
+			return
+		}
+		atomic.AddUint64(&serv.counters.handled, 1)
+		atomic.AddUint64(&serv.counters.latency, uint64(time.Since(start)))
+		// the stream is not reused once the server is draining or with the baseline protocol,
+		// and the request is in-flight until the stream is closed
+		if serv.isDraining() || isBaseline(raw) {
+			break
+		}
+		serv.endRequest(&busy)
 	}
 
+	// the requesting side may have closed the stream completely by now
 	err = stream.Close()
 	if err != nil {
-		log.Errorw("while closing inbound stream", "err", err)
+		log.Debugw("p2p-server: closing inbound stream", "err", err)
+	}
+	if busy {
+		serv.endRequest(&busy)
 	}
 }
 
+// serveRequest writes the response to the given request to the stream and reports whether
+// the stream may be used for the next request. Otherwise, the stream is closed or reset.
+func (serv *P2PExchangeServer) serveRequest(
+	from peer.ID,
+	stream *compressedStream,
+	pbreq *pb.ExtendedHeaderRequest,
+) bool {
+	// continue the trace of the requesting side, if any
+	_, span := serv.tracer.Start(extractTraceContext(serv.ctx, pbreq), "p2p-server: serve request",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(requestAttributes(from.String(), pbreq)...),
+	)
+	defer span.End()
+
+	if serv.streams != nil {
+		select {
+		case serv.streams <- struct{}{}:
+			defer func() { <-serv.streams }()
+		default:
+			log.Warnw("p2p-server: too many concurrent requests", "peer", from.ShortString())
+			span.SetStatus(codes.Error, pb.StatusCode_TOO_MANY_REQUESTS.String())
+			serv.closeWithStatus(stream, pb.StatusCode_TOO_MANY_REQUESTS)
+			return false
+		}
+	}
+	if serv.limiter != nil && !serv.limiter.Allow(from) {
+		log.Warnw("p2p-server: rate limiting request", "peer", from.ShortString())
+		span.SetStatus(codes.Error, pb.StatusCode_TOO_MANY_REQUESTS.String())
+		serv.closeWithStatus(stream, pb.StatusCode_TOO_MANY_REQUESTS)
+		return false
+	}
+	if !serv.scores.allow(from) {
+		log.Warnw("p2p-server: rejecting request", "peer", from.ShortString(), "score", serv.scores.Score(from))
+		span.SetStatus(codes.Error, pb.StatusCode_TOO_MANY_REQUESTS.String())
+		serv.closeWithStatus(stream, pb.StatusCode_TOO_MANY_REQUESTS)
+		return false
+	}
+	// retrieve and write ExtendedHeaders
+	var (
+		n   int
+		err error
+	)
+	switch {
+	case len(pbreq.Hashes) > 0:
+		n, err = serv.handleRequestByHashes(pbreq.Hashes, stream)
+	case pbreq.Hash != nil && pbreq.Amount > 1:
+		n, err = serv.handleRequestSince(pbreq.Hash, pbreq.Amount, stream)
+	case pbreq.Hash != nil:
+		n, err = serv.handleRequestByHash(pbreq.Hash, stream)
+	default:
+		n, err = serv.handleRequest(pbreq.Origin, pbreq.Origin+pbreq.Amount, stream)
+	}
+	span.SetAttributes(attribute.Int("responses", n))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	switch {
+	case errors.Is(err, ErrNotFound):
+		serv.closeWithStatus(stream, pb.StatusCode_NOT_FOUND)
+		return false
+	case err != nil:
+		serv.scores.penalize(from)
+		stream.Reset() //nolint:errcheck
+		return false
+	}
+
+	err = stream.Flush()
+	if err != nil {
+		log.Errorw("p2p-server: flushing response", "err", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		stream.Reset() //nolint:errcheck
+		return false
+	}
+	return true
+}
+
 // handleRequestByHash returns the ExtendedHeader at the given hash
-// if it exists.
-func (serv *P2PExchangeServer) handleRequestByHash(hash []byte, stream network.Stream) {
+// if it exists. It reports the amount of headers written.
+func (serv *P2PExchangeServer) handleRequestByHash(hash []byte, stream network.Stream) (int, error) {
 	log.Debugw("p2p-server: handling header request", "hash", tmbytes.HexBytes(hash).String())
+	err := serv.writeHeaderByHash(hash, stream, 0)
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
 
+// handleRequestSince resolves the height of the ExtendedHeader at the given hash and writes
+// the range of ExtendedHeaders starting at it. It reports the amount of headers written.
+func (serv *P2PExchangeServer) handleRequestSince(hash []byte, amount uint64, stream network.Stream) (int, error) {
+	log.Debugw("p2p-server: handling headers request since hash", "hash", tmbytes.HexBytes(hash).String(),
+		"amount", amount)
 	header, err := serv.store.Get(serv.ctx, hash)
 	if err != nil {
 		log.Errorw("p2p-server: getting header by hash", "hash", tmbytes.HexBytes(hash).String(), "err", err)
-		stream.Reset() //nolint:errcheck
-		return
+		return 0, err
 	}
-	resp, err := ExtendedHeaderToProto(header)
-	if err != nil {
-		log.Errorw("p2p-server: marshaling header to proto", "hash", tmbytes.HexBytes(hash).String(), "err", err)
-		stream.Reset() //nolint:errcheck
-		return
+	from := uint64(header.Height)
+	return serv.handleRequest(from, from+amount, stream)
+}
+
+// handleRequestByHashes writes the ExtendedHeaders at the given hashes in the requested order.
+// The request fails if any of them does not exist. It reports the amount of headers written.
+// Like range requests, the response is truncated to the max response size, pointing the client
+// to the index of the first hash not served.
+func (serv *P2PExchangeServer) handleRequestByHashes(hashes [][]byte, stream network.Stream) (int, error) {
+	log.Debugw("p2p-server: handling headers request by hashes", "amount", len(hashes))
+	var continuation uint64
+	if serv.maxResponseSize > 0 && uint64(len(hashes)) > serv.maxResponseSize {
+		hashes = hashes[:serv.maxResponseSize]
+		continuation = serv.maxResponseSize
 	}
-	_, err = serde.Write(stream, resp)
+	for i, hash := range hashes {
+		var next uint64
+		if i == len(hashes)-1 {
+			next = continuation
+		}
+
+		err := serv.writeHeaderByHash(hash, stream, next)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(hashes), nil
+}
+
+// writeHeaderByHash writes the ExtendedHeader at the given hash to the stream.
+func (serv *P2PExchangeServer) writeHeaderByHash(hash []byte, stream network.Stream, continuation uint64) error {
+	header, err := serv.store.Get(serv.ctx, hash)
 	if err != nil {
-		log.Errorw("p2p-server: writing header to stream", "hash", tmbytes.HexBytes(hash).String(), "err", err)
-		stream.Reset() //nolint:errcheck
-		return
+		log.Errorw("p2p-server: getting header by hash", "hash", tmbytes.HexBytes(hash).String(), "err", err)
+		return err
 	}
+	return serv.writeHeader(stream, header, continuation)
 }
 
 // handleRequest fetches the ExtendedHeader at the given origin and
-// writes it to the stream.
-func (serv *P2PExchangeServer) handleRequest(from, to uint64, stream network.Stream) {
-	var headers []*ExtendedHeader
+// writes it to the stream. It reports the amount of headers written.
+func (serv *P2PExchangeServer) handleRequest(from, to uint64, stream network.Stream) (int, error) {
+	var continuation uint64
+	if serv.maxResponseSize > 0 && to-from > serv.maxResponseSize {
+		to = from + serv.maxResponseSize
+		continuation = to
+	}
+
 	if from == uint64(0) {
 		log.Debug("p2p-server: handling head request")
 
-		head, err := serv.store.Head(serv.ctx)
+		head, err := serv.getHead()
 		if err != nil {
 			log.Errorw("p2p-server: getting head", "err", err)
-			stream.Reset() //nolint:errcheck
-			return
+			return 0, err
 		}
-		headers = make([]*ExtendedHeader, 1)
-		headers[0] = head
-	} else {
-		log.Debugw("p2p-server: handling headers request", "from", from, "to", to)
-
-		headersByRange, err := serv.store.GetRangeByHeight(serv.ctx, from, to)
+		err = serv.writeHeader(stream, head, 0)
 		if err != nil {
-			log.Errorw("p2p-server: getting headers", "from", from, "to", to, "err", err)
-			stream.Reset() //nolint:errcheck
-			return
+			return 0, err
 		}
-		headers = headersByRange
+		return 1, nil
 	}
-	// write all headers to stream
-	for _, header := range headers {
-		resp, err := ExtendedHeaderToProto(header)
-		if err != nil {
-			log.Errorw("p2p-server: marshaling header to proto", "height", header.Height, "err", err)
-			stream.Reset() //nolint:errcheck
-			return
+	log.Debugw("p2p-server: handling headers request", "from", from, "to", to)
+
+	it, err := serv.store.IterateByHeight(serv.ctx, from, to)
+	if err != nil {
+		log.Errorw("p2p-server: getting headers", "from", from, "to", to, "err", err)
+		return 0, err
+	}
+	// write all headers to stream as they are read, pointing to the continuation with the last one
+	var n int
+	for it.Next() {
+		header := it.Value()
+		var next uint64
+		if uint64(header.Height) == to-1 {
+			next = continuation
 		}
 
-		_, err = serde.Write(stream, resp)
+		err = serv.writeHeader(stream, header, next)
 		if err != nil {
-			log.Errorw("p2p-server: writing header to stream", "height", header.Height, "err", err)
-			stream.Reset() //nolint:errcheck
-			return
+			it.Close() //nolint:errcheck
+			return n, err
 		}
+		n++
+	}
+
+	err = it.Close()
+	if err != nil {
+		log.Errorw("p2p-server: getting headers", "from", from, "to", to, "err", err)
 	}
+	return n, err
+}
+
+// closeWithStatus writes a response with the given status code and closes the stream.
+// The baseline protocol has no status codes, so its stream is reset instead.
+func (serv *P2PExchangeServer) closeWithStatus(stream network.Stream, code pb.StatusCode) {
+	if isBaseline(stream) {
+		stream.Reset() //nolint:errcheck
+		return
+	}
+	n, err := serde.Write(stream, &pb.ExtendedHeaderResponse{Code: code})
+	atomic.AddUint64(&serv.counters.bytesSent, uint64(n))
+	if err != nil {
+		log.Errorw("p2p-server: writing status to stream", "code", code, "err", err)
+		stream.Reset() //nolint:errcheck
+		return
+	}
+
+	err = stream.Close()
+	if err != nil {
+		log.Errorw("while closing inbound stream", "err", err)
+	}
+}
+
+// writeHeader writes the given ExtendedHeader to the stream as a successful response.
+// Non-zero 'continuation' tells the client that the response is truncated.
+// With the baseline protocol, the bare header is written, as the client ends reading on the closed stream.
+func (serv *P2PExchangeServer) writeHeader(stream network.Stream, header *ExtendedHeader, continuation uint64) error {
+	pbh, err := ExtendedHeaderToProto(header)
+	if err != nil {
+		log.Errorw("p2p-server: marshaling header to proto", "height", header.Height, "err", err)
+		return err
+	}
+
+	var resp serde.Message = &pb.ExtendedHeaderResponse{
+		Header:       pbh,
+		Code:         pb.StatusCode_OK,
+		Continuation: continuation,
+	}
+	if isBaseline(stream) {
+		resp = pbh
+	}
+	n, err := serde.Write(stream, resp)
+	atomic.AddUint64(&serv.counters.bytesSent, uint64(n))
+	if err != nil {
+		log.Errorw("p2p-server: writing header to stream", "height", header.Height, "err", err)
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,88 @@
+package header
+
+import (
+	"sync"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// streamPool keeps idle outbound streams per peer, so P2PExchange can send subsequent requests
+// over them instead of opening new ones.
+type streamPool struct {
+	maxIdle int
+
+	lk   sync.Mutex
+	idle map[peer.ID][]*compressedStream
+}
+
+func newStreamPool(maxIdle int) *streamPool {
+	return &streamPool{
+		maxIdle: maxIdle,
+		idle:    make(map[peer.ID][]*compressedStream),
+	}
+}
+
+// get takes an idle stream to the given peer out of the pool, if any.
+func (sp *streamPool) get(id peer.ID) *compressedStream {
+	sp.lk.Lock()
+	defer sp.lk.Unlock()
+
+	streams := sp.idle[id]
+	if len(streams) == 0 {
+		return nil
+	}
+	stream := streams[len(streams)-1]
+	streams[len(streams)-1] = nil
+	if len(streams) == 1 {
+		delete(sp.idle, id)
+	} else {
+		sp.idle[id] = streams[:len(streams)-1]
+	}
+	return stream
+}
+
+// put returns the stream to the given peer to the pool or closes it if the pool is full.
+func (sp *streamPool) put(id peer.ID, stream *compressedStream) {
+	sp.lk.Lock()
+	if len(sp.idle[id]) < sp.maxIdle {
+		sp.idle[id] = append(sp.idle[id], stream)
+		sp.lk.Unlock()
+		return
+	}
+	sp.lk.Unlock()
+
+	closeStream(stream)
+}
+
+// drop closes all the idle streams to the given peer.
+func (sp *streamPool) drop(id peer.ID) {
+	sp.lk.Lock()
+	streams := sp.idle[id]
+	delete(sp.idle, id)
+	sp.lk.Unlock()
+
+	for _, stream := range streams {
+		closeStream(stream)
+	}
+}
+
+// close closes all the idle streams.
+func (sp *streamPool) close() {
+	sp.lk.Lock()
+	idle := sp.idle
+	sp.idle = make(map[peer.ID][]*compressedStream)
+	sp.lk.Unlock()
+
+	for _, streams := range idle {
+		for _, stream := range streams {
+			closeStream(stream)
+		}
+	}
+}
+
+func closeStream(stream *compressedStream) {
+	err := stream.Close()
+	if err != nil {
+		log.Debugw("p2p: closing idle stream", "err", err)
+	}
+}
@@ -63,6 +63,37 @@ func (p *P2PSubscriber) Subscribe() (Subscription, error) {
 	return newSubscription(p.topic)
 }
 
+// SubscribeChan returns a channel delivering new ExtendedHeaders from the P2PSubscriber's topic.
+// The channel is closed once the given context is canceled or the subscription fails.
+func (p *P2PSubscriber) SubscribeChan(ctx context.Context) (<-chan *ExtendedHeader, error) {
+	sub, err := p.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(chan *ExtendedHeader)
+	go func() {
+		defer close(headers)
+		defer sub.Cancel()
+		for {
+			header, err := sub.NextHeader(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Errorw("p2p-subscriber: getting next header", "err", err)
+				}
+				return
+			}
+
+			select {
+			case headers <- header:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return headers, nil
+}
+
 // Broadcast broadcasts the given ExtendedHeader to the topic.
 func (p *P2PSubscriber) Broadcast(ctx context.Context, header *ExtendedHeader) error {
 	bin, err := header.MarshalBinary()
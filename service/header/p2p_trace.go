@@ -0,0 +1,76 @@
+package header
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+
+	pb "github.com/celestiaorg/celestia-node/service/header/pb"
+)
+
+// tracerName is the name of the tracer P2PExchangeServer traces requests with.
+const tracerName = "header/p2p"
+
+// traceCarrier carries the trace context within ExtendedHeaderRequests.
+type traceCarrier map[string]string
+
+func (c traceCarrier) Get(key string) string {
+	return c[key]
+}
+
+func (c traceCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c traceCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// injectTraceContext attaches the trace context of the given context to the request, if any.
+// It must be called before the request is sent, as the request must not be modified concurrently.
+func injectTraceContext(ctx context.Context, req *pb.ExtendedHeaderRequest) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	carrier := make(traceCarrier)
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	req.TraceContext = carrier
+}
+
+// extractTraceContext returns the given context continuing the trace the request is part of, if any.
+func extractTraceContext(ctx context.Context, req *pb.ExtendedHeaderRequest) context.Context {
+	if len(req.TraceContext) == 0 {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, traceCarrier(req.TraceContext))
+}
+
+// requestAttributes describes the given request for a trace span.
+func requestAttributes(from string, req *pb.ExtendedHeaderRequest) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String("peer", from)}
+	switch {
+	case len(req.Hashes) > 0:
+		attrs = append(attrs, attribute.Int("hashes", len(req.Hashes)))
+	case req.Hash != nil:
+		attrs = append(attrs, attribute.String("hash", tmbytes.HexBytes(req.Hash).String()))
+		// the range starting at the hash is requested
+		if req.Amount > 1 {
+			attrs = append(attrs, attribute.Int64("amount", int64(req.Amount)))
+		}
+	default:
+		attrs = append(attrs,
+			attribute.Int64("origin", int64(req.Origin)),
+			attribute.Int64("amount", int64(req.Amount)),
+		)
+	}
+	return attrs
+}
@@ -24,6 +24,37 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
+type StatusCode int32
+
+const (
+	StatusCode_INVALID           StatusCode = 0
+	StatusCode_OK                StatusCode = 1
+	StatusCode_NOT_FOUND         StatusCode = 2
+	StatusCode_TOO_MANY_REQUESTS StatusCode = 3
+)
+
+var StatusCode_name = map[int32]string{
+	0: "INVALID",
+	1: "OK",
+	2: "NOT_FOUND",
+	3: "TOO_MANY_REQUESTS",
+}
+
+var StatusCode_value = map[string]int32{
+	"INVALID":           0,
+	"OK":                1,
+	"NOT_FOUND":         2,
+	"TOO_MANY_REQUESTS": 3,
+}
+
+func (x StatusCode) String() string {
+	return proto.EnumName(StatusCode_name, int32(x))
+}
+
+func (StatusCode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_c13a6e9f483d098b, []int{0}
+}
+
 type ExtendedHeader struct {
 	Header       *types.Header              `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
 	Commit       *types.Commit              `protobuf:"bytes,2,opt,name=commit,proto3" json:"commit,omitempty"`
@@ -93,9 +124,17 @@ func (m *ExtendedHeader) GetDah() *da.DataAvailabilityHeader {
 }
 
 type ExtendedHeaderRequest struct {
+	// origin is the height the requested range starts at, or zero for the head.
+	// It is used only if neither hash nor hashes are set.
 	Origin uint64 `protobuf:"varint,1,opt,name=origin,proto3" json:"origin,omitempty"`
-	Hash   []byte `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
-	Amount uint64 `protobuf:"varint,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	// hash is the hash of the single requested header and takes priority over origin.
+	// If amount is above one, the range of headers starting at the hash is requested instead.
+	Hash   []byte   `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Amount uint64   `protobuf:"varint,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	Hashes [][]byte `protobuf:"bytes,4,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	// trace_context carries the W3C trace context of the requesting side, if any,
+	// so the serving side can continue the trace.
+	TraceContext map[string]string `protobuf:"bytes,5,rep,name=trace_context,json=traceContext,proto3" json:"trace_context,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (m *ExtendedHeaderRequest) Reset()         { *m = ExtendedHeaderRequest{} }
@@ -152,35 +191,128 @@ func (m *ExtendedHeaderRequest) GetAmount() uint64 {
 	return 0
 }
 
+func (m *ExtendedHeaderRequest) GetHashes() [][]byte {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+func (m *ExtendedHeaderRequest) GetTraceContext() map[string]string {
+	if m != nil {
+		return m.TraceContext
+	}
+	return nil
+}
+
+type ExtendedHeaderResponse struct {
+	Header *ExtendedHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Code   StatusCode      `protobuf:"varint,2,opt,name=code,proto3,enum=header.pb.StatusCode" json:"code,omitempty"`
+	// continuation is set on the last response of a truncated range and points
+	// to the height the remaining headers should be requested from.
+	// For requests by hashes, it points to the index of the first hash not served.
+	Continuation uint64 `protobuf:"varint,3,opt,name=continuation,proto3" json:"continuation,omitempty"`
+}
+
+func (m *ExtendedHeaderResponse) Reset()         { *m = ExtendedHeaderResponse{} }
+func (m *ExtendedHeaderResponse) String() string { return proto.CompactTextString(m) }
+func (*ExtendedHeaderResponse) ProtoMessage()    {}
+func (*ExtendedHeaderResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c13a6e9f483d098b, []int{2}
+}
+func (m *ExtendedHeaderResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ExtendedHeaderResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ExtendedHeaderResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ExtendedHeaderResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExtendedHeaderResponse.Merge(m, src)
+}
+func (m *ExtendedHeaderResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *ExtendedHeaderResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExtendedHeaderResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExtendedHeaderResponse proto.InternalMessageInfo
+
+func (m *ExtendedHeaderResponse) GetHeader() *ExtendedHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *ExtendedHeaderResponse) GetCode() StatusCode {
+	if m != nil {
+		return m.Code
+	}
+	return StatusCode_INVALID
+}
+
+func (m *ExtendedHeaderResponse) GetContinuation() uint64 {
+	if m != nil {
+		return m.Continuation
+	}
+	return 0
+}
+
 func init() {
+	proto.RegisterEnum("header.pb.StatusCode", StatusCode_name, StatusCode_value)
 	proto.RegisterType((*ExtendedHeader)(nil), "header.pb.ExtendedHeader")
 	proto.RegisterType((*ExtendedHeaderRequest)(nil), "header.pb.ExtendedHeaderRequest")
+	proto.RegisterMapType((map[string]string)(nil), "header.pb.ExtendedHeaderRequest.TraceContextEntry")
+	proto.RegisterType((*ExtendedHeaderResponse)(nil), "header.pb.ExtendedHeaderResponse")
 }
 
 func init() { proto.RegisterFile("extended_header.proto", fileDescriptor_c13a6e9f483d098b) }
 
 var fileDescriptor_c13a6e9f483d098b = []byte{
-	// 308 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x90, 0xc1, 0x4a, 0xf3, 0x40,
-	0x14, 0x85, 0x3b, 0x7f, 0x43, 0xe1, 0x1f, 0xab, 0x8b, 0x81, 0xca, 0x50, 0x64, 0x28, 0x05, 0xc1,
-	0x85, 0xa4, 0xa2, 0x0b, 0xd7, 0x5a, 0x05, 0xd7, 0x23, 0xb8, 0x71, 0x51, 0x6e, 0x9d, 0xc1, 0x0c,
-	0x34, 0x99, 0x9a, 0xdc, 0x16, 0xfb, 0x16, 0x3e, 0x96, 0xcb, 0x2e, 0x5d, 0x4a, 0xf2, 0x06, 0x3e,
-	0x81, 0x64, 0x66, 0xaa, 0x29, 0xc5, 0x4d, 0xc8, 0xe1, 0x7c, 0xe7, 0xe6, 0x9c, 0xd0, 0x9e, 0x7e,
-	0x45, 0x9d, 0x29, 0xad, 0x26, 0x89, 0x06, 0xa5, 0xf3, 0x78, 0x9e, 0x5b, 0xb4, 0xec, 0xff, 0x46,
-	0x4d, 0xfb, 0x47, 0xce, 0xcf, 0x53, 0x93, 0xe1, 0x08, 0x57, 0x73, 0x5d, 0xf8, 0xa7, 0x07, 0xfb,
-	0x83, 0x1d, 0x77, 0x09, 0x33, 0xa3, 0x00, 0x6d, 0x38, 0xd5, 0x3f, 0x6d, 0x10, 0x0a, 0x46, 0x0a,
-	0x10, 0x26, 0xb0, 0x04, 0x33, 0x83, 0xa9, 0x99, 0x19, 0x5c, 0x6d, 0x7d, 0x78, 0xf8, 0x45, 0xe8,
-	0xc1, 0x6d, 0xa8, 0x74, 0xe7, 0x0c, 0x76, 0x46, 0x3b, 0x1e, 0xe1, 0x64, 0x40, 0x4e, 0xf6, 0xce,
-	0x79, 0xfc, 0x7b, 0x31, 0xf6, 0x5d, 0x3c, 0x29, 0x03, 0x57, 0x27, 0x9e, 0x6c, 0x9a, 0x1a, 0xe4,
-	0xff, 0xfe, 0x4a, 0x8c, 0x9d, 0x2f, 0x03, 0xc7, 0xc6, 0x74, 0xff, 0xa7, 0xf7, 0xa4, 0xd0, 0xc8,
-	0xdb, 0x2e, 0x28, 0x76, 0x83, 0x0f, 0x1b, 0xec, 0x5e, 0xa3, 0xec, 0x2e, 0x1b, 0x8a, 0x5d, 0xd2,
-	0xb6, 0x82, 0x84, 0x47, 0x2e, 0x7a, 0xdc, 0x8c, 0x2a, 0x88, 0x6f, 0x00, 0xe1, 0xaa, 0x31, 0x3b,
-	0x54, 0xae, 0x13, 0xc3, 0x47, 0xda, 0xdb, 0xde, 0x2c, 0xf5, 0xcb, 0x42, 0x17, 0xc8, 0x0e, 0x69,
-	0xc7, 0xe6, 0xe6, 0xd9, 0x64, 0x6e, 0x7a, 0x24, 0x83, 0x62, 0x8c, 0x46, 0x09, 0x14, 0x89, 0x9b,
-	0xd7, 0x95, 0xee, 0xbd, 0x66, 0x21, 0xb5, 0x8b, 0xcc, 0x77, 0x8f, 0x64, 0x50, 0xd7, 0xfc, 0xbd,
-	0x14, 0x64, 0x5d, 0x0a, 0xf2, 0x59, 0x0a, 0xf2, 0x56, 0x89, 0xd6, 0xba, 0x12, 0xad, 0x8f, 0x4a,
-	0xb4, 0xa6, 0x1d, 0xf7, 0xcb, 0x2f, 0xbe, 0x03, 0x00, 0x00, 0xff, 0xff, 0x71, 0x1d, 0xa7, 0x89,
-	0x04, 0x02, 0x00, 0x00,
+	// 517 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x53, 0xd1, 0x8e, 0xd2, 0x40,
+	0x14, 0xa5, 0xb4, 0x8b, 0xe1, 0x02, 0x1b, 0x76, 0x22, 0x9b, 0x4a, 0x4c, 0x43, 0x48, 0x4c, 0xd0,
+	0x98, 0xae, 0xe2, 0x83, 0xc6, 0x17, 0x83, 0x80, 0xba, 0x51, 0x21, 0x0e, 0xec, 0x1a, 0x9f, 0x9a,
+	0x81, 0x4e, 0x64, 0x22, 0x74, 0xb0, 0xbd, 0x25, 0xcb, 0x47, 0x98, 0xf8, 0xec, 0x17, 0xf9, 0xb8,
+	0x8f, 0x3e, 0x1a, 0xf8, 0x03, 0xbf, 0xc0, 0x74, 0x5a, 0xd8, 0xb2, 0xec, 0xbe, 0x34, 0x73, 0xee,
+	0x3d, 0x67, 0xee, 0x39, 0xd3, 0x19, 0xa8, 0xf0, 0x0b, 0xe4, 0x9e, 0xcb, 0x5d, 0x67, 0xc2, 0x99,
+	0xcb, 0x7d, 0x7b, 0xee, 0x4b, 0x94, 0x24, 0xbf, 0x41, 0xa3, 0xea, 0x7d, 0xd5, 0xf7, 0x67, 0xc2,
+	0xc3, 0x13, 0x5c, 0xce, 0x79, 0x10, 0x7f, 0x63, 0x62, 0xb5, 0xb6, 0xd7, 0x5d, 0xb0, 0xa9, 0x70,
+	0x19, 0xca, 0x64, 0xab, 0xea, 0xe3, 0x14, 0xc3, 0x65, 0x27, 0x2e, 0x43, 0xe6, 0xb0, 0x05, 0x13,
+	0x53, 0x36, 0x12, 0x53, 0x81, 0xcb, 0x9d, 0xc1, 0xf5, 0x7f, 0x1a, 0x1c, 0x76, 0x13, 0x4b, 0xef,
+	0x54, 0x83, 0x3c, 0x81, 0x5c, 0x4c, 0x31, 0xb5, 0x9a, 0xd6, 0x28, 0x34, 0x4d, 0xfb, 0x6a, 0x47,
+	0x3b, 0xf6, 0x12, 0x33, 0x69, 0x6e, 0xb2, 0x55, 0x8c, 0xe5, 0x6c, 0x26, 0xd0, 0xcc, 0xde, 0xa6,
+	0x68, 0xab, 0x3e, 0x4d, 0x78, 0xa4, 0x0d, 0xa5, 0xad, 0x6f, 0x27, 0xe0, 0x68, 0xea, 0x4a, 0x68,
+	0xed, 0x0b, 0xcf, 0x37, 0xb4, 0x01, 0x47, 0x5a, 0x5c, 0xa4, 0x10, 0x79, 0x0e, 0xba, 0xcb, 0x26,
+	0xa6, 0xa1, 0xa4, 0x0f, 0xd2, 0x52, 0x97, 0xd9, 0x1d, 0x86, 0xac, 0x95, 0x8a, 0x9d, 0x58, 0x8e,
+	0x14, 0xf5, 0x1f, 0x59, 0xa8, 0xec, 0x86, 0xa6, 0xfc, 0x7b, 0xc8, 0x03, 0x24, 0xc7, 0x90, 0x93,
+	0xbe, 0xf8, 0x2a, 0x3c, 0x95, 0xdd, 0xa0, 0x09, 0x22, 0x04, 0x8c, 0x09, 0x0b, 0x26, 0x2a, 0x5f,
+	0x91, 0xaa, 0x75, 0xc4, 0x65, 0x33, 0x19, 0x7a, 0xb1, 0x79, 0x83, 0x26, 0x28, 0xaa, 0x47, 0x7d,
+	0x1e, 0x98, 0x46, 0x4d, 0x6f, 0x14, 0x69, 0x82, 0xc8, 0x67, 0x28, 0xa1, 0xcf, 0xc6, 0xdc, 0x19,
+	0x4b, 0x0f, 0xf9, 0x05, 0x9a, 0x07, 0x35, 0xbd, 0x51, 0x68, 0x36, 0xed, 0xed, 0xbf, 0xb7, 0x6f,
+	0x34, 0x65, 0x0f, 0x23, 0x55, 0x3b, 0x16, 0x75, 0x3d, 0xf4, 0x97, 0xb4, 0x88, 0xa9, 0x52, 0xf5,
+	0x15, 0x1c, 0xed, 0x51, 0x48, 0x19, 0xf4, 0x6f, 0x7c, 0xa9, 0x62, 0xe4, 0x69, 0xb4, 0x24, 0x77,
+	0xe1, 0x60, 0xc1, 0xa6, 0x21, 0x57, 0x21, 0xf2, 0x34, 0x06, 0x2f, 0xb3, 0x2f, 0xb4, 0xfa, 0x2f,
+	0x0d, 0x8e, 0xaf, 0x8f, 0x0e, 0xe6, 0xd2, 0x0b, 0x38, 0x79, 0x7a, 0xed, 0x32, 0xdc, 0xbb, 0xdd,
+	0xed, 0xe6, 0x36, 0x3c, 0x04, 0x63, 0x2c, 0xdd, 0x78, 0xcc, 0x61, 0xb3, 0x92, 0x12, 0x0c, 0x90,
+	0x61, 0x18, 0xb4, 0xa5, 0xcb, 0xa9, 0xa2, 0x90, 0x3a, 0x14, 0xa3, 0xc3, 0x10, 0x5e, 0xc8, 0x50,
+	0x48, 0x2f, 0x39, 0xc8, 0x9d, 0xda, 0xa3, 0xb7, 0x00, 0x57, 0x3a, 0x52, 0x80, 0x3b, 0xa7, 0xbd,
+	0xf3, 0xd6, 0x87, 0xd3, 0x4e, 0x39, 0x43, 0x72, 0x90, 0xed, 0xbf, 0x2f, 0x6b, 0xa4, 0x04, 0xf9,
+	0x5e, 0x7f, 0xe8, 0xbc, 0xe9, 0x9f, 0xf5, 0x3a, 0xe5, 0x2c, 0xa9, 0xc0, 0xd1, 0xb0, 0xdf, 0x77,
+	0x3e, 0xb6, 0x7a, 0x5f, 0x1c, 0xda, 0xfd, 0x74, 0xd6, 0x1d, 0x0c, 0x07, 0x65, 0xfd, 0xb5, 0xf9,
+	0x7b, 0x65, 0x69, 0x97, 0x2b, 0x4b, 0xfb, 0xbb, 0xb2, 0xb4, 0x9f, 0x6b, 0x2b, 0x73, 0xb9, 0xb6,
+	0x32, 0x7f, 0xd6, 0x56, 0x66, 0x94, 0x53, 0x6f, 0xe1, 0xd9, 0xff, 0x01, 0x00, 0x7b, 0xd0, 0x20,
+	0xa8, 0x9d, 0x03, 0x00, 0x00,
 }
 
 func (m *ExtendedHeader) Marshal() (dAtA []byte, err error) {
@@ -274,6 +406,34 @@ func (m *ExtendedHeaderRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.TraceContext) > 0 {
+		for k := range m.TraceContext {
+			v := m.TraceContext[k]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintExtendedHeader(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(k)
+			copy(dAtA[i:], k)
+			i = encodeVarintExtendedHeader(dAtA, i, uint64(len(k)))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintExtendedHeader(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x2a
+		}
+	}
+	if len(m.Hashes) > 0 {
+		for iNdEx := len(m.Hashes) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Hashes[iNdEx])
+			copy(dAtA[i:], m.Hashes[iNdEx])
+			i = encodeVarintExtendedHeader(dAtA, i, uint64(len(m.Hashes[iNdEx])))
+			i--
+			dAtA[i] = 0x22
+		}
+	}
 	if m.Amount != 0 {
 		i = encodeVarintExtendedHeader(dAtA, i, uint64(m.Amount))
 		i--
@@ -294,6 +454,51 @@ func (m *ExtendedHeaderRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *ExtendedHeaderResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ExtendedHeaderResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ExtendedHeaderResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Continuation != 0 {
+		i = encodeVarintExtendedHeader(dAtA, i, uint64(m.Continuation))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Code != 0 {
+		i = encodeVarintExtendedHeader(dAtA, i, uint64(m.Code))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Header != nil {
+		{
+			size, err := m.Header.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintExtendedHeader(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintExtendedHeader(dAtA []byte, offset int, v uint64) int {
 	offset -= sovExtendedHeader(v)
 	base := offset
@@ -346,6 +551,39 @@ func (m *ExtendedHeaderRequest) Size() (n int) {
 	if m.Amount != 0 {
 		n += 1 + sovExtendedHeader(uint64(m.Amount))
 	}
+	if len(m.Hashes) > 0 {
+		for _, b := range m.Hashes {
+			l = len(b)
+			n += 1 + l + sovExtendedHeader(uint64(l))
+		}
+	}
+	if len(m.TraceContext) > 0 {
+		for k, v := range m.TraceContext {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovExtendedHeader(uint64(len(k))) + 1 + len(v) + sovExtendedHeader(uint64(len(v)))
+			n += mapEntrySize + 1 + sovExtendedHeader(uint64(mapEntrySize))
+		}
+	}
+	return n
+}
+
+func (m *ExtendedHeaderResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Header != nil {
+		l = m.Header.Size()
+		n += 1 + l + sovExtendedHeader(uint64(l))
+	}
+	if m.Code != 0 {
+		n += 1 + sovExtendedHeader(uint64(m.Code))
+	}
+	if m.Continuation != 0 {
+		n += 1 + sovExtendedHeader(uint64(m.Continuation))
+	}
 	return n
 }
 
@@ -650,6 +888,289 @@ func (m *ExtendedHeaderRequest) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Hashes", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExtendedHeader
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthExtendedHeader
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthExtendedHeader
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Hashes = append(m.Hashes, make([]byte, postIndex-iNdEx))
+			copy(m.Hashes[len(m.Hashes)-1], dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TraceContext", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExtendedHeader
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthExtendedHeader
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthExtendedHeader
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.TraceContext == nil {
+				m.TraceContext = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowExtendedHeader
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowExtendedHeader
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthExtendedHeader
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthExtendedHeader
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowExtendedHeader
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthExtendedHeader
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthExtendedHeader
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipExtendedHeader(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if (skippy < 0) || (iNdEx+skippy) < 0 {
+						return ErrInvalidLengthExtendedHeader
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.TraceContext[mapkey] = mapvalue
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipExtendedHeader(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthExtendedHeader
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ExtendedHeaderResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowExtendedHeader
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ExtendedHeaderResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ExtendedHeaderResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Header", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExtendedHeader
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthExtendedHeader
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthExtendedHeader
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Header == nil {
+				m.Header = &ExtendedHeader{}
+			}
+			if err := m.Header.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			}
+			m.Code = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExtendedHeader
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Code |= StatusCode(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Continuation", wireType)
+			}
+			m.Continuation = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExtendedHeader
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Continuation |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipExtendedHeader(dAtA[iNdEx:])
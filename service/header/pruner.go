@@ -0,0 +1,54 @@
+package header
+
+import (
+	"context"
+	"time"
+)
+
+// Pruner periodically prunes the Store, so only the given amount of the latest headers is kept.
+type Pruner struct {
+	store    Store
+	interval time.Duration
+	keepLast uint64
+
+	cancel context.CancelFunc
+}
+
+// NewPruner creates a new Pruner.
+func NewPruner(store Store, interval time.Duration, keepLast uint64) *Pruner {
+	return &Pruner{
+		store:    store,
+		interval: interval,
+		keepLast: keepLast,
+	}
+}
+
+// Start starts the pruning routine.
+func (p *Pruner) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.prune(ctx)
+	return nil
+}
+
+// Stop stops the pruning routine.
+func (p *Pruner) Stop(context.Context) error {
+	p.cancel()
+	return nil
+}
+
+func (p *Pruner) prune(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := p.store.Prune(ctx, p.keepLast)
+			if err != nil && ctx.Err() == nil {
+				log.Errorw("pruning headers", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
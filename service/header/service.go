@@ -2,17 +2,32 @@ package header
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	logging "github.com/ipfs/go-log/v2"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
 )
 
 var log = logging.Logger("header-service")
 
+// ErrUnverifiable is returned by Service when a header received from the network cannot be verified
+// against the stored chain, e.g. as it is too far ahead of the head.
+var ErrUnverifiable = errors.New("header: cannot verify against stored chain")
+
+// syncRetryInterval is the interval SyncToHeight requests the missing headers again at,
+// while the network does not have them yet.
+var syncRetryInterval = time.Second * 5
+
 // Service represents the header service that can be started / stopped on a node.
 // Service's main function is to manage its sub-services. Service can contain several
 // sub-services, such as Exchange, P2PExchangeServer, Syncer, and so forth.
+// It is the entry point for getting headers on a node: headers missing in the Store are requested
+// with the Exchange and stored once verified.
 type Service struct {
-	ex Exchange
+	ex    Exchange
+	store Store
 
 	syncer        *Syncer
 	p2pSubscriber *P2PSubscriber
@@ -24,12 +39,14 @@ func NewHeaderService(
 	syncer *Syncer,
 	p2pSub *P2PSubscriber,
 	p2pServer *P2PExchangeServer,
-	ex Exchange) *Service {
+	ex Exchange,
+	store Store) *Service {
 	return &Service{
 		syncer:        syncer,
 		p2pSubscriber: p2pSub,
 		p2pServer:     p2pServer,
 		ex:            ex,
+		store:         store,
 	}
 }
 
@@ -44,3 +61,222 @@ func (s *Service) Stop(context.Context) error {
 	log.Info("stopping header service")
 	return nil
 }
+
+// Head returns the stored head, requesting it from the network if the Store is empty.
+func (s *Service) Head(ctx context.Context) (*ExtendedHeader, error) {
+	head, err := s.store.Head(ctx)
+	if !errors.Is(err, ErrNoHead) {
+		return head, err
+	}
+
+	head, err = s.ex.RequestHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.storeHeader(ctx, head)
+}
+
+// GetByHeight returns the header at the given height, requesting it from the network
+// if it is not stored.
+func (s *Service) GetByHeight(ctx context.Context, height uint64) (*ExtendedHeader, error) {
+	h, err := s.store.GetByHeight(ctx, height)
+	if !errors.Is(err, ErrNotFound) {
+		return h, err
+	}
+
+	log.Debugw("requesting missing header", "height", height)
+	h, err = s.ex.RequestHeader(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(h.Height) != height {
+		return nil, fmt.Errorf("%w: requested height %d, got %d", ErrInvalidResponse, height, h.Height)
+	}
+	return s.storeHeader(ctx, h)
+}
+
+// GetByHash returns the header with the given hash, requesting it from the network if it is not stored.
+func (s *Service) GetByHash(ctx context.Context, hash tmbytes.HexBytes) (*ExtendedHeader, error) {
+	h, err := s.store.Get(ctx, hash)
+	if !errors.Is(err, ErrNotFound) {
+		return h, err
+	}
+
+	log.Debugw("requesting missing header", "hash", hash)
+	h, err = s.ex.RequestByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return s.storeHeader(ctx, h)
+}
+
+// SyncStatus describes how far the Store is synced with the network.
+type SyncStatus struct {
+	// Height is the height of the stored head, zero if there is none yet.
+	Height uint64
+	// NetworkHeight is the height of the latest head known from the network, zero if none is known yet.
+	NetworkHeight uint64
+	// Syncing is set while the Syncer is catching up with the network.
+	Syncing bool
+}
+
+// Synced reports whether the Store caught up with the latest head known from the network.
+func (st SyncStatus) Synced() bool {
+	return !st.Syncing && st.NetworkHeight != 0 && st.Height >= st.NetworkHeight
+}
+
+// SyncStatus returns the current SyncStatus of the Service. Unlike Head, it never requests the network.
+func (s *Service) SyncStatus(ctx context.Context) (SyncStatus, error) {
+	var status SyncStatus
+	head, err := s.store.Head(ctx)
+	switch err {
+	case nil:
+		status.Height = uint64(head.Height)
+	case ErrNoHead:
+	default:
+		return SyncStatus{}, err
+	}
+
+	if s.syncer != nil {
+		status.NetworkHeight = s.syncer.NetworkHeight()
+		status.Syncing = s.syncer.IsSyncing()
+	}
+	return status, nil
+}
+
+// SyncToHeight blocks until the head of the Store reaches the given height, requesting the missing
+// headers from the network as needed. Headers appended by others, e.g. the Syncer, count as well.
+func (s *Service) SyncToHeight(ctx context.Context, height uint64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	heads, err := s.store.WatchHead(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// the head is checked anyway, so the pending notification about it is dropped
+		select {
+		case <-heads:
+		default:
+		}
+
+		head, err := s.Head(ctx)
+		if err != nil {
+			return err
+		}
+		if uint64(head.Height) >= height {
+			return nil
+		}
+
+		err = s.requestAbove(ctx, head, height)
+		switch {
+		case err == nil:
+			// the progress was made, so keep requesting right away
+			continue
+		case ctx.Err() != nil:
+			return ctx.Err()
+		}
+		log.Debugw("requesting headers to sync to height", "height", height, "head", head.Height, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-heads:
+		case <-time.After(syncRetryInterval):
+		}
+	}
+}
+
+// WaitForHeight returns the header at the given height, blocking until it is stored if it is not yet.
+// Unlike GetByHeight, it never requests the network and relies on others, e.g. the Syncer, to store the header.
+func (s *Service) WaitForHeight(ctx context.Context, height uint64) (*ExtendedHeader, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// watch before checking the store, so the header stored in between is not missed
+	heads, err := s.store.WatchHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := s.store.GetByHeight(ctx, height)
+	if !errors.Is(err, ErrNotFound) {
+		return h, err
+	}
+
+	log.Debugw("waiting for header", "height", height)
+	for {
+		select {
+		case head, ok := <-heads:
+			if !ok {
+				return nil, ctx.Err()
+			}
+			switch {
+			case uint64(head.Height) == height:
+				return head, nil
+			case uint64(head.Height) > height:
+				// the head skipped the height, as only the latest head is kept for slow watchers
+				return s.store.GetByHeight(ctx, height)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// requestAbove requests headers above the given head up to the given height and appends them.
+// It errors if none of them could be appended.
+func (s *Service) requestAbove(ctx context.Context, head *ExtendedHeader, height uint64) error {
+	from := uint64(head.Height) + 1
+	amount := height - from + 1
+	if amount > requestSize {
+		amount = requestSize
+	}
+
+	headers, err := s.ex.RequestHeaders(ctx, from, amount)
+	if err != nil {
+		return err
+	}
+	if len(headers) == 0 {
+		return ErrNotFound
+	}
+
+	err = s.store.Append(ctx, headers...)
+	if err != nil {
+		return err
+	}
+	// the Store skips headers it cannot verify
+	has, err := s.store.Has(ctx, headers[0].Hash())
+	if err != nil {
+		return err
+	}
+	if !has {
+		return fmt.Errorf("%w: header at height %d", ErrUnverifiable, headers[0].Height)
+	}
+	return nil
+}
+
+// storeHeader validates the header received from the network and stores it.
+// The Store verifies the header against the stored chain, so it is returned only once stored.
+func (s *Service) storeHeader(ctx context.Context, h *ExtendedHeader) (*ExtendedHeader, error) {
+	err := h.ValidateBasic()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+	}
+
+	err = s.store.Append(ctx, h)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnverifiable, err)
+	}
+	// the Store skips headers it cannot verify
+	has, err := s.store.Has(ctx, h.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, fmt.Errorf("%w: header at height %d", ErrUnverifiable, h.Height)
+	}
+	return h, nil
+}
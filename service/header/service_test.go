@@ -0,0 +1,225 @@
+package header
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+func TestService_Getters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	in := suite.GenExtendedHeaders(10)
+	remote := NewMemStore()
+	err := remote.Append(ctx, in...)
+	require.NoError(t, err)
+
+	local := NewMemStore()
+	err = local.Append(ctx, in[:5]...)
+	require.NoError(t, err)
+
+	ex := &countingExchange{Exchange: NewLocalExchange(remote)}
+	serv := NewHeaderService(nil, nil, nil, ex, local)
+
+	// stored headers are served locally
+	h, err := serv.GetByHeight(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, in[2].Hash(), h.Hash())
+	h, err = serv.GetByHash(ctx, in[1].Hash())
+	require.NoError(t, err)
+	assert.Equal(t, in[1].Hash(), h.Hash())
+	head, err := serv.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[4].Hash(), head.Hash())
+	assert.Zero(t, atomic.LoadInt32(&ex.requests))
+
+	// missing headers are requested and stored
+	h, err = serv.GetByHeight(ctx, 6)
+	require.NoError(t, err)
+	assert.Equal(t, in[5].Hash(), h.Hash())
+	h, err = serv.GetByHash(ctx, in[6].Hash())
+	require.NoError(t, err)
+	assert.Equal(t, in[6].Hash(), h.Hash())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&ex.requests))
+
+	head, err = local.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[6].Hash(), head.Hash())
+
+	// headers not linking to the stored chain are not returned
+	_, err = serv.GetByHeight(ctx, 10)
+	assert.ErrorIs(t, err, ErrUnverifiable)
+	has, err := local.Has(ctx, in[9].Hash())
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	// the empty store gets the head from the network
+	serv = NewHeaderService(nil, nil, nil, ex, NewMemStore())
+	head, err = serv.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[9].Hash(), head.Hash())
+}
+
+func TestService_SyncToHeight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	in := suite.GenExtendedHeaders(12)
+	remote := NewMemStore()
+	err := remote.Append(ctx, in[:8]...)
+	require.NoError(t, err)
+
+	local := NewMemStore()
+	err = local.Append(ctx, in[:2]...)
+	require.NoError(t, err)
+	serv := NewHeaderService(nil, nil, nil, NewLocalExchange(remote), local)
+
+	err = serv.SyncToHeight(ctx, 8)
+	require.NoError(t, err)
+	head, err := local.Head(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, uint64(head.Height), uint64(8))
+
+	// the network does not have the target yet, so it blocks until the store reaches it
+	done := make(chan error, 1)
+	go func() {
+		done <- serv.SyncToHeight(ctx, 12)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("returned before reaching the target: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	for _, h := range in[8:] {
+		err = local.AppendSingle(ctx, h)
+		require.NoError(t, err)
+	}
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	// the context is respected
+	ctx, cancel = context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err = serv.SyncToHeight(ctx, 20)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestService_WaitForHeight(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	in := NewTestSuite(t, 3).GenExtendedHeaders(5)
+	store := NewMemStore()
+	serv := NewHeaderService(nil, nil, nil, NewLocalExchange(NewMemStore()), store)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		for _, h := range in {
+			store.AppendSingle(ctx, h) //nolint:errcheck
+		}
+	}()
+	h, err := serv.WaitForHeight(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, in[2].Hash(), h.Hash())
+
+	// stored headers are returned right away
+	h, err = serv.WaitForHeight(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, in[0].Hash(), h.Hash())
+
+	// the context is respected
+	waitCtx, waitCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer waitCancel()
+	_, err = serv.WaitForHeight(waitCtx, 20)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestService_SyncStatus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	in := suite.GenExtendedHeaders(10)
+	remote := NewMemStore()
+	err := remote.Append(ctx, in...)
+	require.NoError(t, err)
+
+	local := NewMemStore()
+	syncer := NewSyncer(NewLocalExchange(remote), local, in[0].Hash())
+	serv := NewHeaderService(syncer, nil, nil, NewLocalExchange(remote), local)
+
+	// nothing is known before syncing
+	status, err := serv.SyncStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SyncStatus{}, status)
+	assert.False(t, status.Synced())
+
+	syncer.Sync(ctx)
+	status, err = serv.SyncStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SyncStatus{Height: 10, NetworkHeight: 10}, status)
+	assert.True(t, status.Synced())
+
+	// the service without a syncer knows only about its store
+	serv = NewHeaderService(nil, nil, nil, NewLocalExchange(remote), remote)
+	status, err = serv.SyncStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SyncStatus{Height: 10}, status)
+	assert.False(t, status.Synced())
+}
+
+// countingExchange counts all the requests.
+type countingExchange struct {
+	Exchange
+	requests int32
+}
+
+func (c *countingExchange) RequestHead(ctx context.Context) (*ExtendedHeader, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return c.Exchange.RequestHead(ctx)
+}
+
+func (c *countingExchange) RequestHeader(ctx context.Context, height uint64) (*ExtendedHeader, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return c.Exchange.RequestHeader(ctx, height)
+}
+
+func (c *countingExchange) RequestByHash(ctx context.Context, hash tmbytes.HexBytes) (*ExtendedHeader, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return c.Exchange.RequestByHash(ctx, hash)
+}
+
+func (c *countingExchange) RequestHeaders(ctx context.Context, origin, amount uint64) ([]*ExtendedHeader, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return c.Exchange.RequestHeaders(ctx, origin, amount)
+}
+
+func (c *countingExchange) RequestHeaderSince(
+	ctx context.Context,
+	hash tmbytes.HexBytes,
+	amount uint64,
+) ([]*ExtendedHeader, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return c.Exchange.RequestHeaderSince(ctx, hash, amount)
+}
+
+func (c *countingExchange) RequestHeadersByHashes(
+	ctx context.Context,
+	hashes []tmbytes.HexBytes,
+) ([]*ExtendedHeader, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return c.Exchange.RequestHeadersByHashes(ctx, hashes)
+}
@@ -0,0 +1,157 @@
+package header
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/celestiaorg/go-libp2p-messenger/serde"
+
+	pb "github.com/celestiaorg/celestia-node/service/header/pb"
+)
+
+// snapshotMagic starts every snapshot to distinguish it from arbitrary data.
+var snapshotMagic = []byte("CELESTIA-HEADERS")
+
+// snapshotVersion is the version of the snapshot format written by ExportSnapshot.
+// The format is the magic and the version byte followed by length-prefixed protobuf encoded headers
+// in ascending order.
+const snapshotVersion byte = 1
+
+// snapshotBatchSize is the amount of headers written to the Store at once.
+var snapshotBatchSize uint64 = 256
+
+// ErrInvalidSnapshot is returned when a snapshot is malformed or contains an invalid chain of headers.
+var ErrInvalidSnapshot = errors.New("header/snapshot: invalid snapshot")
+
+// ExportSnapshot writes the range [from:to) of ExtendedHeaders kept by the given Store
+// to the given Writer, so it can be imported by another node with ImportSnapshot.
+func ExportSnapshot(ctx context.Context, store Store, w io.Writer, from, to uint64) error {
+	if from == 0 || from >= to {
+		return fmt.Errorf("header/snapshot: invalid range [%d:%d)", from, to)
+	}
+
+	bw := bufio.NewWriter(w)
+	_, err := bw.Write(snapshotMagic)
+	if err != nil {
+		return err
+	}
+	err = bw.WriteByte(snapshotVersion)
+	if err != nil {
+		return err
+	}
+
+	it, err := store.IterateByHeight(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	for it.Next() {
+		msg, err := ExtendedHeaderToProto(it.Value())
+		if err != nil {
+			it.Close() //nolint:errcheck
+			return err
+		}
+
+		_, err = serde.Write(bw, msg)
+		if err != nil {
+			it.Close() //nolint:errcheck
+			return err
+		}
+	}
+
+	err = it.Close()
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ImportSnapshot reads ExtendedHeaders written by ExportSnapshot from the given Reader
+// and appends them to the given Store.
+// The chain of headers is verified with DefaultValidator, starting from the Store's head, if any.
+// Headers not higher than the head are skipped. Import stops on the first invalid header,
+// while the preceding ones are kept.
+func ImportSnapshot(ctx context.Context, store Store, r io.Reader) error {
+	br := bufio.NewReader(r)
+	prefix := make([]byte, len(snapshotMagic)+1)
+	_, err := io.ReadFull(br, prefix)
+	if err != nil {
+		return fmt.Errorf("%w: reading magic: %s", ErrInvalidSnapshot, err)
+	}
+	if !bytes.Equal(prefix[:len(snapshotMagic)], snapshotMagic) {
+		return fmt.Errorf("%w: unknown magic", ErrInvalidSnapshot)
+	}
+	if version := prefix[len(snapshotMagic)]; version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, version)
+	}
+
+	trusted, err := store.Head(ctx)
+	if err != nil && !errors.Is(err, ErrNoHead) {
+		return err
+	}
+
+	batch := make([]*ExtendedHeader, 0, snapshotBatchSize)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		msg := &pb.ExtendedHeader{}
+		_, err = serde.Read(br, msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: reading header: %s", ErrInvalidSnapshot, err)
+		}
+
+		h, err := ProtoToExtendedHeader(msg)
+		if err != nil {
+			return fmt.Errorf("%w: decoding header: %s", ErrInvalidSnapshot, err)
+		}
+		if trusted != nil && h.Height <= trusted.Height {
+			continue
+		}
+
+		err = verifySnapshotLink(ctx, h, trusted)
+		if err != nil {
+			// keep the valid part of the chain
+			if appendErr := store.Append(ctx, batch...); appendErr != nil {
+				return appendErr
+			}
+			return err
+		}
+
+		batch, trusted = append(batch, h), h
+		if uint64(len(batch)) == snapshotBatchSize {
+			err = store.Append(ctx, batch...)
+			if err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	return store.Append(ctx, batch...)
+}
+
+// verifySnapshotLink checks the header is valid and directly follows the trusted one, if given.
+func verifySnapshotLink(ctx context.Context, h, trusted *ExtendedHeader) error {
+	err := h.ValidateBasic()
+	if err != nil {
+		return fmt.Errorf("%w: header at height %d: %s", ErrInvalidSnapshot, h.Height, err)
+	}
+
+	if trusted != nil && h.Height != trusted.Height+1 {
+		return fmt.Errorf("%w: header at height %d does not follow %d", ErrInvalidSnapshot, h.Height, trusted.Height)
+	}
+
+	err = DefaultValidator.Validate(ctx, h, trusted)
+	if err != nil {
+		return fmt.Errorf("%w: header at height %d: %s", ErrInvalidSnapshot, h.Height, err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package header
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// make sure several batches are written and read
+	batchSize := snapshotBatchSize
+	snapshotBatchSize = 3
+	t.Cleanup(func() {
+		snapshotBatchSize = batchSize
+	})
+
+	suite := NewTestSuite(t, 3)
+	genesis := suite.Head()
+	from, err := NewStoreWithHead(sync.MutexWrap(datastore.NewMapDatastore()), genesis)
+	require.NoError(t, err)
+	in := suite.GenExtendedHeaders(10)
+	err = from.Append(ctx, in...)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	err = ExportSnapshot(ctx, from, buf, 1, 11)
+	require.NoError(t, err)
+	snapshot := buf.Bytes()
+
+	tests := []struct {
+		name string
+		head *ExtendedHeader
+	}{
+		{name: "empty store"},
+		{name: "store with genesis", head: genesis},
+		// the headers the store already has are skipped
+		{name: "store with head", head: in[4]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := sync.MutexWrap(datastore.NewMapDatastore())
+			to, err := NewStore(ds)
+			require.NoError(t, err)
+			if tt.head != nil {
+				to, err = NewStoreWithHead(ds, tt.head)
+				require.NoError(t, err)
+			}
+
+			err = ImportSnapshot(ctx, to, bytes.NewReader(snapshot))
+			require.NoError(t, err)
+
+			head, err := to.Head(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, in[len(in)-1].Hash(), head.Hash())
+			for _, h := range in {
+				if tt.head != nil && h.Height < tt.head.Height {
+					continue
+				}
+				out, err := to.GetByHeight(ctx, uint64(h.Height))
+				require.NoError(t, err)
+				assert.Equal(t, h.Hash(), out.Hash())
+			}
+		})
+	}
+}
+
+func TestSnapshot_InvalidLink(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := NewTestSuite(t, 3).GenExtendedHeaders(6)
+	// replace the header at height 4 with one from another chain
+	in[3] = NewTestSuite(t, 3).GenExtendedHeaders(4)[3]
+	// bypass verification on Append to keep the invalid chain
+	from := NewMemStore().(*memStore)
+	from.put(in...)
+
+	buf := &bytes.Buffer{}
+	err := ExportSnapshot(ctx, from, buf, 1, 7)
+	require.NoError(t, err)
+
+	to, err := NewStore(sync.MutexWrap(datastore.NewMapDatastore()))
+	require.NoError(t, err)
+	err = ImportSnapshot(ctx, to, buf)
+	assert.ErrorIs(t, err, ErrInvalidSnapshot)
+	assert.Contains(t, err.Error(), "height 4")
+
+	// the valid part of the chain is kept
+	head, err := to.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[2].Hash(), head.Hash())
+}
+
+func TestSnapshot_InvalidFormat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store, err := NewStore(sync.MutexWrap(datastore.NewMapDatastore()))
+	require.NoError(t, err)
+
+	tests := map[string][]byte{
+		"empty":           {},
+		"unknown magic":   append([]byte("NOT-THE-HEADERS!"), snapshotVersion),
+		"unknown version": append(append([]byte{}, snapshotMagic...), snapshotVersion+1),
+		"malformed":       append(append([]byte{}, snapshotMagic...), snapshotVersion, 3, 1, 2),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ImportSnapshot(ctx, store, bytes.NewReader(data))
+			assert.ErrorIs(t, err, ErrInvalidSnapshot)
+		})
+	}
+
+	_, err = store.Head(ctx)
+	assert.ErrorIs(t, err, ErrNoHead)
+}
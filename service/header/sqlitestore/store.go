@@ -0,0 +1,443 @@
+// Package sqlitestore provides a header.Store keeping headers in an SQLite database.
+// It is kept apart from the header package, as it requires cgo.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	logging "github.com/ipfs/go-log/v2"
+	// registers the "sqlite3" driver
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/tendermint/tendermint/libs/bytes"
+
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+var log = logging.Logger("header/sqlitestore")
+
+// sqliteSchema is the schema of the Store. Heights and hashes are both indexed, so headers
+// can be queried with plain SQL, e.g. SELECT * FROM headers WHERE height BETWEEN 100 AND 200.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS headers (
+	height INTEGER PRIMARY KEY,
+	hash   BLOB    NOT NULL UNIQUE,
+	data   BLOB    NOT NULL
+);
+CREATE TABLE IF NOT EXISTS deleted (
+	height INTEGER PRIMARY KEY
+);`
+
+// Store is a header.Store keeping ExtendedHeaders in an SQLite database,
+// as binary encoded headers along with their heights and hashes.
+// It follows the same semantics as the datastore backed header.Store: the first appended headers are trusted,
+// while the following ones are verified.
+type Store struct {
+	db *sql.DB
+
+	// writeLk serializes writes, so that headers are verified against the stored ones
+	// and new heads are published in order
+	writeLk sync.Mutex
+	heads   header.HeadBroadcaster
+}
+
+var _ header.Store = (*Store)(nil)
+
+// NewStore opens the SQLite database at the given path, creating it if needed,
+// and constructs a Store over it, which must be closed.
+func NewStore(path string) (*Store, error) {
+	// WAL lets reads proceed while headers are written
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000", path))
+	if err != nil {
+		return nil, fmt.Errorf("header/sqlitestore: opening sqlite: %w", err)
+	}
+	_, err = db.Exec(sqliteSchema)
+	if err != nil {
+		db.Close() //nolint:errcheck
+		return nil, fmt.Errorf("header/sqlitestore: creating sqlite schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) Head(ctx context.Context) (*header.ExtendedHeader, error) {
+	h, err := s.queryOne(ctx, "SELECT data FROM headers ORDER BY height DESC LIMIT 1")
+	if errors.Is(err, header.ErrNotFound) {
+		return nil, header.ErrNoHead
+	}
+	return h, err
+}
+
+func (s *Store) Tail(ctx context.Context) (*header.ExtendedHeader, error) {
+	h, err := s.queryOne(ctx, "SELECT data FROM headers ORDER BY height ASC LIMIT 1")
+	if errors.Is(err, header.ErrNotFound) {
+		return nil, header.ErrNoHead
+	}
+	return h, err
+}
+
+func (s *Store) Get(ctx context.Context, hash bytes.HexBytes) (*header.ExtendedHeader, error) {
+	return s.queryOne(ctx, "SELECT data FROM headers WHERE hash = ?", []byte(hash))
+}
+
+func (s *Store) GetByHeight(ctx context.Context, height uint64) (*header.ExtendedHeader, error) {
+	return s.queryOne(ctx, "SELECT data FROM headers WHERE height = ?", sqlHeight(height))
+}
+
+func (s *Store) GetByHashPrefix(ctx context.Context, prefix []byte) ([]*header.ExtendedHeader, error) {
+	if len(prefix) == 0 {
+		return nil, nil
+	}
+
+	// the prefix is turned into a range of hashes, so the hash index is used
+	query, args := "SELECT data FROM headers WHERE hash >= ?", []interface{}{prefix}
+	if upper := prefixUpperBound(prefix); upper != nil {
+		query, args = query+" AND hash < ?", append(args, upper)
+	}
+	query, args = query+" ORDER BY height LIMIT ?", append(args, header.MaxHashPrefixMatches)
+	return s.queryAll(ctx, query, args...)
+}
+
+func (s *Store) GetRangeByHeight(ctx context.Context, from, to uint64) ([]*header.ExtendedHeader, error) {
+	if from >= to {
+		return []*header.ExtendedHeader{}, nil
+	}
+
+	headers, err := s.queryAll(ctx, "SELECT data FROM headers WHERE height >= ? AND height < ? ORDER BY height",
+		sqlHeight(from), sqlHeight(to))
+	if err != nil {
+		return headers, err
+	}
+	// the range must be fully stored
+	if uint64(len(headers)) != to-from {
+		return nil, header.ErrNotFound
+	}
+	return headers, nil
+}
+
+func (s *Store) GetByHeightRange(ctx context.Context, from, to uint64) ([]*header.ExtendedHeader, error) {
+	return header.GetByHeightRange(ctx, s, from, to)
+}
+
+func (s *Store) IterateByHeight(ctx context.Context, from, to uint64) (header.HeaderIterator, error) {
+	return header.NewHeightIterator(ctx, s.GetByHeight, from, to)
+}
+
+func (s *Store) ForEach(ctx context.Context, fn func(*header.ExtendedHeader) error) error {
+	return header.ForEach(ctx, s, fn)
+}
+
+func (s *Store) Has(ctx context.Context, hash bytes.HexBytes) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM headers WHERE hash = ?", []byte(hash)).Scan(&one)
+	switch err {
+	case nil:
+		return true, nil
+	case sql.ErrNoRows:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (s *Store) HasAt(ctx context.Context, height uint64) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM headers WHERE height = ?", sqlHeight(height)).Scan(&one)
+	switch err {
+	case nil:
+		return true, nil
+	case sql.ErrNoRows:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (s *Store) CountHeaders(ctx context.Context) (uint64, error) {
+	var count uint64
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM headers").Scan(&count)
+	return count, err
+}
+
+func (s *Store) AppendSingle(ctx context.Context, h *header.ExtendedHeader) error {
+	return header.AppendSingle(ctx, s, h)
+}
+
+func (s *Store) Append(ctx context.Context, headers ...*header.ExtendedHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	s.writeLk.Lock()
+	defer s.writeLk.Unlock()
+
+	head, err := s.Head(ctx)
+	switch err {
+	case nil:
+	case header.ErrNoHead:
+		// trust the given headers as the initial ones
+		err = s.put(ctx, headers...)
+		if err != nil {
+			return err
+		}
+		s.heads.Publish(headers...)
+		return nil
+	default:
+		return err
+	}
+
+	// headers below the head fill a gap in the stored chain
+	if headers[0].Height < head.Height {
+		return s.fill(ctx, head, headers)
+	}
+
+	verified := header.VerifyAppend(head, headers)
+	if len(verified) == 0 {
+		log.Warn("header/sqlitestore: no valid headers were given")
+		return nil
+	}
+
+	err = s.put(ctx, verified...)
+	if err != nil {
+		return err
+	}
+	s.heads.Publish(verified...)
+	return nil
+}
+
+func (s *Store) WatchHead(ctx context.Context) (<-chan *header.ExtendedHeader, error) {
+	return s.heads.Watch(ctx)
+}
+
+func (s *Store) Prune(ctx context.Context, keepLast uint64) error {
+	if keepLast == 0 {
+		return fmt.Errorf("header/sqlitestore: at least one header must be kept")
+	}
+
+	s.writeLk.Lock()
+	defer s.writeLk.Unlock()
+
+	head, err := s.Head(ctx)
+	switch err {
+	case nil:
+	case header.ErrNoHead:
+		return nil
+	default:
+		return err
+	}
+	if uint64(head.Height) <= keepLast {
+		return nil
+	}
+
+	_, err = s.db.ExecContext(ctx, "DELETE FROM headers WHERE height < ?", head.Height-int64(keepLast)+1)
+	return err
+}
+
+func (s *Store) DeleteByHeight(ctx context.Context, height uint64) error {
+	s.writeLk.Lock()
+	defer s.writeLk.Unlock()
+
+	head, err := s.Head(ctx)
+	if err != nil {
+		return err
+	}
+	if height == uint64(head.Height) {
+		return header.ErrCannotDeleteHead
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM headers WHERE height = ?", sqlHeight(height))
+	if err != nil {
+		return err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return header.ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO deleted (height) VALUES (?)", sqlHeight(height))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) Deleted(ctx context.Context, height uint64) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT 1 FROM deleted WHERE height = ? AND height NOT IN (SELECT height FROM headers)",
+		sqlHeight(height),
+	).Scan(&one)
+	switch err {
+	case nil:
+		return true, nil
+	case sql.ErrNoRows:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (s *Store) ReplaceLast(ctx context.Context, h *header.ExtendedHeader) error {
+	s.writeLk.Lock()
+	defer s.writeLk.Unlock()
+
+	head, err := s.Head(ctx)
+	if err != nil {
+		return err
+	}
+	prev, err := s.GetByHeight(ctx, uint64(head.Height-1))
+	if err != nil && !errors.Is(err, header.ErrNotFound) {
+		return err
+	}
+	ok, err := header.VerifyReplace(head, h, prev)
+	if err != nil || !ok {
+		return err
+	}
+
+	err = s.put(ctx, h)
+	if err != nil {
+		return err
+	}
+	s.heads.Publish(h)
+	return nil
+}
+
+// fill stores the given headers below the head, filling a gap in the stored chain.
+// The caller must hold the write lock.
+func (s *Store) fill(ctx context.Context, head *header.ExtendedHeader, headers []*header.ExtendedHeader) error {
+	last := headers[len(headers)-1]
+	if last.Height >= head.Height {
+		return fmt.Errorf("header/sqlitestore: gap [%d:%d] overlaps head %d", headers[0].Height, last.Height, head.Height)
+	}
+
+	prev, err := s.GetByHeight(ctx, uint64(headers[0].Height-1))
+	if err != nil {
+		return fmt.Errorf("header/sqlitestore: getting header preceding gap at %d: %w", headers[0].Height, err)
+	}
+	next, err := s.GetByHeight(ctx, uint64(last.Height+1))
+	if err != nil && !errors.Is(err, header.ErrNotFound) {
+		return err
+	}
+
+	err = header.VerifyGap(prev, headers, next)
+	if err != nil {
+		return err
+	}
+	return s.put(ctx, headers...)
+}
+
+// put stores the given headers in a single transaction, replacing the ones stored at the same heights.
+func (s *Store) put(ctx context.Context, headers ...*header.ExtendedHeader) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT OR REPLACE INTO headers (height, hash, data) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, h := range headers {
+		b, err := h.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		_, err = stmt.ExecContext(ctx, h.Height, []byte(h.Hash()), b)
+		if err != nil {
+			return fmt.Errorf("header/sqlitestore: putting header at %d: %w", h.Height, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// queryOne queries a single header, failing with header.ErrNotFound if there is none.
+func (s *Store) queryOne(ctx context.Context, query string, args ...interface{}) (*header.ExtendedHeader, error) {
+	var b []byte
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&b)
+	if err == sql.ErrNoRows {
+		return nil, header.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalSQLHeader(b)
+}
+
+// queryAll queries all the headers the given query matches. If the context is canceled in the middle,
+// the headers read so far are returned together with the wrapped context error.
+func (s *Store) queryAll(ctx context.Context, query string, args ...interface{}) ([]*header.ExtendedHeader, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	headers := make([]*header.ExtendedHeader, 0)
+	for rows.Next() {
+		var b []byte
+		err = rows.Scan(&b)
+		if err != nil {
+			return nil, err
+		}
+		h, err := unmarshalSQLHeader(b)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, h)
+	}
+	if ctx.Err() != nil {
+		return headers, fmt.Errorf("header/sqlitestore: getting range interrupted: %w", ctx.Err())
+	}
+	return headers, rows.Err()
+}
+
+func unmarshalSQLHeader(b []byte) (*header.ExtendedHeader, error) {
+	h := &header.ExtendedHeader{}
+	err := h.UnmarshalBinary(b)
+	if err != nil {
+		return nil, fmt.Errorf("header/sqlitestore: decoding sqlite header: %w", err)
+	}
+	return h, nil
+}
+
+// sqlHeight converts the given height to an SQLite integer, which is signed.
+// Heights above the maximum one are capped, as no header is stored there anyway.
+func sqlHeight(height uint64) int64 {
+	if height > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(height)
+}
+
+// prefixUpperBound returns the lowest byte string above all the strings beginning with the given prefix,
+// or nil if there is none, i.e. the prefix consists of 0xFF only.
+func prefixUpperBound(prefix []byte) []byte {
+	upper := append([]byte(nil), prefix...)
+	for i := len(upper) - 1; i >= 0; i-- {
+		if upper[i] < 0xFF {
+			upper[i]++
+			return upper[:i+1]
+		}
+	}
+	return nil
+}
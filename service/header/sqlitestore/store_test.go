@@ -0,0 +1,112 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	mrand "math/rand"
+	"path/filepath"
+	"testing"
+
+	dsbadger "github.com/ipfs/go-ds-badger2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+// TestStore_Reopen tests that the headers survive reopening the Store
+// and can be queried with plain SQL.
+func TestStore_Reopen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "headers.db")
+	store, err := NewStore(path)
+	require.NoError(t, err)
+
+	in := header.NewTestSuite(t, 3).GenExtendedHeaders(10)
+	err = store.Append(ctx, in...)
+	require.NoError(t, err)
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = NewStore(path)
+	require.NoError(t, err)
+	defer store.Close() //nolint:errcheck
+
+	head, err := store.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[9].Hash(), head.Hash())
+	h, err := store.Get(ctx, in[4].Hash())
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, h.Height)
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	rows, err := db.Query("SELECT height, hash FROM headers WHERE height BETWEEN 3 AND 6 ORDER BY height")
+	require.NoError(t, err)
+	defer rows.Close()
+	var heights []int64
+	for rows.Next() {
+		var (
+			height int64
+			hash   []byte
+		)
+		require.NoError(t, rows.Scan(&height, &hash))
+		assert.Equal(t, []byte(in[height-1].Hash()), hash)
+		heights = append(heights, height)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []int64{3, 4, 5, 6}, heights)
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	assert.Equal(t, []byte{0x01, 0x03}, prefixUpperBound([]byte{0x01, 0x02}))
+	assert.Equal(t, []byte{0x02}, prefixUpperBound([]byte{0x01, 0xFF}))
+	assert.Nil(t, prefixUpperBound([]byte{0xFF, 0xFF}))
+}
+
+// BenchmarkStore_RandomGetByHeight compares random-access reads of the Store and
+// the default header.Store over Badger. The amount of headers exceeds the cache of the default one.
+func BenchmarkStore_RandomGetByHeight(b *testing.B) {
+	amount := header.DefaultStoreCacheSize * 4
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts := dsbadger.DefaultOptions
+	ds, err := dsbadger.NewDatastore(b.TempDir(), &opts)
+	require.NoError(b, err)
+	defer ds.Close()
+	badger, err := header.NewStore(ds)
+	require.NoError(b, err)
+
+	sqlite, err := NewStore(filepath.Join(b.TempDir(), "headers.db"))
+	require.NoError(b, err)
+	defer sqlite.Close() //nolint:errcheck
+
+	suite := header.NewTestSuite(b, 3)
+	for i := 0; i < amount; i += 1000 {
+		headers := suite.GenExtendedHeaders(1000)
+		for _, store := range []header.Store{badger, sqlite} {
+			err = store.Append(ctx, headers...)
+			require.NoError(b, err)
+		}
+	}
+
+	for name, store := range map[string]header.Store{"Badger": badger, "SQLite": sqlite} {
+		store := store
+		b.Run(name, func(b *testing.B) {
+			rand := mrand.New(mrand.NewSource(1)) //nolint:gosec
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := store.GetByHeight(ctx, uint64(rand.Intn(amount))+1)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
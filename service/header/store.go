@@ -2,12 +2,18 @@ package header
 
 import (
 	"context"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
 	"strconv"
 	"sync"
 
 	lru "github.com/hashicorp/golang-lru"
+	"github.com/ipfs/bbloom"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
 
 	"github.com/tendermint/tendermint/libs/bytes"
 )
@@ -18,15 +24,34 @@ var (
 	DefaultStoreCacheSize = 1024
 	// DefaultIndexCache defines the amount of max entries allowed in the Height to Hash index cache.
 	DefaultIndexCacheSize = 256
+	// DefaultStoreBloomSize defines the amount of entries the Header Store bloom filter is sized for.
+	// Exceeding it only increases the rate of false positives answered by the datastore.
+	DefaultStoreBloomSize = 1 << 20
+	// MaxHashPrefixMatches defines the amount of max ExtendedHeaders returned by GetByHashPrefix.
+	MaxHashPrefixMatches = 64
 )
 
 type store struct {
 	ds    datastore.Batching
 	cache *lru.ARCCache
 	index *heightIndexer
+	// bloom tracks all the keys on disk, so that Has for missing headers is answered without touching it.
+	bloom *bbloom.Bloom
 
 	headLk sync.RWMutex
 	head   bytes.HexBytes
+
+	tailLk sync.Mutex
+	tail   uint64
+
+	// count is the amount of stored headers, kept on disk together with the headers themselves
+	countLk sync.Mutex
+	count   uint64
+	counted bool
+
+	// appendLk serializes Appends, so that new heads are verified against each other and published in order
+	appendLk sync.Mutex
+	heads    HeadBroadcaster
 }
 
 // NewStore constructs a Store over datastore.
@@ -48,7 +73,7 @@ func NewStoreWithHead(ds datastore.Batching, head *ExtendedHeader) (Store, error
 		return nil, err
 	}
 
-	err = store.newHead(head.Hash())
+	err = store.newTail(uint64(head.Height))
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +83,11 @@ func NewStoreWithHead(ds datastore.Batching, head *ExtendedHeader) (Store, error
 }
 
 func newStore(ds datastore.Batching) (*store, error) {
+	err := checkSchema(ds)
+	if err != nil {
+		return nil, err
+	}
+
 	ds = namespace.Wrap(ds, storePrefix)
 	cache, err := lru.NewARC(DefaultStoreCacheSize)
 	if err != nil {
@@ -69,10 +99,21 @@ func newStore(ds datastore.Batching) (*store, error) {
 		return nil, err
 	}
 
+	err = indexHashes(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	bloom, err := loadBloom(ds)
+	if err != nil {
+		return nil, err
+	}
+
 	return &store{
 		ds:    ds,
 		cache: cache,
 		index: index,
+		bloom: bloom,
 	}, nil
 }
 
@@ -95,6 +136,23 @@ func (s *store) Head(ctx context.Context) (*ExtendedHeader, error) {
 	}
 }
 
+func (s *store) Tail(ctx context.Context) (*ExtendedHeader, error) {
+	// there is no tail without a head
+	_, err := s.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.tailLk.Lock()
+	tail, err := s.loadTail()
+	s.tailLk.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByHeight(ctx, tail)
+}
+
 func (s *store) Get(_ context.Context, hash bytes.HexBytes) (*ExtendedHeader, error) {
 	if v, ok := s.cache.Get(hash.String()); ok {
 		return v.(*ExtendedHeader), nil
@@ -125,40 +183,202 @@ func (s *store) GetByHeight(ctx context.Context, height uint64) (*ExtendedHeader
 	return s.Get(ctx, hash)
 }
 
+func (s *store) GetByHashPrefix(ctx context.Context, prefix []byte) ([]*ExtendedHeader, error) {
+	if len(prefix) == 0 {
+		return nil, nil
+	}
+
+	res, err := s.ds.Query(query.Query{Prefix: hashShardKey(prefix).String()})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	type match struct {
+		hash   bytes.HexBytes
+		height uint64
+	}
+	var matches []match
+	for e := range res.Next() {
+		if e.Error != nil {
+			return nil, e.Error
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		hash, err := hex.DecodeString(datastore.RawKey(e.Key).BaseNamespace())
+		if err != nil {
+			return nil, err
+		}
+		// the shard is narrower than the prefix, if it is longer than two bytes
+		if !hasHashPrefix(hash, prefix) {
+			continue
+		}
+		height, err := strconv.ParseUint(string(e.Value), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, match{hash: hash, height: height})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].height < matches[j].height
+	})
+	if len(matches) > MaxHashPrefixMatches {
+		matches = matches[:MaxHashPrefixMatches]
+	}
+
+	// only the matching headers are loaded, once the index narrowed them down
+	headers := make([]*ExtendedHeader, len(matches))
+	for i, m := range matches {
+		headers[i], err = s.Get(ctx, m.hash)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return headers, nil
+}
+
 func (s *store) GetRangeByHeight(ctx context.Context, from, to uint64) ([]*ExtendedHeader, error) {
-	h, err := s.GetByHeight(ctx, to-1)
+	// ensure the whole range exists before fetching it
+	_, err := s.GetByHeight(ctx, to-1)
 	if err != nil {
 		return nil, err
 	}
 
-	ln := to - from
-	headers := make([]*ExtendedHeader, ln)
-	for i := ln - 1; i > 0; i-- {
-		headers[i] = h
-		h, err = s.Get(ctx, h.LastHeader())
+	headers := make([]*ExtendedHeader, 0, to-from)
+	for height := from; height < to; height++ {
+		h, err := s.GetByHeight(ctx, height)
 		if err != nil {
 			return nil, err
 		}
+		headers = append(headers, h)
+
+		if ctx.Err() != nil {
+			return headers, fmt.Errorf("header/store: getting range interrupted: %w", ctx.Err())
+		}
 	}
-	headers[0] = h
 
 	return headers, nil
 }
 
+func (s *store) GetByHeightRange(ctx context.Context, from, to uint64) ([]*ExtendedHeader, error) {
+	return GetByHeightRange(ctx, s, from, to)
+}
+
+// GetByHeightRange checks the range [from:to) against the head of the Store before getting it.
+// See Store.GetByHeightRange.
+func GetByHeightRange(ctx context.Context, s Store, from, to uint64) ([]*ExtendedHeader, error) {
+	if from >= to {
+		return nil, fmt.Errorf("%w: from %d is not below to %d", ErrInvalidRange, from, to)
+	}
+	head, err := s.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if to > uint64(head.Height)+1 {
+		return nil, fmt.Errorf("%w: to %d is above the head %d", ErrInvalidRange, to, head.Height)
+	}
+
+	return s.GetRangeByHeight(ctx, from, to)
+}
+
+func (s *store) IterateByHeight(ctx context.Context, from, to uint64) (HeaderIterator, error) {
+	return NewHeightIterator(ctx, s.GetByHeight, from, to)
+}
+
+func (s *store) ForEach(ctx context.Context, fn func(*ExtendedHeader) error) error {
+	return ForEach(ctx, s, fn)
+}
+
+// ForEach calls 'fn' for every header stored in the Store from the tail to the head. See Store.ForEach.
+func ForEach(ctx context.Context, s Store, fn func(*ExtendedHeader) error) error {
+	tail, err := s.Tail(ctx)
+	switch err {
+	case nil:
+	case ErrNoHead:
+		return nil
+	default:
+		return err
+	}
+	head, err := s.Head(ctx)
+	if err != nil {
+		return err
+	}
+
+	for height := uint64(tail.Height); height <= uint64(head.Height); height++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		h, err := s.GetByHeight(ctx, height)
+		if err != nil {
+			// deleted headers leave gaps
+			if err == ErrNotFound {
+				continue
+			}
+			return err
+		}
+
+		err = fn(h)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *store) Has(_ context.Context, hash bytes.HexBytes) (bool, error) {
 	if ok := s.cache.Contains(hash.String()); ok {
 		return ok, nil
 	}
 
-	return s.ds.Has(datastore.NewKey(hash.String()))
+	key := datastore.NewKey(hash.String())
+	if !s.bloom.HasTS(key.Bytes()) {
+		return false, nil
+	}
+
+	return s.ds.Has(key)
+}
+
+func (s *store) HasAt(_ context.Context, height uint64) (bool, error) {
+	return s.index.HasHeight(height)
 }
 
+// Append writes the headers in the background and waits for it until the given context is done,
+// so a stalled disk does not block the caller forever. In the latter case the wrapped context error
+// is returned, while the write still completes or fails on its own and later Appends wait for it.
 func (s *store) Append(ctx context.Context, headers ...*ExtendedHeader) error {
-	lh := len(headers)
-	if lh == 0 {
+	if len(headers) == 0 {
 		return nil
 	}
 
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.append(ctx, headers...)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		// prefer the result of the write completed in the meantime
+		select {
+		case err := <-errCh:
+			return err
+		default:
+		}
+		log.Warnw("header/store: append is still in progress", "from", headers[0].Height, "amount", len(headers))
+		return fmt.Errorf("header/store: appending headers: %w", ctx.Err())
+	}
+}
+
+// append verifies and writes the given headers. See Store.Append.
+func (s *store) append(ctx context.Context, headers ...*ExtendedHeader) error {
+	lh := len(headers)
+	s.appendLk.Lock()
+	defer s.appendLk.Unlock()
+
 	head, err := s.Head(ctx)
 	switch err {
 	default:
@@ -167,55 +387,398 @@ func (s *store) Append(ctx context.Context, headers ...*ExtendedHeader) error {
 		// trust the given header as the initial head
 		err = s.put(headers...)
 		if err != nil {
+			log.Errorw("header/store: writing headers", "from", headers[0].Height, "amount", lh, "err", err)
 			return err
 		}
 
 		head = headers[len(headers)-1]
-		err = s.newHead(head.Hash())
+		err = s.newTail(uint64(headers[0].Height))
 		if err != nil {
 			return err
 		}
 
+		s.heads.Publish(headers...)
 		log.Infow("new head", "height", head.Height, "hash", head.Hash())
 		return nil
 	case nil:
 	}
 
-	verified := make([]*ExtendedHeader, 0, lh)
-	for _, h := range headers {
-		if head.Height == h.Height {
-			continue
+	// headers below the head fill a gap in the stored chain
+	if headers[0].Height < head.Height {
+		return s.fill(ctx, head, headers)
+	}
+
+	verified := VerifyAppend(head, headers)
+	if len(verified) == 0 {
+		log.Warn("header/store: no valid headers were given")
+		return nil
+	}
+	head = verified[len(verified)-1]
+
+	err = s.put(verified...)
+	if err != nil {
+		log.Errorw("header/store: writing headers", "from", verified[0].Height, "amount", len(verified), "err", err)
+		return err
+	}
+
+	s.heads.Publish(verified...)
+	log.Infow("new head", "height", head.Height, "hash", head.Hash())
+	return nil
+}
+
+func (s *store) AppendSingle(ctx context.Context, h *ExtendedHeader) error {
+	return AppendSingle(ctx, s, h)
+}
+
+// AppendSingle checks the given ExtendedHeader is above the head of the Store before appending it.
+func AppendSingle(ctx context.Context, s Store, h *ExtendedHeader) error {
+	if h == nil {
+		return fmt.Errorf("header/store: nil header")
+	}
+
+	head, err := s.Head(ctx)
+	switch err {
+	case nil:
+		if h.Height <= head.Height {
+			return fmt.Errorf("%w: height %d, head %d", ErrNotAboveHead, h.Height, head.Height)
 		}
+	case ErrNoHead:
+	default:
+		return err
+	}
+	return s.Append(ctx, h)
+}
+
+// fill stores the given headers below the head, filling a gap in the stored chain.
+// The headers must link to the stored headers around the gap.
+func (s *store) fill(ctx context.Context, head *ExtendedHeader, headers []*ExtendedHeader) error {
+	last := headers[len(headers)-1]
+	if last.Height >= head.Height {
+		return fmt.Errorf("header/store: gap [%d:%d] overlaps head %d", headers[0].Height, last.Height, head.Height)
+	}
 
-		err = VerifyAdjacent(head, h)
+	prev, err := s.GetByHeight(ctx, uint64(headers[0].Height-1))
+	if err != nil {
+		return fmt.Errorf("header/store: getting header preceding gap at %d: %w", headers[0].Height, err)
+	}
+
+	// the gap may be filled in parts, so the header following the given ones may be missing as well
+	next, err := s.GetByHeight(ctx, uint64(last.Height+1))
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+
+	err = VerifyGap(prev, headers, next)
+	if err != nil {
+		return err
+	}
+
+	missing := make([]*ExtendedHeader, 0, len(headers))
+	for _, h := range headers {
+		has, err := s.Has(ctx, h.Hash())
 		if err != nil {
-			log.Errorw("invalid header", "current head", head.Hash(), "height",
-				head.Height, "attempted new header", h.Hash(), "height", h.Height, "err", err)
-			break // if some headers are cryptographically valid, why not include them? Exactly, so let's include
+			return err
+		}
+		if !has {
+			missing = append(missing, h)
 		}
-		verified, head = append(verified, h), h
 	}
-	if len(verified) == 0 {
-		log.Warn("header/store: no valid headers were given")
+	if len(missing) == 0 {
 		return nil
 	}
 
-	err = s.put(verified...)
+	err = s.write(false, missing...)
+	if err != nil {
+		log.Errorw("header/store: writing headers", "from", missing[0].Height, "amount", len(missing), "err", err)
+		return err
+	}
+
+	log.Infow("filled gap", "from", missing[0].Height, "amount", len(missing))
+	return nil
+}
+
+func (s *store) WatchHead(ctx context.Context) (<-chan *ExtendedHeader, error) {
+	return s.heads.Watch(ctx)
+}
+
+func (s *store) CountHeaders(context.Context) (uint64, error) {
+	s.countLk.Lock()
+	defer s.countLk.Unlock()
+	return s.loadCount()
+}
+
+func (s *store) Prune(ctx context.Context, keepLast uint64) error {
+	if keepLast == 0 {
+		return fmt.Errorf("header/store: at least one header must be kept")
+	}
+
+	head, err := s.Head(ctx)
+	if err != nil {
+		return err
+	}
+	if uint64(head.Height) <= keepLast {
+		return nil
+	}
+	newTail := uint64(head.Height) - keepLast + 1
+
+	s.tailLk.Lock()
+	defer s.tailLk.Unlock()
+
+	tail, err := s.loadTail()
 	if err != nil {
 		return err
 	}
+	if tail >= newTail {
+		return nil
+	}
 
-	err = s.newHead(head.Hash())
+	s.countLk.Lock()
+	defer s.countLk.Unlock()
+
+	batch, err := s.ds.Batch()
 	if err != nil {
 		return err
 	}
 
-	log.Infow("new head", "height", head.Height, "hash", head.Hash())
+	hashes, heights := make([]bytes.HexBytes, 0, newTail-tail), make([]uint64, 0, newTail-tail)
+	for height := tail; height < newTail; height++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		hash, err := s.index.HashByHeight(height)
+		if err != nil {
+			if err == datastore.ErrNotFound {
+				continue
+			}
+			return err
+		}
+
+		err = batch.Delete(datastore.NewKey(hash.String()))
+		if err != nil {
+			return err
+		}
+
+		err = s.index.Unindex(batch, height, hash)
+		if err != nil {
+			return err
+		}
+
+		hashes, heights = append(hashes, hash), append(heights, height)
+	}
+
+	err = batch.Put(tailKey, []byte(strconv.FormatUint(newTail, 10)))
+	if err != nil {
+		return err
+	}
+
+	count, err := s.addCount(batch, -int64(len(hashes)))
+	if err != nil {
+		return err
+	}
+
+	err = batch.Commit()
+	if err != nil {
+		log.Errorw("header/store: pruning headers", "from", tail, "to", newTail, "err", err)
+		return err
+	}
+
+	// drop pruned headers from caches only after they are removed from disk
+	for i, hash := range hashes {
+		s.cache.Remove(hash.String())
+		s.index.cache.Remove(heights[i])
+	}
+	s.tail = newTail
+	s.count = count
+
+	log.Infow("pruned headers", "from", tail, "to", newTail, "amount", len(hashes))
+	return nil
+}
+
+func (s *store) DeleteByHeight(ctx context.Context, height uint64) error {
+	// nothing must be appended in the middle, so the head stays the same
+	s.appendLk.Lock()
+	defer s.appendLk.Unlock()
+
+	head, err := s.Head(ctx)
+	if err != nil {
+		return err
+	}
+	if height == uint64(head.Height) {
+		return ErrCannotDeleteHead
+	}
+
+	hash, err := s.index.HashByHeight(height)
+	if err != nil {
+		if err == datastore.ErrNotFound {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	s.tailLk.Lock()
+	defer s.tailLk.Unlock()
+
+	tail, err := s.loadTail()
+	if err != nil {
+		return err
+	}
+
+	s.countLk.Lock()
+	defer s.countLk.Unlock()
+
+	batch, err := s.ds.Batch()
+	if err != nil {
+		return err
+	}
+
+	err = batch.Delete(datastore.NewKey(hash.String()))
+	if err != nil {
+		return err
+	}
+
+	err = s.index.Unindex(batch, height, hash)
+	if err != nil {
+		return err
+	}
+
+	err = batch.Put(deletedKey(height), []byte{})
+	if err != nil {
+		return err
+	}
+
+	count, err := s.addCount(batch, -1)
+	if err != nil {
+		return err
+	}
+
+	// the lowest header is deleted, so the tail moves up to the next stored one
+	newTail := tail
+	if height == tail {
+		for newTail = height + 1; newTail < uint64(head.Height); newTail++ {
+			_, err = s.index.HashByHeight(newTail)
+			if err == nil {
+				break
+			}
+			if err != datastore.ErrNotFound {
+				return err
+			}
+		}
+
+		err = batch.Put(tailKey, []byte(strconv.FormatUint(newTail, 10)))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = batch.Commit()
+	if err != nil {
+		log.Errorw("header/store: deleting header", "height", height, "err", err)
+		return err
+	}
+
+	s.cache.Remove(hash.String())
+	s.index.cache.Remove(height)
+	s.tail = newTail
+	s.count = count
+
+	log.Infow("deleted header", "height", height, "hash", hash)
 	return nil
 }
 
-// put saves the given headers on disk and into cache.
+func (s *store) Deleted(ctx context.Context, height uint64) (bool, error) {
+	has, err := s.HasAt(ctx, height)
+	if err != nil || has {
+		return false, err
+	}
+
+	return s.ds.Has(deletedKey(height))
+}
+
+func (s *store) ReplaceLast(ctx context.Context, h *ExtendedHeader) error {
+	s.appendLk.Lock()
+	defer s.appendLk.Unlock()
+
+	head, err := s.Head(ctx)
+	if err != nil {
+		return err
+	}
+
+	prev, err := s.GetByHeight(ctx, uint64(head.Height-1))
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	ok, err := VerifyReplace(head, h, prev)
+	if err != nil || !ok {
+		return err
+	}
+
+	b, err := h.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	hash, err := h.Hash().MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	batch, err := s.ds.Batch()
+	if err != nil {
+		return err
+	}
+	err = batch.Put(headerKey(h), b)
+	if err != nil {
+		return err
+	}
+	err = s.index.Index(batch, h)
+	if err != nil {
+		return err
+	}
+	err = batch.Put(headKey, hash)
+	if err != nil {
+		return err
+	}
+	err = batch.Delete(headerKey(head))
+	if err != nil {
+		return err
+	}
+	// the height index of the replaced head is overwritten above
+	err = batch.Delete(hashKey(head.Hash()))
+	if err != nil {
+		return err
+	}
+	err = batch.Commit()
+	if err != nil {
+		log.Errorw("header/store: replacing head", "height", h.Height, "err", err)
+		return err
+	}
+
+	s.cache.Remove(head.Hash().String())
+	s.cache.Add(h.Hash().String(), h)
+	s.bloom.AddTS(headerKey(h).Bytes())
+	s.index.Cache(h)
+	s.headLk.Lock()
+	s.head = h.Hash()
+	s.headLk.Unlock()
+
+	s.heads.Publish(h)
+	log.Infow("replaced head", "height", h.Height, "old", head.Hash(), "new", h.Hash())
+	return nil
+}
+
+// put atomically saves the given headers on disk together with their height indexes
+// and makes the last of them a new 'head'.
+// Either all of them are written or none, so a crash in the middle never leaves the store inconsistent.
 func (s *store) put(headers ...*ExtendedHeader) error {
+	return s.write(true, headers...)
+}
+
+// write atomically saves the given headers on disk together with their height indexes.
+// If 'newHead' is set, the last of them becomes a new 'head'.
+func (s *store) write(newHead bool, headers ...*ExtendedHeader) error {
+	s.countLk.Lock()
+	defer s.countLk.Unlock()
+
 	batch, err := s.ds.Batch()
 	if err != nil {
 		return err
@@ -233,6 +796,29 @@ func (s *store) put(headers ...*ExtendedHeader) error {
 		}
 	}
 
+	err = s.index.Index(batch, headers...)
+	if err != nil {
+		return err
+	}
+
+	count, err := s.addCount(batch, int64(len(headers)))
+	if err != nil {
+		return err
+	}
+
+	head := headers[len(headers)-1].Hash()
+	if newHead {
+		b, err := head.MarshalJSON()
+		if err != nil {
+			return err
+		}
+
+		err = batch.Put(headKey, b)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = batch.Commit()
 	if err != nil {
 		return err
@@ -241,9 +827,17 @@ func (s *store) put(headers ...*ExtendedHeader) error {
 	// consistency is important, so change the cache and the head only after the data is on disk
 	for _, h := range headers {
 		s.cache.Add(h.Hash().String(), h)
+		s.bloom.AddTS(headerKey(h).Bytes())
 	}
+	s.index.Cache(headers...)
+	s.count = count
 
-	return s.index.Index(headers...)
+	if newHead {
+		s.headLk.Lock()
+		s.head = head
+		s.headLk.Unlock()
+	}
+	return nil
 }
 
 // loadHead load the head hash from the disk.
@@ -265,23 +859,139 @@ func (s *store) loadHead() error {
 	return nil
 }
 
-// newHead sets a new 'head' and saves it on disk.
-// At this point Header body of the given 'head' must be already written with put.
-func (s *store) newHead(head bytes.HexBytes) error {
-	s.headLk.Lock()
-	s.head = head
-	s.headLk.Unlock()
+// loadTail returns the height of the lowest stored header, loading it from the disk if needed.
+// Stores created before the tail was tracked are assumed to start at the genesis.
+// The caller must hold tailLk.
+func (s *store) loadTail() (uint64, error) {
+	if s.tail != 0 {
+		return s.tail, nil
+	}
+
+	b, err := s.ds.Get(tailKey)
+	switch err {
+	case nil:
+	case datastore.ErrNotFound:
+		s.tail = 1
+		return s.tail, nil
+	default:
+		return 0, err
+	}
+
+	s.tail, err = strconv.ParseUint(string(b), 10, 64)
+	return s.tail, err
+}
+
+// newTail sets a new 'tail' height and saves it on disk.
+func (s *store) newTail(height uint64) error {
+	s.tailLk.Lock()
+	defer s.tailLk.Unlock()
+
+	s.tail = height
+	return s.ds.Put(tailKey, []byte(strconv.FormatUint(height, 10)))
+}
+
+// loadCount returns the amount of stored headers, loading it from the disk if needed.
+// Stores created before the amount was tracked count their height index once.
+// The caller must hold countLk.
+func (s *store) loadCount() (uint64, error) {
+	if s.counted {
+		return s.count, nil
+	}
+
+	b, err := s.ds.Get(countKey)
+	switch err {
+	case nil:
+		s.count, err = strconv.ParseUint(string(b), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	case datastore.ErrNotFound:
+		s.count, err = countKeys(s.ds, heightsPrefix)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		return 0, err
+	}
+
+	s.counted = true
+	return s.count, nil
+}
 
-	b, err := head.MarshalJSON()
+// addCount adds the changed amount of stored headers to the given batch and returns it.
+// The caller must hold countLk and set the amount once the batch is committed.
+func (s *store) addCount(batch datastore.Batch, delta int64) (uint64, error) {
+	count, err := s.loadCount()
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if delta < 0 && uint64(-delta) > count {
+		return 0, fmt.Errorf("header/store: removing %d headers out of %d", -delta, count)
+	}
+
+	count = uint64(int64(count) + delta)
+	return count, batch.Put(countKey, []byte(strconv.FormatUint(count, 10)))
+}
+
+// countKeys counts the keys under the given prefix.
+func countKeys(ds datastore.Datastore, prefix datastore.Key) (uint64, error) {
+	res, err := ds.Query(query.Query{Prefix: prefix.String(), KeysOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer res.Close()
+
+	var n uint64
+	for e := range res.Next() {
+		if e.Error != nil {
+			return 0, e.Error
+		}
+		n++
+	}
+	return n, nil
+}
+
+// bloomFalsePositives is the rate of false positives the bloom filter is sized for.
+const bloomFalsePositives = 0.01
+
+// bloomHashes is the amount of bits bbloom sets per entry for bloomFalsePositives.
+var bloomHashes = math.Ceil(-math.Log(bloomFalsePositives) / math.Ln2)
+
+// FalsePositiveRate estimates the probability of Has looking up a missing header in the datastore,
+// as the bloom filter fills up.
+func (s *store) FalsePositiveRate() float64 {
+	return math.Pow(s.bloom.FillRatioTS(), bloomHashes)
+}
+
+// loadBloom creates a bloom filter of the headers kept in the given datastore.
+func loadBloom(ds datastore.Datastore) (*bbloom.Bloom, error) {
+	bloom, err := bbloom.New(float64(DefaultStoreBloomSize), bloomFalsePositives)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := ds.Query(query.Query{KeysOnly: true})
+	if err != nil {
+		return nil, err
 	}
+	defer res.Close()
 
-	return s.ds.Put(headKey, b)
+	for e := range res.Next() {
+		if e.Error != nil {
+			return nil, e.Error
+		}
+		// only headers are checked with Has, so the height index and metadata are skipped
+		key := datastore.RawKey(e.Key)
+		if len(key.Namespaces()) == 1 {
+			bloom.Add(key.Bytes())
+		}
+	}
+	return bloom, nil
 }
 
 // TODO(@Wondertan): There should be a more clever way to index heights, than just storing HeightToHash pair...
 // heightIndexer simply stores and cashes mappings between header Height and Hash.
+// It also keeps the reverse mappings, so headers can be looked up by a prefix of their hash.
 type heightIndexer struct {
 	ds    datastore.Batching
 	cache *lru.ARCCache
@@ -309,39 +1019,139 @@ func (hi *heightIndexer) HashByHeight(h uint64) (bytes.HexBytes, error) {
 	return hi.ds.Get(heightKey(h))
 }
 
-// Index saves mapping between header Height and Hash.
-func (hi *heightIndexer) Index(headers ...*ExtendedHeader) error {
-	batch, err := hi.ds.Batch()
+// HasHeight checks whether a header with the given height is indexed.
+func (hi *heightIndexer) HasHeight(h uint64) (bool, error) {
+	if hi.cache.Contains(h) {
+		return true, nil
+	}
+
+	return hi.ds.Has(heightKey(h))
+}
+
+// Index adds mappings between header Height and Hash to the given batch.
+func (hi *heightIndexer) Index(batch datastore.Batch, headers ...*ExtendedHeader) error {
+	for _, h := range headers {
+		err := batch.Put(heightKey(uint64(h.Height)), h.Hash())
+		if err != nil {
+			return err
+		}
+
+		err = batch.Put(hashKey(h.Hash()), []byte(strconv.FormatUint(uint64(h.Height), 10)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unindex adds the removal of mappings between the given Height and Hash to the given batch.
+func (hi *heightIndexer) Unindex(batch datastore.Batch, height uint64, hash bytes.HexBytes) error {
+	err := batch.Delete(heightKey(height))
 	if err != nil {
 		return err
 	}
+	return batch.Delete(hashKey(hash))
+}
 
+// Cache caches mappings between header Height and Hash.
+// It must be called only after indexes are written to the disk.
+func (hi *heightIndexer) Cache(headers ...*ExtendedHeader) {
 	for _, h := range headers {
-		err := batch.Put(heightKey(uint64(h.Height)), h.Hash())
+		hi.cache.Add(uint64(h.Height), h.Hash())
+	}
+}
+
+// indexHashes builds the hash index of stores created before it was kept.
+// It is done once, as the index is kept together with the headers afterwards.
+func indexHashes(ds datastore.Batching) error {
+	indexed, err := ds.Has(hashesIndexedKey)
+	if err != nil || indexed {
+		return err
+	}
+
+	res, err := ds.Query(query.Query{Prefix: heightsPrefix.String()})
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	batch, err := ds.Batch()
+	if err != nil {
+		return err
+	}
+	var n int
+	for e := range res.Next() {
+		if e.Error != nil {
+			return e.Error
+		}
+
+		height, err := strconv.ParseUint(datastore.RawKey(e.Key).BaseNamespace(), 10, 64)
+		if err != nil {
+			return err
+		}
+		err = batch.Put(hashKey(e.Value), []byte(strconv.FormatUint(height, 10)))
 		if err != nil {
 			return err
 		}
+		n++
 	}
 
+	err = batch.Put(hashesIndexedKey, []byte(strconv.FormatBool(true)))
+	if err != nil {
+		return err
+	}
 	err = batch.Commit()
 	if err != nil {
 		return err
 	}
 
-	// update the cache only after indexes are written to the disk
-	for _, h := range headers {
-		hi.cache.Add(h.Height, h.Hash())
+	if n > 0 {
+		log.Infow("indexed hashes", "amount", n)
 	}
 	return nil
 }
 
+// hasHashPrefix reports whether the hash begins with the prefix.
+func hasHashPrefix(hash bytes.HexBytes, prefix []byte) bool {
+	return len(hash) >= len(prefix) && string(hash[:len(prefix)]) == string(prefix)
+}
+
 var (
-	storePrefix = datastore.NewKey("headers")
-	headKey     = datastore.NewKey("head")
+	storePrefix      = datastore.NewKey("headers")
+	headKey          = datastore.NewKey("head")
+	tailKey          = datastore.NewKey("tail")
+	countKey         = datastore.NewKey("count")
+	hashesPrefix     = datastore.NewKey("hashes")
+	hashesIndexedKey = datastore.NewKey("hashes-indexed")
+	deletedPrefix    = datastore.NewKey("deleted")
 )
 
+// hashShards is the amount of leading hash bytes the hash index is sharded by.
+const hashShards = 2
+
+// hashKey keys the hash index under the shards of the leading hash bytes, so the headers with a short
+// hash prefix are queried without going through the whole index.
+func hashKey(hash bytes.HexBytes) datastore.Key {
+	return hashShardKey(hash).ChildString(hash.String())
+}
+
+// hashShardKey returns the key of the narrowest shard of the hash index keeping the given hash prefix.
+func hashShardKey(prefix []byte) datastore.Key {
+	key := hashesPrefix
+	for i := 0; i < len(prefix) && i < hashShards; i++ {
+		key = key.ChildString(fmt.Sprintf("%02X", prefix[i]))
+	}
+	return key
+}
+
+// heightKey is zero-padded, so the height index is ordered by height.
 func heightKey(h uint64) datastore.Key {
-	return datastore.NewKey(strconv.Itoa(int(h)))
+	return heightsPrefix.ChildString(fmt.Sprintf("%020d", h))
+}
+
+// deletedKey marks the height deleted with DeleteByHeight.
+func deletedKey(h uint64) datastore.Key {
+	return deletedPrefix.ChildString(strconv.FormatUint(h, 10))
 }
 
 func headerKey(h *ExtendedHeader) datastore.Key {
@@ -0,0 +1,212 @@
+package header
+
+import (
+	"context"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/tendermint/tendermint/libs/bytes"
+)
+
+// DefaultCachingStoreSize defines the default amount of max entries kept by CachingStore.
+var DefaultCachingStoreSize = 4096
+
+// CachingStoreOption is a functional option that configures CachingStore.
+type CachingStoreOption func(*CachingStore)
+
+// WithCacheSize sets the maximum amount of ExtendedHeaders kept in CachingStore's cache.
+func WithCacheSize(n int) CachingStoreOption {
+	return func(cs *CachingStore) {
+		cs.size = n
+	}
+}
+
+// WithCacheTTL sets how long ExtendedHeaders are served from CachingStore's cache before they
+// are read from the wrapped Store again. Zero, the default, keeps them until evicted by the LRU.
+func WithCacheTTL(ttl time.Duration) CachingStoreOption {
+	return func(cs *CachingStore) {
+		cs.ttl = ttl
+	}
+}
+
+// CachingStore wraps any Store keeping recently accessed ExtendedHeaders in an LRU cache in front of it.
+// Cache misses fall through to the wrapped Store.
+type CachingStore struct {
+	Store
+
+	size     int
+	ttl      time.Duration
+	byHash   *lru.Cache
+	byHeight *lru.Cache
+}
+
+// NewCachingStore wraps the given Store with an LRU cache.
+func NewCachingStore(store Store, opts ...CachingStoreOption) (*CachingStore, error) {
+	cs := &CachingStore{
+		Store: store,
+		size:  DefaultCachingStoreSize,
+	}
+	for _, opt := range opts {
+		opt(cs)
+	}
+
+	var err error
+	cs.byHash, err = lru.New(cs.size)
+	if err != nil {
+		return nil, err
+	}
+	cs.byHeight, err = lru.New(cs.size)
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func (cs *CachingStore) Get(ctx context.Context, hash bytes.HexBytes) (*ExtendedHeader, error) {
+	if h, ok := cs.get(cs.byHash, hash.String()); ok {
+		return h, nil
+	}
+
+	h, err := cs.Store.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.add(h)
+	return h, nil
+}
+
+func (cs *CachingStore) GetByHeight(ctx context.Context, height uint64) (*ExtendedHeader, error) {
+	if h, ok := cs.get(cs.byHeight, height); ok {
+		return h, nil
+	}
+
+	h, err := cs.Store.GetByHeight(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.add(h)
+	return h, nil
+}
+
+func (cs *CachingStore) GetRangeByHeight(ctx context.Context, from, to uint64) ([]*ExtendedHeader, error) {
+	headers, err := cs.Store.GetRangeByHeight(ctx, from, to)
+	for _, h := range headers {
+		cs.add(h)
+	}
+	return headers, err
+}
+
+// GetByHeightRange caches the headers of the range, like GetRangeByHeight.
+func (cs *CachingStore) GetByHeightRange(ctx context.Context, from, to uint64) ([]*ExtendedHeader, error) {
+	return GetByHeightRange(ctx, cs, from, to)
+}
+
+func (cs *CachingStore) Tail(ctx context.Context) (*ExtendedHeader, error) {
+	h, err := cs.Store.Tail(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.add(h)
+	return h, nil
+}
+
+func (cs *CachingStore) Has(ctx context.Context, hash bytes.HexBytes) (bool, error) {
+	if _, ok := cs.get(cs.byHash, hash.String()); ok {
+		return true, nil
+	}
+
+	return cs.Store.Has(ctx, hash)
+}
+
+func (cs *CachingStore) Append(ctx context.Context, headers ...*ExtendedHeader) error {
+	// invalidate before writing, so no stale entries are served if the write partially fails
+	for _, h := range headers {
+		cs.byHash.Remove(h.Hash().String())
+		cs.byHeight.Remove(uint64(h.Height))
+	}
+
+	return cs.Store.Append(ctx, headers...)
+}
+
+func (cs *CachingStore) AppendSingle(ctx context.Context, h *ExtendedHeader) error {
+	return AppendSingle(ctx, cs, h)
+}
+
+func (cs *CachingStore) ReplaceLast(ctx context.Context, h *ExtendedHeader) error {
+	head, err := cs.Store.Head(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = cs.Store.ReplaceLast(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	cs.byHash.Remove(head.Hash().String())
+	cs.byHeight.Remove(uint64(head.Height))
+	return nil
+}
+
+func (cs *CachingStore) DeleteByHeight(ctx context.Context, height uint64) error {
+	// the hash is needed to invalidate the cache, while the wrapped Store reports why it cannot be deleted
+	h, getErr := cs.Store.GetByHeight(ctx, height)
+	err := cs.Store.DeleteByHeight(ctx, height)
+	if err != nil {
+		return err
+	}
+	if getErr != nil {
+		// the deleted header is not known here, so just start over
+		cs.byHash.Purge()
+		cs.byHeight.Purge()
+		return nil
+	}
+
+	cs.byHash.Remove(h.Hash().String())
+	cs.byHeight.Remove(height)
+	return nil
+}
+
+func (cs *CachingStore) Prune(ctx context.Context, keepLast uint64) error {
+	err := cs.Store.Prune(ctx, keepLast)
+	if err != nil {
+		return err
+	}
+
+	// pruned headers are not known here, so just start over
+	cs.byHash.Purge()
+	cs.byHeight.Purge()
+	return nil
+}
+
+// cachedHeader is an ExtendedHeader kept in the cache along with the time it was cached at.
+type cachedHeader struct {
+	header   *ExtendedHeader
+	cachedAt time.Time
+}
+
+// get returns the ExtendedHeader cached under the given key, evicting it if its TTL has passed.
+func (cs *CachingStore) get(cache *lru.Cache, key interface{}) (*ExtendedHeader, bool) {
+	v, ok := cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	ch := v.(*cachedHeader)
+	if cs.ttl > 0 && time.Since(ch.cachedAt) >= cs.ttl {
+		cache.Remove(key)
+		return nil, false
+	}
+	return ch.header, true
+}
+
+// add caches the given ExtendedHeader.
+func (cs *CachingStore) add(h *ExtendedHeader) {
+	ch := &cachedHeader{header: h, cachedAt: time.Now()}
+	cs.byHash.Add(h.Hash().String(), ch)
+	cs.byHeight.Add(uint64(h.Height), ch)
+}
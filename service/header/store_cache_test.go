@@ -0,0 +1,107 @@
+package header
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tmrand "github.com/tendermint/tendermint/libs/rand"
+)
+
+func TestCachingStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	store := createStore(t, 0)
+	err := store.Append(ctx, suite.GenExtendedHeaders(10)...)
+	require.NoError(t, err)
+
+	cs, err := NewCachingStore(store, WithCacheSize(5))
+	require.NoError(t, err)
+
+	h, err := cs.GetByHeight(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, store.byHeight[3].Hash(), h.Hash())
+
+	// the header is served from the cache even if the underlying store lost it
+	delete(store.byHash, h.Hash().String())
+	delete(store.byHeight, 3)
+	h, err = cs.Get(ctx, h.Hash())
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, h.Height)
+
+	ok, err := cs.Has(ctx, h.Hash())
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = cs.Has(ctx, tmrand.Bytes(32))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// writes invalidate cached entries
+	err = cs.Append(ctx, h)
+	require.NoError(t, err)
+	assert.False(t, cs.byHash.Contains(h.Hash().String()))
+	assert.False(t, cs.byHeight.Contains(uint64(3)))
+}
+
+func TestCachingStore_TTL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	store := createStore(t, 0)
+	err := store.Append(ctx, suite.GenExtendedHeaders(10)...)
+	require.NoError(t, err)
+
+	ttl := 50 * time.Millisecond
+	cs, err := NewCachingStore(store, WithCacheTTL(ttl))
+	require.NoError(t, err)
+
+	h, err := cs.GetByHeight(ctx, 3)
+	require.NoError(t, err)
+
+	// once the TTL passes, the header is read from the underlying store, which lost it
+	delete(store.byHash, h.Hash().String())
+	delete(store.byHeight, 3)
+	time.Sleep(ttl)
+
+	_, err = cs.GetByHeight(ctx, 3)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = cs.Get(ctx, h.Hash())
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.False(t, cs.byHash.Contains(h.Hash().String()))
+	assert.False(t, cs.byHeight.Contains(uint64(3)))
+}
+
+func BenchmarkStore_GetByHeight(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(&testing.T{}, 3)
+	store, err := NewStoreWithHead(sync.MutexWrap(datastore.NewMapDatastore()), suite.Head())
+	require.NoError(b, err)
+	err = store.Append(ctx, suite.GenExtendedHeaders(DefaultStoreCacheSize*2)...)
+	require.NoError(b, err)
+
+	cs, err := NewCachingStore(store)
+	require.NoError(b, err)
+
+	for name, store := range map[string]Store{"Store": store, "CachingStore": cs} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := store.GetByHeight(ctx, uint64(i%(DefaultStoreCacheSize*2))+1)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package header
+
+import (
+	"context"
+	"fmt"
+)
+
+// heightIterator is a HeaderIterator which loads ExtendedHeaders by height as it advances,
+// so only the current one is kept in memory.
+type heightIterator struct {
+	ctx         context.Context
+	getByHeight func(context.Context, uint64) (*ExtendedHeader, error)
+
+	next, to uint64
+	cur      *ExtendedHeader
+	err      error
+}
+
+// NewHeightIterator creates a HeaderIterator over the range [from:to) ensuring it is stored, which loads
+// the headers with the given function as it advances. It is meant for Store implementations.
+// Headers could be pruned in the middle of the iteration, so Next may still stop with ErrNotFound.
+func NewHeightIterator(
+	ctx context.Context,
+	getByHeight func(context.Context, uint64) (*ExtendedHeader, error),
+	from, to uint64,
+) (HeaderIterator, error) {
+	if from > to {
+		return nil, fmt.Errorf("header/store: invalid range [%d:%d)", from, to)
+	}
+	if from < to {
+		// ensure the whole range exists before iterating over it
+		_, err := getByHeight(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		_, err = getByHeight(ctx, to-1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &heightIterator{
+		ctx:         ctx,
+		getByHeight: getByHeight,
+		next:        from,
+		to:          to,
+	}, nil
+}
+
+func (it *heightIterator) Next() bool {
+	it.cur = nil
+	if it.err != nil || it.next >= it.to {
+		return false
+	}
+	if it.ctx.Err() != nil {
+		it.err = fmt.Errorf("header/store: iterating range interrupted: %w", it.ctx.Err())
+		return false
+	}
+
+	it.cur, it.err = it.getByHeight(it.ctx, it.next)
+	if it.err != nil {
+		return false
+	}
+	it.next++
+	return true
+}
+
+func (it *heightIterator) Value() *ExtendedHeader {
+	return it.cur
+}
+
+func (it *heightIterator) Close() error {
+	// stop any further iteration
+	it.to = it.next
+	it.cur = nil
+	return it.err
+}
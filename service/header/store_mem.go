@@ -0,0 +1,306 @@
+package header
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/tendermint/tendermint/libs/bytes"
+)
+
+// memStore is a Store keeping ExtendedHeaders in memory.
+type memStore struct {
+	lk       sync.RWMutex
+	byHash   map[string]*ExtendedHeader
+	byHeight map[uint64]*ExtendedHeader
+	// deleted keeps the heights removed with DeleteByHeight
+	deleted map[uint64]struct{}
+	// head and tail are nil until the first headers are appended
+	head, tail *ExtendedHeader
+
+	heads HeadBroadcaster
+}
+
+// NewMemStore constructs an in-memory Store, which is suitable for tests and ephemeral nodes.
+// It follows the same semantics as the datastore backed Store: the first appended headers are trusted,
+// while the following ones are verified.
+func NewMemStore() Store {
+	return &memStore{
+		byHash:   make(map[string]*ExtendedHeader),
+		byHeight: make(map[uint64]*ExtendedHeader),
+		deleted:  make(map[uint64]struct{}),
+	}
+}
+
+func (m *memStore) Head(context.Context) (*ExtendedHeader, error) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	if m.head == nil {
+		return nil, ErrNoHead
+	}
+	return m.head, nil
+}
+
+func (m *memStore) Tail(context.Context) (*ExtendedHeader, error) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	if m.tail == nil {
+		return nil, ErrNoHead
+	}
+	return m.tail, nil
+}
+
+func (m *memStore) Get(_ context.Context, hash bytes.HexBytes) (*ExtendedHeader, error) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	if h, ok := m.byHash[hash.String()]; ok {
+		return h, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memStore) GetByHashPrefix(_ context.Context, prefix []byte) ([]*ExtendedHeader, error) {
+	if len(prefix) == 0 {
+		return nil, nil
+	}
+
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+
+	headers := make([]*ExtendedHeader, 0)
+	for _, h := range m.byHeight {
+		if hasHashPrefix(h.Hash(), prefix) {
+			headers = append(headers, h)
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Height < headers[j].Height
+	})
+	if len(headers) > MaxHashPrefixMatches {
+		headers = headers[:MaxHashPrefixMatches]
+	}
+	return headers, nil
+}
+
+func (m *memStore) GetByHeight(_ context.Context, height uint64) (*ExtendedHeader, error) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	if h, ok := m.byHeight[height]; ok {
+		return h, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memStore) GetRangeByHeight(ctx context.Context, from, to uint64) ([]*ExtendedHeader, error) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+
+	headers := make([]*ExtendedHeader, 0, to-from)
+	for height := from; height < to; height++ {
+		h, ok := m.byHeight[height]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		headers = append(headers, h)
+	}
+
+	if ctx.Err() != nil {
+		return headers, fmt.Errorf("header/store: getting range interrupted: %w", ctx.Err())
+	}
+	return headers, nil
+}
+
+func (m *memStore) GetByHeightRange(ctx context.Context, from, to uint64) ([]*ExtendedHeader, error) {
+	return GetByHeightRange(ctx, m, from, to)
+}
+
+func (m *memStore) IterateByHeight(ctx context.Context, from, to uint64) (HeaderIterator, error) {
+	return NewHeightIterator(ctx, m.GetByHeight, from, to)
+}
+
+func (m *memStore) ForEach(ctx context.Context, fn func(*ExtendedHeader) error) error {
+	return ForEach(ctx, m, fn)
+}
+
+func (m *memStore) Has(_ context.Context, hash bytes.HexBytes) (bool, error) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	_, ok := m.byHash[hash.String()]
+	return ok, nil
+}
+
+func (m *memStore) HasAt(_ context.Context, height uint64) (bool, error) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	_, ok := m.byHeight[height]
+	return ok, nil
+}
+
+func (m *memStore) CountHeaders(context.Context) (uint64, error) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	return uint64(len(m.byHeight)), nil
+}
+
+func (m *memStore) AppendSingle(ctx context.Context, h *ExtendedHeader) error {
+	return AppendSingle(ctx, m, h)
+}
+
+func (m *memStore) Append(_ context.Context, headers ...*ExtendedHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if m.head == nil {
+		// trust the given header as the initial head
+		m.put(headers...)
+		m.head, m.tail = headers[len(headers)-1], headers[0]
+		m.heads.Publish(headers...)
+		return nil
+	}
+
+	// headers below the head fill a gap in the stored chain
+	if headers[0].Height < m.head.Height {
+		return m.fill(headers)
+	}
+
+	verified := VerifyAppend(m.head, headers)
+	if len(verified) == 0 {
+		log.Warn("header/store: no valid headers were given")
+		return nil
+	}
+
+	m.put(verified...)
+	m.head = verified[len(verified)-1]
+	m.heads.Publish(verified...)
+	return nil
+}
+
+func (m *memStore) WatchHead(ctx context.Context) (<-chan *ExtendedHeader, error) {
+	return m.heads.Watch(ctx)
+}
+
+func (m *memStore) Prune(_ context.Context, keepLast uint64) error {
+	if keepLast == 0 {
+		return fmt.Errorf("header/store: at least one header must be kept")
+	}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if m.head == nil || uint64(m.head.Height) <= keepLast {
+		return nil
+	}
+	newTail := uint64(m.head.Height) - keepLast + 1
+	if uint64(m.tail.Height) >= newTail {
+		return nil
+	}
+	for height := uint64(m.tail.Height); height < newTail; height++ {
+		if h, ok := m.byHeight[height]; ok {
+			delete(m.byHash, h.Hash().String())
+			delete(m.byHeight, height)
+		}
+	}
+
+	for height := newTail; height <= uint64(m.head.Height); height++ {
+		if tail, ok := m.byHeight[height]; ok {
+			m.tail = tail
+			break
+		}
+	}
+	return nil
+}
+
+func (m *memStore) DeleteByHeight(_ context.Context, height uint64) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if m.head == nil {
+		return ErrNoHead
+	}
+	if height == uint64(m.head.Height) {
+		return ErrCannotDeleteHead
+	}
+
+	h, ok := m.byHeight[height]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.byHash, h.Hash().String())
+	delete(m.byHeight, height)
+	m.deleted[height] = struct{}{}
+
+	if h == m.tail {
+		for height++; height <= uint64(m.head.Height); height++ {
+			if tail, ok := m.byHeight[height]; ok {
+				m.tail = tail
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (m *memStore) Deleted(_ context.Context, height uint64) (bool, error) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	_, stored := m.byHeight[height]
+	_, deleted := m.deleted[height]
+	return deleted && !stored, nil
+}
+
+func (m *memStore) ReplaceLast(_ context.Context, h *ExtendedHeader) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if m.head == nil {
+		return ErrNoHead
+	}
+	ok, err := VerifyReplace(m.head, h, m.byHeight[uint64(m.head.Height-1)])
+	if err != nil || !ok {
+		return err
+	}
+
+	delete(m.byHash, m.head.Hash().String())
+	m.put(h)
+	if m.tail == m.head {
+		m.tail = h
+	}
+	m.head = h
+	m.heads.Publish(h)
+	return nil
+}
+
+// fill stores the given headers below the head, filling a gap in the stored chain.
+// The caller must hold the lock.
+func (m *memStore) fill(headers []*ExtendedHeader) error {
+	last := headers[len(headers)-1]
+	if last.Height >= m.head.Height {
+		return fmt.Errorf("header/store: gap [%d:%d] overlaps head %d", headers[0].Height, last.Height, m.head.Height)
+	}
+
+	prev, ok := m.byHeight[uint64(headers[0].Height-1)]
+	if !ok {
+		return fmt.Errorf("header/store: getting header preceding gap at %d: %w", headers[0].Height, ErrNotFound)
+	}
+
+	err := VerifyGap(prev, headers, m.byHeight[uint64(last.Height+1)])
+	if err != nil {
+		return err
+	}
+
+	m.put(headers...)
+	return nil
+}
+
+// put stores the given headers. The caller must hold the lock.
+func (m *memStore) put(headers ...*ExtendedHeader) {
+	for _, h := range headers {
+		m.byHash[h.Hash().String()] = h
+		m.byHeight[uint64(h.Height)] = h
+	}
+}
@@ -0,0 +1,551 @@
+package header
+
+import (
+	"context"
+	"errors"
+	"math"
+	mrand "math/rand"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStores constructs empty instances of all the Store implementations.
+// The ones outside of the package are added by the external tests, see export_test.go.
+var testStores = map[string]func(t *testing.T) Store{
+	"datastore": func(t *testing.T) Store {
+		store, err := NewStore(sync.MutexWrap(datastore.NewMapDatastore()))
+		require.NoError(t, err)
+		return store
+	},
+	"memory": func(*testing.T) Store {
+		return NewMemStore()
+	},
+	"caching": func(t *testing.T) Store {
+		store, err := NewCachingStore(NewMemStore())
+		require.NoError(t, err)
+		return store
+	},
+}
+
+// TestStore_Semantics checks that the Store implementations behave the same.
+func TestStore_Semantics(t *testing.T) {
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			store := newStore(t)
+			_, err := store.Head(ctx)
+			assert.ErrorIs(t, err, ErrNoHead)
+			_, err = store.Tail(ctx)
+			assert.ErrorIs(t, err, ErrNoHead)
+
+			suite := NewTestSuite(t, 3)
+			in := suite.GenExtendedHeaders(10)
+			// the first headers are trusted
+			err = store.Append(ctx, in[:5]...)
+			require.NoError(t, err)
+
+			// the following ones are verified, stopping at the first invalid one
+			invalid := *in[7]
+			invalid.ChainID = "other"
+			err = store.Append(ctx, in[5], in[6], &invalid, in[8])
+			require.NoError(t, err)
+
+			head, err := store.Head(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, in[6].Hash(), head.Hash())
+
+			tail, err := store.Tail(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, in[0].Hash(), tail.Hash())
+
+			out, err := store.GetRangeByHeight(ctx, 1, 8)
+			require.NoError(t, err)
+			require.Len(t, out, 7)
+			for i, h := range out {
+				assert.Equal(t, in[i].Hash(), h.Hash())
+			}
+
+			it, err := store.IterateByHeight(ctx, 2, 8)
+			require.NoError(t, err)
+			var iterated int
+			for it.Next() {
+				assert.Equal(t, in[iterated+1].Hash(), it.Value().Hash())
+				iterated++
+			}
+			require.NoError(t, it.Close())
+			assert.Equal(t, 6, iterated)
+			assert.False(t, it.Next())
+
+			_, err = store.IterateByHeight(ctx, 5, 9)
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			_, err = store.GetByHeight(ctx, 8)
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = store.Get(ctx, in[7].Hash())
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			ok, err := store.Has(ctx, in[3].Hash())
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			err = store.Prune(ctx, 3)
+			require.NoError(t, err)
+
+			tail, err = store.Tail(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, in[4].Hash(), tail.Hash())
+
+			ok, err = store.Has(ctx, in[3].Hash())
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+// TestStore_GetByHeightRange checks the bounds of the [from:to) range of every Store implementation.
+func TestStore_GetByHeightRange(t *testing.T) {
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			store := newStore(t)
+			_, err := store.GetByHeightRange(ctx, 1, 2)
+			assert.ErrorIs(t, err, ErrNoHead)
+
+			in := NewTestSuite(t, 3).GenExtendedHeaders(5)
+			err = store.Append(ctx, in...)
+			require.NoError(t, err)
+
+			out, err := store.GetByHeightRange(ctx, 1, 6)
+			require.NoError(t, err)
+			require.Len(t, out, 5)
+			for i, h := range out {
+				assert.Equal(t, in[i].Hash(), h.Hash())
+			}
+			out, err = store.GetByHeightRange(ctx, 5, 6)
+			require.NoError(t, err)
+			require.Len(t, out, 1)
+			assert.Equal(t, in[4].Hash(), out[0].Hash())
+
+			for _, r := range [][2]uint64{{3, 3}, {4, 3}, {5, 7}, {6, 7}, {1, math.MaxUint64}, {math.MaxUint64, 0}} {
+				_, err = store.GetByHeightRange(ctx, r[0], r[1])
+				assert.ErrorIs(t, err, ErrInvalidRange, r)
+			}
+			_, err = store.GetByHeightRange(ctx, 0, 3)
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+// TestStore_GetByHeightRange_Fuzz checks GetByHeightRange of every Store implementation against
+// random ranges around the stored ones.
+func TestStore_GetByHeightRange_Fuzz(t *testing.T) {
+	seed := time.Now().UnixNano()
+	rand := mrand.New(mrand.NewSource(seed)) //nolint:gosec
+	t.Logf("seed: %d", seed)
+
+	const total, pruned = 20, 5
+	in := NewTestSuiteWithSeed(t, 3, seed).GenExtendedHeaders(total)
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			store := newStore(t)
+			err := store.Append(ctx, in...)
+			require.NoError(t, err)
+			// the lowest heights are not stored anymore
+			err = store.Prune(ctx, total-pruned)
+			require.NoError(t, err)
+			tail, head := uint64(pruned+1), uint64(total)
+
+			// heights around every boundary are more likely to be hit
+			edges := []uint64{0, 1, tail - 1, tail, tail + 1, head - 1, head, head + 1, head + 2, math.MaxUint64}
+			randHeight := func() uint64 {
+				if rand.Intn(2) == 0 {
+					return edges[rand.Intn(len(edges))]
+				}
+				return uint64(rand.Intn(total + 3))
+			}
+
+			for i := 0; i < 500; i++ {
+				from, to := randHeight(), randHeight()
+				out, err := store.GetByHeightRange(ctx, from, to)
+				switch {
+				case from >= to, to > head+1:
+					assert.ErrorIs(t, err, ErrInvalidRange, "[%d:%d)", from, to)
+				case from < tail:
+					assert.ErrorIs(t, err, ErrNotFound, "[%d:%d)", from, to)
+				default:
+					require.NoError(t, err, "[%d:%d)", from, to)
+					require.Len(t, out, int(to-from), "[%d:%d)", from, to)
+					for j, h := range out {
+						assert.Equal(t, in[from-1+uint64(j)].Hash(), h.Hash())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStore_AppendSingle(t *testing.T) {
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			store := newStore(t)
+			err := store.AppendSingle(ctx, nil)
+			assert.Error(t, err)
+
+			suite := NewTestSuite(t, 3)
+			in := suite.GenExtendedHeaders(3)
+			// the first header is trusted
+			err = store.AppendSingle(ctx, in[1])
+			require.NoError(t, err)
+
+			// headers not above the head are rejected, instead of being filled in or skipped
+			err = store.AppendSingle(ctx, in[1])
+			assert.ErrorIs(t, err, ErrNotAboveHead)
+			err = store.AppendSingle(ctx, in[0])
+			assert.ErrorIs(t, err, ErrNotAboveHead)
+
+			err = store.AppendSingle(ctx, in[2])
+			require.NoError(t, err)
+			head, err := store.Head(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, in[2].Hash(), head.Hash())
+		})
+	}
+}
+
+func TestStore_ReplaceLast(t *testing.T) {
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			suite := NewTestSuiteWithSeed(t, 3, 1)
+			in := suite.GenExtendedHeaders(5)
+			store := newStore(t)
+			err := store.ReplaceLast(ctx, in[4])
+			assert.ErrorIs(t, err, ErrNoHead)
+			err = store.Append(ctx, in...)
+			require.NoError(t, err)
+			_, err = store.GetByHeight(ctx, 5) // cache it in the caching store
+			require.NoError(t, err)
+
+			// the fork shares the chain up to the head
+			fork := NewTestSuiteWithSeed(t, 3, 1)
+			fork.GenExtendedHeaders(4)
+			fork.randBytes(1)
+			forked := fork.GenExtendedHeader()
+			require.NotEqual(t, in[4].Hash(), forked.Hash())
+
+			err = store.ReplaceLast(ctx, in[3])
+			assert.ErrorIs(t, err, ErrHeightMismatch)
+			// the header of another chain does not link to the stored one
+			err = store.ReplaceLast(ctx, NewTestSuiteWithSeed(t, 3, 2).GenExtendedHeaders(5)[4])
+			assert.Error(t, err)
+			err = store.ReplaceLast(ctx, in[4])
+			require.NoError(t, err)
+
+			err = store.ReplaceLast(ctx, forked)
+			require.NoError(t, err)
+			head, err := store.Head(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, forked.Hash(), head.Hash())
+			h, err := store.GetByHeight(ctx, 5)
+			require.NoError(t, err)
+			assert.Equal(t, forked.Hash(), h.Hash())
+			_, err = store.Get(ctx, in[4].Hash())
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			// the chain goes on from the new head
+			next := fork.GenExtendedHeader()
+			err = store.AppendSingle(ctx, next)
+			require.NoError(t, err)
+			head, err = store.Head(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, next.Hash(), head.Hash())
+		})
+	}
+}
+
+func TestStore_CountHeaders(t *testing.T) {
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			store := newStore(t)
+			count, err := store.CountHeaders(ctx)
+			require.NoError(t, err)
+			assert.Zero(t, count)
+
+			suite := NewTestSuite(t, 3)
+			in := suite.GenExtendedHeaders(10)
+			err = store.Append(ctx, in[:8]...)
+			require.NoError(t, err)
+			err = store.AppendSingle(ctx, in[8])
+			require.NoError(t, err)
+			err = store.Append(ctx, in[9])
+			require.NoError(t, err)
+			count, err = store.CountHeaders(ctx)
+			require.NoError(t, err)
+			assert.EqualValues(t, 10, count)
+
+			// deleted headers are not counted until the gap is filled
+			err = store.DeleteByHeight(ctx, 5)
+			require.NoError(t, err)
+			count, err = store.CountHeaders(ctx)
+			require.NoError(t, err)
+			assert.EqualValues(t, 9, count)
+			err = store.Append(ctx, in[4])
+			require.NoError(t, err)
+			count, err = store.CountHeaders(ctx)
+			require.NoError(t, err)
+			assert.EqualValues(t, 10, count)
+
+			err = store.Prune(ctx, 3)
+			require.NoError(t, err)
+			count, err = store.CountHeaders(ctx)
+			require.NoError(t, err)
+			assert.EqualValues(t, 3, count)
+		})
+	}
+}
+
+func TestStore_HasAt(t *testing.T) {
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			store := newStore(t)
+			suite := NewTestSuite(t, 3)
+			in := suite.GenExtendedHeaders(5)
+			err := store.Append(ctx, in...)
+			require.NoError(t, err)
+
+			for _, h := range in {
+				has, err := store.HasAt(ctx, uint64(h.Height))
+				require.NoError(t, err)
+				assert.True(t, has)
+			}
+			has, err := store.HasAt(ctx, uint64(in[4].Height+1))
+			require.NoError(t, err)
+			assert.False(t, has)
+
+			err = store.DeleteByHeight(ctx, uint64(in[2].Height))
+			require.NoError(t, err)
+			has, err = store.HasAt(ctx, uint64(in[2].Height))
+			require.NoError(t, err)
+			assert.False(t, has)
+		})
+	}
+}
+
+func TestStore_GetByHashPrefix(t *testing.T) {
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			store := newStore(t)
+			suite := NewTestSuite(t, 3)
+			// enough headers for some of them to share the first byte of their hashes
+			in := suite.GenExtendedHeaders(100)
+			err := store.Append(ctx, in...)
+			require.NoError(t, err)
+
+			var common []*ExtendedHeader
+			byFirst := make(map[byte][]*ExtendedHeader)
+			for _, h := range in {
+				first := h.Hash()[0]
+				byFirst[first] = append(byFirst[first], h)
+				if len(byFirst[first]) > len(common) {
+					common = byFirst[first]
+				}
+			}
+			require.Greater(t, len(common), 1)
+
+			out, err := store.GetByHashPrefix(ctx, common[0].Hash()[:1])
+			require.NoError(t, err)
+			require.Len(t, out, len(common))
+			for i, h := range out {
+				assert.Equal(t, common[i].Hash(), h.Hash())
+			}
+
+			out, err = store.GetByHashPrefix(ctx, common[1].Hash()[:3])
+			require.NoError(t, err)
+			require.Len(t, out, 1)
+			assert.Equal(t, common[1].Hash(), out[0].Hash())
+
+			limit := MaxHashPrefixMatches
+			MaxHashPrefixMatches = 1
+			out, err = store.GetByHashPrefix(ctx, common[0].Hash()[:1])
+			MaxHashPrefixMatches = limit
+			require.NoError(t, err)
+			require.Len(t, out, 1)
+			assert.Equal(t, common[0].Hash(), out[0].Hash())
+
+			// deleted headers are not matched
+			err = store.DeleteByHeight(ctx, uint64(common[0].Height))
+			require.NoError(t, err)
+			out, err = store.GetByHashPrefix(ctx, common[0].Hash())
+			require.NoError(t, err)
+			assert.Empty(t, out)
+
+			unknown := NewTestSuite(t, 3).GenExtendedHeaders(1)[0]
+			out, err = store.GetByHashPrefix(ctx, unknown.Hash())
+			require.NoError(t, err)
+			assert.NotNil(t, out)
+			assert.Empty(t, out)
+		})
+	}
+}
+
+func TestStore_ForEach(t *testing.T) {
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			store := newStore(t)
+			err := store.ForEach(ctx, func(*ExtendedHeader) error {
+				return errors.New("no headers are expected")
+			})
+			require.NoError(t, err)
+
+			in := NewTestSuite(t, 3).GenExtendedHeaders(10)
+			err = store.Append(ctx, in...)
+			require.NoError(t, err)
+
+			var visited []*ExtendedHeader
+			err = store.ForEach(ctx, func(h *ExtendedHeader) error {
+				visited = append(visited, h)
+				return nil
+			})
+			require.NoError(t, err)
+			require.Len(t, visited, len(in))
+			for i, h := range visited {
+				assert.Equal(t, in[i].Hash(), h.Hash())
+			}
+
+			// iteration stops on the first error
+			stop := errors.New("stop")
+			var calls int
+			err = store.ForEach(ctx, func(h *ExtendedHeader) error {
+				calls++
+				if h.Height == in[3].Height {
+					return stop
+				}
+				return nil
+			})
+			assert.ErrorIs(t, err, stop)
+			assert.Equal(t, 4, calls)
+
+			// gaps are skipped
+			err = store.DeleteByHeight(ctx, uint64(in[5].Height))
+			require.NoError(t, err)
+			calls = 0
+			err = store.ForEach(ctx, func(h *ExtendedHeader) error {
+				assert.NotEqual(t, in[5].Height, h.Height)
+				calls++
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, len(in)-1, calls)
+		})
+	}
+}
+
+func TestStore_DeleteByHeight(t *testing.T) {
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			store := newStore(t)
+			err := store.DeleteByHeight(ctx, 1)
+			assert.ErrorIs(t, err, ErrNoHead)
+
+			suite := NewTestSuite(t, 3)
+			in := suite.GenExtendedHeaders(10)
+			err = store.Append(ctx, in...)
+			require.NoError(t, err)
+
+			// the head cannot be deleted
+			err = store.DeleteByHeight(ctx, 10)
+			assert.ErrorIs(t, err, ErrCannotDeleteHead)
+			head, err := store.Head(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, in[9].Hash(), head.Hash())
+
+			// an intermediate header leaves a gap
+			_, err = store.GetByHeight(ctx, 5) // cache it in the caching store
+			require.NoError(t, err)
+			err = store.DeleteByHeight(ctx, 5)
+			require.NoError(t, err)
+			_, err = store.GetByHeight(ctx, 5)
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = store.Get(ctx, in[4].Hash())
+			assert.ErrorIs(t, err, ErrNotFound)
+			ok, err := store.Has(ctx, in[4].Hash())
+			require.NoError(t, err)
+			assert.False(t, ok)
+			_, err = store.GetRangeByHeight(ctx, 4, 7)
+			assert.ErrorIs(t, err, ErrNotFound)
+			deleted, err := store.Deleted(ctx, 5)
+			require.NoError(t, err)
+			assert.True(t, deleted)
+			deleted, err = store.Deleted(ctx, 4)
+			require.NoError(t, err)
+			assert.False(t, deleted)
+
+			// deleted and non-existent headers are not found
+			err = store.DeleteByHeight(ctx, 5)
+			assert.ErrorIs(t, err, ErrNotFound)
+			err = store.DeleteByHeight(ctx, 11)
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			// the gap can be filled again
+			err = store.Append(ctx, in[4])
+			require.NoError(t, err)
+			h, err := store.GetByHeight(ctx, 5)
+			require.NoError(t, err)
+			assert.Equal(t, in[4].Hash(), h.Hash())
+			deleted, err = store.Deleted(ctx, 5)
+			require.NoError(t, err)
+			assert.False(t, deleted)
+
+			// deleting the tail moves it to the next stored header
+			err = store.DeleteByHeight(ctx, 2)
+			require.NoError(t, err)
+			err = store.DeleteByHeight(ctx, 1)
+			require.NoError(t, err)
+			tail, err := store.Tail(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, in[2].Hash(), tail.Hash())
+		})
+	}
+}
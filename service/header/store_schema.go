@@ -0,0 +1,230 @@
+package header
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// SchemaVersion is the version of the layout Store keeps ExtendedHeaders on disk with.
+type SchemaVersion uint64
+
+const (
+	// SchemaV1 keeps the height index as decimal heights mixed with header hashes.
+	SchemaV1 SchemaVersion = 1
+	// SchemaV2 keeps the height index in its own namespace with fixed-width heights,
+	// so it is ordered by height.
+	SchemaV2 SchemaVersion = 2
+
+	// CurrentSchema is the version Store works with.
+	CurrentSchema = SchemaV2
+)
+
+// ErrUnsupportedSchema is returned when the Store is opened over data kept with a schema
+// it cannot migrate from, e.g. a newer one.
+var ErrUnsupportedSchema = errors.New("header/store: unsupported schema")
+
+var (
+	schemaKey       = datastore.NewKey("schema")
+	heightsPrefix   = datastore.NewKey("heights")
+	storeSchemaKey  = storePrefix.Child(schemaKey)
+	storeHeadKey    = storePrefix.Child(headKey)
+	storeHeightsKey = storePrefix.Child(heightsPrefix)
+)
+
+// loadSchema returns the schema version of the data kept in the given datastore.
+// Data without the version is kept with SchemaV1, while an empty datastore has the current one.
+func loadSchema(ds datastore.Datastore) (SchemaVersion, bool, error) {
+	b, err := ds.Get(storeSchemaKey)
+	switch err {
+	case nil:
+		v, err := strconv.ParseUint(string(b), 10, 64)
+		return SchemaVersion(v), true, err
+	case datastore.ErrNotFound:
+	default:
+		return 0, false, err
+	}
+
+	has, err := ds.Has(storeHeadKey)
+	if err != nil {
+		return 0, false, err
+	}
+	if has {
+		return SchemaV1, false, nil
+	}
+	return CurrentSchema, false, nil
+}
+
+// checkSchema ensures the data in the given datastore is kept with the current schema,
+// migrating the data kept with an older one and marking the datastore with it if it is empty.
+func checkSchema(ds datastore.Batching) error {
+	version, stored, err := loadSchema(ds)
+	if err != nil {
+		return err
+	}
+	switch {
+	case version == CurrentSchema && stored:
+		return nil
+	case version == CurrentSchema:
+		return ds.Put(storeSchemaKey, []byte(strconv.FormatUint(uint64(version), 10)))
+	case version > CurrentSchema:
+		return fmt.Errorf("%w: data is kept with schema v%d, while v%d is expected",
+			ErrUnsupportedSchema, version, CurrentSchema)
+	}
+
+	log.Infow("migrating store schema", "from", version, "to", CurrentSchema)
+	changes, err := MigrateSchema(context.Background(), ds, version, CurrentSchema, false)
+	if err != nil {
+		return fmt.Errorf("header/store: migrating schema from v%d to v%d: %w", version, CurrentSchema, err)
+	}
+	log.Infow("migrated store schema", "from", version, "to", CurrentSchema, "records", len(changes))
+	return nil
+}
+
+// MigrationChange describes a single record changed by a migration.
+type MigrationChange struct {
+	// From is the key of the record before the migration. It is deleted, unless the same as To.
+	From datastore.Key
+	// To is the key of the record after the migration.
+	To datastore.Key
+}
+
+// MigrateSchema migrates ExtendedHeaders kept in the given datastore from one schema version to another.
+// All the changes are written within a single transaction if the datastore supports them,
+// or a single batch otherwise. In dry-run mode the changes are only returned and nothing is written.
+func MigrateSchema(
+	ctx context.Context,
+	ds datastore.Batching,
+	from, to SchemaVersion,
+	dryRun bool,
+) ([]MigrationChange, error) {
+	if from == to {
+		return nil, nil
+	}
+	if from != SchemaV1 || to != SchemaV2 {
+		return nil, fmt.Errorf("header/store: unsupported migration from v%d to v%d", from, to)
+	}
+
+	version, _, err := loadSchema(ds)
+	if err != nil {
+		return nil, err
+	}
+	if version != from {
+		return nil, fmt.Errorf("header/store: data is kept with schema v%d, not v%d", version, from)
+	}
+
+	changes, err := migrateV1ToV2(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	w, err := newMigrationWriter(ds)
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range changes {
+		if ctx.Err() != nil {
+			w.Discard()
+			return nil, ctx.Err()
+		}
+
+		v, err := ds.Get(ch.From)
+		if err != nil {
+			w.Discard()
+			return nil, err
+		}
+
+		err = w.Put(ch.To, v)
+		if err != nil {
+			w.Discard()
+			return nil, err
+		}
+
+		err = w.Delete(ch.From)
+		if err != nil {
+			w.Discard()
+			return nil, err
+		}
+	}
+
+	err = w.Put(storeSchemaKey, []byte(strconv.FormatUint(uint64(to), 10)))
+	if err != nil {
+		w.Discard()
+		return nil, err
+	}
+	return changes, w.Commit()
+}
+
+// migrateV1ToV2 collects the height index records to be moved into their own namespace.
+func migrateV1ToV2(ctx context.Context, ds datastore.Datastore) ([]MigrationChange, error) {
+	res, err := ds.Query(query.Query{Prefix: storePrefix.String(), KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var changes []MigrationChange
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		key := datastore.RawKey(r.Key)
+		if !key.Parent().Equal(storePrefix) {
+			continue
+		}
+		// hashes are longer than any decimal height
+		name := key.BaseNamespace()
+		if len(name) > 20 {
+			continue
+		}
+		height, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		changes = append(changes, MigrationChange{
+			From: key,
+			To:   storePrefix.Child(heightKey(height)),
+		})
+	}
+	return changes, nil
+}
+
+// migrationWriter is the common part of datastore.Txn and datastore.Batch used by migrations.
+type migrationWriter interface {
+	Put(datastore.Key, []byte) error
+	Delete(datastore.Key) error
+	Commit() error
+	// Discard drops all the pending changes.
+	Discard()
+}
+
+func newMigrationWriter(ds datastore.Batching) (migrationWriter, error) {
+	if tds, ok := ds.(datastore.TxnDatastore); ok {
+		return tds.NewTransaction(false)
+	}
+
+	batch, err := ds.Batch()
+	if err != nil {
+		return nil, err
+	}
+	return discardingBatch{batch}, nil
+}
+
+// discardingBatch is a datastore.Batch, whose pending changes are dropped by not committing it.
+type discardingBatch struct {
+	datastore.Batch
+}
+
+func (discardingBatch) Discard() {}
@@ -0,0 +1,85 @@
+package header
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateSchema(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	in := NewTestSuite(t, 3).GenExtendedHeaders(5)
+	writeV1(t, ds, in)
+
+	_, err := MigrateSchema(ctx, ds, SchemaV2, SchemaV1, false)
+	assert.Error(t, err)
+
+	// dry run changes nothing
+	changes, err := MigrateSchema(ctx, ds, SchemaV1, SchemaV2, true)
+	require.NoError(t, err)
+	assert.Len(t, changes, len(in))
+	version, _, err := loadSchema(ds)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaV1, version)
+
+	changes, err = MigrateSchema(ctx, ds, SchemaV1, SchemaV2, false)
+	require.NoError(t, err)
+	assert.Len(t, changes, len(in))
+
+	store, err := NewStore(ds)
+	require.NoError(t, err)
+	for _, h := range in {
+		out, err := store.GetByHeight(ctx, uint64(h.Height))
+		require.NoError(t, err)
+		assert.Equal(t, h.Hash(), out.Hash())
+	}
+}
+
+// TestStore_MigratesSchema tests that the Store migrates the data kept with an outdated schema once opened.
+func TestStore_MigratesSchema(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	in := NewTestSuite(t, 3).GenExtendedHeaders(5)
+	writeV1(t, ds, in)
+
+	store, err := NewStore(ds)
+	require.NoError(t, err)
+	version, stored, err := loadSchema(ds)
+	require.NoError(t, err)
+	assert.True(t, stored)
+	assert.Equal(t, CurrentSchema, version)
+	for _, h := range in {
+		out, err := store.GetByHeight(ctx, uint64(h.Height))
+		require.NoError(t, err)
+		assert.Equal(t, h.Hash(), out.Hash())
+	}
+
+	// newer schemas are not supported
+	err = ds.Put(storeSchemaKey, []byte(strconv.FormatUint(uint64(CurrentSchema+1), 10)))
+	require.NoError(t, err)
+	_, err = NewStore(ds)
+	assert.ErrorIs(t, err, ErrUnsupportedSchema)
+}
+
+// writeV1 writes the given headers into the datastore the way the Store kept them with SchemaV1.
+func writeV1(t *testing.T, ds datastore.Datastore, headers []*ExtendedHeader) {
+	for _, h := range headers {
+		b, err := h.MarshalBinary()
+		require.NoError(t, err)
+		require.NoError(t, ds.Put(storePrefix.ChildString(h.Hash().String()), b))
+		require.NoError(t, ds.Put(storePrefix.ChildString(strconv.Itoa(int(h.Height))), h.Hash()))
+	}
+	head, err := headers[len(headers)-1].Hash().MarshalJSON()
+	require.NoError(t, err)
+	require.NoError(t, ds.Put(storeHeadKey, head))
+}
@@ -0,0 +1,22 @@
+package header_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/celestia-node/service/header"
+	"github.com/celestiaorg/celestia-node/service/header/sqlitestore"
+)
+
+func init() {
+	header.TestStores["sqlite"] = func(t *testing.T) header.Store {
+		store, err := sqlitestore.NewStore(filepath.Join(t.TempDir(), "headers.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			store.Close() //nolint:errcheck
+		})
+		return store
+	}
+}
@@ -2,13 +2,18 @@ package header
 
 import (
 	"context"
+	"errors"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
 	"github.com/ipfs/go-datastore/sync"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
 	tmrand "github.com/tendermint/tendermint/libs/rand"
 )
 
@@ -50,3 +55,506 @@ func TestStore(t *testing.T) {
 	require.NoError(t, err)
 	assert.False(t, ok)
 }
+
+func TestStore_Prune(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	genesis := suite.Head()
+	store, err := NewStoreWithHead(ds, genesis)
+	require.NoError(t, err)
+
+	in := suite.GenExtendedHeaders(10)
+	err = store.Append(ctx, in...)
+	require.NoError(t, err)
+
+	tail, err := store.Tail(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, genesis.Hash(), tail.Hash())
+
+	err = store.Prune(ctx, 4)
+	require.NoError(t, err)
+
+	// heights 7 to 10 are kept
+	for height := uint64(0); height <= 6; height++ {
+		_, err = store.GetByHeight(ctx, height)
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+	for _, h := range in[6:] {
+		out, err := store.GetByHeight(ctx, uint64(h.Height))
+		require.NoError(t, err)
+		assert.Equal(t, h.Hash(), out.Hash())
+	}
+
+	ok, err := store.Has(ctx, in[0].Hash())
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	head, err := store.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[len(in)-1].Hash(), head.Hash())
+
+	tail, err = store.Tail(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[6].Hash(), tail.Hash())
+	// the tail survives restarts
+	store, err = NewStore(ds)
+	require.NoError(t, err)
+	tail, err = store.Tail(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[6].Hash(), tail.Hash())
+
+	err = store.Prune(ctx, 0)
+	assert.Error(t, err)
+}
+
+func TestStore_TailNoHead(t *testing.T) {
+	store, err := NewStore(sync.MutexWrap(datastore.NewMapDatastore()))
+	require.NoError(t, err)
+
+	_, err = store.Tail(context.Background())
+	assert.ErrorIs(t, err, ErrNoHead)
+}
+
+// TestStore_AppendCrash simulates a crash in the middle of Append and ensures
+// the store is consistent after reopening.
+func TestStore_Has(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	store, err := NewStoreWithHead(ds, suite.Head())
+	require.NoError(t, err)
+
+	in := suite.GenExtendedHeaders(5)
+	err = store.Append(ctx, in...)
+	require.NoError(t, err)
+
+	// reopen the store, so that headers are known only by the bloom filter loaded from disk
+	store, err = NewStore(ds)
+	require.NoError(t, err)
+
+	for _, h := range in {
+		ok, err := store.Has(ctx, h.Hash())
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+
+	ok, err := store.Has(ctx, tmrand.Bytes(32))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// pruned headers stay in the bloom filter, but are still reported as missing
+	err = store.Prune(ctx, 1)
+	require.NoError(t, err)
+	ok, err = store.Has(ctx, in[0].Hash())
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// TestStore_BloomFalsePositives tests that the bloom filter loaded from disk has no false negatives
+// and its false positive rate matches the estimated one.
+func TestStore_BloomFalsePositives(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// overfill the filter with the headers below, so false positives are frequent enough to measure
+	const amount = 256
+	size := DefaultStoreBloomSize
+	DefaultStoreBloomSize = amount / 4
+	t.Cleanup(func() {
+		DefaultStoreBloomSize = size
+	})
+
+	suite := NewTestSuite(t, 3)
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	store, err := NewStore(ds)
+	require.NoError(t, err)
+	in := suite.GenExtendedHeaders(amount)
+	err = store.Append(ctx, in...)
+	require.NoError(t, err)
+
+	// reopen the store, so that the filter is loaded from disk
+	reopened, err := newStore(ds)
+	require.NoError(t, err)
+	for _, h := range in {
+		assert.True(t, reopened.bloom.HasTS(headerKey(h).Bytes()), "false negative at %d", h.Height)
+	}
+
+	const samples = 100000
+	var positives int
+	for i := 0; i < samples; i++ {
+		if reopened.bloom.HasTS(datastore.NewKey(tmbytes.HexBytes(tmrand.Bytes(32)).String()).Bytes()) {
+			positives++
+		}
+	}
+	rate := float64(positives) / samples
+	t.Logf("false positive rate: measured %f, estimated %f", rate, reopened.FalsePositiveRate())
+	assert.InEpsilon(t, reopened.FalsePositiveRate(), rate, 0.05)
+}
+
+func TestStore_AppendCrash(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	ds := &crashingDatastore{Batching: sync.MutexWrap(datastore.NewMapDatastore())}
+	store, err := NewStoreWithHead(ds, suite.Head())
+	require.NoError(t, err)
+
+	in := suite.GenExtendedHeaders(5)
+	err = store.Append(ctx, in[:2]...)
+	require.NoError(t, err)
+
+	ds.crash = true
+	err = store.Append(ctx, in[2:]...)
+	require.Error(t, err)
+	ds.crash = false
+
+	// reopen the store over the same datastore
+	store, err = NewStore(ds)
+	require.NoError(t, err)
+
+	head, err := store.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[1].Hash(), head.Hash())
+
+	for _, h := range in[2:] {
+		ok, err := store.Has(ctx, h.Hash())
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		_, err = store.GetByHeight(ctx, uint64(h.Height))
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+
+	out, err := store.GetRangeByHeight(ctx, 1, uint64(head.Height)+1)
+	require.NoError(t, err)
+	for i, h := range in[:2] {
+		assert.Equal(t, h.Hash(), out[i].Hash())
+	}
+}
+
+func TestStore_GetRangeByHeight_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	store, err := NewStoreWithHead(ds, suite.Head())
+	require.NoError(t, err)
+	err = store.Append(ctx, suite.GenExtendedHeaders(10)...)
+	require.NoError(t, err)
+
+	// reopen the store, so headers are read from the datastore, canceling after a few of them
+	const received = 3
+	rangeCtx, rangeCancel := context.WithCancel(ctx)
+	defer rangeCancel()
+	store, err = NewStore(&cancelingDatastore{Batching: ds, after: received + 1, cancel: rangeCancel})
+	require.NoError(t, err)
+
+	out, err := store.GetRangeByHeight(rangeCtx, 1, 11)
+	assert.ErrorIs(t, err, context.Canceled)
+	require.Len(t, out, received)
+	for i, h := range out {
+		assert.EqualValues(t, i+1, h.Height)
+	}
+}
+
+// cancelingDatastore cancels the context after the given amount of headers were read.
+type cancelingDatastore struct {
+	datastore.Batching
+	after  int
+	reads  int
+	cancel context.CancelFunc
+}
+
+func (cd *cancelingDatastore) Get(key datastore.Key) ([]byte, error) {
+	val, err := cd.Batching.Get(key)
+	// headers are stored under their hashes, so count only those
+	if err == nil && len(key.BaseNamespace()) == 64 {
+		cd.reads++
+		if cd.reads == cd.after {
+			cd.cancel()
+		}
+	}
+	return val, err
+}
+
+// crashingDatastore simulates a crash by dropping batches instead of committing them.
+type crashingDatastore struct {
+	datastore.Batching
+	crash bool
+}
+
+func (cd *crashingDatastore) Batch() (datastore.Batch, error) {
+	batch, err := cd.Batching.Batch()
+	if err != nil {
+		return nil, err
+	}
+	return &crashingBatch{Batch: batch, ds: cd}, nil
+}
+
+type crashingBatch struct {
+	datastore.Batch
+	ds *crashingDatastore
+}
+
+func (cb *crashingBatch) Commit() error {
+	if cb.ds.crash {
+		return errors.New("crashed")
+	}
+	return cb.Batch.Commit()
+}
+
+func TestStore_AppendStalled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	in := suite.GenExtendedHeaders(6)
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	_, err := NewStoreWithHead(ds, in[0])
+	require.NoError(t, err)
+
+	stalling := &stallingDatastore{Batching: ds, release: make(chan struct{})}
+	store, err := NewStore(stalling)
+	require.NoError(t, err)
+
+	appendCtx, appendCancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer appendCancel()
+	start := time.Now()
+	err = store.Append(appendCtx, in[1:]...)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the write completes once the disk is back
+	close(stalling.release)
+	require.Eventually(t, func() bool {
+		head, err := store.Head(ctx)
+		require.NoError(t, err)
+		return head.Height == in[len(in)-1].Height
+	}, time.Second, time.Millisecond*10)
+
+	err = store.Append(ctx, suite.GenExtendedHeader())
+	require.NoError(t, err)
+}
+
+// stallingDatastore simulates a stalled disk by blocking commits of batches until released.
+type stallingDatastore struct {
+	datastore.Batching
+	release chan struct{}
+}
+
+func (sd *stallingDatastore) Batch() (datastore.Batch, error) {
+	batch, err := sd.Batching.Batch()
+	if err != nil {
+		return nil, err
+	}
+	return &stallingBatch{Batch: batch, release: sd.release}, nil
+}
+
+type stallingBatch struct {
+	datastore.Batch
+	release chan struct{}
+}
+
+func (sb *stallingBatch) Commit() error {
+	<-sb.release
+	return sb.Batch.Commit()
+}
+
+func TestStore_AppendFillsGap(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	store, err := NewStoreWithHead(ds, suite.Head())
+	require.NoError(t, err)
+
+	in := suite.GenExtendedHeaders(20)
+	err = store.Append(ctx, in...)
+	require.NoError(t, err)
+
+	store = storeWithGap(t, ds, 8, 13)
+	for height := uint64(8); height < 13; height++ {
+		_, err = store.GetByHeight(ctx, height)
+		require.ErrorIs(t, err, ErrNotFound)
+	}
+
+	// headers from another chain are rejected
+	other := NewTestSuite(t, 3).GenExtendedHeaders(12)
+	err = store.Append(ctx, other[7:12]...)
+	assert.Error(t, err)
+
+	// the gap can be filled in parts
+	err = store.Append(ctx, in[7:10]...)
+	require.NoError(t, err)
+	err = store.Append(ctx, in[10:12]...)
+	require.NoError(t, err)
+
+	for _, h := range in {
+		out, err := store.GetByHeight(ctx, uint64(h.Height))
+		require.NoError(t, err)
+		assert.Equal(t, h.Hash(), out.Hash())
+	}
+	// the head stays the same
+	head, err := store.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, in[len(in)-1].Hash(), head.Hash())
+}
+
+// TestStore_CountHeadersPersisted tests that the amount of headers survives restarts
+// and is recovered for stores which did not track it.
+func TestStore_CountHeadersPersisted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	store, err := NewStoreWithHead(ds, suite.Head())
+	require.NoError(t, err)
+	err = store.Append(ctx, suite.GenExtendedHeaders(10)...)
+	require.NoError(t, err)
+	err = store.Prune(ctx, 7)
+	require.NoError(t, err)
+
+	store, err = NewStore(ds)
+	require.NoError(t, err)
+	count, err := store.CountHeaders(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, count)
+
+	err = ds.Delete(storePrefix.Child(countKey))
+	require.NoError(t, err)
+	store, err = NewStore(ds)
+	require.NoError(t, err)
+	count, err = store.CountHeaders(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, count)
+
+	err = store.Append(ctx, suite.GenExtendedHeader())
+	require.NoError(t, err)
+	count, err = store.CountHeaders(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 8, count)
+}
+
+func TestStore_GetByHashPrefixIndexed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	store, err := NewStoreWithHead(ds, suite.Head())
+	require.NoError(t, err)
+	in := suite.GenExtendedHeaders(10)
+	err = store.Append(ctx, in...)
+	require.NoError(t, err)
+
+	// drop the hash index as if the store was created before it was kept
+	res, err := ds.Query(query.Query{Prefix: storePrefix.Child(hashesPrefix).String(), KeysOnly: true})
+	require.NoError(t, err)
+	entries, err := res.Rest()
+	require.NoError(t, err)
+	require.Len(t, entries, 11)
+	for _, e := range entries {
+		require.NoError(t, ds.Delete(datastore.NewKey(e.Key)))
+	}
+	require.NoError(t, ds.Delete(storePrefix.Child(hashesIndexedKey)))
+
+	store, err = NewStore(ds)
+	require.NoError(t, err)
+	for _, h := range in {
+		out, err := store.GetByHashPrefix(ctx, h.Hash()[:4])
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		assert.Equal(t, h.Hash(), out[0].Hash())
+	}
+}
+
+// storeWithGap removes headers in range [from:to) from the store over the given datastore
+// as if they were lost in a crash and reopens the store.
+func storeWithGap(t *testing.T, ds datastore.Batching, from, to uint64) Store {
+	s, err := newStore(ds)
+	require.NoError(t, err)
+	for height := from; height < to; height++ {
+		hash, err := s.index.HashByHeight(height)
+		require.NoError(t, err)
+		require.NoError(t, s.ds.Delete(datastore.NewKey(hash.String())))
+		require.NoError(t, s.ds.Delete(heightKey(height)))
+	}
+	// the count is recounted on the next load
+	require.NoError(t, s.ds.Delete(countKey))
+
+	store, err := NewStore(ds)
+	require.NoError(t, err)
+	return store
+}
+
+// BenchmarkStore_Range compares the memory kept alive while reading a large range of headers
+// at once and with an iterator.
+func BenchmarkStore_Range(b *testing.B) {
+	const amount = 100000
+
+	ctx := context.Background()
+	store, err := newStore(sync.MutexWrap(datastore.NewMapDatastore()))
+	require.NoError(b, err)
+
+	suite := NewTestSuite(b, 3)
+	for i := 0; i < amount; i += 1000 {
+		// verification is not what is benchmarked, so the headers are put directly
+		err = store.put(suite.GenExtendedHeaders(1000)...)
+		require.NoError(b, err)
+	}
+
+	// liveBytes returns the size of the heap still in use after calling f
+	liveBytes := func(f func()) uint64 {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		f()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		if after.HeapAlloc < before.HeapAlloc {
+			return 0
+		}
+		return after.HeapAlloc - before.HeapAlloc
+	}
+
+	b.Run("slice", func(b *testing.B) {
+		b.ReportAllocs()
+		var live uint64
+		for i := 0; i < b.N; i++ {
+			var headers []*ExtendedHeader
+			live += liveBytes(func() {
+				headers, err = store.GetRangeByHeight(ctx, 1, amount+1)
+				require.NoError(b, err)
+			})
+			runtime.KeepAlive(headers)
+		}
+		b.ReportMetric(float64(live)/float64(b.N), "live-B/op")
+	})
+
+	b.Run("iterator", func(b *testing.B) {
+		b.ReportAllocs()
+		var live uint64
+		for i := 0; i < b.N; i++ {
+			var last *ExtendedHeader
+			live += liveBytes(func() {
+				it, err := store.IterateByHeight(ctx, 1, amount+1)
+				require.NoError(b, err)
+				for it.Next() {
+					last = it.Value()
+				}
+				require.NoError(b, it.Close())
+			})
+			runtime.KeepAlive(last)
+		}
+		b.ReportMetric(float64(live)/float64(b.N), "live-B/op")
+	})
+}
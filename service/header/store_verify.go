@@ -0,0 +1,70 @@
+package header
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// ConsistencyError is returned by VerifyStore for the first header breaking the chain of the Store.
+type ConsistencyError struct {
+	// Height is the height of the offending header.
+	Height uint64
+	// Reason describes how the chain is broken.
+	Reason error
+}
+
+func (e *ConsistencyError) Error() string {
+	return fmt.Sprintf("header/store: inconsistent at height %d: %s", e.Height, e.Reason)
+}
+
+func (e *ConsistencyError) Unwrap() error {
+	return e.Reason
+}
+
+// VerifyStore walks the given Store from its tail to its head ensuring every header is stored
+// and links to the previous one. The first broken link is reported with a ConsistencyError.
+func VerifyStore(ctx context.Context, store Store) error {
+	tail, err := store.Tail(ctx)
+	if err != nil {
+		return err
+	}
+	head, err := store.Head(ctx)
+	if err != nil {
+		return err
+	}
+
+	it, err := store.IterateByHeight(ctx, uint64(tail.Height), uint64(head.Height)+1)
+	if err != nil {
+		return err
+	}
+
+	var prev *ExtendedHeader
+	height := uint64(tail.Height)
+	for ; it.Next(); height++ {
+		h := it.Value()
+		switch {
+		case uint64(h.Height) != height:
+			it.Close() //nolint:errcheck
+			return &ConsistencyError{Height: height, Reason: fmt.Errorf("indexed header has height %d", h.Height)}
+		case prev != nil && !bytes.Equal(h.LastHeader(), prev.Hash()):
+			it.Close() //nolint:errcheck
+			return &ConsistencyError{
+				Height: height,
+				Reason: fmt.Errorf("%w: expected parent %X, got %X", ErrForked, prev.Hash(), h.LastHeader()),
+			}
+		}
+		prev = h
+	}
+
+	err = it.Close()
+	switch {
+	case err == nil:
+		log.Infow("verified store", "from", tail.Height, "to", head.Height)
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	default:
+		return &ConsistencyError{Height: height, Reason: err}
+	}
+}
@@ -0,0 +1,55 @@
+package header
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	store, err := NewStore(ds)
+	require.NoError(t, err)
+
+	suite := NewTestSuite(t, 3)
+	in := suite.GenExtendedHeaders(10)
+	err = store.Append(ctx, in...)
+	require.NoError(t, err)
+
+	err = VerifyStore(ctx, store)
+	require.NoError(t, err)
+
+	// replace the header at height 5 with the one of another chain
+	foreign := NewTestSuite(t, 3).GenExtendedHeaders(5)[4]
+	b, err := foreign.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, ds.Put(storePrefix.Child(headerKey(foreign)), b))
+	require.NoError(t, ds.Put(storePrefix.Child(heightKey(5)), foreign.Hash()))
+
+	// reopen the store to drop its caches
+	store, err = NewStore(ds)
+	require.NoError(t, err)
+
+	err = VerifyStore(ctx, store)
+	var cerr *ConsistencyError
+	require.ErrorAs(t, err, &cerr)
+	assert.EqualValues(t, 5, cerr.Height)
+	assert.ErrorIs(t, err, ErrForked)
+
+	// a missing header breaks the chain even earlier
+	require.NoError(t, ds.Delete(storePrefix.Child(heightKey(3))))
+	store, err = NewStore(ds)
+	require.NoError(t, err)
+
+	err = VerifyStore(ctx, store)
+	require.ErrorAs(t, err, &cerr)
+	assert.EqualValues(t, 3, cerr.Height)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,59 @@
+package header
+
+import (
+	"context"
+	"sync"
+)
+
+// HeadBroadcaster delivers new heads of a Store to all its watchers.
+// Publishing never blocks on slow watchers, as only the latest head not yet received is kept for
+// each of them, so slow watchers skip the heads superseded in the meantime.
+// Store implementations use it to serve WatchHead.
+type HeadBroadcaster struct {
+	lk       sync.Mutex
+	watchers map[chan *ExtendedHeader]struct{}
+}
+
+// Watch registers a new watcher, which lives until the given context is canceled.
+func (b *HeadBroadcaster) Watch(ctx context.Context) (<-chan *ExtendedHeader, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	out := make(chan *ExtendedHeader, 1)
+	b.lk.Lock()
+	if b.watchers == nil {
+		b.watchers = make(map[chan *ExtendedHeader]struct{})
+	}
+	b.watchers[out] = struct{}{}
+	b.lk.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.lk.Lock()
+		defer b.lk.Unlock()
+		delete(b.watchers, out)
+		close(out)
+	}()
+	return out, nil
+}
+
+// Publish sends the last of the given headers to all the watchers as the latest head.
+// Callers must publish headers in the order they become heads.
+func (b *HeadBroadcaster) Publish(headers ...*ExtendedHeader) {
+	if len(headers) == 0 {
+		return
+	}
+	head := headers[len(headers)-1]
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	for out := range b.watchers {
+		// drop the stale head the watcher did not read yet
+		select {
+		case <-out:
+		default:
+		}
+		out <- head
+	}
+}
@@ -0,0 +1,116 @@
+package header
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStore_WatchHead tests that watchers receive new heads in order and never twice,
+// ending with the latest one, while the same headers are appended concurrently.
+func TestStore_WatchHead(t *testing.T) {
+	for name, newStore := range testStores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+
+			store := newStore(t)
+			suite := NewTestSuite(t, 3)
+			in := suite.GenExtendedHeaders(50)
+
+			const watchers = 3
+			heads := make([]<-chan *ExtendedHeader, watchers)
+			for i := range heads {
+				var err error
+				heads[i], err = store.WatchHead(ctx)
+				require.NoError(t, err)
+			}
+
+			err := store.AppendSingle(ctx, in[0])
+			require.NoError(t, err)
+
+			// every appender tries to append the whole chain, so most of the appends are rejected
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for _, h := range in[1:] {
+						store.Append(ctx, h) //nolint:errcheck
+					}
+				}()
+			}
+			wg.Wait()
+
+			last := in[len(in)-1]
+			for _, ch := range heads {
+				// the heads superseded before being read are skipped
+				var height int64
+				for height < last.Height {
+					select {
+					case got := <-ch:
+						require.Greater(t, got.Height, height)
+						height = got.Height
+						assert.Equal(t, in[height-1].Hash(), got.Hash())
+					case <-ctx.Done():
+						t.Fatal(ctx.Err())
+					}
+				}
+
+				select {
+				case got := <-ch:
+					t.Fatalf("unexpected head %d", got.Height)
+				case <-time.After(time.Millisecond * 50):
+				}
+			}
+
+			// channels are closed with the context
+			watchCtx, watchCancel := context.WithCancel(ctx)
+			ch, err := store.WatchHead(watchCtx)
+			require.NoError(t, err)
+			watchCancel()
+			select {
+			case _, ok := <-ch:
+				assert.False(t, ok)
+			case <-ctx.Done():
+				t.Fatal(ctx.Err())
+			}
+
+			_, err = store.WatchHead(watchCtx)
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+	}
+}
+
+// TestStore_WatchHead_Slow tests that only the latest head is kept for the watchers not reading.
+func TestStore_WatchHead_Slow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	store := NewMemStore()
+	heads, err := store.WatchHead(ctx)
+	require.NoError(t, err)
+
+	in := NewTestSuite(t, 3).GenExtendedHeaders(10)
+	for _, h := range in {
+		err = store.AppendSingle(ctx, h)
+		require.NoError(t, err)
+	}
+
+	select {
+	case got := <-heads:
+		assert.Equal(t, in[len(in)-1].Hash(), got.Hash())
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+	select {
+	case got := <-heads:
+		t.Fatalf("unexpected head %d", got.Height)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
@@ -39,6 +39,8 @@ func TestSubscriber(t *testing.T) {
 	// subscribe
 	subscription, err := p2pSub1.Subscribe()
 	require.NoError(t, err)
+	headers, err := p2pSub1.SubscribeChan(ctx)
+	require.NoError(t, err)
 
 	// get mock host and create new gossipsub on it
 	pubsub2, err := pubsub.NewGossipSub(ctx, net.Hosts()[1],
@@ -75,5 +77,13 @@ func TestSubscriber(t *testing.T) {
 
 	assert.Equal(t, expectedHeader.Height, header.Height)
 	assert.Equal(t, expectedHeader.Hash(), header.Hash())
-	assert.Equal(t, expectedHeader.DAH.Hash(), header.DAH.Hash())
+	assert.True(t, EqualDataAvailabilityHeaders(expectedHeader.DAH, header.DAH))
+
+	// the same header must be delivered over the channel
+	select {
+	case header = <-headers:
+		assert.Equal(t, expectedHeader.Hash(), header.Hash())
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
 }
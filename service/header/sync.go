@@ -2,7 +2,10 @@ package header
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p-core/peer"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
@@ -10,34 +13,139 @@ import (
 	tmbytes "github.com/tendermint/tendermint/libs/bytes"
 )
 
+// SyncProgress reports a range of headers stored by the Syncer.
+type SyncProgress struct {
+	// From and To define the range [From:To) of stored headers.
+	From, To uint64
+}
+
+// SyncerOption is a functional option that configures Syncer.
+type SyncerOption func(*Syncer)
+
+// WithCheckpoints makes Syncer persist the progress of syncing to the given CheckpointStore,
+// so that interrupted ranges are resumed on the next Sync.
+func WithCheckpoints(cs *CheckpointStore) SyncerOption {
+	return func(s *Syncer) {
+		s.checkpoints = cs
+	}
+}
+
+// WithSyncStateStore makes Syncer persist its SyncState to the given SyncStateStore on Stop
+// and initialize from it on Start.
+func WithSyncStateStore(ss *SyncStateStore) SyncerOption {
+	return func(s *Syncer) {
+		s.stateStore = ss
+	}
+}
+
 // Syncer implements simplest possible synchronization for headers.
+// Besides catching up with the network head, it back-fills gaps in the stored chain of headers.
 type Syncer struct {
-	exchange Exchange
-	store    Store
-	trusted  tmbytes.HexBytes
+	exchange    Exchange
+	store       Store
+	checkpoints *CheckpointStore
+	trusted     tmbytes.HexBytes
+	stateStore  *SyncStateStore
+	progress    chan SyncProgress
+	cancel      context.CancelFunc
+	// done is closed once the syncing routine returns
+	done chan struct{}
+
+	stateLk sync.Mutex
+	state   SyncState
 
 	// inProgress is set to 1 once syncing commences and
 	// is set to 0 once syncing is either finished or
 	// not currently in progress
 	inProgress uint64
+	// networkHeight is the height of the latest head known from the network
+	networkHeight uint64
 }
 
 // NewSyncer creates a new instance of Syncer.
-func NewSyncer(exchange Exchange, store Store, trusted tmbytes.HexBytes) *Syncer {
-	return &Syncer{
+func NewSyncer(exchange Exchange, store Store, trusted tmbytes.HexBytes, opts ...SyncerOption) *Syncer {
+	s := &Syncer{
 		exchange:   exchange,
 		store:      store,
 		trusted:    trusted,
+		progress:   make(chan SyncProgress, 32),
 		inProgress: 0, // syncing is not currently in progress
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// Start starts the syncing routine.
-func (s *Syncer) Start(context.Context) error {
-	go s.Sync(context.TODO()) // TODO @Wondertan: leaving this to you to implement in disconnection toleration PR.
+// Start starts the syncing routine, initializing from the persisted SyncState, if any.
+func (s *Syncer) Start(ctx context.Context) error {
+	if s.stateStore != nil {
+		state, err := s.stateStore.Get(ctx)
+		switch err {
+		case nil:
+			log.Infow("loaded sync state", "last_synced", state.LastSyncedHeight,
+				"last_attempted", state.LastAttemptedHeight)
+			s.stateLk.Lock()
+			s.state = state
+			s.stateLk.Unlock()
+			s.observeNetworkHeight(state.LastAttemptedHeight)
+		case ErrNotFound:
+		default:
+			return err
+		}
+	}
+
+	// the routine left behind by the timed out Stop, if any, closes its own channel
+	ctx, s.cancel = context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.done = done
+	go func() {
+		defer close(done)
+		s.Sync(ctx)
+	}()
 	return nil
 }
 
+// Stop cancels the syncing routine and persists the SyncState, if enabled.
+// The headers the routine is writing to the Store are let to be stored first,
+// so the persisted SyncState never falls behind the Store.
+func (s *Syncer) Stop(ctx context.Context) error {
+	s.cancel()
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if s.stateStore == nil {
+		return nil
+	}
+	head, err := s.store.Head(ctx)
+	switch err {
+	case nil:
+		s.stateLk.Lock()
+		s.state.LastSyncedHeight = uint64(head.Height)
+		s.stateLk.Unlock()
+	case ErrNoHead:
+	default:
+		return err
+	}
+	return s.stateStore.Put(ctx, s.State())
+}
+
+// State returns the current SyncState of the Syncer.
+func (s *Syncer) State() SyncState {
+	s.stateLk.Lock()
+	defer s.stateLk.Unlock()
+	return s.state
+}
+
+// Progress returns the channel every range of synced headers is reported to.
+// Reports are dropped if the channel is not read.
+func (s *Syncer) Progress() <-chan SyncProgress {
+	return s.progress
+}
+
 // Sync syncs all headers up to the latest known header in the network.
 func (s *Syncer) Sync(ctx context.Context) {
 	log.Info("syncing headers")
@@ -46,6 +154,22 @@ func (s *Syncer) Sync(ctx context.Context) {
 	// when method returns, toggle inProgress off
 	defer s.finishSync()
 	// TODO(@Wondertan): Retry logic
+	err := s.resume(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Errorw("resuming interrupted sync", "err", err)
+		}
+		return
+	}
+
+	err = s.fillGaps(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Errorw("filling gaps", "err", err)
+		}
+		return
+	}
+
 	for {
 		localHead, err := s.getHead(ctx)
 		if err != nil {
@@ -58,12 +182,15 @@ func (s *Syncer) Sync(ctx context.Context) {
 			log.Errorw("requesting network head", "err", err)
 			return
 		}
+		s.observeNetworkHeight(uint64(netHead.Height))
 
 		if localHead.Height >= netHead.Height {
 			// we are now synced
+			s.synced(uint64(localHead.Height))
 			log.Info("synced headers")
 			return
 		}
+		s.attempt(uint64(localHead.Height), uint64(netHead.Height))
 
 		err = s.syncDiff(ctx, localHead, netHead)
 		if err != nil {
@@ -78,6 +205,47 @@ func (s *Syncer) IsSyncing() bool {
 	return atomic.LoadUint64(&s.inProgress) == 1
 }
 
+// NetworkHeight returns the height of the latest head known from the network,
+// either requested while syncing or received via gossip. It is zero until any is known.
+func (s *Syncer) NetworkHeight() uint64 {
+	return atomic.LoadUint64(&s.networkHeight)
+}
+
+// observeNetworkHeight raises the known network height to the given one, if higher.
+func (s *Syncer) observeNetworkHeight(height uint64) {
+	for {
+		known := atomic.LoadUint64(&s.networkHeight)
+		if height <= known || atomic.CompareAndSwapUint64(&s.networkHeight, known, height) {
+			return
+		}
+	}
+}
+
+// attempt records the attempt to sync from the given local height up to the given network height.
+// The start of an unfinished attempt, e.g. from before restart, is kept.
+func (s *Syncer) attempt(local, network uint64) {
+	s.stateLk.Lock()
+	defer s.stateLk.Unlock()
+	if s.state.Finished() {
+		s.state.SyncStartedAt = time.Now()
+	} else if local < s.state.LastSyncedHeight {
+		log.Warnw("stored head is behind the last synced height", "head", local,
+			"last_synced", s.state.LastSyncedHeight)
+	}
+	s.state.LastSyncedHeight = local
+	s.state.LastAttemptedHeight = network
+}
+
+// synced records that syncing reached the given height.
+func (s *Syncer) synced(height uint64) {
+	s.stateLk.Lock()
+	defer s.stateLk.Unlock()
+	s.state.LastSyncedHeight = height
+	if s.state.LastAttemptedHeight < height {
+		s.state.LastAttemptedHeight = height
+	}
+}
+
 // syncInProgress indicates Syncer's sync status is in progress.
 func (s *Syncer) syncInProgress() {
 	atomic.StoreUint64(&s.inProgress, 1)
@@ -96,12 +264,19 @@ func (s *Syncer) Validate(ctx context.Context, p peer.ID, msg *pubsub.Message) p
 			"err", err, "peer", p.ShortString())
 		return pubsub.ValidationReject
 	}
+	headersReceived.Add(ctx, 1)
 
 	// if syncing is still in progress - just ignore the new header as
 	// Syncer will fetch it after anyway, but if syncer is done, append
 	// the header.
 	if !s.IsSyncing() {
-		err := s.store.Append(ctx, header)
+		err := s.store.AppendSingle(ctx, header)
+		if errors.Is(err, ErrNotAboveHead) {
+			// stale or duplicate headers are routinely gossiped by honest peers once stored
+			log.Debugw("ignoring header from PubSub not above head",
+				"hash", header.Hash().String(), "height", header.Height, "peer", p.ShortString())
+			return pubsub.ValidationIgnore
+		}
 		if err != nil {
 			log.Errorw("appending store with header from PubSub",
 				"hash", header.Hash().String(), "height", header.Height, "peer", p.ShortString())
@@ -111,6 +286,7 @@ func (s *Syncer) Validate(ctx context.Context, p peer.ID, msg *pubsub.Message) p
 		}
 
 		// we are good to go
+		s.observeNetworkHeight(uint64(header.Height))
 		return pubsub.ValidationAccept
 	}
 
@@ -134,7 +310,8 @@ func (s *Syncer) getHead(ctx context.Context) (*ExtendedHeader, error) {
 			return nil, err
 		}
 
-		err = s.store.Append(ctx, trusted)
+		// not canceled along with syncing, see Stop
+		err = s.store.AppendSingle(context.Background(), trusted)
 		if err != nil {
 			log.Errorw("appending header at trusted hash to store", "err", err)
 			return nil, err
@@ -152,25 +329,174 @@ var requestSize uint64 = 128
 
 // syncDiff requests headers from knownHead up to new head.
 func (s *Syncer) syncDiff(ctx context.Context, knownHead, newHead *ExtendedHeader) error {
-	start, end := uint64(knownHead.Height+1), uint64(newHead.Height)
-	for start < end {
-		amount := end - start
+	err := s.syncRange(ctx, uint64(knownHead.Height+1), uint64(newHead.Height))
+	if err != nil {
+		return err
+	}
+
+	// not canceled along with syncing, see Stop
+	err = s.store.AppendSingle(context.Background(), newHead)
+	if err != nil {
+		return err
+	}
+
+	s.report(uint64(newHead.Height), uint64(newHead.Height+1))
+	return nil
+}
+
+// fillGaps finds ranges of headers missing between the tail and the head of the store
+// and requests them from the network.
+func (s *Syncer) fillGaps(ctx context.Context) error {
+	head, err := s.store.Head(ctx)
+	if err == ErrNoHead {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	tail, err := s.store.Tail(ctx)
+	if err != nil {
+		return err
+	}
+
+	gaps, err := s.findGaps(ctx, uint64(tail.Height), uint64(head.Height))
+	if err != nil {
+		return err
+	}
+
+	for _, gap := range gaps {
+		log.Infow("filling gap", "from", gap[0], "to", gap[1])
+		err = s.syncRange(ctx, gap[0], gap[1])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findGaps returns the ranges [from:to) of headers missing in the store between the given heights.
+// Only the height index is looked up and not even that if the store holds every header in between.
+// Heights deleted with DeleteByHeight are not gaps.
+func (s *Syncer) findGaps(ctx context.Context, tail, head uint64) ([][2]uint64, error) {
+	count, err := s.store.CountHeaders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if count == head-tail+1 {
+		return nil, nil
+	}
+
+	var (
+		gaps  [][2]uint64
+		start uint64
+	)
+	for height := tail + 1; height < head; height++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		has, err := s.store.HasAt(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			// headers proven invalid are deleted on purpose and must not be requested again
+			has, err = s.store.Deleted(ctx, height)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		switch {
+		case has:
+			if start != 0 {
+				gaps, start = append(gaps, [2]uint64{start, height}), 0
+			}
+		case start == 0:
+			start = height
+		}
+	}
+	if start != 0 {
+		gaps = append(gaps, [2]uint64{start, head})
+	}
+	return gaps, nil
+}
+
+// resume finishes the jobs interrupted during the previous Sync.
+func (s *Syncer) resume(ctx context.Context) error {
+	if s.checkpoints == nil {
+		return nil
+	}
+
+	jobs, err := s.checkpoints.Jobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		log.Infow("resuming sync", "from", job.LastCompleted+1, "to", job.To)
+		err = s.syncJob(ctx, job)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncRange requests the range [from:to) of headers in chunks and stores them.
+func (s *Syncer) syncRange(ctx context.Context, from, to uint64) error {
+	return s.syncJob(ctx, SyncJob{From: from, To: to, LastCompleted: from - 1})
+}
+
+// syncJob requests the remaining headers of the job in chunks and stores them,
+// checkpointing the progress after every chunk.
+func (s *Syncer) syncJob(ctx context.Context, job SyncJob) error {
+	for !job.Done() {
+		from := job.LastCompleted + 1
+		amount := job.To - from
 		if amount > requestSize {
 			amount = requestSize
 		}
 
-		headers, err := s.exchange.RequestHeaders(ctx, start, amount)
+		headers, err := s.exchange.RequestHeaders(ctx, from, amount)
 		if err != nil {
 			return err
 		}
 
-		err = s.store.Append(ctx, headers...)
+		// the write is not canceled along with syncing, as the Store may complete it regardless,
+		// so Stop waits for it instead
+		err = s.store.Append(context.Background(), headers...)
 		if err != nil {
 			return err
 		}
 
-		start += amount
+		s.report(from, from+amount)
+		job.LastCompleted = from + amount - 1
+		err = s.checkpoint(ctx, job)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	return s.store.Append(ctx, newHead)
+// checkpoint persists the progress of the job, if checkpoints are enabled.
+// Done jobs are removed.
+func (s *Syncer) checkpoint(ctx context.Context, job SyncJob) error {
+	if s.checkpoints == nil {
+		return nil
+	}
+	if job.Done() {
+		return s.checkpoints.Delete(ctx, job.From)
+	}
+	return s.checkpoints.Put(ctx, job)
+}
+
+// report sends the progress of syncing, unless nobody reads it.
+func (s *Syncer) report(from, to uint64) {
+	select {
+	case s.progress <- SyncProgress{From: from, To: to}:
+	default:
+	}
 }
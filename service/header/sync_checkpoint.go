@@ -0,0 +1,86 @@
+package header
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+)
+
+var checkpointPrefix = datastore.NewKey("sync_checkpoints")
+
+// SyncJob describes the progress of syncing a range [From:To) of headers.
+type SyncJob struct {
+	From, To uint64
+	// LastCompleted is the height of the last stored header of the range.
+	// It is From-1 until the first headers are stored.
+	LastCompleted uint64
+}
+
+// Done reports whether all the headers of the range are stored.
+func (j SyncJob) Done() bool {
+	return j.LastCompleted+1 >= j.To
+}
+
+// CheckpointStore persistently keeps the progress of unfinished SyncJobs,
+// so that Syncer can resume them after restart.
+type CheckpointStore struct {
+	ds datastore.Datastore
+}
+
+// NewCheckpointStore creates a new CheckpointStore over the given datastore.
+func NewCheckpointStore(ds datastore.Datastore) *CheckpointStore {
+	return &CheckpointStore{ds: namespace.Wrap(ds, checkpointPrefix)}
+}
+
+// Put stores the progress of the given job, overriding the previous one.
+func (cs *CheckpointStore) Put(_ context.Context, job SyncJob) error {
+	b := make([]byte, 24)
+	binary.BigEndian.PutUint64(b, job.From)
+	binary.BigEndian.PutUint64(b[8:], job.To)
+	binary.BigEndian.PutUint64(b[16:], job.LastCompleted)
+	return cs.ds.Put(checkpointKey(job.From), b)
+}
+
+// Delete removes the job starting from the given height.
+func (cs *CheckpointStore) Delete(_ context.Context, from uint64) error {
+	return cs.ds.Delete(checkpointKey(from))
+}
+
+// Jobs returns all the stored jobs ordered by their ranges.
+func (cs *CheckpointStore) Jobs(context.Context) ([]SyncJob, error) {
+	res, err := cs.ds.Query(query.Query{})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var jobs []SyncJob
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		if len(r.Value) != 24 {
+			return nil, fmt.Errorf("header/sync: malformed checkpoint %s", r.Key)
+		}
+
+		jobs = append(jobs, SyncJob{
+			From:          binary.BigEndian.Uint64(r.Value),
+			To:            binary.BigEndian.Uint64(r.Value[8:]),
+			LastCompleted: binary.BigEndian.Uint64(r.Value[16:]),
+		})
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].From < jobs[j].From
+	})
+	return jobs, nil
+}
+
+func checkpointKey(from uint64) datastore.Key {
+	return datastore.NewKey(strconv.FormatUint(from, 10))
+}
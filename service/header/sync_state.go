@@ -0,0 +1,63 @@
+package header
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+)
+
+var syncStateKey = datastore.NewKey("sync_state")
+
+// SyncState is the progress of syncing Syncer persists on Stop and initializes from on Start.
+type SyncState struct {
+	// LastSyncedHeight is the height of the stored head once syncing stopped.
+	LastSyncedHeight uint64 `json:"last_synced_height"`
+	// LastAttemptedHeight is the height of the network head the last sync attempted to reach.
+	LastAttemptedHeight uint64 `json:"last_attempted_height"`
+	// SyncStartedAt is the time the attempt to reach LastAttemptedHeight started at.
+	SyncStartedAt time.Time `json:"sync_started_at"`
+}
+
+// Finished reports whether the last attempted sync was completed.
+func (st SyncState) Finished() bool {
+	return st.LastSyncedHeight >= st.LastAttemptedHeight
+}
+
+// SyncStateStore persistently keeps the SyncState between restarts.
+type SyncStateStore struct {
+	ds datastore.Datastore
+}
+
+// NewSyncStateStore creates a new SyncStateStore over the given datastore.
+func NewSyncStateStore(ds datastore.Datastore) *SyncStateStore {
+	return &SyncStateStore{ds: ds}
+}
+
+// Put stores the given SyncState, overriding the previous one.
+func (ss *SyncStateStore) Put(_ context.Context, state SyncState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ss.ds.Put(syncStateKey, b)
+}
+
+// Get returns the stored SyncState or ErrNotFound if none was stored yet.
+func (ss *SyncStateStore) Get(context.Context) (SyncState, error) {
+	b, err := ss.ds.Get(syncStateKey)
+	if err != nil {
+		if err == datastore.ErrNotFound {
+			return SyncState{}, ErrNotFound
+		}
+		return SyncState{}, err
+	}
+
+	var state SyncState
+	err = json.Unmarshal(b, &state)
+	if err != nil {
+		return SyncState{}, err
+	}
+	return state, nil
+}
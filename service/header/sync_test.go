@@ -2,10 +2,14 @@ package header
 
 import (
 	"context"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/sync"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -39,3 +43,308 @@ func TestSync(t *testing.T) {
 	require.Nil(t, err)
 	assert.Equal(t, exp.Height, have.Height)
 }
+
+func TestSync_FillGaps(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	head := suite.Head()
+	in := suite.GenExtendedHeaders(100)
+
+	remoteStore, err := NewStoreWithHead(sync.MutexWrap(datastore.NewMapDatastore()), head)
+	require.NoError(t, err)
+	err = remoteStore.Append(ctx, in...)
+	require.NoError(t, err)
+
+	// the local store is synced, but misses headers 50 to 60
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	localStore, err := NewStoreWithHead(ds, in[0])
+	require.NoError(t, err)
+	err = localStore.Append(ctx, in[1:]...)
+	require.NoError(t, err)
+	localStore = storeWithGap(t, ds, 50, 61)
+
+	requestSize = 4
+	exchange := &recordingExchange{Exchange: NewLocalExchange(remoteStore)}
+	syncer := NewSyncer(exchange, localStore, head.Hash())
+	syncer.Sync(ctx)
+
+	for _, h := range in {
+		out, err := localStore.GetByHeight(ctx, uint64(h.Height))
+		require.NoError(t, err)
+		assert.Equal(t, h.Hash(), out.Hash())
+	}
+	// exactly the gap is requested
+	assert.Equal(t, [][2]uint64{{50, 4}, {54, 4}, {58, 3}}, exchange.requests)
+
+	var progress []SyncProgress
+	for len(syncer.Progress()) > 0 {
+		progress = append(progress, <-syncer.Progress())
+	}
+	assert.Equal(t, []SyncProgress{{50, 54}, {54, 58}, {58, 61}}, progress)
+}
+
+// TestSync_SkipDeleted tests that the headers deleted from the store are not refetched as gaps,
+// even after the Syncer and the store are restarted.
+func TestSync_SkipDeleted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	head := suite.Head()
+	in := suite.GenExtendedHeaders(100)
+
+	remoteStore, err := NewStoreWithHead(sync.MutexWrap(datastore.NewMapDatastore()), head)
+	require.NoError(t, err)
+	err = remoteStore.Append(ctx, in...)
+	require.NoError(t, err)
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	localStore, err := NewStoreWithHead(ds, in[0])
+	require.NoError(t, err)
+	err = localStore.Append(ctx, in[1:]...)
+	require.NoError(t, err)
+	err = localStore.DeleteByHeight(ctx, 50)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		localStore, err = NewStore(ds)
+		require.NoError(t, err)
+
+		exchange := &recordingExchange{Exchange: NewLocalExchange(remoteStore)}
+		syncer := NewSyncer(exchange, localStore, head.Hash())
+		syncer.Sync(ctx)
+		assert.Empty(t, exchange.requests)
+
+		_, err = localStore.GetByHeight(ctx, 50)
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+}
+
+func TestSync_ResumeFromCheckpoint(t *testing.T) {
+	suite := NewTestSuite(t, 3)
+	head := suite.Head()
+	in := suite.GenExtendedHeaders(100)
+
+	remoteStore, err := NewStoreWithHead(sync.MutexWrap(datastore.NewMapDatastore()), head)
+	require.NoError(t, err)
+	err = remoteStore.Append(context.Background(), in...)
+	require.NoError(t, err)
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	localStore, err := NewStoreWithHead(ds, in[0])
+	require.NoError(t, err)
+
+	// kill syncing once three chunks are stored
+	requestSize = 10
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exchange := &killingExchange{Exchange: NewLocalExchange(remoteStore), requests: 3, kill: cancel}
+	syncer := NewSyncer(exchange, localStore, head.Hash(), WithCheckpoints(NewCheckpointStore(ds)))
+	syncer.Sync(ctx)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	jobs, err := NewCheckpointStore(ds).Jobs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []SyncJob{{From: 2, To: 100, LastCompleted: 31}}, jobs)
+
+	// restart over the same datastore
+	localStore, err = NewStore(ds)
+	require.NoError(t, err)
+	for _, h := range in {
+		_, err = localStore.GetByHeight(ctx, uint64(h.Height))
+		if h.Height <= 31 {
+			assert.NoError(t, err, h.Height)
+		} else {
+			assert.ErrorIs(t, err, ErrNotFound, h.Height)
+		}
+	}
+
+	recording := &recordingExchange{Exchange: NewLocalExchange(remoteStore)}
+	syncer = NewSyncer(recording, localStore, head.Hash(), WithCheckpoints(NewCheckpointStore(ds)))
+	syncer.Sync(ctx)
+
+	// syncing resumes right after the last completed height
+	require.NotEmpty(t, recording.requests)
+	assert.Equal(t, [2]uint64{32, 10}, recording.requests[0])
+	for _, h := range in {
+		out, err := localStore.GetByHeight(ctx, uint64(h.Height))
+		require.NoError(t, err)
+		assert.Equal(t, h.Hash(), out.Hash())
+	}
+
+	jobs, err = NewCheckpointStore(ds).Jobs(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestSyncer_Stop(t *testing.T) {
+	suite := NewTestSuite(t, 3)
+	head := suite.Head()
+
+	localStore, err := NewStoreWithHead(sync.MutexWrap(datastore.NewMapDatastore()), head)
+	require.NoError(t, err)
+
+	// the network never responds, so only cancellation can end syncing
+	syncer := NewSyncer(blockingExchange{}, localStore, head.Hash())
+	err = syncer.Start(context.Background())
+	require.NoError(t, err)
+	assert.Eventually(t, syncer.IsSyncing, time.Second, time.Millisecond*10)
+
+	err = syncer.Stop(context.Background())
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return !syncer.IsSyncing()
+	}, time.Second, time.Millisecond*10)
+}
+
+// TestSyncer_ValidateStale tests that Syncer ignores gossiped headers not above the head,
+// instead of rejecting them, as honest peers routinely gossip them.
+func TestSyncer_ValidateStale(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	suite := NewTestSuite(t, 3)
+	head := suite.Head()
+	store, err := NewStoreWithHead(sync.MutexWrap(datastore.NewMapDatastore()), head)
+	require.NoError(t, err)
+	syncer := NewSyncer(NewLocalExchange(store), store, head.Hash())
+
+	gossip := func(h *ExtendedHeader) pubsub.ValidationResult {
+		data, err := h.MarshalBinary()
+		require.NoError(t, err)
+		return syncer.Validate(ctx, "peer", &pubsub.Message{Message: &pubsub_pb.Message{Data: data}})
+	}
+
+	// the gossiped header becomes the head, so it is stale once gossiped again
+	next := suite.GenExtendedHeaders(1)[0]
+	assert.Equal(t, pubsub.ValidationAccept, gossip(next))
+	assert.Equal(t, pubsub.ValidationIgnore, gossip(next))
+	assert.Equal(t, pubsub.ValidationIgnore, gossip(next))
+}
+
+// TestSyncer_PersistState tests that Syncer persists its SyncState on Stop
+// and resumes syncing from it after restart.
+func TestSyncer_PersistState(t *testing.T) {
+	suite := NewTestSuite(t, 3)
+	head := suite.Head()
+	in := suite.GenExtendedHeaders(100)
+	netHead := in[len(in)-1]
+
+	remoteStore, err := NewStoreWithHead(sync.MutexWrap(datastore.NewMapDatastore()), head)
+	require.NoError(t, err)
+	err = remoteStore.Append(context.Background(), in...)
+	require.NoError(t, err)
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	localStore, err := NewStoreWithHead(ds, in[0])
+	require.NoError(t, err)
+
+	// stop syncing once three chunks are stored
+	prevSize := requestSize
+	requestSize = 10
+	t.Cleanup(func() {
+		requestSize = prevSize
+	})
+	stalling := &stallingExchange{Exchange: NewLocalExchange(remoteStore), requests: 3}
+	syncer := NewSyncer(stalling, localStore, head.Hash(), WithSyncStateStore(NewSyncStateStore(ds)))
+	err = syncer.Start(context.Background())
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		h, err := localStore.Head(context.Background())
+		return err == nil && uint64(h.Height) == uint64(in[0].Height)+30
+	}, time.Second, time.Millisecond*10)
+	err = syncer.Stop(context.Background())
+	require.NoError(t, err)
+
+	state, err := NewSyncStateStore(ds).Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(in[0].Height)+30, state.LastSyncedHeight)
+	assert.Equal(t, uint64(netHead.Height), state.LastAttemptedHeight)
+	assert.False(t, state.Finished())
+	assert.False(t, state.SyncStartedAt.IsZero())
+
+	// restart over the same datastore
+	localStore, err = NewStore(ds)
+	require.NoError(t, err)
+	recording := &recordingExchange{Exchange: NewLocalExchange(remoteStore)}
+	syncer = NewSyncer(recording, localStore, head.Hash(), WithSyncStateStore(NewSyncStateStore(ds)))
+	err = syncer.Start(context.Background())
+	require.NoError(t, err)
+	// the network height is known from the state even before the network is requested
+	assert.Equal(t, uint64(netHead.Height), syncer.NetworkHeight())
+	require.Eventually(t, func() bool {
+		h, err := localStore.Head(context.Background())
+		return err == nil && h.Height == netHead.Height
+	}, time.Second, time.Millisecond*10)
+	err = syncer.Stop(context.Background())
+	require.NoError(t, err)
+
+	// syncing resumes right after the last synced height
+	require.NotEmpty(t, recording.requests)
+	assert.Equal(t, state.LastSyncedHeight+1, recording.requests[0][0])
+
+	// the attempt is finished, but still started before restart
+	resumed, err := NewSyncStateStore(ds).Get(context.Background())
+	require.NoError(t, err)
+	assert.True(t, resumed.Finished())
+	assert.Equal(t, uint64(netHead.Height), resumed.LastSyncedHeight)
+	assert.True(t, state.SyncStartedAt.Equal(resumed.SyncStartedAt))
+}
+
+// recordingExchange records origins and amounts of range requests.
+type recordingExchange struct {
+	Exchange
+	requests [][2]uint64
+}
+
+func (r *recordingExchange) RequestHeaders(ctx context.Context, origin, amount uint64) ([]*ExtendedHeader, error) {
+	r.requests = append(r.requests, [2]uint64{origin, amount})
+	return r.Exchange.RequestHeaders(ctx, origin, amount)
+}
+
+// blockingExchange blocks every request until the context is done.
+type blockingExchange struct {
+	Exchange
+}
+
+func (blockingExchange) RequestHead(ctx context.Context) (*ExtendedHeader, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// killingExchange serves the given amount of range requests and then kills syncing
+// by canceling its context.
+type killingExchange struct {
+	Exchange
+	requests int
+	kill     context.CancelFunc
+}
+
+func (k *killingExchange) RequestHeaders(ctx context.Context, origin, amount uint64) ([]*ExtendedHeader, error) {
+	if k.requests == 0 {
+		k.kill()
+		return nil, ctx.Err()
+	}
+	k.requests--
+	return k.Exchange.RequestHeaders(ctx, origin, amount)
+}
+
+// stallingExchange serves the given amount of range requests and then blocks
+// the following ones until their context is done.
+type stallingExchange struct {
+	Exchange
+	requests int32
+	served   int32
+}
+
+func (s *stallingExchange) RequestHeaders(ctx context.Context, origin, amount uint64) ([]*ExtendedHeader, error) {
+	if atomic.LoadInt32(&s.served) == s.requests {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	defer atomic.AddInt32(&s.served, 1)
+	return s.Exchange.RequestHeaders(ctx, origin, amount)
+}
@@ -3,26 +3,38 @@
 package header
 
 import (
+	"bytes"
 	mrand "math/rand"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/tendermint/tendermint/crypto/ed25519"
 	"github.com/tendermint/tendermint/crypto/tmhash"
-	"github.com/tendermint/tendermint/libs/bytes"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
 	tmrand "github.com/tendermint/tendermint/libs/rand"
+	"github.com/tendermint/tendermint/pkg/consts"
 	"github.com/tendermint/tendermint/pkg/da"
+	"github.com/tendermint/tendermint/pkg/wrapper"
 	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
 	"github.com/tendermint/tendermint/proto/tendermint/version"
 	"github.com/tendermint/tendermint/types"
-	tmtime "github.com/tendermint/tendermint/types/time"
+
+	"github.com/celestiaorg/nmt/namespace"
+	"github.com/celestiaorg/rsmt2d"
 )
 
+// testSuiteGenesis is the time of the first header of every TestSuite.
+// Every following header is a second later.
+var testSuiteGenesis = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
 // TestSuite provides everything you need to test chain of Headers.
 // If not, please don't hesitate to extend it for your case.
 type TestSuite struct {
-	t *testing.T
+	t    testing.TB
+	rand *mrand.Rand
 
 	vals    []types.PrivValidator
 	valSet  *types.ValidatorSet
@@ -33,23 +45,51 @@ type TestSuite struct {
 }
 
 // NewTestSuite setups a new test suite with a given number of validators.
-func NewTestSuite(t *testing.T, num int) *TestSuite {
-	valSet, vals := types.RandValidatorSet(num, 10)
-	head := RandExtendedHeader(t)
-	head.NextValidatorsHash = valSet.Hash()
-	head.Height = 0
-	return &TestSuite{
-		t:      t,
-		vals:   vals,
-		valSet: valSet,
-		head:   head,
+// The random seed is logged, so failing tests can be replayed with NewTestSuiteWithSeed.
+func NewTestSuite(t testing.TB, num int) *TestSuite {
+	seed := time.Now().UnixNano()
+	t.Logf("header test suite seed: %d", seed)
+	return NewTestSuiteWithSeed(t, num, seed)
+}
+
+// NewTestSuiteWithSeed setups a new test suite with a given number of validators, generating
+// all the data from the given seed. Suites with the same seed generate the same headers.
+func NewTestSuiteWithSeed(t testing.TB, num int, seed int64) *TestSuite {
+	s := &TestSuite{
+		t:    t,
+		rand: mrand.New(mrand.NewSource(seed)), //nolint:gosec
+	}
+
+	vals := make([]types.PrivValidator, num)
+	validators := make([]*types.Validator, num)
+	for i := range vals {
+		pv := types.NewMockPVWithParams(ed25519.GenPrivKeyFromSecret(s.randBytes(32)), false, false)
+		vals[i] = pv
+		validators[i] = types.NewValidator(pv.PrivKey.PubKey(), 10)
 	}
+	sort.Sort(types.PrivValidatorsByAddress(vals))
+	s.vals, s.valSet = vals, types.NewValidatorSet(validators)
+
+	rh := s.randRawHeader()
+	rh.Height = 0
+	rh.NextValidatorsHash = s.valSet.Hash()
+	dah := EmptyDAH()
+	s.head = &ExtendedHeader{
+		RawHeader:    *rh,
+		Commit:       s.Commit(rh),
+		ValidatorSet: s.valSet,
+		DAH:          &dah,
+	}
+	return s
 }
 
+// Head returns the last generated ExtendedHeader.
 func (s *TestSuite) Head() *ExtendedHeader {
 	return s.head
 }
 
+// GenExtendedHeaders generates a chain of 'num' ExtendedHeaders following the Head.
+// Heights are sequential and every header refers to the Hash of the previous one as its parent.
 func (s *TestSuite) GenExtendedHeaders(num int) []*ExtendedHeader {
 	headers := make([]*ExtendedHeader, num)
 	for i := range headers {
@@ -58,24 +98,51 @@ func (s *TestSuite) GenExtendedHeaders(num int) []*ExtendedHeader {
 	return headers
 }
 
+// GenExtendedHeader generates the next ExtendedHeader of the chain and makes it the Head.
 func (s *TestSuite) GenExtendedHeader() *ExtendedHeader {
 	s.height++
 	dah := da.MinDataAvailabilityHeader()
 	rh := s.GenRawHeader(s.height, s.Head().Hash(), s.Head().Commit.Hash(), dah.Hash())
-	s.head = &ExtendedHeader{
-		RawHeader:    *rh,
-		Commit:       s.Commit(rh),
-		ValidatorSet: s.valSet,
-		DAH:          &dah,
+	eh, err := NewExtendedHeaderFromTendermint(&types.Block{Header: *rh}, s.Commit(rh), s.valSet)
+	require.NoError(s.t, err)
+	s.head = eh
+	return s.head
+}
+
+// GenExtendedHeaderWithNamespace generates the next ExtendedHeader of the chain, like GenExtendedHeader,
+// whose data square contains at least one share of the given namespace.ID, and makes it the Head.
+// The rest of the shares are random and all of them are sorted by namespace, so the NMT row roots are valid.
+func (s *TestSuite) GenExtendedHeaderWithNamespace(t *testing.T, nid namespace.ID) *ExtendedHeader {
+	require.Len(t, nid, consts.NamespaceSize)
+
+	const squareSize = 2
+	shares := make([][]byte, squareSize*squareSize)
+	for i := range shares {
+		shares[i] = s.randBytes(consts.ShareSize)
 	}
+	copy(shares[s.rand.Intn(len(shares))], nid)
+	sort.Slice(shares, func(i, j int) bool {
+		return bytes.Compare(shares[i][:consts.NamespaceSize], shares[j][:consts.NamespaceSize]) < 0
+	})
+
+	tree := wrapper.NewErasuredNamespacedMerkleTree(squareSize)
+	eds, err := rsmt2d.ComputeExtendedDataSquare(shares, rsmt2d.NewRSGF8Codec(), tree.Constructor)
+	require.NoError(t, err)
+	dah := da.NewDataAvailabilityHeader(eds)
+
+	s.height++
+	rh := s.GenRawHeader(s.height, s.Head().Hash(), s.Head().Commit.Hash(), dah.Hash())
+	eh, err := newExtendedHeader(&types.Block{Header: *rh}, s.Commit(rh), s.valSet, eds)
+	require.NoError(t, err)
+	s.head = eh
 	return s.head
 }
 
 func (s *TestSuite) GenRawHeader(
-	height int64, lastHeader, lastCommit, dataHash bytes.HexBytes) *RawHeader {
-	rh := RandRawHeader(s.t)
+	height int64, lastHeader, lastCommit, dataHash tmbytes.HexBytes) *RawHeader {
+	rh := s.randRawHeader()
 	rh.Height = height
-	rh.Time = time.Now()
+	rh.Time = testSuiteGenesis.Add(time.Duration(height) * time.Second)
 	rh.LastBlockID = types.BlockID{Hash: lastHeader}
 	rh.LastCommitHash = lastCommit
 	rh.DataHash = dataHash
@@ -89,7 +156,7 @@ func (s *TestSuite) Commit(h *RawHeader) *types.Commit {
 	bid := types.BlockID{
 		Hash: h.Hash(),
 		// Unfortunately, we still have to commit PartSetHeader even we don't need it in Celestia
-		PartSetHeader: types.PartSetHeader{Total: 1, Hash: tmrand.Bytes(32)},
+		PartSetHeader: types.PartSetHeader{Total: 1, Hash: s.randBytes(32)},
 	}
 	round := int32(0)
 	comms := make([]types.CommitSig, len(s.vals))
@@ -99,7 +166,7 @@ func (s *TestSuite) Commit(h *RawHeader) *types.Commit {
 			ValidatorIndex:   int32(i),
 			Height:           h.Height,
 			Round:            round,
-			Timestamp:        tmtime.Now(),
+			Timestamp:        h.Time,
 			Type:             tmproto.PrecommitType,
 			BlockID:          bid,
 		}
@@ -122,8 +189,37 @@ func (s *TestSuite) nextProposer() *types.Validator {
 	return val
 }
 
+// randRawHeader provides a RawHeader fixture generated from the random source of the suite.
+func (s *TestSuite) randRawHeader() *RawHeader {
+	return &RawHeader{
+		Version: version.Consensus{Block: 11, App: 1},
+		ChainID: "test",
+		Height:  s.rand.Int63(),
+		Time:    testSuiteGenesis,
+		LastBlockID: types.BlockID{
+			Hash:          s.randBytes(32),
+			PartSetHeader: types.PartSetHeader{Total: 123, Hash: s.randBytes(32)},
+		},
+		LastCommitHash:     s.randBytes(32),
+		DataHash:           s.randBytes(32),
+		ValidatorsHash:     s.randBytes(32),
+		NextValidatorsHash: s.randBytes(32),
+		ConsensusHash:      s.randBytes(32),
+		AppHash:            s.randBytes(32),
+		LastResultsHash:    s.randBytes(32),
+		EvidenceHash:       tmhash.Sum([]byte{}),
+		ProposerAddress:    s.randBytes(20),
+	}
+}
+
+func (s *TestSuite) randBytes(n int) []byte {
+	b := make([]byte, n)
+	s.rand.Read(b) //nolint:gosec
+	return b
+}
+
 // RandExtendedHeader provides an ExtendedHeader fixture.
-func RandExtendedHeader(t *testing.T) *ExtendedHeader {
+func RandExtendedHeader(t testing.TB) *ExtendedHeader {
 	rh := RandRawHeader(t)
 	valSet, vals := types.RandValidatorSet(5, 1)
 	voteSet := types.NewVoteSet(rh.ChainID, rh.Height, 0, tmproto.PrecommitType, valSet)
@@ -139,7 +235,7 @@ func RandExtendedHeader(t *testing.T) *ExtendedHeader {
 }
 
 // RandRawHeader provides a RawHeader fixture.
-func RandRawHeader(t *testing.T) *RawHeader {
+func RandRawHeader(t testing.TB) *RawHeader {
 	return &RawHeader{
 		Version:            version.Consensus{Block: 11, App: 1},
 		ChainID:            "test",
@@ -159,7 +255,7 @@ func RandRawHeader(t *testing.T) *RawHeader {
 }
 
 // RandBlockID provides a BlockID fixture.
-func RandBlockID(t *testing.T) types.BlockID {
+func RandBlockID(t testing.TB) types.BlockID {
 	bid := types.BlockID{
 		Hash: make([]byte, 32),
 		PartSetHeader: types.PartSetHeader{
@@ -0,0 +1,256 @@
+// Package testing provides test doubles of the header service for tests of higher-level services.
+package testing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/tendermint/tendermint/libs/bytes"
+
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+// Names of the header.Exchange methods, as recorded by MockExchange.
+const (
+	RequestHead            = "RequestHead"
+	RequestHeader          = "RequestHeader"
+	RequestHeaders         = "RequestHeaders"
+	RequestByHash          = "RequestByHash"
+	RequestHeadersByHashes = "RequestHeadersByHashes"
+	RequestHeaderSince     = "RequestHeaderSince"
+	Probe                  = "Probe"
+)
+
+// ErrUnexpectedCall is returned by MockExchange for calls no Expectation matches.
+var ErrUnexpectedCall = errors.New("header/testing: unexpected call")
+
+// Call is a single call of a MockExchange method.
+type Call struct {
+	// Method is the name of the called method.
+	Method string
+	// Args are the arguments of the call, except the context.
+	Args []interface{}
+}
+
+// Expectation is a call expected by MockExchange along with the response to it.
+type Expectation struct {
+	method  string
+	args    []interface{}
+	headers []*header.ExtendedHeader
+	err     error
+	times   int
+	calls   int
+}
+
+// Return sets the headers to respond to the expected call with.
+// Methods returning a single header respond with the first one.
+func (e *Expectation) Return(headers ...*header.ExtendedHeader) *Expectation {
+	e.headers = headers
+	return e
+}
+
+// ReturnError sets the error to respond to the expected call with.
+func (e *Expectation) ReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Times limits the amount of calls the Expectation matches. By default, it matches any amount.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+func (e *Expectation) matches(method string, args []interface{}) bool {
+	if e.method != method || (e.times > 0 && e.calls >= e.times) {
+		return false
+	}
+	// expectations without arguments match any
+	return e.args == nil || reflect.DeepEqual(e.args, args)
+}
+
+func (e *Expectation) String() string {
+	return fmt.Sprintf("%s%v", e.method, e.args)
+}
+
+// MockExchange is a header.Exchange responding as configured with Expect* methods.
+// Every call is recorded and responded with the first matching Expectation in the order they were set.
+type MockExchange struct {
+	lk           sync.Mutex
+	calls        []Call
+	counts       map[string]int
+	expectations []*Expectation
+	failures     map[string]map[int]error
+}
+
+var _ header.Exchange = (*MockExchange)(nil)
+
+// NewMockExchange creates a new MockExchange without any expectations.
+func NewMockExchange() *MockExchange {
+	return &MockExchange{
+		counts:   make(map[string]int),
+		failures: make(map[string]map[int]error),
+	}
+}
+
+// ExpectRequestHead expects RequestHead to be called.
+func (m *MockExchange) ExpectRequestHead() *Expectation {
+	return m.expect(RequestHead, nil)
+}
+
+// ExpectRequestHeader expects RequestHeader to be called for the given height.
+func (m *MockExchange) ExpectRequestHeader(height uint64) *Expectation {
+	return m.expect(RequestHeader, []interface{}{height})
+}
+
+// ExpectRequestHeaders expects RequestHeaders to be called for the given range.
+func (m *MockExchange) ExpectRequestHeaders(origin, amount uint64) *Expectation {
+	return m.expect(RequestHeaders, []interface{}{origin, amount})
+}
+
+// ExpectRequestByHash expects RequestByHash to be called for the given hash.
+func (m *MockExchange) ExpectRequestByHash(hash bytes.HexBytes) *Expectation {
+	return m.expect(RequestByHash, []interface{}{hash})
+}
+
+// ExpectRequestHeadersByHashes expects RequestHeadersByHashes to be called for the given hashes.
+func (m *MockExchange) ExpectRequestHeadersByHashes(hashes []bytes.HexBytes) *Expectation {
+	return m.expect(RequestHeadersByHashes, []interface{}{hashes})
+}
+
+// ExpectRequestHeaderSince expects RequestHeaderSince to be called for the given hash and amount.
+func (m *MockExchange) ExpectRequestHeaderSince(hash bytes.HexBytes, amount uint64) *Expectation {
+	return m.expect(RequestHeaderSince, []interface{}{hash, amount})
+}
+
+// ExpectProbe expects Probe to be called. Only the error set on the Expectation is responded with.
+func (m *MockExchange) ExpectProbe() *Expectation {
+	return m.expect(Probe, nil)
+}
+
+// ExpectAny expects the given method to be called with any arguments.
+func (m *MockExchange) ExpectAny(method string) *Expectation {
+	return m.expect(method, nil)
+}
+
+// FailAt makes the n-th call of the given method, counting from 1, fail with the given error
+// regardless of expectations.
+func (m *MockExchange) FailAt(method string, n int, err error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if m.failures[method] == nil {
+		m.failures[method] = make(map[int]error)
+	}
+	m.failures[method][n] = err
+}
+
+// Calls returns all the recorded calls in the order they were made.
+func (m *MockExchange) Calls() []Call {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// CallCount returns the amount of calls of the given method.
+func (m *MockExchange) CallCount(method string) int {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return m.counts[method]
+}
+
+// AssertExpectations fails the test if any Expectation limited with Times was not met.
+func (m *MockExchange) AssertExpectations(t testing.TB) {
+	t.Helper()
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	for _, e := range m.expectations {
+		if e.times > 0 && e.calls != e.times {
+			t.Errorf("header/testing: expected %s to be called %d time(s), got %d", e, e.times, e.calls)
+		}
+	}
+}
+
+func (m *MockExchange) RequestHead(context.Context) (*header.ExtendedHeader, error) {
+	return m.single(RequestHead)
+}
+
+func (m *MockExchange) RequestHeader(_ context.Context, height uint64) (*header.ExtendedHeader, error) {
+	return m.single(RequestHeader, height)
+}
+
+func (m *MockExchange) RequestHeaders(_ context.Context, origin, amount uint64) ([]*header.ExtendedHeader, error) {
+	return m.call(RequestHeaders, origin, amount)
+}
+
+func (m *MockExchange) RequestByHash(_ context.Context, hash bytes.HexBytes) (*header.ExtendedHeader, error) {
+	return m.single(RequestByHash, hash)
+}
+
+func (m *MockExchange) RequestHeadersByHashes(
+	_ context.Context,
+	hashes []bytes.HexBytes,
+) ([]*header.ExtendedHeader, error) {
+	return m.call(RequestHeadersByHashes, hashes)
+}
+
+func (m *MockExchange) RequestHeaderSince(
+	_ context.Context,
+	hash bytes.HexBytes,
+	amount uint64,
+) ([]*header.ExtendedHeader, error) {
+	return m.call(RequestHeaderSince, hash, amount)
+}
+
+func (m *MockExchange) Probe(context.Context) error {
+	_, err := m.call(Probe)
+	return err
+}
+
+func (m *MockExchange) expect(method string, args []interface{}) *Expectation {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	e := &Expectation{method: method, args: args}
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// call records the call and responds to it.
+func (m *MockExchange) call(method string, args ...interface{}) ([]*header.ExtendedHeader, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	m.calls = append(m.calls, Call{Method: method, Args: args})
+	m.counts[method]++
+	if err, ok := m.failures[method][m.counts[method]]; ok {
+		return nil, err
+	}
+
+	for _, e := range m.expectations {
+		if !e.matches(method, args) {
+			continue
+		}
+
+		e.calls++
+		if e.err != nil {
+			return nil, e.err
+		}
+		return e.headers, nil
+	}
+	return nil, fmt.Errorf("%w: %s%v", ErrUnexpectedCall, method, args)
+}
+
+// single responds to the call of a method returning a single header.
+func (m *MockExchange) single(method string, args ...interface{}) (*header.ExtendedHeader, error) {
+	headers, err := m.call(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) == 0 {
+		return nil, header.ErrNotFound
+	}
+	return headers[0], nil
+}
@@ -0,0 +1,102 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/libs/bytes"
+
+	"github.com/celestiaorg/celestia-node/service/header"
+)
+
+func TestMockExchange(t *testing.T) {
+	ctx := context.Background()
+	suite := header.NewTestSuite(t, 3)
+	headers := suite.GenExtendedHeaders(5)
+
+	ex := NewMockExchange()
+	ex.ExpectRequestHead().Return(headers[4])
+	ex.ExpectRequestHeader(2).Return(headers[1]).Times(1)
+	ex.ExpectRequestHeaders(1, 3).Return(headers[:3]...)
+	ex.ExpectRequestByHash(headers[2].Hash()).ReturnError(header.ErrNotFound)
+	ex.ExpectAny(RequestHeadersByHashes).Return(headers[3:]...)
+	ex.ExpectRequestHeaderSince(headers[1].Hash(), 2).Return(headers[1:3]...)
+
+	head, err := ex.RequestHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, headers[4], head)
+
+	h, err := ex.RequestHeader(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, headers[1], h)
+
+	// the expectation is met, so the same call is unexpected
+	_, err = ex.RequestHeader(ctx, 2)
+	assert.ErrorIs(t, err, ErrUnexpectedCall)
+	_, err = ex.RequestHeader(ctx, 3)
+	assert.ErrorIs(t, err, ErrUnexpectedCall)
+
+	out, err := ex.RequestHeaders(ctx, 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, headers[:3], out)
+
+	_, err = ex.RequestByHash(ctx, headers[2].Hash())
+	assert.ErrorIs(t, err, header.ErrNotFound)
+
+	hashes := []bytes.HexBytes{headers[3].Hash(), headers[4].Hash()}
+	out, err = ex.RequestHeadersByHashes(ctx, hashes)
+	require.NoError(t, err)
+	assert.Equal(t, headers[3:], out)
+
+	out, err = ex.RequestHeaderSince(ctx, headers[1].Hash(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, headers[1:3], out)
+
+	assert.Equal(t, []Call{
+		{Method: RequestHead},
+		{Method: RequestHeader, Args: []interface{}{uint64(2)}},
+		{Method: RequestHeader, Args: []interface{}{uint64(2)}},
+		{Method: RequestHeader, Args: []interface{}{uint64(3)}},
+		{Method: RequestHeaders, Args: []interface{}{uint64(1), uint64(3)}},
+		{Method: RequestByHash, Args: []interface{}{headers[2].Hash()}},
+		{Method: RequestHeadersByHashes, Args: []interface{}{hashes}},
+		{Method: RequestHeaderSince, Args: []interface{}{headers[1].Hash(), uint64(2)}},
+	}, ex.Calls())
+	assert.Equal(t, 3, ex.CallCount(RequestHeader))
+	ex.AssertExpectations(t)
+}
+
+func TestMockExchange_FailAt(t *testing.T) {
+	ctx := context.Background()
+	suite := header.NewTestSuite(t, 3)
+	head := suite.GenExtendedHeaders(1)[0]
+
+	errFail := errors.New("failed")
+	ex := NewMockExchange()
+	ex.ExpectRequestHead().Return(head)
+	ex.FailAt(RequestHead, 2, errFail)
+
+	for i, expected := range []error{nil, errFail, nil} {
+		h, err := ex.RequestHead(ctx)
+		if expected != nil {
+			assert.ErrorIs(t, err, expected, i)
+			continue
+		}
+		require.NoError(t, err, i)
+		assert.Equal(t, head, h, i)
+	}
+}
+
+func TestMockExchange_AssertExpectations(t *testing.T) {
+	ex := NewMockExchange()
+	ex.ExpectRequestHead().Times(2)
+	_, err := ex.RequestHead(context.Background())
+	assert.ErrorIs(t, err, header.ErrNotFound)
+
+	tt := new(testing.T)
+	ex.AssertExpectations(tt)
+	assert.True(t, tt.Failed())
+}
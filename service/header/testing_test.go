@@ -0,0 +1,52 @@
+package header
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/nmt/namespace"
+)
+
+func TestNewTestSuiteWithSeed(t *testing.T) {
+	gen := func(seed int64) []*ExtendedHeader {
+		return NewTestSuiteWithSeed(t, 3, seed).GenExtendedHeaders(5)
+	}
+
+	a, b, other := gen(42), gen(42), gen(43)
+	for i := range a {
+		require.NoError(t, a[i].ValidateBasic())
+		assert.Equal(t, a[i].Hash(), b[i].Hash())
+		assert.Equal(t, a[i].ValidatorSet.Hash(), b[i].ValidatorSet.Hash())
+		assert.Equal(t, a[i].Commit.Hash(), b[i].Commit.Hash())
+		assert.NotEqual(t, a[i].Hash(), other[i].Hash())
+		assert.NotEqual(t, a[i].ValidatorSet.Hash(), other[i].ValidatorSet.Hash())
+	}
+	// the chain is still valid
+	for i := 1; i < len(a); i++ {
+		assert.NoError(t, VerifyAdjacent(a[i-1], a[i]))
+	}
+}
+
+func TestTestSuite_GenExtendedHeaderWithNamespace(t *testing.T) {
+	suite := NewTestSuite(t, 3)
+	prev := suite.GenExtendedHeader()
+
+	nid := namespace.ID{1, 2, 3, 4, 5, 6, 7, 8}
+	eh := suite.GenExtendedHeaderWithNamespace(t, nid)
+	require.NoError(t, eh.ValidateBasic())
+	assert.NoError(t, VerifyAdjacent(prev, eh))
+	assert.Equal(t, eh, suite.Head())
+
+	// some of the original rows cover the namespace, so it can be looked up by row roots
+	var found bool
+	for _, row := range eh.DAH.RowsRoots[:len(eh.DAH.RowsRoots)/2] {
+		minNID, maxNID := row[:len(nid)], row[len(nid):len(nid)*2]
+		if bytes.Compare(minNID, nid) <= 0 && bytes.Compare(nid, maxNID) <= 0 {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,103 @@
+package header
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tendermint/tendermint/pkg/consts"
+)
+
+// ErrInvalidSquare is returned for headers committing to a data square of invalid dimension.
+var ErrInvalidSquare = errors.New("header: invalid data square")
+
+// Validator validates untrusted ExtendedHeaders received from the network.
+type Validator interface {
+	// Validate checks the untrusted header against the trusted one.
+	// The trusted header may be nil, in which case only the checks not requiring it are performed.
+	Validate(ctx context.Context, untrusted, trusted *ExtendedHeader) error
+}
+
+// ValidatorFunc is an adapter allowing ordinary functions to be used as Validators.
+type ValidatorFunc func(ctx context.Context, untrusted, trusted *ExtendedHeader) error
+
+// Validate calls f(ctx, untrusted, trusted).
+func (f ValidatorFunc) Validate(ctx context.Context, untrusted, trusted *ExtendedHeader) error {
+	return f(ctx, untrusted, trusted)
+}
+
+// DefaultValidator checks that:
+//   - the untrusted header is higher than the trusted one
+//   - the untrusted header links to the trusted one, if they are adjacent
+//   - the commit of the untrusted header is signed by +2/3 of its validator set
+//   - the untrusted header commits to its DataAvailabilityHeader
+//   - the data square is a power of two and does not exceed the protocol maximum
+var DefaultValidator Validator = ValidatorFunc(validate)
+
+// ChainValidators composes the given Validators into one, which runs them in order
+// and fails on the first error.
+func ChainValidators(vs ...Validator) Validator {
+	return ValidatorFunc(func(ctx context.Context, untrusted, trusted *ExtendedHeader) error {
+		for _, v := range vs {
+			if err := v.Validate(ctx, untrusted, trusted); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func validate(_ context.Context, untrusted, trusted *ExtendedHeader) error {
+	if trusted != nil {
+		if untrusted.Height <= trusted.Height {
+			return fmt.Errorf("header height %d is not higher than trusted %d", untrusted.Height, trusted.Height)
+		}
+
+		if untrusted.Height == trusted.Height+1 && !bytes.Equal(untrusted.LastHeader(), trusted.Hash()) {
+			return fmt.Errorf("header at height %d does not link to trusted header: expected parent %X, got %X",
+				untrusted.Height, trusted.Hash(), untrusted.LastHeader())
+		}
+	}
+
+	err := validateSquare(untrusted)
+	if err != nil {
+		return err
+	}
+
+	if valSetHash := untrusted.ValidatorSet.Hash(); !bytes.Equal(untrusted.ValidatorsHash, valSetHash) {
+		return fmt.Errorf("expected validator hash of header to match validator set hash (%X != %X)",
+			untrusted.ValidatorsHash, valSetHash)
+	}
+
+	err = untrusted.ValidatorSet.VerifyCommitLight(untrusted.ChainID, untrusted.Commit.BlockID,
+		untrusted.Height, untrusted.Commit)
+	if err != nil {
+		return err
+	}
+
+	if dahHash := untrusted.DAH.Hash(); !bytes.Equal(untrusted.DataHash, dahHash) {
+		return fmt.Errorf("expected data hash of header to match DAH hash (%X != %X)", untrusted.DataHash, dahHash)
+	}
+	return nil
+}
+
+// validateSquare checks the dimension of the data square the header commits to.
+func validateSquare(eh *ExtendedHeader) error {
+	if eh.DAH == nil || len(eh.DAH.RowsRoots) != len(eh.DAH.ColumnRoots) {
+		return fmt.Errorf("%w: rows and columns do not match", ErrInvalidSquare)
+	}
+
+	dim := eh.SquareDimension()
+	switch {
+	case len(eh.DAH.RowsRoots) != 2*dim:
+		return fmt.Errorf("%w: extended width %d is odd", ErrInvalidSquare, len(eh.DAH.RowsRoots))
+	case dim < consts.MinSquareSize:
+		return fmt.Errorf("%w: dimension %d is below minimum %d", ErrInvalidSquare, dim, consts.MinSquareSize)
+	case dim > consts.MaxSquareSize:
+		return fmt.Errorf("%w: dimension %d exceeds maximum %d", ErrInvalidSquare, dim, consts.MaxSquareSize)
+	case dim&(dim-1) != 0:
+		return fmt.Errorf("%w: dimension %d is not a power of two", ErrInvalidSquare, dim)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package header
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tmrand "github.com/tendermint/tendermint/libs/rand"
+	"github.com/tendermint/tendermint/pkg/consts"
+)
+
+func TestDefaultValidator(t *testing.T) {
+	h := NewTestSuite(t, 2).GenExtendedHeaders(3)
+	tests := []struct {
+		prepare func() (untrusted, trusted *ExtendedHeader)
+		err     bool
+	}{
+		{
+			prepare: func() (*ExtendedHeader, *ExtendedHeader) {
+				return h[1], h[0]
+			},
+			err: false,
+		},
+		{
+			// non-adjacent headers are not linked directly
+			prepare: func() (*ExtendedHeader, *ExtendedHeader) {
+				return h[2], h[0]
+			},
+			err: false,
+		},
+		{
+			// trusted header is unknown
+			prepare: func() (*ExtendedHeader, *ExtendedHeader) {
+				return h[1], nil
+			},
+			err: false,
+		},
+		{
+			prepare: func() (*ExtendedHeader, *ExtendedHeader) {
+				return h[0], h[1]
+			},
+			err: true,
+		},
+		{
+			prepare: func() (*ExtendedHeader, *ExtendedHeader) {
+				return h[1], h[1]
+			},
+			err: true,
+		},
+		{
+			prepare: func() (*ExtendedHeader, *ExtendedHeader) {
+				untrusted := *h[1]
+				untrusted.LastBlockID.Hash = tmrand.Bytes(32)
+				return &untrusted, h[0]
+			},
+			err: true,
+		},
+		{
+			prepare: func() (*ExtendedHeader, *ExtendedHeader) {
+				untrusted := *h[1]
+				untrusted.ValidatorsHash = tmrand.Bytes(32)
+				return &untrusted, nil
+			},
+			err: true,
+		},
+		{
+			prepare: func() (*ExtendedHeader, *ExtendedHeader) {
+				untrusted := *h[1]
+				untrusted.Commit = NewTestSuite(t, 2).Commit(&untrusted.RawHeader)
+				return &untrusted, nil
+			},
+			err: true,
+		},
+		{
+			prepare: func() (*ExtendedHeader, *ExtendedHeader) {
+				untrusted := *h[1]
+				untrusted.DataHash = tmrand.Bytes(32)
+				return &untrusted, nil
+			},
+			err: true,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			untrusted, trusted := test.prepare()
+			err := DefaultValidator.Validate(context.Background(), untrusted, trusted)
+			if test.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDefaultValidator_SquareDimension(t *testing.T) {
+	suite := NewTestSuite(t, 2)
+	// withSquare returns the next header of the chain committing to a random square of the given dimension
+	withSquare := func(dim int) *ExtendedHeader {
+		h := *suite.GenExtendedHeader()
+		dah := DataAvailabilityHeader{
+			RowsRoots:   make([][]byte, dim*2),
+			ColumnRoots: make([][]byte, dim*2),
+		}
+		for i := range dah.RowsRoots {
+			dah.RowsRoots[i], dah.ColumnRoots[i] = tmrand.Bytes(90), tmrand.Bytes(90)
+		}
+		h.DAH = &dah
+		h.DataHash = dah.Hash()
+		h.Commit = suite.Commit(&h.RawHeader)
+		return &h
+	}
+
+	tests := []struct {
+		name string
+		dim  int
+		err  bool
+	}{
+		{name: "minimum", dim: 1},
+		{name: "large", dim: 64},
+		{name: "maximum", dim: consts.MaxSquareSize},
+		{name: "oversized", dim: consts.MaxSquareSize * 2, err: true},
+		{name: "not power of two", dim: 3, err: true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			h := withSquare(test.dim)
+			assert.Equal(t, test.dim, h.SquareDimension())
+
+			err := DefaultValidator.Validate(context.Background(), h, nil)
+			if test.err {
+				assert.ErrorIs(t, err, ErrInvalidSquare)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// headers of the suite commit to the minimal square
+	assert.Equal(t, 1, suite.GenExtendedHeader().SquareDimension())
+}
+
+func TestChainValidators(t *testing.T) {
+	h := NewTestSuite(t, 2).GenExtendedHeaders(2)
+	errFailed := errors.New("failed")
+
+	var called []int
+	record := func(i int, err error) Validator {
+		return ValidatorFunc(func(context.Context, *ExtendedHeader, *ExtendedHeader) error {
+			called = append(called, i)
+			return err
+		})
+	}
+
+	err := ChainValidators(record(0, nil), DefaultValidator, record(1, nil)).Validate(context.Background(), h[1], h[0])
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, called)
+
+	called = nil
+	err = ChainValidators(record(0, errFailed), record(1, nil)).Validate(context.Background(), h[1], h[0])
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, []int{0}, called)
+}
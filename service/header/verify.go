@@ -2,10 +2,83 @@ package header
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"time"
+
+	tmmath "github.com/tendermint/tendermint/libs/math"
+)
+
+// DefaultTrustLevel is the fraction of the trusted voting power which must sign
+// a non-adjacent header for it to be trusted by ExtendedHeader.Verify.
+var DefaultTrustLevel = tmmath.Fraction{Numerator: 1, Denominator: 3}
+
+var (
+	// ErrWrongChain is returned when the header belongs to another chain than the trusted one.
+	ErrWrongChain = errors.New("header: wrong chain")
+	// ErrNotAfterTrusted is returned when the header is not higher or not newer than the trusted one.
+	ErrNotAfterTrusted = errors.New("header: not after trusted header")
+	// ErrForked is returned when an adjacent header does not link to the trusted one.
+	ErrForked = errors.New("header: forked from trusted header")
+	// ErrValidatorsMismatch is returned when the validator set of the header is not the expected one.
+	ErrValidatorsMismatch = errors.New("header: validator set mismatch")
+	// ErrNotEnoughTrust is returned when the trusted validators signed less than DefaultTrustLevel of a header.
+	ErrNotEnoughTrust = errors.New("header: not enough trust")
+	// ErrInvalidCommit is returned when the commit of the header is not signed by +2/3 of its validators.
+	ErrInvalidCommit = errors.New("header: invalid commit")
+	// ErrDataHashMismatch is returned when the header does not commit to its DataAvailabilityHeader.
+	ErrDataHashMismatch = errors.New("header: data hash mismatch")
 )
 
+// Verify performs the light client verification of the header against the trusted one.
+// An adjacent header must link to the trusted one and be signed by the validators the trusted one
+// points to as the next ones, while a non-adjacent header must be signed by at least
+// DefaultTrustLevel of the trusted validators.
+// The returned error wraps one of ErrWrongChain, ErrNotAfterTrusted, ErrForked, ErrValidatorsMismatch,
+// ErrNotEnoughTrust, ErrInvalidCommit and ErrDataHashMismatch.
+func (eh *ExtendedHeader) Verify(trusted *ExtendedHeader) error {
+	if eh.ChainID != trusted.ChainID {
+		return fmt.Errorf("%w: %q, not %q", ErrWrongChain, eh.ChainID, trusted.ChainID)
+	}
+
+	if eh.Height <= trusted.Height {
+		return fmt.Errorf("%w: height %d is not higher than %d", ErrNotAfterTrusted, eh.Height, trusted.Height)
+	}
+	if !eh.Time.After(trusted.Time) {
+		return fmt.Errorf("%w: time %v is not after %v", ErrNotAfterTrusted, eh.Time, trusted.Time)
+	}
+
+	if valSetHash := eh.ValidatorSet.Hash(); !bytes.Equal(eh.ValidatorsHash, valSetHash) {
+		return fmt.Errorf("%w: header validators hash %X, validator set hash %X",
+			ErrValidatorsMismatch, eh.ValidatorsHash, valSetHash)
+	}
+
+	if eh.Height == trusted.Height+1 {
+		if !bytes.Equal(eh.LastHeader(), trusted.Hash()) {
+			return fmt.Errorf("%w: expected parent %X, got %X", ErrForked, trusted.Hash(), eh.LastHeader())
+		}
+		if !bytes.Equal(eh.ValidatorsHash, trusted.NextValidatorsHash) {
+			return fmt.Errorf("%w: expected next validators %X, got %X",
+				ErrValidatorsMismatch, trusted.NextValidatorsHash, eh.ValidatorsHash)
+		}
+	} else {
+		err := trusted.ValidatorSet.VerifyCommitLightTrusting(trusted.ChainID, eh.Commit, DefaultTrustLevel)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrNotEnoughTrust, err)
+		}
+	}
+
+	err := eh.ValidatorSet.VerifyCommitLight(eh.ChainID, eh.Commit.BlockID, eh.Height, eh.Commit)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCommit, err)
+	}
+
+	if dahHash := eh.DAH.Hash(); !bytes.Equal(eh.DataHash, dahHash) {
+		return fmt.Errorf("%w: header data hash %X, DAH hash %X", ErrDataHashMismatch, eh.DataHash, dahHash)
+	}
+	return nil
+}
+
 func VerifyAdjacent(trusted, untrusted *ExtendedHeader) error {
 	if untrusted.Height != trusted.Height+1 {
 		return fmt.Errorf("headers must be adjacent in height")
@@ -44,3 +117,85 @@ func VerifyAdjacent(trusted, untrusted *ExtendedHeader) error {
 
 	return nil
 }
+
+// verifyLink checks that the untrusted header is adjacent to the trusted one and refers to it as its parent.
+func verifyLink(trusted, untrusted *ExtendedHeader) error {
+	if !bytes.Equal(untrusted.LastHeader(), trusted.Hash()) {
+		return fmt.Errorf("header at height %d does not link to the previous header: expected parent %X, got %X",
+			untrusted.Height, trusted.Hash(), untrusted.LastHeader())
+	}
+
+	return VerifyAdjacent(trusted, untrusted)
+}
+
+// VerifyChain verifies the given headers are adjacent, in ascending order of heights,
+// and each of them links to the preceding one by hash.
+func VerifyChain(headers []*ExtendedHeader) error {
+	for i := 1; i < len(headers); i++ {
+		err := verifyLink(headers[i-1], headers[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyReplace verifies the given header may replace the head, linking to the header preceding
+// the head, if known. It reports whether the header differs from the head.
+func VerifyReplace(head, h, prev *ExtendedHeader) (bool, error) {
+	if h.Height != head.Height {
+		return false, fmt.Errorf("%w: head at %d, replacement at %d", ErrHeightMismatch, head.Height, h.Height)
+	}
+	if bytes.Equal(h.Hash(), head.Hash()) {
+		return false, nil
+	}
+	if prev == nil {
+		return true, nil
+	}
+
+	err := verifyLink(prev, h)
+	if err != nil {
+		log.Errorw("invalid header", "height", h.Height, "hash", h.Hash(), "err", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// VerifyAppend verifies the given headers are a continuation of the chain with the given head
+// and returns the valid ones. Headers are verified in order until the first invalid one,
+// while the header at the head's height is skipped.
+func VerifyAppend(head *ExtendedHeader, headers []*ExtendedHeader) []*ExtendedHeader {
+	verified := make([]*ExtendedHeader, 0, len(headers))
+	for _, h := range headers {
+		if head.Height == h.Height {
+			continue
+		}
+
+		err := VerifyAdjacent(head, h)
+		if err != nil {
+			log.Errorw("invalid header", "current head", head.Hash(), "height",
+				head.Height, "attempted new header", h.Hash(), "height", h.Height, "err", err)
+			break // if some headers are cryptographically valid, why not include them? Exactly, so let's include
+		}
+		verified, head = append(verified, h), h
+	}
+	return verified
+}
+
+// VerifyGap verifies the given headers form a chain linking the header preceding them
+// with the following one, if known.
+func VerifyGap(prev *ExtendedHeader, headers []*ExtendedHeader, next *ExtendedHeader) error {
+	if next != nil {
+		headers = append(headers[:len(headers):len(headers)], next)
+	}
+
+	for _, h := range headers {
+		err := verifyLink(prev, h)
+		if err != nil {
+			log.Errorw("invalid header", "height", h.Height, "hash", h.Hash(), "err", err)
+			return err
+		}
+		prev = h
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	tmrand "github.com/tendermint/tendermint/libs/rand"
 )
@@ -71,3 +72,155 @@ func TestVerifyAdjacent(t *testing.T) {
 		})
 	}
 }
+
+func TestTestSuite_GenExtendedHeaders(t *testing.T) {
+	suite := NewTestSuite(t, 3)
+	prev := suite.Head()
+	headers := suite.GenExtendedHeaders(10)
+	require.Len(t, headers, 10)
+	for _, h := range headers {
+		assert.Equal(t, prev.Height+1, h.Height)
+		assert.Equal(t, prev.Hash(), h.LastHeader())
+		assert.NoError(t, verifyLink(prev, h))
+		prev = h
+	}
+	assert.Equal(t, prev, suite.Head())
+
+	// the next batch continues the chain
+	next := suite.GenExtendedHeaders(1)[0]
+	assert.NoError(t, verifyLink(prev, next))
+}
+
+func TestVerifyChain(t *testing.T) {
+	headers := NewTestSuite(t, 3).GenExtendedHeaders(5)
+	assert.NoError(t, VerifyChain(headers))
+	assert.NoError(t, VerifyChain(headers[:1]))
+	assert.NoError(t, VerifyChain(nil))
+
+	// a missing header breaks the chain
+	gap := append([]*ExtendedHeader{}, headers[:2]...)
+	assert.Error(t, VerifyChain(append(gap, headers[3:]...)))
+	// a header from another chain does not link
+	forked := append([]*ExtendedHeader{}, headers...)
+	forked[2] = NewTestSuite(t, 3).GenExtendedHeaders(3)[2]
+	assert.Error(t, VerifyChain(forked))
+}
+
+func TestExtendedHeader_Verify(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare returns the trusted and untrusted headers
+		prepare func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader)
+		err     error
+	}{
+		{
+			name: "valid adjacent",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				return h[0], h[1]
+			},
+		},
+		{
+			name: "valid non-adjacent",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				return h[0], h[4]
+			},
+		},
+		{
+			name: "wrong chain",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				h[1].ChainID = "toaster"
+				return h[0], h[1]
+			},
+			err: ErrWrongChain,
+		},
+		{
+			name: "not higher",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				return h[1], h[0]
+			},
+			err: ErrNotAfterTrusted,
+		},
+		{
+			name: "not newer",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				h[4].Time = h[0].Time
+				return h[0], h[4]
+			},
+			err: ErrNotAfterTrusted,
+		},
+		{
+			name: "invalid signature",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				h[1].Commit.Signatures[0].Signature = tmrand.Bytes(64)
+				return h[0], h[1]
+			},
+			err: ErrInvalidCommit,
+		},
+		{
+			name: "invalid signature non-adjacent",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				h[4].Commit.Signatures[0].Signature = tmrand.Bytes(64)
+				return h[0], h[4]
+			},
+			err: ErrNotEnoughTrust,
+		},
+		{
+			name: "forked chain",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				h[1].LastBlockID.Hash = tmrand.Bytes(32)
+				return h[0], h[1]
+			},
+			err: ErrForked,
+		},
+		{
+			name: "forked chain non-adjacent",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				// another chain with the same ID, but other validators
+				fork := NewTestSuite(t, 3).GenExtendedHeaders(5)
+				return h[0], fork[4]
+			},
+			err: ErrNotEnoughTrust,
+		},
+		{
+			name: "unexpected validators",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				h[0].NextValidatorsHash = tmrand.Bytes(32)
+				return h[0], h[1]
+			},
+			err: ErrValidatorsMismatch,
+		},
+		{
+			name: "validator set mismatch",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				h[1].ValidatorsHash = tmrand.Bytes(32)
+				return h[0], h[1]
+			},
+			err: ErrValidatorsMismatch,
+		},
+		{
+			name: "data hash mismatch",
+			prepare: func(h []*ExtendedHeader) (*ExtendedHeader, *ExtendedHeader) {
+				// the DAH is not signed, so the commit stays valid
+				h[1].DAH = &DataAvailabilityHeader{
+					RowsRoots:   [][]byte{tmrand.Bytes(32)},
+					ColumnRoots: [][]byte{tmrand.Bytes(32)},
+				}
+				return h[0], h[1]
+			},
+			err: ErrDataHashMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			trusted, untrusted := test.prepare(NewTestSuite(t, 3).GenExtendedHeaders(5))
+			err := untrusted.Verify(trusted)
+			if test.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
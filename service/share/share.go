@@ -2,6 +2,8 @@ package share
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"math/rand"
 
@@ -20,6 +22,10 @@ import (
 
 var log = logging.Logger("share")
 
+// ErrInvalidNamespaceProof is returned when Shares of a namespace cannot be proven to be committed to the Root
+// completely.
+var ErrInvalidNamespaceProof = errors.New("share: invalid namespace proof")
+
 // TODO(@Wondertan): We prefix real data of shares with namespaces to be able to recover them during erasure coding
 //  recovery. However, that is storage and bandwidth overhead(8 bytes per each share) which we can avoid by getting
 //  namespaces from CIDs stored in IPLD NMT Nodes, instead of encoding namespaces in erasure coding.
@@ -134,50 +140,87 @@ func (s *service) GetShares(context.Context, *Root) ([][]Share, error) {
 }
 
 func (s *service) GetSharesByNamespace(ctx context.Context, root *Root, nID namespace.ID) ([]Share, error) {
-	rowRootCIDs := make([]cid.Cid, 0)
+	rowRoots := make([][]byte, 0)
 	for _, row := range root.RowsRoots {
 		if !nID.Less(nmt.MinNamespace(row, nID.Size())) && nID.LessOrEqual(nmt.MaxNamespace(row, nID.Size())) {
-			rowRootCIDs = append(rowRootCIDs, plugin.MustCidFromNamespacedSha256(row))
+			rowRoots = append(rowRoots, row)
 		}
 	}
-	if len(rowRootCIDs) == 0 {
+	if len(rowRoots) == 0 {
 		return nil, ipld.ErrNotFoundInRange
 	}
 
 	type res struct {
-		nodes []format.Node
-		err   error
+		idx    int
+		shares []Share
+		err    error
 	}
 	resultCh := make(chan *res)
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	for _, rootCID := range rowRootCIDs {
-		go func(rootCID cid.Cid) {
-			nodes, err := ipld.GetLeavesByNamespace(ctx, s.dag, rootCID, nID)
-			resultCh <- &res{nodes: nodes, err: err}
-		}(rootCID)
+	for i, rowRoot := range rowRoots {
+		go func(i int, rowRoot []byte) {
+			shares, err := getSharesByNamespace(ctx, s.dag, rowRoot, nID, len(root.RowsRoots))
+			select {
+			case resultCh <- &res{idx: i, shares: shares, err: err}:
+			case <-ctx.Done():
+			}
+		}(i, rowRoot)
 	}
 
-	namespacedShares := make([]Share, 0)
-	for i := 0; i < len(rowRootCIDs); i++ {
+	// keep the shares in the order of rows
+	rowShares := make([][]Share, len(rowRoots))
+	for range rowRoots {
 		select {
 		case result := <-resultCh:
 			if result.err != nil {
 				return nil, result.err
 			}
-			for _, node := range result.nodes {
-				namespacedShares = append(namespacedShares, node.RawData()[1:])
-			}
+			rowShares[result.idx] = result.shares
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		}
 	}
 
+	namespacedShares := make([]Share, 0)
+	for _, shares := range rowShares {
+		namespacedShares = append(namespacedShares, shares...)
+	}
 	return namespacedShares, nil
 }
 
+// getSharesByNamespace loads all the Shares of the given namespace.ID committed to the given row root
+// and verifies their inclusion and completeness against it.
+func getSharesByNamespace(
+	ctx context.Context,
+	dag format.NodeGetter,
+	rowRoot []byte,
+	nID namespace.ID,
+	width int,
+) ([]Share, error) {
+	rootCid := plugin.MustCidFromNamespacedSha256(rowRoot)
+	nodes, proof, err := ipld.GetLeavesByNamespaceWithProof(ctx, dag, rootCid, nID, width)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	shares, leaves := make([]Share, len(nodes)), make([][]byte, len(nodes))
+	for i, nd := range nodes {
+		// we exclude one byte, as it is not part of the share, but encoding of IPLD NMT Node type.
+		shares[i] = nd.RawData()[1:]
+		leaves[i] = shares[i]
+	}
+	if !proof.VerifyNamespace(sha256.New(), nID, leaves, rowRoot) {
+		return nil, ErrInvalidNamespaceProof
+	}
+	return shares, nil
+}
+
 // translate transforms square coordinates into IPLD NMT tree path to a leaf node.
 // It also adds randomization to evenly spread fetching from Rows and Columns.
 func translate(dah *Root, row, col int) (cid.Cid, int) {
@@ -4,9 +4,17 @@ import (
 	"context"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	mdutils "github.com/ipfs/go-merkledag/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/celestiaorg/celestia-node/ipld"
+	"github.com/celestiaorg/celestia-node/ipld/plugin"
+	"github.com/celestiaorg/nmt/namespace"
 )
 
 func TestGetShare(t *testing.T) {
@@ -68,6 +76,46 @@ func TestService_GetSharesByNamespaceNotFoundInRange(t *testing.T) {
 	require.Error(t, err, "namespaceID not found in range")
 }
 
+func TestService_GetSharesByNamespaceInvalidProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	dag := mdutils.Mock()
+	shares := RandShares(t, 16)
+	nID := namespace.ID(shares[5][:ipld.NamespaceSize])
+	root := FillDAG(t, shares, dag)
+
+	// find the leaf of the namespace
+	rowRoot := plugin.MustCidFromNamespacedSha256(root.RowsRoots[1])
+	leaf, err := ipld.GetLeaf(ctx, dag, rowRoot, 1, len(root.RowsRoots))
+	require.NoError(t, err)
+
+	// serve a forged share in place of the real one
+	forged := make([]byte, len(leaf.RawData())-1)
+	copy(forged, leaf.RawData()[1:])
+	forged[len(forged)-1]++
+	getter := &forgingGetter{NodeGetter: dag, forged: plugin.NewNMTLeafNode(leaf.Cid(), forged)}
+
+	serv := &service{dag: dag}
+	_, err = serv.GetSharesByNamespace(ctx, root, nID)
+	require.NoError(t, err)
+	_, err = getSharesByNamespace(ctx, getter, root.RowsRoots[1], nID, len(root.RowsRoots))
+	assert.ErrorIs(t, err, ErrInvalidNamespaceProof)
+}
+
+// forgingGetter serves the forged node in place of the original one with the same CID.
+type forgingGetter struct {
+	format.NodeGetter
+	forged format.Node
+}
+
+func (fg *forgingGetter) Get(ctx context.Context, id cid.Cid) (format.Node, error) {
+	if id.Equals(fg.forged.Cid()) {
+		return fg.forged, nil
+	}
+	return fg.NodeGetter.Get(ctx, id)
+}
+
 func BenchmarkService_GetSharesByNamespace(b *testing.B) {
 	var tests = []struct {
 		amountShares int
@@ -91,3 +139,37 @@ func BenchmarkService_GetSharesByNamespace(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkService_GetSharesByNamespaceSize(b *testing.B) {
+	const squareSize = 16
+	var tests = []struct {
+		namespaceSize int
+	}{
+		{namespaceSize: 1},
+		{namespaceSize: 8},
+		{namespaceSize: 32},
+		{namespaceSize: 128},
+	}
+
+	for _, tt := range tests {
+		b.Run(strconv.Itoa(tt.namespaceSize), func(b *testing.B) {
+			t := &testing.T{}
+			shares := RandShares(t, squareSize*squareSize)
+			// shares are sorted by namespaces, so a run of shares keeps them sorted
+			nID := namespace.ID(shares[0][:ipld.NamespaceSize:ipld.NamespaceSize])
+			for _, share := range shares[:tt.namespaceSize] {
+				copy(share[:ipld.NamespaceSize], nID)
+			}
+
+			dag := mdutils.Mock()
+			root := FillDAG(t, shares, dag)
+			serv := NewService(dag, NewLightAvailability(dag))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				shares, err := serv.GetSharesByNamespace(context.Background(), root, nID)
+				require.NoError(b, err)
+				require.Len(b, shares, tt.namespaceSize)
+			}
+		})
+	}
+}
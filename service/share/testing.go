@@ -36,8 +36,12 @@ func RandServiceWithSquare(t *testing.T, n int) (Service, *Root) {
 }
 
 func RandFillDAG(t *testing.T, n int, dag format.DAGService) *Root {
-	shares := RandShares(t, n*n)
-	sharesSlices := make([][]byte, n*n)
+	return FillDAG(t, RandShares(t, n*n), dag)
+}
+
+// FillDAG erasure codes the given Shares and stores NMT trees of the resulting square in the DAG.
+func FillDAG(t *testing.T, shares []Share, dag format.DAGService) *Root {
+	sharesSlices := make([][]byte, len(shares))
 	for i, share := range shares {
 		sharesSlices[i] = share
 	}